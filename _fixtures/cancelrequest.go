@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+func slow() int {
+	time.Sleep(time.Hour)
+	return 1
+}
+
+func main() {
+	runtime.Breakpoint()
+	fmt.Println(slow)
+}
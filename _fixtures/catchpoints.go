@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+var stage int
+
+type myError struct {
+	msg string
+}
+
+func (err *myError) Error() string {
+	return err.msg
+}
+
+func worker(done chan struct{}) {
+	close(done)
+}
+
+func recovered() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = r.(error)
+		}
+	}()
+	panic(&myError{"recovered"})
+}
+
+func main() {
+	stage = 1
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1)
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	<-sigs
+
+	stage = 2
+	f, err := os.Open("/dev/null")
+	if err == nil {
+		f.Close()
+	}
+
+	stage = 3
+	done := make(chan struct{})
+	go worker(done)
+	<-done
+
+	stage = 4
+	fmt.Println(recovered())
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type Endpoint struct {
+	Host string
+	Port int
+}
+
+type Server struct {
+	Endpoint
+	Name    string
+	Backups []*Endpoint
+}
+
+type Config struct {
+	Servers   []Server
+	Primary   *Server
+	RetryMax  int
+	retryBase int
+}
+
+func (cfg *Config) Retries() int {
+	return cfg.RetryMax - cfg.retryBase
+}
+
+func (cfg Config) Describe() string {
+	return fmt.Sprintf("%d servers", len(cfg.Servers))
+}
+
+var configCount = 1
+var configName = "default"
+
+func main() {
+	config := Config{
+		Servers:  []Server{{Endpoint: Endpoint{Host: "localhost", Port: 8080}, Name: "main"}},
+		RetryMax: 3,
+	}
+	config.Primary = &config.Servers[0]
+	configPtr := &config
+	runtime.Breakpoint()
+	fmt.Println(config.Describe(), configPtr.Retries(), configCount, configName)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+)
+
+type node struct {
+	next *node
+	val  int
+}
+
+var head *node
+var objs []interface{}
+var addr uintptr
+
+func main() {
+	leaf := &node{val: 3}
+	head = &node{val: 1, next: &node{val: 2, next: leaf}}
+	objs = []interface{}{leaf}
+	buf := make([]byte, 16)
+	hidden := &node{val: 4}
+	addr = uintptr(unsafe.Pointer(hidden))
+	runtime.Breakpoint()
+	fmt.Println(head, leaf, buf, hidden)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type ID struct {
+	prefix string
+	n      int
+}
+
+type Money struct {
+	units int64
+	cents int64
+}
+
+type Account struct {
+	id      ID
+	balance Money
+	owner   string
+}
+
+func main() {
+	id := ID{"acct", 42}
+	acct := Account{ID{"acct", 7}, Money{12, 5}, "alice"}
+	ids := []ID{{"a", 1}, {"b", 2}}
+	pid := &id
+	runtime.Breakpoint()
+	fmt.Println(id, acct, ids, pid)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type Point struct {
+	X, Y int
+}
+
+var primes = []int{2, 3, 5, 7}
+
+func getPrimes() []int {
+	return primes
+}
+
+var origin Point
+
+func getOrigin() *Point {
+	return &origin
+}
+
+func main() {
+	pts := []Point{{1, 2}, {3, 4}}
+	p := &Point{5, 6}
+	s := []int{1, 2, 3}
+	str := "hello"
+	runtime.Breakpoint()
+	fmt.Println(pts, p, s, str, getPrimes, getOrigin)
+}
@@ -0,0 +1,21 @@
+package main
+
+import "fmt"
+
+func g(x int) int {
+	return x * 2
+}
+
+func h(y int) int {
+	return y + 1
+}
+
+func f(a, b int) int {
+	return a - b
+}
+
+func main() {
+	x, y := 3, 4
+	r := f(g(x), h(y))
+	fmt.Println(r)
+}
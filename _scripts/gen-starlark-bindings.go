@@ -269,6 +269,7 @@ func genDocs(bindings []binding) []byte {
 	fmt.Fprintf(&buf, "write_file(path, contents) | Writes string to a file\n")
 	fmt.Fprintf(&buf, "cur_scope() | Returns the current evaluation scope\n")
 	fmt.Fprintf(&buf, "default_load_config() | Returns the current default load configuration\n")
+	fmt.Fprintf(&buf, "pretty_printer(Type, Fn, Regexp) | Registers Fn as the pretty printer of the variables of type Type\n")
 
 	return buf.Bytes()
 }
@@ -22,6 +22,7 @@ import (
 	"github.com/undoio/delve/pkg/gobuild"
 	"github.com/undoio/delve/pkg/goversion"
 	"github.com/undoio/delve/pkg/logflags"
+	"github.com/undoio/delve/pkg/proc"
 	"github.com/undoio/delve/pkg/proc/gdbserial"
 	"github.com/undoio/delve/pkg/terminal"
 	"github.com/undoio/delve/pkg/version"
@@ -160,6 +161,9 @@ func New(docCall bool) *cobra.Command {
 This command will cause Delve to take control of an already running process, and
 begin a new debug session.  When exiting the debug session you will have the
 option to let the process continue or kill it.
+
+With --backend=undo the process is recorded with LiveRecorder until the
+recording is stopped (by pressing ctrl-C), Delve then replays the recording.
 `,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
@@ -408,6 +412,7 @@ are:
 	native		Native backend.
 	lldb		Uses lldb-server or debugserver.
 	rr		Uses mozilla rr (https://github.com/mozilla/rr).
+	undo		Uses LiveRecorder (https://undo.io).
 
 `})
 
@@ -503,6 +508,12 @@ func dapCmd(cmd *cobra.Command, args []string) {
 			fmt.Fprintf(os.Stderr, "Warning: program flags ignored with dap; specify via launch/attach request instead\n")
 		}
 
+		prettyPrinters, err := conf.GetPrettyPrinters()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+
 		disconnectChan := make(chan struct{})
 		config := &service.Config{
 			DisconnectChan: disconnectChan,
@@ -512,6 +523,7 @@ func dapCmd(cmd *cobra.Command, args []string) {
 				DebugInfoDirectories: conf.DebugInfoDirectories,
 				CheckGoVersion:       checkGoVersion,
 				DisableASLR:          disableASLR,
+				PrettyPrinters:       prettyPrinters,
 			},
 			CheckLocalConnUser: checkLocalConnUser,
 		}
@@ -986,6 +998,12 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 		return 1
 	}
 
+	prettyPrinters, err := conf.GetPrettyPrinters()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
 	var listener net.Listener
 	var clientConn net.Conn
 
@@ -1031,9 +1049,13 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 				DebugInfoDirectories: conf.DebugInfoDirectories,
 				CheckGoVersion:       checkGoVersion,
 				TTY:                  tty,
-				Redirects:            redirects,
+				Stdin:                redirects[0],
+				Stdout:               proc.OutputRedirect{Path: redirects[1]},
+				Stderr:               proc.OutputRedirect{Path: redirects[2]},
 				DisableASLR:          disableASLR,
 				RrOnProcessPid:       rrOnProcessPid,
+				PrettyPrinters:       prettyPrinters,
+				SubstitutePath:       conf.SubstitutePath.Pairs(),
 			},
 		})
 	default:
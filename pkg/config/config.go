@@ -7,8 +7,10 @@ import (
 	"os"
 	"os/user"
 	"path"
+	"regexp"
 	"runtime"
 
+	"github.com/undoio/delve/pkg/proc"
 	"github.com/undoio/delve/service/api"
 	"gopkg.in/yaml.v2"
 )
@@ -34,6 +36,27 @@ type SubstitutePathRule struct {
 // SubstitutePathRules is a slice of source code path substitution rules.
 type SubstitutePathRules []SubstitutePathRule
 
+// Pairs returns the rules as pairs of From and To paths.
+func (rules SubstitutePathRules) Pairs() [][2]string {
+	r := make([][2]string, 0, len(rules))
+	for _, rule := range rules {
+		r = append(r, [2]string{rule.From, rule.To})
+	}
+	return r
+}
+
+// PrettyPrinter describes how the values of a type are displayed.
+type PrettyPrinter struct {
+	// Name of the type formatted by this pretty printer.
+	Type string `yaml:"type,omitempty"`
+	// Regular expression matching the names of the types formatted by this
+	// pretty printer, used instead of Type.
+	TypeRegexp string `yaml:"type-regexp,omitempty"`
+	// Expression evaluated to format a value, the value is bound to the
+	// identifier 'v'.
+	Expr string `yaml:"expr"`
+}
+
 // Config defines all configuration options available to be set through the config file.
 type Config struct {
 	// Commands aliases.
@@ -106,6 +129,10 @@ type Config struct {
 	// TraceShowTimestamp controls whether to show timestamp in the trace
 	// output.
 	TraceShowTimestamp bool `yaml:"trace-show-timestamp"`
+
+	// PrettyPrinters are used to format the values of variables of the
+	// types they match.
+	PrettyPrinters []PrettyPrinter `yaml:"pretty-printers,omitempty"`
 }
 
 func (c *Config) GetSourceListLineCount() int {
@@ -131,6 +158,33 @@ func (c *Config) GetDisassembleFlavour() api.AssemblyFlavour {
 	}
 }
 
+// GetPrettyPrinters returns the pretty printers of the configuration,
+// compiling their type regular expressions.
+func (c *Config) GetPrettyPrinters() ([]proc.PrettyPrinter, error) {
+	if c == nil || len(c.PrettyPrinters) == 0 {
+		return nil, nil
+	}
+	r := make([]proc.PrettyPrinter, 0, len(c.PrettyPrinters))
+	for _, pp := range c.PrettyPrinters {
+		if pp.Expr == "" {
+			return nil, fmt.Errorf("pretty printer for %q has no expression", pp.Type+pp.TypeRegexp)
+		}
+		ppp := proc.PrettyPrinter{Type: pp.Type, Expr: pp.Expr}
+		switch {
+		case pp.TypeRegexp != "":
+			re, err := regexp.Compile(pp.TypeRegexp)
+			if err != nil {
+				return nil, fmt.Errorf("invalid type regexp of pretty printer: %v", err)
+			}
+			ppp.TypeRegexp = re
+		case pp.Type == "":
+			return nil, fmt.Errorf("pretty printer for %q has no type", pp.Expr)
+		}
+		r = append(r, ppp)
+	}
+	return r, nil
+}
+
 // LoadConfig attempts to populate a Config object from the config.yml file.
 func LoadConfig() (*Config, error) {
 	err := createConfigPath()
@@ -303,6 +357,12 @@ substitute-path:
 
 # List of directories to use when searching for separate debug info files.
 debug-info-directories: ["/usr/lib/debug/.build-id"]
+
+# Pretty printers format the values of the matching types with an expression,
+# the value being formatted is bound to the identifier 'v'.
+# pretty-printers:
+#   - {type: main.UserID, expr: v.name}
+#   - {type-regexp: '^main\.\w+Error$', expr: v.msg}
 `)
 	return err
 }
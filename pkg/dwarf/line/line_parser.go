@@ -58,6 +58,9 @@ type FileEntry struct {
 	DirIdx      uint64
 	LastModTime uint64
 	Length      uint64
+	// Source is the contents of the file, if they are embedded in the
+	// line table.
+	Source string
 }
 
 type DebugLines []*DebugLineInfo
@@ -287,11 +290,10 @@ func parseFileEntries5(info *DebugLineInfo, buf *bytes.Buffer) bool {
 			entry = new(FileEntry)
 		)
 
+		diridx = -1
 		fileEntryFormReader.reset()
 
 		for fileEntryFormReader.next(buf) {
-			diridx = -1
-
 			switch fileEntryFormReader.contentType {
 			case _DW_LNCT_path:
 				switch fileEntryFormReader.formCode {
@@ -311,6 +313,16 @@ func parseFileEntries5(info *DebugLineInfo, buf *bytes.Buffer) bool {
 				entry.Length = fileEntryFormReader.u64
 			case _DW_LNCT_MD5:
 				// not implemented
+			case _DW_LNCT_LLVM_source:
+				switch fileEntryFormReader.formCode {
+				case _DW_FORM_string:
+					entry.Source = fileEntryFormReader.str
+				case _DW_FORM_line_strp:
+					buf := bytes.NewBuffer(info.debugLineStr[fileEntryFormReader.u64:])
+					entry.Source, _ = dwarf.ReadString(buf)
+				default:
+					info.Logf("unsupported string form %#x", fileEntryFormReader.formCode)
+				}
 			}
 		}
 		if fileEntryFormReader.err != nil {
@@ -1,10 +1,12 @@
 package line
 
 import (
+	"bytes"
 	"compress/zlib"
 	"debug/elf"
 	"debug/macho"
 	"debug/pe"
+	"encoding/binary"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -18,6 +20,7 @@ import (
 	"unsafe"
 
 	"github.com/undoio/delve/pkg/dwarf/godwarf"
+	"github.com/undoio/delve/pkg/dwarf/leb128"
 	"github.com/undoio/delve/pkg/goversion"
 )
 
@@ -379,3 +382,48 @@ func TestDebugLineDwarf4(t *testing.T) {
 	}
 
 }
+
+func TestEmbeddedSource(t *testing.T) {
+	// Checks that the contents of source files embedded in a DWARF 5 line
+	// table with DW_LNCT_LLVM_source are read.
+	const src = "int main(void) {\n\treturn 0;\n}\n"
+
+	hdr := new(bytes.Buffer)
+	hdr.Write([]byte{1, 1, 1, 0xfb, 14, 13})              // min_inst_length, max_ops_per_inst, default_is_stmt, line_base, line_range, opcode_base
+	hdr.Write([]byte{0, 1, 1, 1, 1, 0, 0, 0, 1, 0, 0, 1}) // standard_opcode_lengths
+	hdr.Write([]byte{1, _DW_LNCT_path, _DW_FORM_string})  // directory_entry_format
+	hdr.WriteByte(1)                                      // directories_count
+	hdr.WriteString("/build\x00")
+	hdr.Write([]byte{3, _DW_LNCT_path, _DW_FORM_string}) // file_name_entry_format
+	hdr.Write([]byte{_DW_LNCT_directory_index, _DW_FORM_udata})
+	leb128.EncodeUnsigned(hdr, _DW_LNCT_LLVM_source)
+	leb128.EncodeUnsigned(hdr, _DW_FORM_string)
+	hdr.WriteByte(2) // file_names_count
+	hdr.WriteString("main.c\x00\x00" + src + "\x00")
+	hdr.WriteString("other.c\x00\x00\x00")
+
+	unit := new(bytes.Buffer)
+	binary.Write(unit, binary.LittleEndian, uint16(5)) // version
+	unit.Write([]byte{8, 0})                           // address_size, segment_selector_size
+	binary.Write(unit, binary.LittleEndian, uint32(hdr.Len()))
+	unit.Write(hdr.Bytes())
+
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, uint32(unit.Len()))
+	buf.Write(unit.Bytes())
+
+	dbl := Parse("", buf, nil, nil, 0, false, 8)
+	if dbl == nil {
+		t.Fatal("could not parse line table")
+	}
+	entry := dbl.Lookup["/build/main.c"]
+	if entry == nil {
+		t.Fatalf("/build/main.c not found in %v", dbl.FileNames)
+	}
+	if entry.Source != src {
+		t.Errorf("wrong source for %s: %q", entry.Path, entry.Source)
+	}
+	if entry := dbl.Lookup["/build/other.c"]; entry == nil || entry.Source != "" {
+		t.Errorf("wrong entry for /build/other.c: %#v", entry)
+	}
+}
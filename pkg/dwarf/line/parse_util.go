@@ -39,6 +39,10 @@ const (
 	_DW_LNCT_timestamp
 	_DW_LNCT_size
 	_DW_LNCT_MD5
+
+	// _DW_LNCT_LLVM_source is the LLVM extension used to embed the contents
+	// of source files in the line table (clang -gembed-source).
+	_DW_LNCT_LLVM_source = 0x2001
 )
 
 var ErrBufferUnderflow = errors.New("buffer underflow")
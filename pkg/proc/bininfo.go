@@ -756,6 +756,16 @@ func (bi *BinaryInfo) Types() ([]string, error) {
 	return types, nil
 }
 
+// PackageVariableNames returns the names of all package variables in the
+// binary, the values of the variables are not read.
+func (bi *BinaryInfo) PackageVariableNames() []string {
+	names := make([]string, 0, len(bi.packageVars))
+	for _, pv := range bi.packageVars {
+		names = append(names, pv.name)
+	}
+	return names
+}
+
 func (bi *BinaryInfo) EntryLineForFunc(fn *Function) (string, int) {
 	return bi.pcToLine(fn, fn.Entry)
 }
@@ -808,6 +818,22 @@ func (bi *BinaryInfo) AllPCsForFileLines(filename string, linenos []int) map[int
 	return r
 }
 
+// EmbeddedSource returns the contents of filename if they are embedded in
+// the line table of one of the images (see DW_LNCT_LLVM_source).
+func (bi *BinaryInfo) EmbeddedSource(filename string) (string, bool) {
+	for _, image := range bi.Images {
+		for _, cu := range image.compileUnits {
+			if cu.lineInfo == nil {
+				continue
+			}
+			if entry := cu.lineInfo.Lookup[filename]; entry != nil && entry.Source != "" {
+				return entry.Source, true
+			}
+		}
+	}
+	return "", false
+}
+
 // PCToFunc returns the concrete function containing the given PC address.
 // If the PC address belongs to an inlined call it will return the containing function.
 func (bi *BinaryInfo) PCToFunc(pc uint64) *Function {
@@ -836,6 +862,20 @@ type Image struct {
 	StaticBase uint64
 	addr       uint64
 
+	// BuildID is the GNU build ID of the image, empty if it does not have one.
+	BuildID string
+	// DebugInfoPath is the path of the separate file the debug info of the
+	// image was read from, empty if the debug info was read from Path or
+	// was not found.
+	DebugInfoPath string
+	// DebugInfoDownloaded is true if the file at DebugInfoPath was
+	// downloaded from a debuginfod server.
+	DebugInfoDownloaded bool
+
+	// loadStart and loadEnd are the boundaries of the loadable segments of
+	// the image, StaticBase is not included.
+	loadStart, loadEnd uint64
+
 	index int // index of this object in BinaryInfo.SharedObjects
 
 	closer         io.Closer
@@ -879,6 +919,16 @@ func (image *Image) Stripped() bool {
 	return image.dwarf == nil
 }
 
+// AddressRange returns the range of addresses [start, end) the loadable
+// segments of the image are mapped at. Both are zero if the range is not
+// known.
+func (image *Image) AddressRange() (start, end uint64) {
+	if image.loadEnd == 0 {
+		return 0, 0
+	}
+	return image.loadStart + image.StaticBase, image.loadEnd + image.StaticBase
+}
+
 // AddImage adds the specified image to bi, loading data asynchronously.
 // Addr is the relocated entry point for the executable and staticBase (i.e.
 // the relocation offset) for all other images.
@@ -1319,10 +1369,12 @@ func (bi *BinaryInfo) openSeparateDebugInfo(image *Image, exe *elf.File, debugIn
 		}
 	}
 
-	if debugFilePath == "" && len(bi.BuildID) > 2 {
+	buildID := image.BuildID
+
+	if debugFilePath == "" && len(buildID) > 2 {
 		// Build ID method: look for a file named .build-id/nn/nnnnnnnn.debug in
 		// every debug info directory.
-		find(nil, fmt.Sprintf(".build-id/%s/%s.debug", bi.BuildID[:2], bi.BuildID[2:]))
+		find(nil, fmt.Sprintf(".build-id/%s/%s.debug", buildID[:2], buildID[2:]))
 	}
 
 	if debugFilePath == "" {
@@ -1361,11 +1413,11 @@ func (bi *BinaryInfo) openSeparateDebugInfo(image *Image, exe *elf.File, debugIn
 		}
 	}
 
-	if debugFilePath == "" && len(bi.BuildID) > 2 {
+	if debugFilePath == "" && len(buildID) > 2 {
 		// Previous versions of delve looked for the build id in every debug info
 		// directory that contained the build-id substring. This behavior deviates
 		// from the ones specified by GDB but we keep it for backwards compatibility.
-		find(func(dir string) bool { return strings.Contains(dir, "build-id") }, fmt.Sprintf("%s/%s.debug", bi.BuildID[:2], bi.BuildID[2:]))
+		find(func(dir string) bool { return strings.Contains(dir, "build-id") }, fmt.Sprintf("%s/%s.debug", buildID[:2], buildID[2:]))
 	}
 
 	if debugFilePath == "" {
@@ -1378,12 +1430,14 @@ func (bi *BinaryInfo) openSeparateDebugInfo(image *Image, exe *elf.File, debugIn
 
 	// We cannot find the debug information locally on the system. Try and see if we're on a system that
 	// has debuginfod so that we can use that in order to find any relevant debug information.
+	downloaded := false
 	if debugFilePath == "" {
 		var err error
-		debugFilePath, err = debuginfod.GetDebuginfo(bi.BuildID)
+		debugFilePath, err = debuginfod.GetDebuginfo(buildID)
 		if err != nil {
 			return nil, nil, ErrNoDebugInfoFound
 		}
+		downloaded = true
 	}
 
 	sepFile, err := os.OpenFile(debugFilePath, 0, os.ModePerm)
@@ -1402,6 +1456,8 @@ func (bi *BinaryInfo) openSeparateDebugInfo(image *Image, exe *elf.File, debugIn
 		return nil, nil, fmt.Errorf("can't open separate debug file %q: %v", debugFilePath, &ErrUnsupportedArch{os: "linux", cpuArch: elfFile.Machine})
 	}
 
+	image.DebugInfoPath = debugFilePath
+	image.DebugInfoDownloaded = downloaded
 	return sepFile, elfFile, nil
 }
 
@@ -1439,6 +1495,18 @@ func loadBinaryInfoElf(bi *BinaryInfo, image *Image, path string, addr uint64, w
 		image.StaticBase = addr
 	}
 
+	for _, prog := range elfFile.Progs {
+		if prog.Type != elf.PT_LOAD {
+			continue
+		}
+		if image.loadEnd == 0 || prog.Vaddr < image.loadStart {
+			image.loadStart = prog.Vaddr
+		}
+		if end := prog.Vaddr + prog.Memsz; end > image.loadEnd {
+			image.loadEnd = end
+		}
+	}
+
 	dwarfFile := elfFile
 
 	bi.loadBuildID(image, elfFile)
@@ -1554,7 +1622,10 @@ func (bi *BinaryInfo) loadBuildID(image *Image, file *elf.File) {
 		bi.logger.Warnf("can't read build-id desc: %v", err)
 		return
 	}
-	bi.BuildID = hex.EncodeToString(descBinary)
+	image.BuildID = hex.EncodeToString(descBinary)
+	if image.index == 0 {
+		bi.BuildID = image.BuildID
+	}
 }
 
 func (bi *BinaryInfo) getDebugLink(exe *elf.File) (debugLink string, crc uint32) {
@@ -101,6 +101,15 @@ type Breaklet struct {
 	// For WatchOutOfScopeBreakpoints and StackResizeBreakpoints the watchpoint
 	// field contains the watchpoint related to this out of scope sentinel.
 	watchpoint *Breakpoint
+
+	// catchFilter, if not nil, is called for CatchBreakpoints before
+	// evaluating the condition of the catchpoint, it returns false if the
+	// event does not match the filter of the catchpoint.
+	catchFilter func(th Thread, p *Target) bool
+
+	// catchSyscall is the number of the syscall caught by a syscall
+	// catchpoint, -1 if all syscalls are caught.
+	catchSyscall int
 }
 
 // BreakpointKind determines the behavior of delve when the
@@ -135,6 +144,21 @@ const (
 	// been loaded and we should try to enable suspended breakpoints.
 	PluginOpenBreakpoint
 
+	// TimelineBreakpoint is a breakpoint used to record goroutine scheduling
+	// events while replaying a recording, it never stops execution.
+	TimelineBreakpoint
+
+	// CatchBreakpoint is a breakpoint used to implement a catchpoint, the
+	// logical breakpoint it belongs to is triggered when the breaklet is hit
+	// and its filter matches.
+	CatchBreakpoint
+
+	// InternalBreakpoint is a breakpoint (or watchpoint) set by the debugger
+	// for its own use, for example to run to a location. It stops execution
+	// like a user breakpoint but it has no logical breakpoint and it is never
+	// shown to the user.
+	InternalBreakpoint
+
 	steppingMask = NextBreakpoint | NextDeferBreakpoint | StepBreakpoint
 )
 
@@ -210,6 +234,12 @@ func (bp *Breakpoint) VerboseDescr() []string {
 			r = append(r, fmt.Sprintf("StackResizeBreakpoint Cond=%q", exprToString(breaklet.Cond)))
 		case PluginOpenBreakpoint:
 			r = append(r, "PluginOpenBreakpoint")
+		case TimelineBreakpoint:
+			r = append(r, "TimelineBreakpoint")
+		case CatchBreakpoint:
+			r = append(r, fmt.Sprintf("CatchBreakpoint LogicalID=%d", breaklet.LogicalID))
+		case InternalBreakpoint:
+			r = append(r, fmt.Sprintf("InternalBreakpoint Cond=%q", exprToString(breaklet.Cond)))
 		default:
 			r = append(r, fmt.Sprintf("Unknown %d", breaklet.Kind))
 		}
@@ -270,16 +300,26 @@ func (bpstate *BreakpointState) checkCond(tgt *Target, breaklet *Breaklet, threa
 
 	switch breaklet.Kind {
 	case UserBreakpoint:
-		var goroutineID int64
-		lbp := bpstate.Breakpoint.Logical
-		if lbp != nil {
-			if g, err := GetG(thread); err == nil {
-				goroutineID = g.ID
-				lbp.HitCount[goroutineID]++
+		active = countHit(tgt, bpstate.Breakpoint.Logical, thread)
+
+	case CatchBreakpoint:
+		lbp := tgt.Breakpoints().Logical[breaklet.LogicalID]
+		if lbp == nil || (breaklet.catchFilter != nil && !breaklet.catchFilter(thread, tgt)) {
+			return
+		}
+		if lbp.Cond != nil {
+			active, condErr = evalBreakpointCondition(tgt, thread, lbp.Cond)
+			if condErr != nil && bpstate.CondError == nil {
+				bpstate.CondError = condErr
 			}
-			lbp.TotalHitCount++
+			if !active {
+				return
+			}
+		}
+		active = countHit(tgt, lbp, thread)
+		if active {
+			bpstate.Catchpoint = lbp
 		}
-		active = checkHitCond(lbp, goroutineID)
 
 	case StepBreakpoint, NextBreakpoint, NextDeferBreakpoint:
 		nextDeferOk := true
@@ -304,7 +344,7 @@ func (bpstate *BreakpointState) checkCond(tgt *Target, breaklet *Breaklet, threa
 			}
 		}
 
-	case StackResizeBreakpoint, PluginOpenBreakpoint:
+	case StackResizeBreakpoint, PluginOpenBreakpoint, TimelineBreakpoint, InternalBreakpoint:
 		// no further checks
 
 	default:
@@ -333,6 +373,32 @@ func (bpstate *BreakpointState) checkCond(tgt *Target, breaklet *Breaklet, threa
 	}
 }
 
+// countHit updates the hit counts of lbp for a hit by thread and returns
+// true if its hit condition is satisfied.
+func countHit(tgt *Target, lbp *LogicalBreakpoint, thread Thread) bool {
+	var goroutineID int64
+	if lbp != nil {
+		g, err := GetG(thread)
+		if err == nil {
+			goroutineID = g.ID
+		}
+		// Hits that happen before the After breakpoint is triggered are
+		// not counted, like hits that do not satisfy Cond.
+		if !checkAfterCond(lbp, goroutineID) {
+			return false
+		}
+		if err == nil {
+			lbp.HitCount[goroutineID]++
+		}
+		lbp.TotalHitCount++
+	}
+	active := checkHitCond(lbp, goroutineID)
+	if active && lbp != nil {
+		recordAfterHit(tgt, lbp, goroutineID)
+	}
+	return active
+}
+
 // checkHitCond evaluates bp's hit condition on thread.
 func checkHitCond(lbp *LogicalBreakpoint, goroutineID int64) bool {
 	if lbp == nil || lbp.HitCond == nil {
@@ -362,6 +428,45 @@ func checkHitCond(lbp *LogicalBreakpoint, goroutineID int64) bool {
 	return false
 }
 
+// checkAfterCond returns true if the After breakpoint of lbp has been
+// triggered, by goroutineID if lbp.AfterPerG is set.
+func checkAfterCond(lbp *LogicalBreakpoint, goroutineID int64) bool {
+	if lbp.After == 0 {
+		return true
+	}
+	if lbp.afterHit == nil || lbp.afterHit.logicalID != lbp.After {
+		return false
+	}
+	if lbp.AfterPerG && goroutineID > 0 {
+		return lbp.afterHit.perG[goroutineID]
+	}
+	return true
+}
+
+// recordAfterHit records that lbp was triggered by goroutineID in lbp and
+// in all the logical breakpoints of tgt that must be triggered after lbp.
+// The record is kept by the dependent breakpoints, so that it outlives lbp
+// if it is cleared.
+func recordAfterHit(tgt *Target, lbp *LogicalBreakpoint, goroutineID int64) {
+	if lbp.hits == nil {
+		lbp.hits = &afterHit{logicalID: lbp.LogicalID, perG: make(map[int64]bool)}
+	}
+	if goroutineID > 0 {
+		lbp.hits.perG[goroutineID] = true
+	}
+	for _, dep := range tgt.Breakpoints().Logical {
+		if dep.After != lbp.LogicalID {
+			continue
+		}
+		if dep.afterHit == nil || dep.afterHit.logicalID != lbp.LogicalID {
+			dep.afterHit = &afterHit{logicalID: lbp.LogicalID, perG: make(map[int64]bool)}
+		}
+		if goroutineID > 0 {
+			dep.afterHit.perG[goroutineID] = true
+		}
+	}
+}
+
 func isPanicCall(frames []Stackframe) (bool, int) {
 	// In Go prior to 1.17 the call stack for a panic is:
 	//  0. deferred function call
@@ -481,6 +586,10 @@ type BreakpointMap struct {
 	// WatchOutOfScope is the list of watchpoints that went out of scope during
 	// the last resume operation
 	WatchOutOfScope []*Breakpoint
+
+	// syscallCatchpoint holds the breaklets of the syscall catchpoints, it
+	// is the breakpoint of threads stopped at the entry of a caught syscall.
+	syscallCatchpoint *Breakpoint
 }
 
 // NewBreakpointMap creates a new BreakpointMap.
@@ -595,6 +704,17 @@ func (t *Target) setEBPFTracepointOnFunc(fn *Function, goidOffset int64) error {
 // SetWatchpoint sets a data breakpoint at addr and stores it in the
 // process wide break point table.
 func (t *Target) SetWatchpoint(logicalID int, scope *EvalScope, expr string, wtype WatchType, cond ast.Expr) (*Breakpoint, error) {
+	return t.setWatchpoint(logicalID, scope, expr, UserBreakpoint, wtype, cond)
+}
+
+// SetInternalWatchpoint sets a data breakpoint of InternalBreakpoint kind
+// on expr. No logical breakpoint is created for it, the watchpoint must be
+// removed with ClearInternalBreakpoint.
+func (t *Target) SetInternalWatchpoint(scope *EvalScope, expr string, wtype WatchType) (*Breakpoint, error) {
+	return t.setWatchpoint(0, scope, expr, InternalBreakpoint, wtype, nil)
+}
+
+func (t *Target) setWatchpoint(logicalID int, scope *EvalScope, expr string, kind BreakpointKind, wtype WatchType, cond ast.Expr) (*Breakpoint, error) {
 	if (wtype&WatchWrite == 0) && (wtype&WatchRead == 0) {
 		return nil, errors.New("at least one of read and write must be set for watchpoint")
 	}
@@ -633,10 +753,19 @@ func (t *Target) SetWatchpoint(logicalID int, scope *EvalScope, expr string, wty
 		return nil, errors.New("can not watch stack allocated variable for reads")
 	}
 
-	bp, err := t.setBreakpointInternal(logicalID, xv.Addr, UserBreakpoint, wtype.withSize(uint8(sz)), cond)
+	bp, err := t.setBreakpointInternal(logicalID, xv.Addr, kind, wtype.withSize(uint8(sz)), cond)
 	if err != nil {
 		return bp, err
 	}
+	if len(bp.Breaklets) > 1 {
+		// An internal watchpoint was added to an existing watchpoint, it can
+		// only be reused if it triggers on the same accesses.
+		if bp.WatchType.withSize(0) != wtype.withSize(0) {
+			t.ClearInternalBreakpoint(bp)
+			return nil, fmt.Errorf("can not watch %q, a different watchpoint is already set on %#x", expr, bp.Addr)
+		}
+		return bp, nil
+	}
 	bp.WatchExpr = expr
 
 	if stackWatch {
@@ -659,7 +788,7 @@ func (t *Target) setBreakpointInternal(logicalID int, addr uint64, kind Breakpoi
 	}
 	bpmap := t.Breakpoints()
 	newBreaklet := &Breaklet{Kind: kind, Cond: cond}
-	if kind == UserBreakpoint {
+	if kind == UserBreakpoint || kind == CatchBreakpoint {
 		newBreaklet.LogicalID = logicalID
 	}
 
@@ -797,6 +926,29 @@ func (t *Target) ClearBreakpoint(addr uint64) error {
 	return nil
 }
 
+// ClearInternalBreakpoint removes the InternalBreakpoint breaklets of bp,
+// erasing the breakpoint if nothing else is set at its address.
+func (t *Target) ClearInternalBreakpoint(bp *Breakpoint) error {
+	for i := range bp.Breaklets {
+		if bp.Breaklets[i].Kind == InternalBreakpoint {
+			bp.Breaklets[i] = nil
+		}
+	}
+	cleared, err := t.finishClearBreakpoint(bp)
+	if err != nil || !cleared {
+		return err
+	}
+	for _, thread := range t.ThreadList() {
+		if thread.Breakpoint().Breakpoint == bp {
+			thread.Breakpoint().Clear()
+		}
+	}
+	if bp.WatchExpr != "" && bp.watchStackOff != 0 {
+		return t.clearStackWatchBreakpoints(bp)
+	}
+	return nil
+}
+
 // ClearSteppingBreakpoints removes all stepping breakpoints from the map,
 // calling clearBreakpoint on each one.
 func (t *Target) ClearSteppingBreakpoints() error {
@@ -883,6 +1035,8 @@ type BreakpointState struct {
 	// CondError contains any error encountered while evaluating the
 	// breakpoint's condition.
 	CondError error
+	// Catchpoint is the catchpoint triggered by the thread, if any.
+	Catchpoint *LogicalBreakpoint
 }
 
 // Clear zeros the struct.
@@ -892,6 +1046,7 @@ func (bpstate *BreakpointState) Clear() {
 	bpstate.Stepping = false
 	bpstate.SteppingInto = false
 	bpstate.CondError = nil
+	bpstate.Catchpoint = nil
 }
 
 func (bpstate *BreakpointState) String() string {
@@ -1009,9 +1164,40 @@ type LogicalBreakpoint struct {
 	// Cond: if not nil the breakpoint will be triggered only if evaluating Cond returns true
 	Cond ast.Expr
 
+	// After: if not zero the breakpoint will be triggered only after the
+	// breakpoint with this logical ID has been triggered. If AfterPerG is
+	// set it must have been triggered by the same goroutine.
+	After     int
+	AfterPerG bool
+	afterHit  *afterHit
+	// hits records the goroutines that triggered the breakpoint, it is
+	// kept by the TargetGroup when the breakpoint is deleted.
+	hits *afterHit
+
+	// Once: the breakpoint should be cleared the first time it is triggered.
+	Once bool
+
 	UserData interface{} // Any additional information about the breakpoint
 }
 
+// afterHit records the goroutines that triggered the After breakpoint of a
+// logical breakpoint.
+type afterHit struct {
+	logicalID int            // the After breakpoint at the time of the hits
+	perG      map[int64]bool // goroutines that triggered it
+}
+
+func (h *afterHit) copy() *afterHit {
+	if h == nil {
+		return nil
+	}
+	r := &afterHit{logicalID: h.logicalID, perG: make(map[int64]bool, len(h.perG))}
+	for goid := range h.perG {
+		r.perG[goid] = true
+	}
+	return r
+}
+
 // SetBreakpoint describes how a breakpoint should be set.
 type SetBreakpoint struct {
 	FunctionName string
@@ -1019,6 +1205,7 @@ type SetBreakpoint struct {
 	Line         int
 	Expr         func(*Target) []uint64
 	PidAddrs     []PidAddr
+	Catch        *Catchpoint
 }
 
 type PidAddr struct {
@@ -0,0 +1,385 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"go/constant"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CatchKind is the kind of event a catchpoint stops on.
+type CatchKind uint8
+
+const (
+	// CatchSignal stops when the target receives a signal.
+	CatchSignal CatchKind = iota + 1
+	// CatchSyscall stops when a thread enters a syscall.
+	CatchSyscall
+	// CatchGoroutineStart stops when a goroutine is created.
+	CatchGoroutineStart
+	// CatchGoroutineExit stops when a goroutine exits.
+	CatchGoroutineExit
+	// CatchPanic stops when a goroutine panics, whether the panic is later
+	// recovered or not.
+	CatchPanic
+)
+
+var catchKindNames = map[CatchKind]string{
+	CatchSignal:         "signal",
+	CatchSyscall:        "syscall",
+	CatchGoroutineStart: "goroutine-start",
+	CatchGoroutineExit:  "goroutine-exit",
+	CatchPanic:          "panic",
+}
+
+func (kind CatchKind) String() string {
+	if name, ok := catchKindNames[kind]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Catchpoint describes the event a catchpoint stops on.
+type Catchpoint struct {
+	Kind CatchKind
+	// Filter restricts the events the catchpoint stops on, an empty filter
+	// matches every event. It is:
+	//  - the name (SIGSEGV or SEGV) or number of a signal for CatchSignal
+	//  - the name (openat) or number of a syscall for CatchSyscall
+	//  - a regular expression matched against the name of the start function
+	//    of the goroutine for CatchGoroutineStart and CatchGoroutineExit
+	//  - the type of the value passed to panic for CatchPanic
+	Filter string
+}
+
+// ParseCatchpoint parses the description of a catchpoint, the kind of the
+// catchpoint followed by an optional filter, for example "signal SIGSEGV".
+func ParseCatchpoint(descr string) (*Catchpoint, error) {
+	descr = strings.TrimSpace(descr)
+	name, filter := descr, ""
+	if i := strings.IndexAny(descr, " \t"); i >= 0 {
+		name, filter = descr[:i], strings.TrimSpace(descr[i+1:])
+	}
+	for kind, kindName := range catchKindNames {
+		if kindName != name {
+			continue
+		}
+		if filter != "" && (kind == CatchGoroutineStart || kind == CatchGoroutineExit) {
+			if _, err := regexp.Compile(filter); err != nil {
+				return nil, fmt.Errorf("invalid goroutine filter: %v", err)
+			}
+		}
+		return &Catchpoint{Kind: kind, Filter: filter}, nil
+	}
+	return nil, fmt.Errorf("unknown catchpoint kind %q", name)
+}
+
+func (catch *Catchpoint) String() string {
+	if catch.Filter == "" {
+		return catch.Kind.String()
+	}
+	return catch.Kind.String() + " " + catch.Filter
+}
+
+// setCatchpoint sets the breakpoints implementing the catchpoint lbp.
+func (t *Target) setCatchpoint(lbp *LogicalBreakpoint) error {
+	catch := lbp.Set.Catch
+	var fnName string
+	var addrs []uint64
+	var filter func(th Thread, p *Target) bool
+	var err error
+
+	switch catch.Kind {
+	case CatchSignal:
+		fnName = "runtime.sighandler"
+		if catch.Filter != "" {
+			sig, err := t.evalSyscallConst(catch.Filter, "SIG")
+			if err != nil {
+				return fmt.Errorf("unknown signal %q", catch.Filter)
+			}
+			filter = func(th Thread, p *Target) bool {
+				if n, ok := p.firstArgReg(th); ok {
+					return int64(uint32(n)) == sig
+				}
+				return p.evalThreadInt(th, "sig") == sig
+			}
+		}
+
+	case CatchSyscall:
+		return t.setSyscallCatchpoint(lbp)
+
+	case CatchGoroutineStart:
+		// The new goroutine is only known when runtime.newproc1 returns it.
+		addrs, err = findRetPC(t, "runtime.newproc1")
+		if err != nil {
+			return err
+		}
+		if catch.Filter != "" {
+			if !t.BinInfo().regabi {
+				return errors.New("goroutine-start filters are not supported by this target")
+			}
+			re, err := regexp.Compile(catch.Filter)
+			if err != nil {
+				return err
+			}
+			filter = func(th Thread, p *Target) bool {
+				g := p.newprocResult(th)
+				return g != nil && matchStartFunc(p, g, re)
+			}
+		}
+
+	case CatchGoroutineExit:
+		fnName = "runtime.goexit1"
+		if catch.Filter != "" {
+			re, err := regexp.Compile(catch.Filter)
+			if err != nil {
+				return err
+			}
+			filter = func(th Thread, p *Target) bool {
+				g, _ := GetG(th)
+				return g != nil && matchStartFunc(p, g, re)
+			}
+		}
+
+	case CatchPanic:
+		fnName = "runtime.gopanic"
+		if catch.Filter != "" {
+			filter = func(th Thread, p *Target) bool {
+				return p.panicValueType(th) == catch.Filter
+			}
+		}
+
+	default:
+		return fmt.Errorf("breakpoint %d can not be enabled", lbp.LogicalID)
+	}
+
+	if fnName != "" {
+		// The breakpoints are set on the entry point of the function, where
+		// the location of its arguments is known even in optimized code.
+		fns, err := t.BinInfo().FindFunction(fnName)
+		if err != nil {
+			return err
+		}
+		for _, fn := range fns {
+			if _, l, _ := t.BinInfo().PCToLine(fn.Entry); l == 0 {
+				// ABI wrappers have no line information, they jump to the
+				// function and would report the event twice.
+				continue
+			}
+			addrs = append(addrs, fn.Entry)
+		}
+	}
+	for _, addr := range addrs {
+		bp, err := t.SetBreakpoint(lbp.LogicalID, addr, CatchBreakpoint, nil)
+		if err != nil {
+			return err
+		}
+		bp.Breaklets[len(bp.Breaklets)-1].catchFilter = filter
+	}
+	return nil
+}
+
+// setSyscallCatchpoint adds the syscall catchpoint lbp to the breaklets of
+// the syscall catchpoint breakpoint and asks the backend to stop on the
+// entry of the syscalls caught.
+func (t *Target) setSyscallCatchpoint(lbp *LogicalBreakpoint) error {
+	nr := int64(-1)
+	if filter := lbp.Set.Catch.Filter; filter != "" {
+		var err error
+		nr, err = t.evalSyscallConst(filter, "SYS_")
+		if err != nil {
+			return fmt.Errorf("unknown syscall %q", filter)
+		}
+	}
+	bpmap := t.Breakpoints()
+	if bpmap.syscallCatchpoint == nil {
+		bpmap.syscallCatchpoint = &Breakpoint{}
+	}
+	bp := bpmap.syscallCatchpoint
+	bp.Breaklets = append(bp.Breaklets, &Breaklet{
+		Kind:         CatchBreakpoint,
+		LogicalID:    lbp.LogicalID,
+		catchSyscall: int(nr),
+		catchFilter: func(th Thread, _ *Target) bool {
+			return nr < 0 || th.Common().SyscallNr == int(nr)
+		},
+	})
+	if err := t.proc.CatchSyscalls(bpmap.syscallFilter()); err != nil {
+		bp.Breaklets = bp.Breaklets[:len(bp.Breaklets)-1]
+		return err
+	}
+	return nil
+}
+
+// syscallFilter returns a function that reports whether the syscall with
+// the given number is caught by a syscall catchpoint, or nil if there are
+// no syscall catchpoints.
+func (bpmap *BreakpointMap) syscallFilter() func(nr int) bool {
+	bp := bpmap.syscallCatchpoint
+	if bp == nil || len(bp.Breaklets) == 0 {
+		return nil
+	}
+	nrs := make(map[int]bool)
+	for _, breaklet := range bp.Breaklets {
+		if breaklet.catchSyscall < 0 {
+			return func(int) bool { return true }
+		}
+		nrs[breaklet.catchSyscall] = true
+	}
+	return func(nr int) bool { return nrs[nr] }
+}
+
+// clearCatchpoint removes all the breaklets of the catchpoint with the
+// specified logical ID.
+func (t *Target) clearCatchpoint(logicalID int) error {
+	isCatchpoint := func(breaklet *Breaklet) bool {
+		return breaklet.Kind == CatchBreakpoint && breaklet.LogicalID == logicalID
+	}
+	for _, bp := range t.Breakpoints().M {
+		for i := range bp.Breaklets {
+			if isCatchpoint(bp.Breaklets[i]) {
+				bp.Breaklets[i] = nil
+			}
+		}
+		cleared, err := t.finishClearBreakpoint(bp)
+		if err != nil {
+			return err
+		}
+		if cleared {
+			for _, thread := range t.ThreadList() {
+				if thread.Breakpoint().Breakpoint == bp {
+					thread.Breakpoint().Clear()
+				}
+			}
+		}
+	}
+
+	bpmap := t.Breakpoints()
+	bp := bpmap.syscallCatchpoint
+	if bp == nil {
+		return nil
+	}
+	n := len(bp.Breaklets)
+	breaklets := bp.Breaklets[:0]
+	for _, breaklet := range bp.Breaklets {
+		if !isCatchpoint(breaklet) {
+			breaklets = append(breaklets, breaklet)
+		}
+	}
+	bp.Breaklets = breaklets
+	if len(bp.Breaklets) == n {
+		return nil
+	}
+	return t.proc.CatchSyscalls(bpmap.syscallFilter())
+}
+
+// hasCatchpoint returns true if the catchpoint with the specified logical
+// ID is set on t.
+func (t *Target) hasCatchpoint(logicalID int) bool {
+	hasBreaklet := func(bp *Breakpoint) bool {
+		for _, breaklet := range bp.Breaklets {
+			if breaklet.Kind == CatchBreakpoint && breaklet.LogicalID == logicalID {
+				return true
+			}
+		}
+		return false
+	}
+	for _, bp := range t.Breakpoints().M {
+		if hasBreaklet(bp) {
+			return true
+		}
+	}
+	bp := t.Breakpoints().syscallCatchpoint
+	return bp != nil && hasBreaklet(bp)
+}
+
+// setSyscallCatchpointHit makes threads stopped by the backend at the
+// entry of a caught syscall report the syscall catchpoint breakpoint.
+func (t *Target) setSyscallCatchpointHit(thread Thread) {
+	bp := t.Breakpoints().syscallCatchpoint
+	if bp == nil || !thread.Common().SyscallEntry || thread.Breakpoint().Breakpoint != nil {
+		return
+	}
+	thread.Breakpoint().Breakpoint = bp
+}
+
+// evalSyscallConst returns the value of name, either a number or the name
+// of a constant of package syscall of the target, with or without prefix.
+func (t *Target) evalSyscallConst(name, prefix string) (int64, error) {
+	if n, err := strconv.ParseInt(name, 0, 64); err == nil {
+		return n, nil
+	}
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, prefix) {
+		name = prefix + name
+	}
+	scope := globalScope(t, t.BinInfo(), t.BinInfo().Images[0], t.Memory())
+	v, err := scope.EvalExpression("syscall."+name, loadSingleValue)
+	if err != nil {
+		return 0, err
+	}
+	if v.Unreadable != nil {
+		return 0, v.Unreadable
+	}
+	if v.Value == nil || v.Value.Kind() != constant.Int {
+		return 0, fmt.Errorf("%s is not an integer constant", name)
+	}
+	n, _ := constant.Int64Val(v.Value)
+	return n, nil
+}
+
+// matchStartFunc returns true if the name of the start function of g
+// matches re.
+func matchStartFunc(t *Target, g *G, re *regexp.Regexp) bool {
+	loc := g.StartLoc(t)
+	return loc.Fn != nil && re.MatchString(loc.Fn.Name)
+}
+
+// panicValueType returns the type of the value passed to runtime.gopanic,
+// th must be stopped at the entry point of runtime.gopanic.
+func (t *Target) panicValueType(th Thread) string {
+	bi := t.BinInfo()
+	if typeAddr, ok := t.firstArgReg(th); ok {
+		// The value is an empty interface, its type is passed in the first
+		// register.
+		if typeAddr == 0 {
+			return ""
+		}
+		rtyp, err := bi.findType(bi.runtimeTypeTypename())
+		if err != nil {
+			return ""
+		}
+		typ, _, err := runtimeTypeToDIE(newVariable("", typeAddr, rtyp, bi, th.ProcessMemory()), 0)
+		if err != nil {
+			return ""
+		}
+		return typ.String()
+	}
+	scope, err := ThreadScope(t, th)
+	if err != nil {
+		return ""
+	}
+	v, err := scope.EvalExpression("e", loadSingleValue)
+	if err != nil || v.Unreadable != nil || len(v.Children) == 0 {
+		return ""
+	}
+	return v.Children[0].TypeString()
+}
+
+// firstArgReg returns the value of the first integer register used to pass
+// arguments, th must be stopped at the entry point of a function. Only
+// supported with the register based calling convention, arguments are read
+// this way because the location lists of optimized runtime functions do not
+// always cover their entry point.
+func (t *Target) firstArgReg(th Thread) (uint64, bool) {
+	if !t.BinInfo().regabi {
+		return 0, false
+	}
+	regs, err := th.Registers()
+	if err != nil {
+		return 0, false
+	}
+	return t.BinInfo().Arch.RegistersToDwarfRegisters(0, regs).Uint64Val(0), true
+}
@@ -264,6 +264,9 @@ func (p *process) GetDirection() proc.Direction { return proc.Forward }
 // When does not apply to core files, it is to support the Mozilla 'rr' backend.
 func (p *process) When() (string, error) { return "", nil }
 
+// Position for core files returns an error, there is no recording of a core file.
+func (p *process) Position() (string, error) { return "", ErrContinueCore }
+
 // Checkpoint for core files returns an error, there is no execution of a core file.
 func (p *process) Checkpoint(string) (int, error) { return -1, ErrContinueCore }
 
@@ -273,6 +276,26 @@ func (p *process) Checkpoints() ([]proc.Checkpoint, error) { return nil, nil }
 // ClearCheckpoint clears a checkpoint, but will only return an error for core files.
 func (p *process) ClearCheckpoint(int) error { return errors.New("checkpoint not found") }
 
+// SessionBreakpoints returns nil on core files, there is no session to save breakpoints in.
+func (p *process) SessionBreakpoints() ([]proc.SessionBreakpoint, int, error) { return nil, 0, nil }
+
+// SaveSessionBreakpoints does nothing on core files.
+func (p *process) SaveSessionBreakpoints([]proc.SessionBreakpoint, int) error { return nil }
+
+// TimeLimits for core files returns an error, there is no execution of a core file.
+func (p *process) TimeLimits() (string, string, error) { return "", "", ErrContinueCore }
+
+// SetTimeLimits for core files returns an error, there is no execution of a core file.
+func (p *process) SetTimeLimits(string, string) error { return ErrContinueCore }
+
+// ComparePositions for core files returns an error, core files are not recordings.
+func (p *process) ComparePositions(string, string) (int, error) { return 0, ErrContinueCore }
+
+// SaveRecording for core files returns an error, core files are not recordings.
+func (p *process) SaveRecording(string, string, string) ([]proc.Checkpoint, error) {
+	return nil, proc.ErrNotRecorded
+}
+
 func (p *process) SupportsBPF() bool {
 	return false
 }
@@ -314,6 +337,11 @@ func (p *process) FollowExec(bool) error {
 	return nil
 }
 
+// CatchSyscalls does nothing, the target can not be resumed.
+func (p *process) CatchSyscalls(func(nr int) bool) error {
+	return nil
+}
+
 // ProcessMemory returns the memory of this thread's process.
 func (t *thread) ProcessMemory() proc.MemoryReadWriter {
 	return t.p
@@ -55,6 +55,10 @@ type EvalScope struct {
 	callCtx *callContext
 
 	dictAddr uint64 // dictionary address for instantiated generic functions
+
+	// prettyValue is the value being formatted by a pretty printer, see
+	// PrettyPrint.
+	prettyValue *Variable
 }
 
 type localsFlags uint8
@@ -193,6 +197,11 @@ func (scope *EvalScope) EvalExpression(expr string, cfg LoadConfig) (*Variable,
 	scope.loadCfg = &cfg
 
 	ev, err := scope.evalAST(t)
+	if err != nil && cancelled(cfg.Cancel) {
+		// Variables that could not be loaded after cancelling cause misleading
+		// errors.
+		err = ErrCancelled
+	}
 	if err != nil {
 		scope.callCtx.doReturn(nil, err)
 		return nil, err
@@ -549,16 +558,20 @@ func (scope *EvalScope) findGlobal(pkgName, varName string) (*Variable, error) {
 	return nil, fmt.Errorf("could not find symbol value for %s.%s", pkgName, varName)
 }
 
+func (scope *EvalScope) packageVarVariable(pkgvar *packageVar) (*Variable, error) {
+	reader := pkgvar.cu.image.dwarfReader
+	reader.Seek(pkgvar.offset)
+	entry, err := reader.Next()
+	if err != nil {
+		return nil, err
+	}
+	return extractVarInfoFromEntry(scope.target, scope.BinInfo, pkgvar.cu.image, regsReplaceStaticBase(scope.Regs, pkgvar.cu.image), scope.Mem, godwarf.EntryToTree(entry), 0)
+}
+
 func (scope *EvalScope) findGlobalInternal(name string) (*Variable, error) {
 	for _, pkgvar := range scope.BinInfo.packageVars {
 		if pkgvar.name == name || strings.HasSuffix(pkgvar.name, "/"+name) {
-			reader := pkgvar.cu.image.dwarfReader
-			reader.Seek(pkgvar.offset)
-			entry, err := reader.Next()
-			if err != nil {
-				return nil, err
-			}
-			return extractVarInfoFromEntry(scope.target, scope.BinInfo, pkgvar.cu.image, regsReplaceStaticBase(scope.Regs, pkgvar.cu.image), scope.Mem, godwarf.EntryToTree(entry), 0)
+			return scope.packageVarVariable(&pkgvar)
 		}
 	}
 	for _, fn := range scope.BinInfo.Functions {
@@ -1313,6 +1326,10 @@ func (scope *EvalScope) evalIdent(node *ast.Ident) (*Variable, error) {
 		return nilVariable, nil
 	}
 
+	if scope.prettyValue != nil && node.Name == prettyPrinterValueName {
+		return scope.prettyValue.clone(), nil
+	}
+
 	vars, err := scope.Locals(0)
 	if err != nil {
 		return nil, err
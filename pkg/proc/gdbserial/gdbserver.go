@@ -136,6 +136,14 @@ var ErrDirChange = errors.New("direction change with internal breakpoints")
 // injection while the recording is being run backwards.
 var ErrStartCallInjectionBackwards = errors.New("can not start a call injection while running backwards")
 
+// ErrTimeLimitsNotSupported is returned when trying to limit the execution
+// of a recording made by rr.
+var ErrTimeLimitsNotSupported = errors.New("time limits are only supported by the undo backend")
+
+// ErrSaveRecordingNotSupported is returned when trying to save part of a
+// recording made by rr.
+var ErrSaveRecordingNotSupported = errors.New("saving recordings is only supported by the undo backend")
+
 var checkCanUnmaskSignalsOnce sync.Once
 var canUnmaskSignalsCached bool
 
@@ -431,7 +439,7 @@ func getLdEnvVars() []string {
 // LLDBLaunch starts an instance of lldb-server and connects to it, asking
 // it to launch the specified target program with the specified arguments
 // (cmd) on the specified directory wd.
-func LLDBLaunch(cmd []string, wd string, flags proc.LaunchFlags, debugInfoDirs []string, tty string, redirects [3]string) (*proc.TargetGroup, error) {
+func LLDBLaunch(cmd []string, wd string, flags proc.LaunchFlags, debugInfoDirs []string, tty string, stdin string, stdout proc.OutputRedirect, stderr proc.OutputRedirect) (*proc.TargetGroup, error) {
 	if runtime.GOOS == "windows" {
 		return nil, ErrUnsupportedOS
 	}
@@ -464,6 +472,15 @@ func LLDBLaunch(cmd []string, wd string, flags proc.LaunchFlags, debugInfoDirs [
 		} else {
 			found := [3]bool{}
 			names := [3]string{"stdin", "stdout", "stderr"}
+			// Output redirected to a file that is already open is written to
+			// the standard output or error of the debugserver process.
+			redirects := [3]string{stdin, stdout.Path, stderr.Path}
+			if stdout.File != nil {
+				redirects[1] = "/dev/stdout"
+			}
+			if stderr.File != nil {
+				redirects[2] = "/dev/stderr"
+			}
 			for i := range redirects {
 				if redirects[i] != "" {
 					found[i] = true
@@ -511,6 +528,12 @@ func LLDBLaunch(cmd []string, wd string, flags proc.LaunchFlags, debugInfoDirs [
 	if logflags.LLDBServerOutput() || logflags.GdbWire() || foreground || hasRedirects {
 		process.Stdout = os.Stdout
 		process.Stderr = os.Stderr
+		if stdout.File != nil {
+			process.Stdout = stdout.File
+		}
+		if stderr.File != nil {
+			process.Stderr = stderr.File
+		}
 	}
 	if foreground || hasRedirects {
 		if isatty.IsTerminal(os.Stdin.Fd()) {
@@ -1124,10 +1147,14 @@ func (p *gdbProcess) restartWorker(cctx *proc.ContinueOnceContext, pos string) (
 	// Is this a checkpoint on a server using local checkpoints?
 	if p.conn.undoSession != nil {
 		var err error
-		pos, err = p.conn.undoSession.resolveUserTime(pos)
+		pos, err = p.conn.undoSession.resolveUserTime(&p.conn, pos)
 		if err != nil {
 			return nil, err
 		}
+	} else if strings.HasPrefix(pos, "@") {
+		// rr does not record the wall-clock time of events, wall-clock
+		// positions are only supported by the undo backend.
+		return nil, errors.New("wall-clock positions are not supported by rr")
 	}
 
 	p.exited = false
@@ -1195,6 +1222,18 @@ func (p *gdbProcess) When() (string, error) {
 	return result, nil
 }
 
+// Position returns the current position in the recording in a form that
+// can be passed to Restart. Only supported by the undo backend.
+func (p *gdbProcess) Position() (string, error) {
+	if p.tracedir == "" {
+		return "", proc.ErrNotRecorded
+	}
+	if p.conn.undoSession == nil {
+		return "", errors.New("recording positions are not supported by rr")
+	}
+	return undoPosition(&p.conn)
+}
+
 const (
 	checkpointPrefix = "Checkpoint "
 )
@@ -1241,7 +1280,7 @@ func (p *gdbProcess) Checkpoints() ([]proc.Checkpoint, error) {
 
 	// Handle locally managed checkpoints first
 	if p.conn.undoSession != nil {
-		return p.conn.undoSession.getCheckpoints()
+		return p.conn.undoSession.getCheckpoints(&p.conn)
 	}
 
 	resp, err := p.conn.qRRCmd("info checkpoints")
@@ -1292,6 +1331,78 @@ func (p *gdbProcess) ClearCheckpoint(id int) error {
 	return nil
 }
 
+// SessionBreakpoints returns the breakpoints saved in the session file of
+// the recording. Only the Undo backend has a session file, for other
+// recordings no breakpoints are returned.
+func (p *gdbProcess) SessionBreakpoints() ([]proc.SessionBreakpoint, int, error) {
+	if p.tracedir == "" {
+		return nil, 0, proc.ErrNotRecorded
+	}
+	if p.conn.undoSession != nil {
+		bps, maxID := p.conn.undoSession.getBreakpoints()
+		return bps, maxID, nil
+	}
+	return nil, 0, nil
+}
+
+// TimeLimits returns the portion of the recording that execution is limited to.
+func (p *gdbProcess) TimeLimits() (string, string, error) {
+	if p.tracedir == "" {
+		return "", "", proc.ErrNotRecorded
+	}
+	if p.conn.undoSession == nil {
+		return "", "", ErrTimeLimitsNotSupported
+	}
+	start, end := p.conn.undoSession.getTimeLimits()
+	return start, end, nil
+}
+
+// SetTimeLimits limits execution to the portion of the recording between start and end.
+func (p *gdbProcess) SetTimeLimits(start, end string) error {
+	if p.tracedir == "" {
+		return proc.ErrNotRecorded
+	}
+	if p.conn.undoSession == nil {
+		return ErrTimeLimitsNotSupported
+	}
+	return p.conn.undoSession.setTimeLimits(&p.conn, start, end)
+}
+
+// ComparePositions compares two positions in the recording.
+func (p *gdbProcess) ComparePositions(a, b string) (int, error) {
+	if p.tracedir == "" {
+		return 0, proc.ErrNotRecorded
+	}
+	if p.conn.undoSession == nil {
+		return 0, errors.New("recording positions are not supported by rr")
+	}
+	return p.conn.undoSession.comparePositions(&p.conn, a, b)
+}
+
+// SaveRecording saves the portion of the recording between from and to as
+// a new recording in path.
+func (p *gdbProcess) SaveRecording(path, from, to string) ([]proc.Checkpoint, error) {
+	if p.tracedir == "" {
+		return nil, proc.ErrNotRecorded
+	}
+	if p.conn.undoSession == nil {
+		return nil, ErrSaveRecordingNotSupported
+	}
+	return p.conn.undoSession.saveRecording(&p.conn, path, from, to)
+}
+
+// SaveSessionBreakpoints replaces the breakpoints saved in the session file
+// of the recording. It does nothing for recordings other than Undo.
+func (p *gdbProcess) SaveSessionBreakpoints(bps []proc.SessionBreakpoint, maxID int) error {
+	if p.tracedir == "" {
+		return proc.ErrNotRecorded
+	}
+	if p.conn.undoSession != nil {
+		return p.conn.undoSession.setBreakpoints(&p.conn, bps, maxID)
+	}
+	return nil
+}
+
 // ChangeDirection sets whether to run the program forwards or in reverse execution.
 func (p *gdbProcess) ChangeDirection(dir proc.Direction) error {
 	if p.tracedir == "" {
@@ -1403,6 +1514,15 @@ func (p *gdbProcess) FollowExec(bool) error {
 	return errors.New("follow exec not supported")
 }
 
+// CatchSyscalls makes the target stop at the entry of the syscalls for
+// which filter returns true, a nil filter disables syscall catching.
+func (p *gdbProcess) CatchSyscalls(filter func(nr int) bool) error {
+	if filter == nil {
+		return nil
+	}
+	return errors.New("catching syscalls is not supported by this backend")
+}
+
 type threadUpdater struct {
 	p    *gdbProcess
 	seen map[int]bool
@@ -661,6 +661,18 @@ func (conn *gdbConn) step(th *gdbThread, tu *threadUpdater, ignoreFaultSignal bo
 			if conn.isDebugserver {
 				return nil
 			}
+		case _SIGKILL:
+			if conn.undoSession != nil {
+				// Synthetic SIGKILL reported at the end of recorded history (see
+				// undoHandleStopPacket), stepping again would not make progress.
+				return nil
+			}
+		case 0:
+			if conn.undoSession != nil && conn.direction == proc.Backward {
+				// Start of recorded history or of the time limits (see
+				// undoHandleStopPacket), stepping again would not make progress.
+				return nil
+			}
 		case debugServerTargetExcBadAccess, debugServerTargetExcBadInstruction, debugServerTargetExcArithmetic, debugServerTargetExcEmulation, debugServerTargetExcSoftware, debugServerTargetExcBreakpoint:
 			if ignoreFaultSignal {
 				return nil
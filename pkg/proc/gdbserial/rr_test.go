@@ -12,10 +12,16 @@ import (
 	"github.com/undoio/delve/pkg/logflags"
 	"github.com/undoio/delve/pkg/proc"
 	"github.com/undoio/delve/pkg/proc/gdbserial"
+	"github.com/undoio/delve/pkg/proc/gdbserial/undotest"
 	protest "github.com/undoio/delve/pkg/proc/test"
 )
 
 func TestMain(m *testing.M) {
+	if os.Getenv(undotest.ServerEnvVar) != "" {
+		// This test binary is being used as a fake udbserver, see
+		// withFakeUndoRecording.
+		os.Exit(undotest.ServerMain(os.Args[1:]))
+	}
 	var logConf string
 	flag.StringVar(&logConf, "log", "", "configures logging")
 	flag.Parse()
@@ -30,7 +36,7 @@ func withTestRecording(name string, t testing.TB, fn func(grp *proc.TargetGroup,
 		t.Skip("test skipped, rr not found")
 	}
 	t.Log("recording")
-	grp, tracedir, err := gdbserial.RecordAndReplay([]string{fixture.Path}, ".", true, []string{}, [3]string{})
+	grp, tracedir, err := gdbserial.RecordAndReplay([]string{fixture.Path}, ".", true, []string{}, "", proc.OutputRedirect{}, proc.OutputRedirect{})
 	if err != nil {
 		t.Fatal("Launch():", err)
 	}
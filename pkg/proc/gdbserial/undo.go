@@ -2,19 +2,23 @@ package gdbserial
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/undoio/delve/pkg/proc"
 )
@@ -22,15 +26,27 @@ import (
 // State relating to an Undo "session" - used to correctly interpret and handle time-travel
 // operations on a gdbConn when running with the Undo backend.
 //
-// The current checkpoints are persisted to disk in an "Undo session file" via the save() method.
-// They are restored via the load() method.
+// The current checkpoints and breakpoints are persisted to disk in an "Undo session file" via the
+// save() method. They are restored via the load() method.
 type undoSession struct {
 	checkpointNextId int                     // For allocating checkpoint IDs
 	checkpoints      map[int]proc.Checkpoint // Map checkpoint IDs to Delve's proc.Checkpoint
+	breakpoints      []sessionBreakpoint     // Breakpoints shared with UDB, in session file format
+	breakpointsMax   int                     // Highest breakpoint number allocated so far
+	timeLimits       sessionTimeLimits       // Portion of history that execution is limited to
 	volatile         bool                    // Is the Undo connection currently in volatile mode?
 	sessionState     *session                // The most recently loaded / saved session state file contents.
+	capabilities     map[string]bool         // Optional features of udbserver, nil until queried
 }
 
+// Optional udbserver features. Delve only uses them if udbserver lists them in its response to
+// get_capabilities, versions of udbserver that don't implement get_capabilities have none of them.
+const (
+	undoCapabilityWallClock     = "wallclock"      // get_wallclock and find_wallclock
+	undoCapabilitySaveRecording = "save_recording" // save_recording
+	undoCapabilityTimeLimits    = "time_limits"    // set_time_limits
+)
+
 // Create a new undoSession structure.
 func newUndoSession() *undoSession {
 	return &undoSession{
@@ -58,6 +74,23 @@ func undoCmd(conn *gdbConn, args ...string) (string, error) {
 	return string(resp), nil
 }
 
+// Check whether udbserver supports an optional feature, querying its capabilities the first time.
+func (uc *undoSession) hasCapability(conn *gdbConn, name string) bool {
+	if uc.capabilities == nil {
+		uc.capabilities = make(map[string]bool)
+		resp, err := undoCmd(conn, "get_capabilities")
+		if err != nil && !isProtocolErrorUnsupported(err) {
+			conn.log.Errorf("could not get udbserver capabilities: %v", err)
+		}
+		for _, capability := range strings.Split(resp, ";") {
+			if capability != "" {
+				uc.capabilities[capability] = true
+			}
+		}
+	}
+	return uc.capabilities[name]
+}
+
 // Validate a checkpoint note to ensure easy interopability with UDB bookmarks.
 // Returns nil (no error) if a checkpoint is validated successfully.
 func validateCheckpointNote(where string) error {
@@ -130,8 +163,8 @@ func (uc *undoSession) deleteCheckpoint(conn *gdbConn, id int) {
 }
 
 // Fetch all Delve checkpoint structures and return an array for user display (with the When field
-// rewritten in human readable form).
-func (uc *undoSession) getCheckpoints() ([]proc.Checkpoint, error) {
+// rewritten in human readable form, including the wall-clock time if it is known).
+func (uc *undoSession) getCheckpoints(conn *gdbConn) ([]proc.Checkpoint, error) {
 	r := make([]proc.Checkpoint, 0, len(uc.checkpoints))
 	for _, cp := range uc.checkpoints {
 		// Convert the internal representation of time (which is based on the serial
@@ -141,6 +174,9 @@ func (uc *undoSession) getCheckpoints() ([]proc.Checkpoint, error) {
 			return nil, err
 		}
 		cp.When = undoTimeString(bbcount, pc)
+		if wallclock := undoGetWallClock(conn, bbcount); !wallclock.IsZero() {
+			cp.When += " " + uc.wallClockString(wallclock)
+		}
 		r = append(r, cp)
 	}
 	return r, nil
@@ -152,24 +188,121 @@ type bookmarkTime struct {
 	Pc      uint64 `json:"pc"`
 }
 
+// Represents a single serialised breakpoint in our session file format.
+//
+// The location uses the GDB linespec syntax, either "FILE:LINE" or "FUNCTION", which Delve
+// understands as well. Conditions are passed through as text: a condition written in C by a UDB
+// user will fail to parse in Delve and the other way round.
+//
+// UDB stores more information about breakpoints than Delve models, those fields are kept in extra
+// and written back unmodified.
+type sessionBreakpoint struct {
+	Number    int    `json:"number"`
+	Location  string `json:"location"`
+	Condition string `json:"condition,omitempty"`
+	Enabled   bool   `json:"enabled"`
+
+	extra map[string]json.RawMessage
+}
+
+func (bp *sessionBreakpoint) UnmarshalJSON(data []byte) error {
+	type fields sessionBreakpoint
+	if err := json.Unmarshal(data, (*fields)(bp)); err != nil {
+		return err
+	}
+	var err error
+	bp.extra, err = jsonExtraFields(data, bp)
+	return err
+}
+
+func (bp sessionBreakpoint) MarshalJSON() ([]byte, error) {
+	type fields sessionBreakpoint
+	return marshalWithExtraFields(fields(bp), bp.extra)
+}
+
+// Represents the time limits in our session file format. Execution is limited to the portion of
+// history between Start and End, a nil value means no limit. Fields written by UDB that Delve
+// doesn't know about are kept in extra.
+type sessionTimeLimits struct {
+	Start *bookmarkTime `json:"start,omitempty"`
+	End   *bookmarkTime `json:"end,omitempty"`
+
+	extra map[string]json.RawMessage
+}
+
+func (limits *sessionTimeLimits) UnmarshalJSON(data []byte) error {
+	type fields sessionTimeLimits
+	if err := json.Unmarshal(data, (*fields)(limits)); err != nil {
+		return err
+	}
+	var err error
+	limits.extra, err = jsonExtraFields(data, limits)
+	return err
+}
+
+func (limits sessionTimeLimits) MarshalJSON() ([]byte, error) {
+	type fields sessionTimeLimits
+	return marshalWithExtraFields(fields(limits), limits.extra)
+}
+
+// Returns the members of the JSON object in data that don't correspond to any field of the
+// struct pointed to by v, or nil if there are none.
+func jsonExtraFields(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	typ := reflect.TypeOf(v).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = typ.Field(i).Name
+		}
+		delete(m, name)
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// Marshals v, which must encode as a JSON object, adding the members in extra to it.
+func marshalWithExtraFields(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	buf, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return buf, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		if _, ok := m[k]; !ok {
+			m[k] = v
+		}
+	}
+	return json.Marshal(m)
+}
+
 // Represents the overall structure of our session file format.
 //
-// Delve can only generate complete state for a v0 session file (which just contains the bookmarks
-// data). When saving state for a recording that does not already have a session file it will use
+// Delve can only generate complete state for a v0 session file (which just contains the bookmarks,
+// breakpoints and time limits data). When saving state for a recording that does not already have a session file it will use
 // version 1.
 //
 // When saving state for a recording that already had a session file it will use that same format
 // again at save time. That means it will pass through, unmodified, any of the UDB-only fields.
 //
-// To make this happen, all fields from the v1 telemetry format are tagged "omitempty".
+// To make this happen, all fields from the v1 telemetry format are tagged "omitempty" and any field
+// that isn't listed here is kept in extra.
 type session struct {
 	Version               int                     `json:"version,omitempty"`
 	Bookmarks             map[string]bookmarkTime `json:"bookmarks"`
 	UndoStack             interface{}             `json:"undo_stack,omitempty"`
 	UndoStackIndex        interface{}             `json:"undo_stack_index,omitempty"`
-	Breakpoints           interface{}             `json:"breakpoints,omitempty"`
-	BreakpointsMax        interface{}             `json:"breakpoints_max,omitempty"`
-	TimeLimits            interface{}             `json:"time_limits,omitempty"`
+	Breakpoints           []sessionBreakpoint     `json:"breakpoints,omitempty"`
+	BreakpointsMax        int                     `json:"breakpoints_max,omitempty"`
+	TimeLimits            *sessionTimeLimits      `json:"time_limits,omitempty"`
 	WallClockTimeZone     interface{}             `json:"wallclock_timezone,omitempty"`
 	ReplayStandardStreams interface{}             `json:"replay_standard_streams,omitempty"`
 	SelectedTid           interface{}             `json:"selected_tid,omitempty"`
@@ -177,14 +310,27 @@ type session struct {
 	SubstitutePaths       interface{}             `json:"substitute_paths,omitempty"`
 	ConvenienceVariables  interface{}             `json:"convenience_variables,omitempty"`
 	TelemetryId           interface{}             `json:"telemetry_id,omitempty"`
+
+	extra map[string]json.RawMessage
+}
+
+func (state *session) UnmarshalJSON(data []byte) error {
+	type fields session
+	if err := json.Unmarshal(data, (*fields)(state)); err != nil {
+		return err
+	}
+	var err error
+	state.extra, err = jsonExtraFields(data, state)
+	return err
+}
+
+func (state session) MarshalJSON() ([]byte, error) {
+	type fields session
+	return marshalWithExtraFields(fields(state), state.extra)
 }
 
 // Get the path to the UDB session file for the current recording.
 func getSessionPath(conn *gdbConn) (string, error) {
-	user, err := user.Current()
-	if err != nil {
-		return "", err
-	}
 	recording_ids, err := undoCmd(conn, "get_recording_ids")
 	if err != nil {
 		return "", err
@@ -194,6 +340,16 @@ func getSessionPath(conn *gdbConn) (string, error) {
 		panic("unexpected response from get_recording_ids")
 	}
 
+	return getSessionPathForUUID(uuids[1])
+}
+
+// Get the path to the UDB session file for the recording with the given UUID.
+func getSessionPathForUUID(uuid string) (string, error) {
+	user, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+
 	// This directory stores sessions.
 	xdg_data_dir, present := os.LookupEnv("XDG_DATA_HOME")
 	if !present {
@@ -206,7 +362,7 @@ func getSessionPath(conn *gdbConn) (string, error) {
 		return "", err
 	}
 
-	file := filepath.Join(undo_sessions_dir, string(uuids[1])+".json")
+	file := filepath.Join(undo_sessions_dir, uuid+".json")
 
 	return file, nil
 }
@@ -225,7 +381,6 @@ func (uc *undoSession) load(conn *gdbConn) error {
 	defer file.Close()
 
 	decoder := json.NewDecoder(file)
-	decoder.DisallowUnknownFields()
 
 	// Clear out the session data.
 	*uc = *newUndoSession()
@@ -241,14 +396,20 @@ func (uc *undoSession) load(conn *gdbConn) error {
 	}
 
 	// Translate the loaded Undo bookmarks into Delve checkpoints.
-	for name, position := range uc.sessionState.Bookmarks {
-		cpid := uc.checkpointNextId
-		uc.checkpointNextId++
-		uc.checkpoints[cpid] = proc.Checkpoint{
-			ID:    cpid,
-			When:  fmt.Sprintf("%x,%x", position.Bbcount, position.Pc),
-			Where: name,
+	for _, cp := range bookmarksToCheckpoints(uc.sessionState.Bookmarks, uc.checkpointNextId) {
+		uc.checkpoints[cp.ID] = cp
+		uc.checkpointNextId = cp.ID + 1
+	}
+
+	uc.breakpoints = uc.sessionState.Breakpoints
+	uc.breakpointsMax = uc.sessionState.BreakpointsMax
+	// Time limits that udbserver can't enforce are left in the session state, to be written back
+	// unchanged like the UDB-only fields.
+	if uc.sessionState.TimeLimits != nil && uc.hasCapability(conn, undoCapabilityTimeLimits) {
+		if err := sendTimeLimits(conn, uc.sessionState.TimeLimits); err != nil {
+			return err
 		}
+		uc.timeLimits = *uc.sessionState.TimeLimits
 	}
 
 	return err
@@ -267,13 +428,40 @@ func (uc *undoSession) save(conn *gdbConn) error {
 			Version: 0,
 		}
 	}
-	uc.sessionState.Bookmarks = make(map[string]bookmarkTime)
 
 	// Local copy of the checkpoints.
 	var checkpoints []proc.Checkpoint
 	for _, cp := range uc.checkpoints {
 		checkpoints = append(checkpoints, cp)
 	}
+	bookmarks, err := checkpointsToBookmarks(checkpoints)
+	if err != nil {
+		return err
+	}
+	uc.sessionState.Bookmarks = bookmarks
+
+	uc.sessionState.Breakpoints = uc.breakpoints
+	uc.sessionState.BreakpointsMax = uc.breakpointsMax
+	if uc.hasCapability(conn, undoCapabilityTimeLimits) {
+		uc.sessionState.TimeLimits = nil
+		if uc.timeLimits.Start != nil || uc.timeLimits.End != nil || len(uc.timeLimits.extra) > 0 {
+			limits := uc.timeLimits
+			uc.sessionState.TimeLimits = &limits
+		}
+	}
+
+	path, err := getSessionPath(conn)
+	if err != nil {
+		return err
+	}
+
+	return writeSessionFile(path, uc.sessionState)
+}
+
+// Translate Delve checkpoints into Undo bookmarks, making their names unique.
+func checkpointsToBookmarks(checkpoints []proc.Checkpoint) (map[string]bookmarkTime, error) {
+	bookmarks := make(map[string]bookmarkTime)
+
 	// Sort the checkpoints by descending note length - this is to avoid adding a suffix to a
 	// entries that we've already added a suffix to. e.g. if we've previously saved this session
 	// with a duplicated checkpoint note called "test" then we'll have extended one to
@@ -290,23 +478,54 @@ func (uc *undoSession) save(conn *gdbConn) error {
 		// be unique.
 		base_name := cp.Where
 		name := base_name
-		for i := 0; uc.sessionState.Bookmarks[name] != (bookmarkTime{}); i++ {
+		for i := 0; bookmarks[name] != (bookmarkTime{}); i++ {
 			name = fmt.Sprintf("%s-%d", base_name, i)
 		}
 
 		var time bookmarkTime
 		_, err := fmt.Sscanf(cp.When, "%x,%x", &time.Bbcount, &time.Pc)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		uc.sessionState.Bookmarks[name] = time
+		bookmarks[name] = time
 	}
 
-	path, err := getSessionPath(conn)
-	if err != nil {
-		return err
+	return bookmarks, nil
+}
+
+// Translate Undo bookmarks into Delve checkpoints, with IDs starting at nextId. IDs are assigned in
+// order of time (and name for bookmarks at the same time), so that they don't depend on the order
+// of the bookmarks in the session file.
+func bookmarksToCheckpoints(bookmarks map[string]bookmarkTime, nextId int) []proc.Checkpoint {
+	names := make([]string, 0, len(bookmarks))
+	for name := range bookmarks {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ti, tj := bookmarks[names[i]], bookmarks[names[j]]
+		if ti.Bbcount != tj.Bbcount {
+			return ti.Bbcount < tj.Bbcount
+		}
+		if ti.Pc != tj.Pc {
+			return ti.Pc < tj.Pc
+		}
+		return names[i] < names[j]
+	})
+	r := make([]proc.Checkpoint, 0, len(names))
+	for _, name := range names {
+		position := bookmarks[name]
+		r = append(r, proc.Checkpoint{
+			ID:    nextId,
+			When:  fmt.Sprintf("%x,%x", position.Bbcount, position.Pc),
+			Where: name,
+		})
+		nextId++
 	}
+	return r
+}
 
+// Write a session file.
+func writeSessionFile(path string, state *session) error {
 	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
@@ -315,7 +534,7 @@ func (uc *undoSession) save(conn *gdbConn) error {
 
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "    ")
-	err = encoder.Encode(uc.sessionState)
+	err = encoder.Encode(state)
 	if err != nil {
 		return err
 	}
@@ -323,10 +542,45 @@ func (uc *undoSession) save(conn *gdbConn) error {
 	return err
 }
 
+// Translate the breakpoints from the session file into Delve's representation. Also returns the
+// highest breakpoint number that was ever allocated for the session.
+func (uc *undoSession) getBreakpoints() ([]proc.SessionBreakpoint, int) {
+	r := make([]proc.SessionBreakpoint, 0, len(uc.breakpoints))
+	for _, bp := range uc.breakpoints {
+		r = append(r, proc.SessionBreakpoint{ID: bp.Number, Location: bp.Location, Cond: bp.Condition, Enabled: bp.Enabled})
+	}
+	return r, uc.breakpointsMax
+}
+
+// Replace the breakpoints in the session file with the supplied Delve breakpoints.
+func (uc *undoSession) setBreakpoints(conn *gdbConn, bps []proc.SessionBreakpoint, maxID int) error {
+	// Fields of the old breakpoints that Delve doesn't model are carried over to the breakpoints
+	// with the same number.
+	extra := make(map[int]map[string]json.RawMessage)
+	for _, bp := range uc.breakpoints {
+		extra[bp.Number] = bp.extra
+	}
+	uc.breakpoints = make([]sessionBreakpoint, 0, len(bps))
+	for _, bp := range bps {
+		uc.breakpoints = append(uc.breakpoints, sessionBreakpoint{
+			Number:    bp.ID,
+			Location:  bp.Location,
+			Condition: bp.Cond,
+			Enabled:   bp.Enabled,
+			extra:     extra[bp.ID],
+		})
+		if bp.ID > maxID {
+			maxID = bp.ID
+		}
+	}
+	uc.breakpointsMax = maxID
+	return uc.save(conn)
+}
+
 // Transform a user-specified time into a canonical form. The returned string has been validated
 // (unknown checkpoint IDs, misspelt magic values and incorrectly formatted times will be rejected)
 // and is suitable for passing to travelToTime.
-func (uc *undoSession) resolveUserTime(pos string) (string, error) {
+func (uc *undoSession) resolveUserTime(conn *gdbConn, pos string) (string, error) {
 	// Validate and transform input.
 	//
 	// We will accept:
@@ -336,6 +590,9 @@ func (uc *undoSession) resolveUserTime(pos string) (string, error) {
 	//                 grouping of digits).
 	//   BBCOUNT:PC  - an Undo bbcount, as above, followed by a colon and then a program
 	//                 counter value in hex (with leading 0x).
+	//   @WALLCLOCK  - a wall-clock time in RFC 3339 format, the time zone can be omitted in
+	//                 which case the session's wall-clock time zone is used. Resolves to the
+	//                 first basic block executed at or after that instant.
 	if pos == "start" || pos == "end" {
 		// Special case values - valid with no extra checking.
 	} else if len(pos) > 1 && pos[:1] == "c" {
@@ -345,6 +602,29 @@ func (uc *undoSession) resolveUserTime(pos string) (string, error) {
 			return "", err
 		}
 		pos = checkpoint.When
+	} else if len(pos) > 1 && pos[:1] == "@" {
+		if !uc.hasCapability(conn, undoCapabilityWallClock) {
+			return "", errors.New("wall-clock times are not supported by this version of udbserver")
+		}
+		wallclock, err := uc.parseWallClock(pos[1:])
+		if err != nil {
+			return "", err
+		}
+		resp, err := undoCmd(conn, "find_wallclock", fmt.Sprintf("%x", wallclock.Unix()), fmt.Sprintf("%x", wallclock.Nanosecond()))
+		if isProtocolErrorUnsupported(err) {
+			return "", errors.New("wall-clock times are not available for this recording")
+		}
+		if _, isProtocolError := err.(*GdbProtocolError); isProtocolError {
+			return "", fmt.Errorf("no execution recorded at or after %s", uc.wallClockString(wallclock))
+		}
+		if err != nil {
+			return "", err
+		}
+		bbcount, pc, err := undoParseServerTime(resp)
+		if err != nil {
+			return "", err
+		}
+		pos = fmt.Sprintf("%x;%x", bbcount, pc)
 	} else if pos != "" {
 		// Validate a potential bbcount or precise time.
 		pos = strings.ReplaceAll(pos, ",", "")
@@ -370,27 +650,240 @@ func (uc *undoSession) resolveUserTime(pos string) (string, error) {
 	return pos, nil
 }
 
+// Parse a time returned by resolveUserTime (other than the "start" and "end" magic values) into
+// bbcount and PC.
+func parseResolvedTime(pos string) (bookmarkTime, error) {
+	var time bookmarkTime
+	_, err := fmt.Sscanf(strings.Replace(pos, ";", ",", 1), "%x,%x", &time.Bbcount, &time.Pc)
+	return time, err
+}
+
+// Describe the time limits for user display. Empty strings are returned for missing limits.
+func (uc *undoSession) getTimeLimits() (string, string) {
+	var start, end string
+	if uc.timeLimits.Start != nil {
+		start = undoTimeString(uc.timeLimits.Start.Bbcount, uc.timeLimits.Start.Pc)
+	}
+	if uc.timeLimits.End != nil {
+		end = undoTimeString(uc.timeLimits.End.Bbcount, uc.timeLimits.End.Pc)
+	}
+	return start, end
+}
+
+// Limit execution to the portion of history between two user-specified times (see
+// resolveUserTime), the current time must be within the new limits. An empty string (or the magic
+// values "start" and "end") removes the corresponding limit. The limits are saved in the session
+// file and udbserver stops execution when it reaches them.
+func (uc *undoSession) setTimeLimits(conn *gdbConn, start, end string) error {
+	if !uc.hasCapability(conn, undoCapabilityTimeLimits) {
+		return errors.New("time limits are not supported by this version of udbserver")
+	}
+	limits := sessionTimeLimits{extra: uc.timeLimits.extra}
+	for _, limit := range []struct {
+		pos  string
+		dest **bookmarkTime
+	}{{start, &limits.Start}, {end, &limits.End}} {
+		pos, err := uc.resolveUserTime(conn, limit.pos)
+		if err != nil {
+			return err
+		}
+		if pos == "" || pos == "start" || pos == "end" {
+			continue
+		}
+		time, err := parseResolvedTime(pos)
+		if err != nil {
+			return err
+		}
+		*limit.dest = &time
+	}
+	if limits.Start != nil && limits.End != nil && limits.Start.Bbcount > limits.End.Bbcount {
+		return errors.New("start of the time limits is after their end")
+	}
+
+	resp, err := undoCmd(conn, "get_time")
+	if err != nil {
+		return err
+	}
+	bbcount, _, err := undoParseServerTime(resp)
+	if err != nil {
+		return err
+	}
+	if !limits.contains(bbcount) {
+		return errors.New("current position is outside of the time limits")
+	}
+
+	if err := sendTimeLimits(conn, &limits); err != nil {
+		return err
+	}
+	uc.timeLimits = limits
+	return uc.save(conn)
+}
+
+// Pass the time limits to udbserver, which stops resumed execution at them.
+func sendTimeLimits(conn *gdbConn, limits *sessionTimeLimits) error {
+	args := []string{"set_time_limits"}
+	for _, limit := range []*bookmarkTime{limits.Start, limits.End} {
+		if limit == nil {
+			args = append(args, "", "")
+			continue
+		}
+		args = append(args, fmt.Sprintf("%x", limit.Bbcount), fmt.Sprintf("%x", limit.Pc))
+	}
+	_, err := undoCmd(conn, args...)
+	return err
+}
+
+// Compare two user-specified times (see resolveUserTime). The "start" and "end" magic values refer
+// to the extremes of history, regardless of the time limits.
+func (uc *undoSession) comparePositions(conn *gdbConn, a, b string) (int, error) {
+	var bbcounts [2]uint64
+	for i, pos := range []string{a, b} {
+		pos, err := uc.resolveUserTime(conn, pos)
+		if err != nil {
+			return 0, err
+		}
+		switch pos {
+		case "", "start":
+			bbcounts[i] = 0
+		case "end":
+			bbcounts[i] = math.MaxUint64
+		default:
+			time, err := parseResolvedTime(pos)
+			if err != nil {
+				return 0, err
+			}
+			bbcounts[i] = time.Bbcount
+		}
+	}
+	switch {
+	case bbcounts[0] < bbcounts[1]:
+		return -1, nil
+	case bbcounts[0] > bbcounts[1]:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Check whether a bbcount is within the time limits.
+func (limits *sessionTimeLimits) contains(bbcount uint64) bool {
+	return (limits.Start == nil || bbcount >= limits.Start.Bbcount) && (limits.End == nil || bbcount <= limits.End.Bbcount)
+}
+
 // Move the replay process to the a point in time. The "pos" argument should be obtained by calling
-// resolveUserTime to ensure that it is valid.
+// resolveUserTime to ensure that it is valid. The "start" and "end" magic values refer to the time
+// limits, if set.
 func (uc *undoSession) travelToTime(conn *gdbConn, pos string) error {
 	var args []string
-	switch pos {
-	case "start", "":
+	switch {
+	case (pos == "start" || pos == "") && uc.timeLimits.Start != nil:
+		args = []string{"goto_time", fmt.Sprintf("%x", uc.timeLimits.Start.Bbcount), fmt.Sprintf("%x", uc.timeLimits.Start.Pc)}
+	case pos == "start" || pos == "":
 		// Find the actual min BB count.
 		minBbCount, _, err := undoGetLogExtent(conn)
 		if err != nil {
 			return err
 		}
-		args = []string{"goto_time", fmt.Sprint(minBbCount), "0"}
-	case "end":
+		args = []string{"goto_time", fmt.Sprintf("%x", minBbCount), "0"}
+	case pos == "end" && uc.timeLimits.End != nil:
+		args = []string{"goto_time", fmt.Sprintf("%x", uc.timeLimits.End.Bbcount), fmt.Sprintf("%x", uc.timeLimits.End.Pc)}
+	case pos == "end":
 		args = []string{"goto_record_mode"}
 	default:
+		time, err := parseResolvedTime(pos)
+		if err != nil {
+			return err
+		}
+		if !uc.timeLimits.contains(time.Bbcount) {
+			return errors.New("time is outside of the time limits")
+		}
 		args = []string{"goto_time", pos}
 	}
 	_, err := undoCmd(conn, args...)
 	return err
 }
 
+// Save the portion of history between two user-specified times (see resolveUserTime) as a new
+// standalone recording in path, using the udbserver save_recording capability. An empty string (or
+// the magic values "start" and "end") means the corresponding extreme of recorded history, the time
+// limits are ignored. Checkpoints within the saved portion of history are written to the session
+// file of the new recording and returned.
+//
+// The returned checkpoints are renumbered with the IDs they will have when the new recording is
+// replayed, their notes are the (possibly de-duplicated) names of the bookmarks in its session file.
+func (uc *undoSession) saveRecording(conn *gdbConn, path, from, to string) ([]proc.Checkpoint, error) {
+	if !uc.hasCapability(conn, undoCapabilitySaveRecording) {
+		return nil, errors.New("saving recordings is not supported by this version of udbserver")
+	}
+	start, end, err := undoGetLogExtent(conn)
+	if err != nil {
+		return nil, err
+	}
+	for _, limit := range []struct {
+		pos  string
+		dest *uint64
+	}{{from, &start}, {to, &end}} {
+		pos, err := uc.resolveUserTime(conn, limit.pos)
+		if err != nil {
+			return nil, err
+		}
+		if pos == "" || pos == "start" || pos == "end" {
+			continue
+		}
+		time, err := parseResolvedTime(pos)
+		if err != nil {
+			return nil, err
+		}
+		*limit.dest = time.Bbcount
+	}
+	if start > end {
+		return nil, errors.New("start of the saved range is after its end")
+	}
+
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	// The response is the UUID of the new recording.
+	uuid, err := undoCmd(conn, "save_recording", hex.EncodeToString([]byte(path)), fmt.Sprintf("%x", start), fmt.Sprintf("%x", end))
+	if err != nil {
+		return nil, err
+	}
+
+	// Carry the checkpoints inside the range into the session file of the new recording.
+	var checkpoints []proc.Checkpoint
+	for _, cp := range uc.checkpoints {
+		bbcount, _, err := undoParseServerTime(cp.When)
+		if err != nil {
+			return nil, err
+		}
+		if bbcount >= start && bbcount <= end {
+			checkpoints = append(checkpoints, cp)
+		}
+	}
+	if len(checkpoints) == 0 {
+		return nil, nil
+	}
+	bookmarks, err := checkpointsToBookmarks(checkpoints)
+	if err != nil {
+		return nil, err
+	}
+	sessionPath, err := getSessionPathForUUID(uuid)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeSessionFile(sessionPath, &session{Version: 0, Bookmarks: bookmarks}); err != nil {
+		return nil, err
+	}
+
+	checkpoints = bookmarksToCheckpoints(bookmarks, 1)
+	for i := range checkpoints {
+		bbcount, pc, _ := undoParseServerTime(checkpoints[i].When)
+		checkpoints[i].When = undoTimeString(bbcount, pc)
+	}
+	return checkpoints, nil
+}
+
 // Activate volatile mode.
 // On success, returns a callback that can be used to deactivate volatile mode (and a nil error).
 // The deactivate callback should be used before volatile is next activated, since volatile mode
@@ -516,7 +1009,7 @@ func UndoIsAvailable() error {
 	return nil
 }
 
-func UndoRecord(cmd []string, wd string, quiet bool, redirects [3]string) (recording string, err error) {
+func UndoRecord(cmd []string, wd string, quiet bool, stdin string, stdout proc.OutputRedirect, stderr proc.OutputRedirect) (recording string, err error) {
 	if err := UndoIsAvailable(); err != nil {
 		return "", err
 	}
@@ -533,7 +1026,7 @@ func UndoRecord(cmd []string, wd string, quiet bool, redirects [3]string) (recor
 	lrcmd := exec.Command("live-record", args...)
 	var closefn func()
 	// FIXME: pass quiet to openRedirects(), not false.
-	lrcmd.Stdin, lrcmd.Stdout, lrcmd.Stderr, closefn, err = openRedirects(redirects, false)
+	lrcmd.Stdin, lrcmd.Stdout, lrcmd.Stderr, closefn, err = openRedirects(stdin, stdout, stderr, false)
 	if err != nil {
 		return "", err
 	}
@@ -558,6 +1051,57 @@ func UndoRecord(cmd []string, wd string, quiet bool, redirects [3]string) (recor
 	return recording, err
 }
 
+// UndoAttachAsync attaches LiveRecorder to the running process pid and
+// starts recording it. The returned run function waits until the recording
+// ends, either because stop was called or because the process exited, and
+// returns the path of the recording.
+func UndoAttachAsync(pid int, quiet bool) (run func() (string, error), stop func() error, err error) {
+	if err := UndoIsAvailable(); err != nil {
+		return nil, nil, err
+	}
+
+	file, err := ioutil.TempFile("/tmp", "undo")
+	if err != nil {
+		return nil, nil, err
+	}
+	file.Close()
+
+	recording := file.Name()
+	lrcmd := exec.Command("live-record", "--pid", strconv.Itoa(pid), "-o", recording)
+	if !quiet {
+		lrcmd.Stdout = os.Stdout
+		lrcmd.Stderr = os.Stderr
+	}
+	lrcmd.Env = os.Environ()
+
+	// Start recording straight away, so that stop can be called before run.
+	if err := lrcmd.Start(); err != nil {
+		os.Remove(recording)
+		return nil, nil, err
+	}
+
+	run = func() (string, error) {
+		// Ignore failures from Wait - live-record exits with an error status
+		// when it is interrupted.
+		_ = lrcmd.Wait()
+
+		if isRecording, err := UndoIsRecording(recording); !isRecording {
+			os.Remove(recording)
+			if err == nil {
+				err = fmt.Errorf("recording failed")
+			}
+			return "", err
+		}
+		return recording, nil
+	}
+
+	stop = func() error {
+		return lrcmd.Process.Signal(os.Interrupt)
+	}
+
+	return run, stop, nil
+}
+
 func UndoReplay(recording string, quiet bool, debugInfoDirs []string, cmdline string) (tgt *proc.TargetGroup, err error) {
 	if err := UndoIsAvailable(); err != nil {
 		return nil, err
@@ -615,8 +1159,8 @@ func UndoReplay(recording string, quiet bool, debugInfoDirs []string, cmdline st
 }
 
 // RecordAndReplay acts like calling Record and then Replay.
-func UndoRecordAndReplay(cmd []string, wd string, quiet bool, debugInfoDirs []string, redirects [3]string) (tgt *proc.TargetGroup, recording string, err error) {
-	recording, err = UndoRecord(cmd, wd, quiet, redirects)
+func UndoRecordAndReplay(cmd []string, wd string, quiet bool, debugInfoDirs []string, stdin string, stdout proc.OutputRedirect, stderr proc.OutputRedirect) (tgt *proc.TargetGroup, recording string, err error) {
+	recording, err = UndoRecord(cmd, wd, quiet, stdin, stdout, stderr)
 	if err != nil || recording == "" {
 		return nil, "", err
 	}
@@ -700,25 +1244,31 @@ func undoGetLogExtent(conn *gdbConn) (uint64, uint64, error) {
 	return bbcount_min, bbcount_max, nil
 }
 
-// Fetch whether the replay session is currently at the end of recorded history.
-func undoAtEndOfHistory(conn *gdbConn) (bool, error) {
+// Fetch whether the replay session is currently at the end of recorded history, and whether the
+// last resume stopped at one of the time limits.
+func undoAtEndOfHistory(conn *gdbConn) (bool, bool, error) {
 	info_fields, err := undoGetInfo(conn)
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
+	at_end, at_limit := false, false
 	for _, value := range info_fields {
-		if value == "has_exited" || value == "at_event_log_end" {
-			return true, nil
+		switch value {
+		case "has_exited", "at_event_log_end":
+			at_end = true
+		case "at_time_limit":
+			at_limit = true
 		}
 	}
-	return false, nil
+	return at_end, at_limit, nil
 }
 
 // Transform a stopPacket if necessary to represent the state of the replay session.
 //
 // Usually the packet will be passed through unaltered. Currently the only transformation
 // implemented is modify a packet at the end of replay history to look like a SIGKILL, to be
-// consistent with how RR would report this condition.
+// consistent with how RR would report this condition. udbserver stops at the time limits itself,
+// they are reported like the corresponding end of history.
 func undoHandleStopPacket(conn *gdbConn, sp stopPacket) (stopPacket, error) {
 	// TODO: find a different way of indicating end of history as opposed to actual process
 	// exit.
@@ -726,12 +1276,21 @@ func undoHandleStopPacket(conn *gdbConn, sp stopPacket) (stopPacket, error) {
 	// TODO: find a different way of indicating the start of history (currently registers as a
 	// "hardcoded breakpoint") - should we use the atstart flag that rr uses somehow?.
 
-	at_end, err := undoAtEndOfHistory(conn)
+	at_end, at_limit, err := undoAtEndOfHistory(conn)
 	if err != nil {
 		return stopPacket{}, err
 	}
 
-	if at_end {
+	if at_limit {
+		sp.watchAddr = 0
+		if conn.direction == proc.Backward {
+			// Signal 0 is how the start of history is reported in reverse execution.
+			sp.sig = 0
+			return sp, nil
+		}
+	}
+
+	if at_end || at_limit {
 		// Mirror the behaviour of rr, in which the server will send a fake SIGKILL
 		// at the end of history.
 		sp.sig = _SIGKILL
@@ -780,7 +1339,7 @@ func undoTimeString(bbcount uint64, pc uint64) string {
 
 	// Chop 3 digits at a time from the low-order end of the bbcount string.
 	var bbcount_rem uint64
-	for bbcount_rem = bbcount; bbcount_rem > 1000; bbcount_rem = bbcount_rem / 1000 {
+	for bbcount_rem = bbcount; bbcount_rem >= 1000; bbcount_rem = bbcount_rem / 1000 {
 		// Format the group with leading zeros.
 		group := fmt.Sprintf("%03d", bbcount_rem%1000)
 		bbcount_groups = append([]string{group}, bbcount_groups...)
@@ -812,6 +1371,70 @@ func undoParseServerTime(resp string) (uint64, uint64, error) {
 	return bbcount, pc, nil
 }
 
+// Layout used to display wall-clock times, RFC 3339 with microsecond precision.
+const undoWallClockLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// Fetch the wall-clock time that the recorded process saw at a bbcount. Returns the zero time if it
+// is not known: udbserver sends an empty (unsupported) response in that case. The wall-clock time
+// is only used to decorate times shown to the user, errors are logged and the zero time returned.
+func undoGetWallClock(conn *gdbConn, bbcount uint64) time.Time {
+	if !conn.undoSession.hasCapability(conn, undoCapabilityWallClock) {
+		return time.Time{}
+	}
+	resp, err := undoCmd(conn, "get_wallclock", fmt.Sprintf("%x", bbcount))
+	if err != nil {
+		if !isProtocolErrorUnsupported(err) {
+			conn.log.Errorf("could not get wall-clock time: %v", err)
+		}
+		return time.Time{}
+	}
+	var sec, nsec int64
+	if _, err := fmt.Sscanf(resp, "%x,%x", &sec, &nsec); err != nil {
+		conn.log.Errorf("can not parse wall-clock time %q", resp)
+		return time.Time{}
+	}
+	return time.Unix(sec, nsec)
+}
+
+// Return the time zone used to display and parse wall-clock times. This is UDB's
+// "wallclock_timezone" session setting: either "local", "utc" or the name of a time zone from
+// the IANA database. Without a (valid) setting UTC is used.
+func (uc *undoSession) wallClockLocation() *time.Location {
+	if uc.sessionState == nil {
+		return time.UTC
+	}
+	tz, _ := uc.sessionState.WallClockTimeZone.(string)
+	switch strings.ToLower(tz) {
+	case "", "utc":
+		return time.UTC
+	case "local":
+		return time.Local
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Format a wall-clock time for user display.
+func (uc *undoSession) wallClockString(wallclock time.Time) string {
+	return wallclock.In(uc.wallClockLocation()).Format(undoWallClockLayout)
+}
+
+// Parse a user-specified wall-clock time. The time zone offset is optional, when it is missing the
+// time is interpreted in the session's wall-clock time zone.
+func (uc *undoSession) parseWallClock(s string) (time.Time, error) {
+	if wallclock, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return wallclock, nil
+	}
+	wallclock, err := time.ParseInLocation("2006-01-02T15:04:05.999999999", s, uc.wallClockLocation())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse wall-clock time %q, expected RFC 3339 format (e.g. 2006-01-02T15:04:05Z)", s)
+	}
+	return wallclock, nil
+}
+
 // Fetch a representation of the current time as a string.
 func undoWhen(conn *gdbConn) (string, error) {
 	resp, err := undoCmd(conn, "get_time")
@@ -836,6 +1459,26 @@ func undoWhen(conn *gdbConn) (string, error) {
 	}
 
 	history_perc_fmt := fmt.Sprintf("%d%%", history_perc)
-	result := fmt.Sprintf("[replaying %s %s]", history_perc_fmt, undoTimeString(bbcount, pc))
+	time_fmt := undoTimeString(bbcount, pc)
+
+	// Add the wall-clock time, if known.
+	if wallclock := undoGetWallClock(conn, bbcount); !wallclock.IsZero() {
+		time_fmt += " " + conn.undoSession.wallClockString(wallclock)
+	}
+
+	result := fmt.Sprintf("[replaying %s %s]", history_perc_fmt, time_fmt)
 	return result, nil
 }
+
+// Fetch the current time in standard Undo time notation, which is accepted by resolveUserTime.
+func undoPosition(conn *gdbConn) (string, error) {
+	resp, err := undoCmd(conn, "get_time")
+	if err != nil {
+		return "", err
+	}
+	bbcount, pc, err := undoParseServerTime(resp)
+	if err != nil {
+		return "", err
+	}
+	return undoTimeString(bbcount, pc), nil
+}
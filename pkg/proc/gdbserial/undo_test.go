@@ -1,13 +1,24 @@
 package gdbserial_test
 
 import (
+	"debug/elf"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/undoio/delve/pkg/proc"
 	"github.com/undoio/delve/pkg/proc/gdbserial"
+	"github.com/undoio/delve/pkg/proc/gdbserial/undotest"
 	protest "github.com/undoio/delve/pkg/proc/test"
+	"golang.org/x/arch/x86/x86asm"
 )
 
 func withUndoRecording(name string, t testing.TB, fn func(grp *proc.TargetGroup, fixture protest.Fixture)) {
@@ -17,7 +28,7 @@ func withUndoRecording(name string, t testing.TB, fn func(grp *proc.TargetGroup,
 		t.Skip("test skipped, Undo tools not found")
 	}
 	t.Log("recording")
-	grp, recording, err := gdbserial.UndoRecordAndReplay([]string{fixture.Path}, ".", true, []string{}, [3]string{})
+	grp, recording, err := gdbserial.UndoRecordAndReplay([]string{fixture.Path}, ".", true, []string{}, "", proc.OutputRedirect{}, proc.OutputRedirect{})
 	if err != nil {
 		t.Fatal("Launch():", err)
 	}
@@ -233,3 +244,645 @@ func TestUndoIssue1376(t *testing.T) {
 		assertNoError(grp.Continue(), t, "Continue (backward)")
 	})
 }
+
+// withFakeUndoRecording replays a scripted recording of fixture name with
+// the fake udbserver from package undotest, so that it doesn't need a
+// LiveRecorder installation. The history function receives the debug
+// information of the fixture and returns the execution history to replay.
+func withFakeUndoRecording(name string, t *testing.T, history func(bi *proc.BinaryInfo) []undotest.Event, fn func(grp *proc.TargetGroup, fixture protest.Fixture)) {
+	fixture, recording := fakeUndoRecording(name, t, history)
+	replayFakeUndoRecording(t, fixture, recording, fn)
+}
+
+// replayFakeUndoRecording replays a recording set up by fakeUndoRecording.
+func replayFakeUndoRecording(t *testing.T, fixture protest.Fixture, recording string, fn func(grp *proc.TargetGroup, fixture protest.Fixture)) {
+	grp, err := gdbserial.UndoReplay(recording, true, []string{}, fixture.Path)
+	if err != nil {
+		t.Fatal("UndoReplay():", err)
+	}
+	defer grp.Detach(true)
+
+	fn(grp, fixture)
+}
+
+// fakeUndoRecording sets up the scripted recording used by
+// withFakeUndoRecording and returns its path without replaying it. The
+// fake udbserver will not have the optional capabilities listed in
+// unsupported.
+func fakeUndoRecording(name string, t *testing.T, history func(bi *proc.BinaryInfo) []undotest.Event, unsupported ...string) (protest.Fixture, string) {
+	fixture := protest.BuildFixture(name, 0)
+
+	bi := proc.NewBinaryInfo(runtime.GOOS, runtime.GOARCH)
+	assertNoError(bi.LoadBinaryInfo(fixture.Path, 0, nil), t, "LoadBinaryInfo")
+	exitStatus := 0
+	recording := undotest.Setup(t, &undotest.Recording{
+		Exe:        fixture.Path,
+		UUID:       "6b0b7a3c-5d3f-4e1a-9d7e-1c2b3a4d5e6f",
+		Pid:        fakeUndoTid,
+		ExitStatus: &exitStatus,
+		History:    history(bi),
+
+		Unsupported: unsupported,
+	})
+	return fixture, recording
+}
+
+const fakeUndoTid = 0x1234
+
+// fakeUndoEvent returns an event of the execution history at the entry
+// point of function fname.
+func fakeUndoEvent(t *testing.T, bi *proc.BinaryInfo, bbcount uint64, fname string) undotest.Event {
+	fns := bi.LookupFunc()[fname]
+	if len(fns) != 1 {
+		t.Fatalf("could not find function %s", fname)
+	}
+	return undotest.Event{Bbcount: bbcount, Thread: fakeUndoTid, PC: fns[0].Entry}
+}
+
+func setFunctionEntryBreakpoint(p *proc.Target, t *testing.T, fname string) *proc.Breakpoint {
+	fns := p.BinInfo().LookupFunc()[fname]
+	if len(fns) != 1 {
+		t.Fatalf("could not find function %s", fname)
+	}
+	bp, err := p.SetBreakpoint(0, fns[0].Entry, proc.UserBreakpoint, nil)
+	assertNoError(err, t, "SetBreakpoint")
+	return bp
+}
+
+func assertFunction(p *proc.Target, t *testing.T, fname string) {
+	t.Helper()
+	loc, err := p.CurrentThread().Location()
+	assertNoError(err, t, "Location")
+	if loc.Fn == nil || loc.Fn.Name != fname {
+		t.Fatalf("expected to be stopped in %s, got %#x (%v)", fname, loc.PC, loc.Fn)
+	}
+}
+
+func continuetestprogHistory(t *testing.T) func(bi *proc.BinaryInfo) []undotest.Event {
+	return func(bi *proc.BinaryInfo) []undotest.Event {
+		return []undotest.Event{
+			fakeUndoEvent(t, bi, 100, "runtime.rt0_go"),
+			fakeUndoEvent(t, bi, 1000, "main.main"),
+			fakeUndoEvent(t, bi, 2000, "main.sleepytime"),
+			fakeUndoEvent(t, bi, 3000, "main.sayhi"),
+			fakeUndoEvent(t, bi, 4000, "runtime.main"),
+		}
+	}
+}
+
+func TestFakeUndoRestartAfterExit(t *testing.T) {
+	withFakeUndoRecording("continuetestprog", t, continuetestprogHistory(t), func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		p := grp.Selected
+		setFunctionEntryBreakpoint(p, t, "main.sayhi")
+		assertNoError(grp.Continue(), t, "Continue")
+		assertFunction(p, t, "main.sayhi")
+		err := grp.Continue()
+		if _, isexited := err.(proc.ErrProcessExited); err == nil || !isexited {
+			t.Fatalf("program did not exit: %v", err)
+		}
+
+		assertNoError(grp.Restart(""), t, "Restart")
+		assertNoError(grp.Continue(), t, "Continue (after restart)")
+		assertFunction(p, t, "main.sayhi")
+	})
+}
+
+func TestFakeUndoReverseContinue(t *testing.T) {
+	withFakeUndoRecording("continuetestprog", t, continuetestprogHistory(t), func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		p := grp.Selected
+		setFunctionEntryBreakpoint(p, t, "main.main")
+		setFunctionEntryBreakpoint(p, t, "main.sleepytime")
+		setFunctionEntryBreakpoint(p, t, "main.sayhi")
+		assertNoError(grp.Restart("3000"), t, "Restart")
+		assertFunction(p, t, "main.sayhi")
+
+		assertNoError(grp.ChangeDirection(proc.Backward), t, "Switching to backward direction")
+		assertNoError(grp.Continue(), t, "Continue (backward)")
+		assertFunction(p, t, "main.sleepytime")
+		assertNoError(grp.Continue(), t, "Continue (backward)")
+		assertFunction(p, t, "main.main")
+
+		// Backward continue should stop at the start of history.
+		assertNoError(grp.Continue(), t, "Continue (backward)")
+		assertFunction(p, t, "runtime.rt0_go")
+	})
+}
+
+func TestFakeUndoCheckpoints(t *testing.T) {
+	withFakeUndoRecording("continuetestprog", t, continuetestprogHistory(t), func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		p := grp.Selected
+		setFunctionEntryBreakpoint(p, t, "main.sleepytime")
+		assertNoError(grp.Continue(), t, "Continue")
+		when0, _ := getPosition(grp, t)
+		if !strings.HasPrefix(when0, "[replaying 48% 2,000:0x") {
+			t.Fatalf("unexpected output of when: %q", when0)
+		}
+
+		cpid, err := grp.Checkpoint("sleepy")
+		assertNoError(err, t, "Checkpoint")
+		checkpoints, err := grp.Checkpoints()
+		assertNoError(err, t, "Checkpoints")
+		if len(checkpoints) != 1 || checkpoints[0].When != when0[len("[replaying 48% "):len(when0)-1] {
+			t.Fatalf("unexpected checkpoints %v", checkpoints)
+		}
+
+		assertNoError(grp.Restart("end"), t, "Restart (end)")
+		assertNoError(grp.Restart(fmt.Sprintf("c%d", cpid)), t, "Restart (checkpoint)")
+		assertFunction(p, t, "main.sleepytime")
+		when1, _ := getPosition(grp, t)
+		if when1 != when0 {
+			t.Fatalf("output of when mismatched %q != %q", when0, when1)
+		}
+
+		// The checkpoint is saved in the session file of the recording.
+		buf, err := ioutil.ReadFile(filepath.Join(os.Getenv("XDG_DATA_HOME"), "undo", "sessions", "6b0b7a3c-5d3f-4e1a-9d7e-1c2b3a4d5e6f.json"))
+		assertNoError(err, t, "ReadFile")
+		if !strings.Contains(string(buf), "\"sleepy\"") {
+			t.Fatalf("checkpoint not saved in session file:\n%s", buf)
+		}
+
+		assertNoError(grp.ClearCheckpoint(cpid), t, "ClearCheckpoint")
+		checkpoints, err = grp.Checkpoints()
+		assertNoError(err, t, "Checkpoints")
+		if len(checkpoints) != 0 {
+			t.Fatalf("wrong number of checkpoints %v (zero expected)", checkpoints)
+		}
+
+		_, err = grp.Checkpoint("start of sleepytime")
+		if err == nil {
+			t.Fatalf("checkpoint note starting with a reserved word accepted")
+		}
+	})
+}
+
+func TestFakeUndoSessionUnknownFields(t *testing.T) {
+	// Fields of the session file that Delve doesn't know about are written
+	// back unchanged.
+	fixture, recording := fakeUndoRecording("continuetestprog", t, continuetestprogHistory(t))
+	sessionPath := filepath.Join(os.Getenv("XDG_DATA_HOME"), "undo", "sessions", "6b0b7a3c-5d3f-4e1a-9d7e-1c2b3a4d5e6f.json")
+	assertNoError(os.MkdirAll(filepath.Dir(sessionPath), 0755), t, "MkdirAll")
+	session := `{
+		"version": 1,
+		"bookmarks": {},
+		"breakpoints": [
+			{"number": 1, "location": "main.main", "enabled": true, "ignore_count": 3, "commands": ["info locals"]}
+		],
+		"breakpoints_max": 1,
+		"time_limits": {"start": {"bbcount": 1000, "pc": 0}, "inclusive": true},
+		"future_field": {"a": [1, 2]}
+	}`
+	assertNoError(ioutil.WriteFile(sessionPath, []byte(session), 0644), t, "WriteFile")
+
+	replayFakeUndoRecording(t, fixture, recording, func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		// Adding and removing a checkpoint saves the session file.
+		cpid, err := grp.Checkpoint("tmp")
+		assertNoError(err, t, "Checkpoint")
+		assertNoError(grp.ClearCheckpoint(cpid), t, "ClearCheckpoint")
+
+		buf, err := ioutil.ReadFile(sessionPath)
+		assertNoError(err, t, "ReadFile")
+		var before, after interface{}
+		assertNoError(json.Unmarshal([]byte(session), &before), t, "Unmarshal")
+		assertNoError(json.Unmarshal(buf, &after), t, "Unmarshal")
+		if !reflect.DeepEqual(before, after) {
+			t.Fatalf("session file changed:\n%s", buf)
+		}
+	})
+}
+
+func TestFakeUndoRestartTime(t *testing.T) {
+	withFakeUndoRecording("continuetestprog", t, continuetestprogHistory(t), func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		p := grp.Selected
+		assertNoError(grp.Restart("2,000"), t, "Restart (bbcount)")
+		assertFunction(p, t, "main.sleepytime")
+
+		sayhi := p.BinInfo().LookupFunc()["main.sayhi"][0]
+		assertNoError(grp.Restart(fmt.Sprintf("3000:%#x", sayhi.Entry)), t, "Restart (bbcount and pc)")
+		assertFunction(p, t, "main.sayhi")
+
+		assertNoError(grp.Restart("start"), t, "Restart (start)")
+		assertFunction(p, t, "runtime.rt0_go")
+
+		if err := grp.Restart("tomorrow"); err == nil {
+			t.Fatalf("invalid time accepted")
+		}
+	})
+}
+
+func TestFakeUndoTimeLimits(t *testing.T) {
+	withFakeUndoRecording("continuetestprog", t, continuetestprogHistory(t), func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		p := grp.Selected
+		setFunctionEntryBreakpoint(p, t, "main.sayhi")
+		assertNoError(grp.Restart("2000"), t, "Restart")
+		cpid, err := grp.Checkpoint("sleepy")
+		assertNoError(err, t, "Checkpoint")
+
+		if err := grp.SetTimeLimits("3000", ""); err == nil {
+			t.Fatalf("time limits excluding the current position accepted")
+		}
+		if err := grp.SetTimeLimits("2000", "1000"); err == nil {
+			t.Fatalf("time limits with start after end accepted")
+		}
+		assertNoError(grp.SetTimeLimits("1,000", fmt.Sprintf("c%d", cpid)), t, "SetTimeLimits")
+		start, end, err := grp.TimeLimits()
+		assertNoError(err, t, "TimeLimits")
+		sleepytime := p.BinInfo().LookupFunc()["main.sleepytime"][0]
+		if start != "1,000:0x0" || end != fmt.Sprintf("2,000:%#x", sleepytime.Entry) {
+			t.Fatalf("wrong time limits %q %q", start, end)
+		}
+
+		// The breakpoint on main.sayhi is after the end limit, continue stops
+		// at the limit as if it was the end of the recording.
+		err = grp.Continue()
+		if _, isexited := err.(proc.ErrProcessExited); err == nil || !isexited {
+			t.Fatalf("continue did not stop at the end limit: %v", err)
+		}
+		if when, loc := getPosition(grp, t); !strings.HasPrefix(when, "[replaying 48% 2,000:") || loc.PC != sleepytime.Entry {
+			t.Fatalf("not stopped at the end limit: %q %#x", when, loc.PC)
+		}
+
+		assertNoError(grp.ChangeDirection(proc.Backward), t, "Switching to backward direction")
+		assertNoError(grp.Continue(), t, "Continue (backward)")
+		assertFunction(p, t, "main.main")
+		assertNoError(grp.ChangeDirection(proc.Forward), t, "Switching to forward direction")
+
+		if err := grp.Restart("3000"); err == nil {
+			t.Fatalf("restart outside of the time limits accepted")
+		}
+		assertNoError(grp.Restart("end"), t, "Restart (end)")
+		assertFunction(p, t, "main.sleepytime")
+		assertNoError(grp.Restart(""), t, "Restart")
+		assertFunction(p, t, "main.main")
+
+		// The time limits are saved in the session file of the recording.
+		buf, err := ioutil.ReadFile(filepath.Join(os.Getenv("XDG_DATA_HOME"), "undo", "sessions", "6b0b7a3c-5d3f-4e1a-9d7e-1c2b3a4d5e6f.json"))
+		assertNoError(err, t, "ReadFile")
+		if !strings.Contains(string(buf), "\"time_limits\"") {
+			t.Fatalf("time limits not saved in session file:\n%s", buf)
+		}
+
+		assertNoError(grp.SetTimeLimits("", ""), t, "SetTimeLimits (clear)")
+		assertNoError(grp.Continue(), t, "Continue")
+		assertFunction(p, t, "main.sayhi")
+	})
+}
+
+func TestFakeUndoSessionTimeLimits(t *testing.T) {
+	// Time limits saved in the session file are enforced as soon as the
+	// recording is replayed.
+	fixture, recording := fakeUndoRecording("continuetestprog", t, continuetestprogHistory(t))
+	writeFakeUndoSession(t, `{"bookmarks": {}, "time_limits": {"end": {"bbcount": 2000, "pc": 0}}}`)
+	replayFakeUndoRecording(t, fixture, recording, func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		p := grp.Selected
+		setFunctionEntryBreakpoint(p, t, "main.sayhi")
+		err := grp.Continue()
+		if _, isexited := err.(proc.ErrProcessExited); err == nil || !isexited {
+			t.Fatalf("continue did not stop at the end limit: %v", err)
+		}
+		if when, err := grp.When(); err != nil || !strings.HasPrefix(when, "[replaying 48% 2,000:") {
+			t.Fatalf("not stopped at the end limit: %q %v", when, err)
+		}
+	})
+}
+
+func TestFakeUndoTimeLimitsUnsupported(t *testing.T) {
+	// Without the time_limits capability the time limits can't be set, the
+	// ones in the session file are ignored and written back unchanged.
+	fixture, recording := fakeUndoRecording("continuetestprog", t, continuetestprogHistory(t), "time_limits")
+	sessionPath := writeFakeUndoSession(t, `{"bookmarks": {}, "time_limits": {"end": {"bbcount": 2000, "pc": 0}}}`)
+	replayFakeUndoRecording(t, fixture, recording, func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		p := grp.Selected
+		if start, end, err := grp.TimeLimits(); err != nil || start != "" || end != "" {
+			t.Fatalf("unexpected time limits %q %q %v", start, end, err)
+		}
+		if err := grp.SetTimeLimits("1000", ""); err == nil {
+			t.Fatalf("time limits set without the time_limits capability")
+		}
+		setFunctionEntryBreakpoint(p, t, "main.sayhi")
+		assertNoError(grp.Continue(), t, "Continue")
+		assertFunction(p, t, "main.sayhi")
+
+		// Adding and removing a checkpoint saves the session file.
+		cpid, err := grp.Checkpoint("tmp")
+		assertNoError(err, t, "Checkpoint")
+		assertNoError(grp.ClearCheckpoint(cpid), t, "ClearCheckpoint")
+		buf, err := ioutil.ReadFile(sessionPath)
+		assertNoError(err, t, "ReadFile")
+		var saved struct {
+			TimeLimits map[string]map[string]uint64 `json:"time_limits"`
+		}
+		assertNoError(json.Unmarshal(buf, &saved), t, "Unmarshal")
+		if end := saved.TimeLimits["end"]; len(saved.TimeLimits) != 1 || end["bbcount"] != 2000 || end["pc"] != 0 {
+			t.Fatalf("time limits not written back:\n%s", buf)
+		}
+	})
+}
+
+// writeFakeUndoSession writes the session file of the recording set up by
+// fakeUndoRecording and returns its path.
+func writeFakeUndoSession(t *testing.T, session string) string {
+	sessionPath := filepath.Join(os.Getenv("XDG_DATA_HOME"), "undo", "sessions", "6b0b7a3c-5d3f-4e1a-9d7e-1c2b3a4d5e6f.json")
+	assertNoError(os.MkdirAll(filepath.Dir(sessionPath), 0755), t, "MkdirAll")
+	assertNoError(ioutil.WriteFile(sessionPath, []byte(session), 0644), t, "WriteFile")
+	return sessionPath
+}
+
+// wallClockHistory is continuetestprogHistory with a wall-clock time for
+// every event after the first one, starting at 2026-10-16T10:03:10Z and
+// 1.5s apart.
+func wallClockHistory(t *testing.T) func(bi *proc.BinaryInfo) []undotest.Event {
+	return func(bi *proc.BinaryInfo) []undotest.Event {
+		h := continuetestprogHistory(t)(bi)
+		base := time.Date(2026, 10, 16, 10, 3, 10, 0, time.UTC)
+		for i := 1; i < len(h); i++ {
+			h[i].Wallclock = base.Add(time.Duration(i-1) * 1500 * time.Millisecond).UnixNano()
+		}
+		return h
+	}
+}
+
+func TestFakeUndoWallClock(t *testing.T) {
+	withFakeUndoRecording("continuetestprog", t, wallClockHistory(t), func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		p := grp.Selected
+		// The wall-clock time is not known at the start of the recording.
+		when, _ := getPosition(grp, t)
+		if strings.Contains(when, "2026") {
+			t.Fatalf("unexpected wall-clock time at the start of the recording: %q", when)
+		}
+
+		assertNoError(grp.Restart("@2026-10-16T10:03:11Z"), t, "Restart (wall-clock)")
+		assertFunction(p, t, "main.sleepytime")
+		when, _ = getPosition(grp, t)
+		if !strings.HasSuffix(when, " 2026-10-16T10:03:11.500000Z]") {
+			t.Fatalf("unexpected output of when: %q", when)
+		}
+
+		_, err := grp.Checkpoint("sleepy")
+		assertNoError(err, t, "Checkpoint")
+		checkpoints, err := grp.Checkpoints()
+		assertNoError(err, t, "Checkpoints")
+		if len(checkpoints) != 1 || !strings.HasSuffix(checkpoints[0].When, " 2026-10-16T10:03:11.500000Z") {
+			t.Fatalf("unexpected checkpoints %v", checkpoints)
+		}
+
+		// Without a time zone the time is interpreted as UTC.
+		assertNoError(grp.Restart("@2026-10-16T10:03:12"), t, "Restart (wall-clock without time zone)")
+		assertFunction(p, t, "main.sayhi")
+		assertNoError(grp.Restart("@2026-10-16T12:03:10+02:00"), t, "Restart (wall-clock with offset)")
+		assertFunction(p, t, "main.main")
+
+		if err := grp.Restart("@2026-10-16T10:04:00Z"); err == nil {
+			t.Fatalf("restart after the end of the recording accepted")
+		}
+		if err := grp.Restart("@yesterday"); err == nil {
+			t.Fatalf("invalid wall-clock time accepted")
+		}
+	})
+}
+
+func TestFakeUndoWallClockUnsupported(t *testing.T) {
+	// Without the wallclock capability times are shown without their
+	// wall-clock time and wall-clock positions are rejected.
+	fixture, recording := fakeUndoRecording("continuetestprog", t, wallClockHistory(t), "wallclock")
+	replayFakeUndoRecording(t, fixture, recording, func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Restart("2000"), t, "Restart")
+		when, _ := getPosition(grp, t)
+		if !strings.HasPrefix(when, "[replaying 48% 2,000:0x") || strings.Contains(when, "2026") {
+			t.Fatalf("unexpected output of when: %q", when)
+		}
+		_, err := grp.Checkpoint("sleepy")
+		assertNoError(err, t, "Checkpoint")
+		checkpoints, err := grp.Checkpoints()
+		assertNoError(err, t, "Checkpoints")
+		if len(checkpoints) != 1 || strings.Contains(checkpoints[0].When, "2026") {
+			t.Fatalf("unexpected checkpoints %v", checkpoints)
+		}
+		if err := grp.Restart("@2026-10-16T10:03:11Z"); err == nil {
+			t.Fatalf("wall-clock position accepted")
+		}
+	})
+}
+
+func TestFakeUndoSaveRecording(t *testing.T) {
+	withFakeUndoRecording("continuetestprog", t, continuetestprogHistory(t), func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Restart("4000"), t, "Restart")
+		_, err := grp.Checkpoint("late")
+		assertNoError(err, t, "Checkpoint")
+		assertNoError(grp.Restart("2000"), t, "Restart")
+		_, err = grp.Checkpoint("sleepy")
+		assertNoError(err, t, "Checkpoint")
+
+		if _, err := grp.SaveRecording(filepath.Join(t.TempDir(), "bad.undo"), "3000", "2000"); err == nil {
+			t.Fatalf("range with start after end accepted")
+		}
+
+		saved := filepath.Join(t.TempDir(), "saved.undo")
+		cps, err := grp.SaveRecording(saved, "1,500", "3000")
+		assertNoError(err, t, "SaveRecording")
+		// The checkpoint is renumbered, it is the first one of the saved
+		// recording.
+		if len(cps) != 1 || cps[0].ID != 1 || cps[0].Where != "sleepy" {
+			t.Fatalf("wrong checkpoints carried into the saved recording %v", cps)
+		}
+
+		grp2, err := gdbserial.UndoReplay(saved, true, []string{}, fixture.Path)
+		assertNoError(err, t, "UndoReplay (saved recording)")
+		defer grp2.Detach(true)
+		assertFunction(grp2.Selected, t, "main.sleepytime")
+		checkpoints, err := grp2.Checkpoints()
+		assertNoError(err, t, "Checkpoints")
+		if len(checkpoints) != 1 || checkpoints[0] != cps[0] {
+			t.Fatalf("wrong checkpoints in the saved recording %v", checkpoints)
+		}
+		assertNoError(grp2.Restart("end"), t, "Restart (end)")
+		assertFunction(grp2.Selected, t, "main.sayhi")
+	})
+}
+
+// fakeUndoInstructions decodes the instructions of function fname.
+func fakeUndoInstructions(t *testing.T, bi *proc.BinaryInfo, fname string) (pcs []uint64, insts []x86asm.Inst) {
+	fns := bi.LookupFunc()[fname]
+	if len(fns) != 1 {
+		t.Fatalf("could not find function %s", fname)
+	}
+	fn := fns[0]
+	f, err := elf.Open(bi.Images[0].Path)
+	assertNoError(err, t, "elf.Open")
+	defer f.Close()
+	text := f.Section(".text")
+	buf := make([]byte, fn.End-fn.Entry)
+	_, err = text.ReadAt(buf, int64(fn.Entry-text.Addr))
+	assertNoError(err, t, "ReadAt")
+	for off := 0; off < len(buf); {
+		inst, err := x86asm.Decode(buf[off:], 64)
+		assertNoError(err, t, "Decode")
+		pcs = append(pcs, fn.Entry+uint64(off))
+		insts = append(insts, inst)
+		off += inst.Len
+	}
+	return pcs, insts
+}
+
+// callStackHistory is the history of continuetestprog's call to
+// main.sleepytime, with enough of the stack to unwind from it.
+func callStackHistory(t *testing.T, callpc, entry2 *uint64) func(bi *proc.BinaryInfo) []undotest.Event {
+	return func(bi *proc.BinaryInfo) []undotest.Event {
+		const mainsp = 0xc000100000
+		sleepytime := bi.LookupFunc()["main.sleepytime"][0].Entry
+
+		pcs, insts := fakeUndoInstructions(t, bi, "main.main")
+		for i, inst := range insts {
+			if rel, ok := inst.Args[0].(x86asm.Rel); ok && inst.Op == x86asm.CALL && pcs[i]+uint64(inst.Len)+uint64(int64(rel)) == sleepytime {
+				*callpc = pcs[i]
+			}
+		}
+		if *callpc == 0 {
+			t.Fatal("could not find call to main.sleepytime")
+		}
+		retaddr := make([]byte, 8)
+		binary.LittleEndian.PutUint64(retaddr, *callpc+5)
+
+		pcs, _ = fakeUndoInstructions(t, bi, "main.sleepytime")
+		*entry2 = pcs[1]
+
+		return []undotest.Event{
+			fakeUndoEvent(t, bi, 100, "runtime.rt0_go"),
+			fakeUndoEvent(t, bi, 1000, "main.main"),
+			{Bbcount: 1500, Thread: fakeUndoTid, PC: *callpc, Regs: map[string]uint64{"rsp": mainsp}},
+			{Bbcount: 2000, Thread: fakeUndoTid, PC: sleepytime, Regs: map[string]uint64{"rsp": mainsp - 8}, Writes: []undotest.Memory{{Addr: mainsp - 8, Data: retaddr}}},
+			{Bbcount: 2100, Thread: fakeUndoTid, PC: *entry2},
+			fakeUndoEvent(t, bi, 3000, "main.sayhi"),
+			fakeUndoEvent(t, bi, 4000, "runtime.main"),
+		}
+	}
+}
+
+func TestFakeUndoSaveRecordingUnsupported(t *testing.T) {
+	fixture, recording := fakeUndoRecording("continuetestprog", t, continuetestprogHistory(t), "save_recording")
+	replayFakeUndoRecording(t, fixture, recording, func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		if _, err := grp.SaveRecording(filepath.Join(t.TempDir(), "saved.undo"), "", ""); err == nil {
+			t.Fatalf("recording saved without the save_recording capability")
+		}
+	})
+}
+
+func TestFakeUndoCallEntryAndSite(t *testing.T) {
+	var callpc, entry2 uint64
+	withFakeUndoRecording("continuetestprog", t, callStackHistory(t, &callpc, &entry2), func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		p := grp.Selected
+		if err := grp.StepBackToCallEntry(); err == nil {
+			t.Fatal("StepBackToCallEntry succeeded while running forward")
+		}
+
+		assertNoError(grp.Restart("2100"), t, "Restart")
+		assertNoError(grp.ChangeDirection(proc.Backward), t, "Switching to backward direction")
+		assertNoError(grp.StepBackToCallEntry(), t, "StepBackToCallEntry")
+		assertFunction(p, t, "main.sleepytime")
+		if loc, _ := p.CurrentThread().Location(); loc.PC != p.BinInfo().LookupFunc()["main.sleepytime"][0].Entry {
+			t.Fatalf("not stopped at the entry of main.sleepytime: %#x", loc.PC)
+		}
+		if err := grp.StepBackToCallEntry(); err == nil {
+			t.Fatal("StepBackToCallEntry succeeded at the entry of the function")
+		}
+
+		assertNoError(grp.Restart("2100"), t, "Restart")
+		assertNoError(grp.ChangeDirection(proc.Backward), t, "Switching to backward direction")
+		assertNoError(grp.StepBackToCallSite(), t, "StepBackToCallSite")
+		assertFunction(p, t, "main.main")
+		if loc, _ := p.CurrentThread().Location(); loc.PC != callpc {
+			t.Fatalf("not stopped at the call site of main.sleepytime: %#x (expected %#x)", loc.PC, callpc)
+		}
+	})
+}
+
+func TestFakeUndoGoroutineTimeline(t *testing.T) {
+	history := func(bi *proc.BinaryInfo) []undotest.Event {
+		// Every event on a breakpoint is followed by the execution of the next
+		// instruction, which is where stepping over the breakpoint stops.
+		var h []undotest.Event
+		add := func(bbcount uint64, fname string, ret bool) {
+			pcs, insts := fakeUndoInstructions(t, bi, fname)
+			i := 0
+			if ret {
+				for i = range insts {
+					if insts[i].Op == x86asm.RET {
+						break
+					}
+				}
+			}
+			h = append(h, undotest.Event{Bbcount: bbcount, Thread: fakeUndoTid, PC: pcs[i]})
+			if i+1 < len(pcs) {
+				h = append(h, undotest.Event{Bbcount: bbcount + 1, Thread: fakeUndoTid, PC: pcs[i+1]})
+			}
+		}
+		add(100, "runtime.rt0_go", false)
+		add(1000, "main.main", false)
+		add(1500, "runtime.newproc1", true)
+		add(2000, "runtime.execute", false)
+		add(2500, "runtime.gopark", false)
+		add(3000, "main.sayhi", false)
+		add(3500, "runtime.ready", false)
+		add(4000, "runtime.execute", false)
+		add(4500, "runtime.main", false)
+		return h
+	}
+	withFakeUndoRecording("continuetestprog", t, history, func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		p := grp.Selected
+		bp := setFunctionEntryBreakpoint(p, t, "main.sayhi")
+		assertNoError(grp.Restart("1000"), t, "Restart")
+
+		checkEvents := func(events []proc.GoroutineEvent, kinds ...proc.GoroutineEventKind) {
+			t.Helper()
+			if len(events) != len(kinds) {
+				t.Fatalf("wrong number of events %v", events)
+			}
+			for i := range events {
+				if events[i].Kind != kinds[i] || events[i].ThreadID != fakeUndoTid {
+					t.Fatalf("wrong event %d %v", i, events[i])
+				}
+			}
+		}
+
+		events, err := grp.GoroutineTimeline("", "")
+		assertNoError(err, t, "GoroutineTimeline")
+		checkEvents(events, proc.GoroutineCreated, proc.GoroutineRunning, proc.GoroutineBlocked, proc.GoroutineUnblocked, proc.GoroutineRunning)
+		if !strings.HasPrefix(events[2].Position, "2,500:0x") {
+			t.Fatalf("wrong position of event %v", events[2])
+		}
+
+		// The position in the recording is restored and the user breakpoint
+		// hit during the replay is not counted.
+		assertFunction(p, t, "main.main")
+		if bp.Logical.TotalHitCount != 0 {
+			t.Fatalf("breakpoint hit count changed to %d", bp.Logical.TotalHitCount)
+		}
+
+		events, err = grp.GoroutineTimeline("2000", "3500")
+		assertNoError(err, t, "GoroutineTimeline (from, to)")
+		checkEvents(events, proc.GoroutineRunning, proc.GoroutineBlocked, proc.GoroutineUnblocked)
+		assertFunction(p, t, "main.main")
+		start, end, err := grp.TimeLimits()
+		assertNoError(err, t, "TimeLimits")
+		if start != "" || end != "" {
+			t.Fatalf("time limits not restored: %q %q", start, end)
+		}
+
+		// The replay does not extend past the end limit set by the user.
+		assertNoError(grp.SetTimeLimits("", "3000"), t, "SetTimeLimits")
+		_, limit, err := grp.TimeLimits()
+		assertNoError(err, t, "TimeLimits")
+		limitedEvents, err := grp.GoroutineTimeline("2000", "3500")
+		assertNoError(err, t, "GoroutineTimeline (limited)")
+		checkEvents(limitedEvents, proc.GoroutineRunning, proc.GoroutineBlocked)
+		start, end, err = grp.TimeLimits()
+		assertNoError(err, t, "TimeLimits")
+		if start != "" || end != limit {
+			t.Fatalf("time limits not restored: %q %q", start, end)
+		}
+		assertNoError(grp.SetTimeLimits("", ""), t, "SetTimeLimits (clear)")
+
+		// Every event is a travel target for restart.
+		assertNoError(grp.Restart(events[1].Position), t, "Restart (event)")
+		assertFunction(p, t, "runtime.gopark")
+	})
+}
@@ -0,0 +1,158 @@
+package undotest
+
+import (
+	"debug/elf"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// ServerEnvVar is set in the environment of the fake udbserver executable
+// installed by Install. Test binaries that want to act as the fake server
+// should check it at the start of TestMain:
+//
+//	if os.Getenv(undotest.ServerEnvVar) != "" {
+//		os.Exit(undotest.ServerMain(os.Args[1:]))
+//	}
+const ServerEnvVar = "DELVE_UNDOTEST_SERVER"
+
+// RecordingEnvVar names the recording that the fake live-record executable
+// installed by Install produces when it is attached to a process.
+const RecordingEnvVar = "DELVE_UNDOTEST_RECORDING"
+
+// Install creates a fake LiveRecorder installation in dir, containing the
+// udb, live-record and udbserver executables that gdbserial.UndoIsAvailable
+// looks for. The udbserver executable runs the program at path server
+// (usually os.Args[0], the test binary) with ServerEnvVar set.
+// Dir must be added to PATH by the caller.
+//
+// The fake live-record executable can only attach to a running process
+// (live-record --pid): instead of recording it waits to be interrupted, or
+// for the process to exit, and then copies the recording named by
+// RecordingEnvVar. The fake udb executable always fails. Recordings must be
+// created with WriteRecording.
+func Install(dir, server string) error {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		return fmt.Errorf("fake udbserver not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	server, err := filepath.Abs(server)
+	if err != nil {
+		return err
+	}
+	scripts := map[string]string{
+		"udb":           "echo 'udb: not available in a fake Undo installation' >&2\nexit 1\n",
+		"live-record":   fmt.Sprintf("%s=live-record exec %s \"$@\"\n", ServerEnvVar, shellQuote(server)),
+		"udbserver_x64": fmt.Sprintf("%s=1 exec %s \"$@\"\n", ServerEnvVar, shellQuote(server)),
+	}
+	for name, body := range scripts {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body), 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrependPath adds dir at the start of the PATH environment variable and
+// returns a function that restores its previous value.
+func PrependPath(dir string) func() {
+	old := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+old)
+	return func() {
+		os.Setenv("PATH", old)
+	}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "'\\''") + "'"
+}
+
+// Setup prepares a fake LiveRecorder installation for the current test,
+// using the test binary as the fake udbserver, and writes rec to a
+// temporary directory. It returns the path of the recording, which is
+// also the one produced by the fake live-record executable.
+// Undo session files are kept in the same temporary directory. PATH and
+// XDG_DATA_HOME are restored when the test finishes.
+func Setup(t testing.TB, rec *Recording) string {
+	t.Helper()
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("fake udbserver only supported on linux/amd64")
+	}
+
+	dir, err := ioutil.TempDir("", "undotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := Install(dir, os.Args[0]); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(PrependPath(dir))
+
+	// Keep session files out of the user's home directory.
+	oldXdg, hadXdg := os.LookupEnv("XDG_DATA_HOME")
+	os.Setenv("XDG_DATA_HOME", dir)
+	t.Cleanup(func() {
+		if hadXdg {
+			os.Setenv("XDG_DATA_HOME", oldXdg)
+		} else {
+			os.Unsetenv("XDG_DATA_HOME")
+		}
+	})
+
+	path := filepath.Join(dir, "recording.undo")
+	if err := WriteRecording(path, rec); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(RecordingEnvVar, path)
+	t.Cleanup(func() { os.Unsetenv(RecordingEnvVar) })
+	return path
+}
+
+// Values used by SetupExe for the recordings it writes.
+const (
+	// ExeUUID is the UUID of the recording, it determines the name of its
+	// session file.
+	ExeUUID = "0d2f5e8a-3c41-4b7e-a9f6-2e8d1c7b5a34"
+	// ExePid is the process ID of the recorded program, also used as the ID
+	// of its thread.
+	ExePid = 0x1234
+	// ExeStack is the address of a zeroed memory region of 0x1000 bytes
+	// that can be used as the stack of the recorded thread.
+	ExeStack = 0x7f0000000000
+)
+
+// SetupExe calls Setup with a recording of the executable exe, that
+// exited with status 0 after executing the events returned by history.
+// The history function receives the addresses of the symbols of exe, which
+// are also returned along with the path of the recording.
+func SetupExe(t testing.TB, exe string, history func(syms map[string]uint64) []Event) (string, map[string]uint64) {
+	t.Helper()
+	ef, err := elf.Open(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elfsyms, err := ef.Symbols()
+	ef.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	syms := make(map[string]uint64)
+	for _, sym := range elfsyms {
+		syms[sym.Name] = sym.Value
+	}
+
+	exitStatus := 0
+	recording := Setup(t, &Recording{
+		Exe:        exe,
+		UUID:       ExeUUID,
+		Pid:        ExePid,
+		ExitStatus: &exitStatus,
+		Memory:     []Memory{{Addr: ExeStack, Data: make([]byte, 0x1000)}},
+		History:    history(syms),
+	})
+	return recording, syms
+}
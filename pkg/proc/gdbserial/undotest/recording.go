@@ -0,0 +1,150 @@
+// Package undotest provides a fake udbserver, speaking the subset of the
+// gdb remote serial protocol and of the vUDB extension packets used by the
+// Undo backend, so that the Undo code paths can be tested on machines
+// without a LiveRecorder installation.
+//
+// Instead of a real LiveRecorder recording the fake server replays a
+// scripted execution history, described by a Recording, against the real
+// executable of the recorded program.
+package undotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// recordingMarker is the header that gdbserial.UndoIsRecording looks for
+// at the start of a recording file.
+var recordingMarker = []byte("HD\x10\x00\x00\x00UndoDB recording")
+
+// Recording is a scripted execution history.
+type Recording struct {
+	// Exe is the path to the executable of the recorded program, its loadable
+	// segments are used as the initial contents of memory.
+	Exe string `json:"exe"`
+	// UUID is the ID of the recording, it determines the name of the Undo
+	// session file.
+	UUID string `json:"uuid"`
+	// Pid is the process ID of the recorded program.
+	Pid int `json:"pid"`
+	// ExitStatus is the wait status of the recorded program at the end of
+	// history, if nil the program was still running when the recording
+	// ended.
+	ExitStatus *int `json:"exit_status,omitempty"`
+	// Memory is the initial contents of memory not backed by the executable
+	// (for example the stacks of the program's threads).
+	Memory []Memory `json:"memory,omitempty"`
+	// History is the list of execution events, sorted by bbcount.
+	History []Event `json:"history"`
+	// Unsupported lists optional capabilities (as reported by
+	// get_capabilities) that the fake server should not have, to emulate
+	// older versions of udbserver. The commands implementing them are
+	// answered as unsupported.
+	Unsupported []string `json:"unsupported,omitempty"`
+}
+
+// Event is a point in the execution history of a recording, it is the
+// granularity at which the fake server can stop the program.
+type Event struct {
+	Bbcount uint64 `json:"bbcount"`
+	Thread  int    `json:"thread"`
+	PC      uint64 `json:"pc"`
+	// Regs are the values of registers, other than the PC, that changed
+	// since the previous event on the same thread.
+	Regs map[string]uint64 `json:"regs,omitempty"`
+	// Writes are the memory writes executed between the previous event and
+	// this one.
+	Writes []Memory `json:"writes,omitempty"`
+	// Wallclock is the wall-clock time seen by the program at this event,
+	// in nanoseconds since the Unix epoch, zero if unknown.
+	Wallclock int64 `json:"wallclock,omitempty"`
+}
+
+// Memory is the contents of a range of memory.
+type Memory struct {
+	Addr uint64 `json:"addr"`
+	Data []byte `json:"data"`
+}
+
+// WriteRecording writes rec to path in a format that is recognized as a
+// LiveRecorder recording by the Undo backend and can be replayed by the
+// fake server.
+func WriteRecording(path string, rec *Recording) error {
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(append([]byte{}, recordingMarker...), buf...), 0644)
+}
+
+// ReadRecording reads a recording written by WriteRecording.
+func ReadRecording(path string) (*Recording, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(buf, recordingMarker) {
+		return nil, fmt.Errorf("%s is not a recording", path)
+	}
+	rec := &Recording{}
+	if err := json.Unmarshal(buf[len(recordingMarker):], rec); err != nil {
+		return nil, err
+	}
+	if len(rec.History) == 0 {
+		return nil, errors.New("empty execution history")
+	}
+	for i := 1; i < len(rec.History); i++ {
+		if rec.History[i].Bbcount < rec.History[i-1].Bbcount {
+			return nil, fmt.Errorf("execution history not sorted by bbcount at event %d", i)
+		}
+	}
+	return rec, nil
+}
+
+// slice returns a recording containing the portion of the history of rec
+// between bbcounts start and end. The memory writes and registers of the
+// events before start are folded into the initial state of the new
+// recording, threads that have no events left are dropped.
+func (rec *Recording) slice(start, end uint64) (*Recording, error) {
+	r := &Recording{
+		Exe:    rec.Exe,
+		UUID:   rec.UUID,
+		Pid:    rec.Pid,
+		Memory: append([]Memory{}, rec.Memory...),
+
+		Unsupported: rec.Unsupported,
+	}
+	regs := map[int]map[string]uint64{}
+	for _, ev := range rec.History {
+		switch {
+		case ev.Bbcount < start:
+			r.Memory = append(r.Memory, ev.Writes...)
+			if regs[ev.Thread] == nil {
+				regs[ev.Thread] = map[string]uint64{}
+			}
+			for name, val := range ev.Regs {
+				regs[ev.Thread][name] = val
+			}
+		case ev.Bbcount <= end:
+			if regs[ev.Thread] != nil {
+				merged := regs[ev.Thread]
+				for name, val := range ev.Regs {
+					merged[name] = val
+				}
+				ev.Regs = merged
+				delete(regs, ev.Thread)
+			}
+			r.History = append(r.History, ev)
+		}
+	}
+	if len(r.History) == 0 {
+		return nil, errors.New("empty execution history")
+	}
+	if r.History[len(r.History)-1].Bbcount == rec.History[len(rec.History)-1].Bbcount {
+		r.ExitStatus = rec.ExitStatus
+	}
+	return r, nil
+}
@@ -0,0 +1,907 @@
+package undotest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// register describes a register in the target description sent by the
+// fake server.
+type register struct {
+	name    string
+	bitsize int
+	regnum  int
+}
+
+// registers is the amd64 register set exported by the fake server, the
+// regnums of fs_base and gs_base match the ones used by gdbserver, Delve
+// reads them directly to find the G struct.
+var registers = func() []register {
+	r := []register{}
+	for i, name := range []string{"rax", "rbx", "rcx", "rdx", "rsi", "rdi", "rbp", "rsp", "r8", "r9", "r10", "r11", "r12", "r13", "r14", "r15", "rip"} {
+		r = append(r, register{name, 64, i})
+	}
+	for i, name := range []string{"eflags", "cs", "ss", "ds", "es", "fs", "gs"} {
+		r = append(r, register{name, 32, 17 + i})
+	}
+	r = append(r, register{"fs_base", 64, 58}, register{"gs_base", 64, 59})
+	return r
+}()
+
+func targetXML() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<?xml version=\"1.0\"?><target><architecture>i386:x86-64</architecture>")
+	for _, reg := range registers {
+		fmt.Fprintf(&buf, "<reg name=\"%s\" bitsize=\"%d\" regnum=\"%d\"/>", reg.name, reg.bitsize, reg.regnum)
+	}
+	fmt.Fprintf(&buf, "</target>")
+	return buf.String()
+}
+
+type watchpoint struct {
+	size int
+}
+
+// Server is a fake udbserver replaying a Recording.
+type Server struct {
+	rec    *Recording
+	tmpdir string
+	base   []Memory // executable segments followed by rec.Memory
+
+	cur      int // index of the current event in rec.History
+	selected int // thread selected by the last Hg packet, 0 for the current thread
+	volatile bool
+
+	// Indexes in rec.History of the events that execution is limited to,
+	// -1 when there is no limit, and whether the last resume stopped at
+	// one of them.
+	limitStart, limitEnd int
+	atLimit              bool
+
+	breakpoints map[uint64]bool
+	watchpoints map[uint64]watchpoint
+
+	// Registers and memory written by the client since the last time
+	// travel, they are discarded as soon as the current event changes.
+	regWrites map[int]map[string]uint64
+	memWrites map[uint64]byte
+
+	ack bool
+}
+
+// NewServer returns a fake server for rec, positioned at the start of
+// history.
+func NewServer(rec *Recording) (*Server, error) {
+	s := &Server{
+		rec:         rec,
+		breakpoints: make(map[uint64]bool),
+		watchpoints: make(map[uint64]watchpoint),
+		limitStart:  -1,
+		limitEnd:    -1,
+		ack:         true,
+	}
+	f, err := elf.Open(rec.Exe)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_LOAD {
+			continue
+		}
+		data := make([]byte, prog.Memsz)
+		if _, err := prog.ReadAt(data[:prog.Filesz], 0); err != nil && err != io.EOF {
+			return nil, err
+		}
+		s.base = append(s.base, Memory{Addr: prog.Vaddr, Data: data})
+	}
+	s.base = append(s.base, rec.Memory...)
+
+	// udbserver keeps a copy of the executable of the recorded program in a
+	// temporary directory, Delve loads symbols from there.
+	s.tmpdir, err = ioutil.TempDir("", "undotest")
+	if err != nil {
+		return nil, err
+	}
+	exe, err := filepath.Abs(rec.Exe)
+	if err != nil {
+		return nil, err
+	}
+	symfile := filepath.Join(s.tmpdir, "symbol-files", exe)
+	if err := os.MkdirAll(filepath.Dir(symfile), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Symlink(exe, symfile); err != nil {
+		return nil, err
+	}
+	s.rec.Exe = exe
+	s.resetWrites()
+	return s, nil
+}
+
+// Close releases the resources used by the server.
+func (s *Server) Close() error {
+	return os.RemoveAll(s.tmpdir)
+}
+
+// Serve handles gdb remote serial protocol requests on conn until the
+// client detaches, kills the target or closes the connection.
+func (s *Server) Serve(conn io.ReadWriter) error {
+	rdr := bufio.NewReader(conn)
+	for {
+		pkt, err := readPacket(rdr)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if s.ack {
+			if _, err := conn.Write([]byte{'+'}); err != nil {
+				return err
+			}
+		}
+		resp, done := s.handle(pkt)
+		if err := writePacket(conn, resp); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// readPacket reads the next packet, skipping acknowledgments and interrupt
+// requests (the fake server never runs long enough to be interrupted).
+func readPacket(rdr *bufio.Reader) (string, error) {
+	for {
+		ch, err := rdr.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if ch != '$' {
+			continue
+		}
+		pkt, err := rdr.ReadString('#')
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.ReadFull(rdr, make([]byte, 2)); err != nil {
+			return "", err
+		}
+		return unescape(pkt[:len(pkt)-1]), nil
+	}
+}
+
+func unescape(pkt string) string {
+	if !strings.Contains(pkt, "}") {
+		return pkt
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(pkt); i++ {
+		if pkt[i] == '}' && i+1 < len(pkt) {
+			i++
+			buf.WriteByte(pkt[i] ^ 0x20)
+			continue
+		}
+		buf.WriteByte(pkt[i])
+	}
+	return buf.String()
+}
+
+func writePacket(w io.Writer, resp string) error {
+	var sum uint8
+	for i := 0; i < len(resp); i++ {
+		sum += resp[i]
+	}
+	_, err := fmt.Fprintf(w, "$%s#%02x", resp, sum)
+	return err
+}
+
+const errFault = "E14"
+
+// handle executes a single packet and returns its response, done is true
+// if the connection should be closed after sending it.
+func (s *Server) handle(pkt string) (resp string, done bool) {
+	switch {
+	case pkt == "QStartNoAckMode":
+		s.ack = false
+		return "OK", false
+	case strings.HasPrefix(pkt, "qSupported"):
+		return "PacketSize=4000;qXfer:features:read+;multiprocess-", false
+	case strings.HasPrefix(pkt, "qXfer:features:read:target.xml:"):
+		return s.qXfer(pkt, targetXML()), false
+	case pkt == "qfThreadInfo":
+		tids := []string{}
+		for _, tid := range s.threads() {
+			tids = append(tids, fmt.Sprintf("%x", tid))
+		}
+		return "m" + strings.Join(tids, ","), false
+	case pkt == "qsThreadInfo":
+		return "l", false
+	case pkt == "qProcessInfo":
+		return fmt.Sprintf("pid:%x;", s.rec.Pid), false
+	case pkt == "?":
+		return s.stopPacket(5, 0), false
+	case strings.HasPrefix(pkt, "Hg"):
+		tid, err := strconv.ParseUint(pkt[2:], 16, 64)
+		if err != nil || tid == 0 {
+			s.selected = 0
+		} else {
+			s.selected = int(tid)
+		}
+		return "OK", false
+	case strings.HasPrefix(pkt, "Hc"):
+		return "OK", false
+	case pkt == "g":
+		return s.readRegisters(), false
+	case strings.HasPrefix(pkt, "G"):
+		return s.writeRegisters(pkt[1:]), false
+	case strings.HasPrefix(pkt, "p"):
+		return s.readRegister(pkt[1:]), false
+	case strings.HasPrefix(pkt, "P"):
+		return s.writeRegister(pkt[1:]), false
+	case strings.HasPrefix(pkt, "m"):
+		return s.readMemory(pkt[1:]), false
+	case strings.HasPrefix(pkt, "M"):
+		return s.writeMemory(pkt[1:]), false
+	case strings.HasPrefix(pkt, "Z"), strings.HasPrefix(pkt, "z"):
+		return s.breakpoint(pkt[0] == 'Z', pkt[1:]), false
+	case strings.HasPrefix(pkt, "vCont;"):
+		if strings.Contains(pkt, ";s") || strings.Contains(pkt, ";S") {
+			return s.step(true), false
+		}
+		return s.cont(true), false
+	case pkt == "bc":
+		return s.cont(false), false
+	case pkt == "bs":
+		return s.step(false), false
+	case strings.HasPrefix(pkt, "vUDB;"):
+		return s.undoCmd(strings.Split(pkt[len("vUDB;"):], ";")), false
+	case pkt == "k":
+		return "X09", true
+	case pkt == "D":
+		return "OK", true
+	}
+	// Everything else (qRegisterInfo, qThreadStopInfo, qXfer:auxv, ...) is
+	// reported as unsupported, matching what Delve sees from udbserver.
+	return "", false
+}
+
+func (s *Server) qXfer(pkt, content string) string {
+	args := pkt[strings.LastIndex(pkt, ":")+1:]
+	var off, length int
+	if _, err := fmt.Sscanf(args, "%x,%x", &off, &length); err != nil {
+		return "E01"
+	}
+	if off >= len(content) {
+		return "l"
+	}
+	if off+length >= len(content) {
+		return "l" + content[off:]
+	}
+	return "m" + content[off:off+length]
+}
+
+// capabilities maps the optional capabilities reported by get_capabilities
+// to the commands that implement them.
+var capabilities = map[string][]string{
+	"wallclock":      {"get_wallclock", "find_wallclock"},
+	"save_recording": {"save_recording"},
+	"time_limits":    {"set_time_limits"},
+}
+
+func (s *Server) hasCapability(name string) bool {
+	for _, unsupported := range s.rec.Unsupported {
+		if unsupported == name {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) undoCmd(args []string) string {
+	for name, cmds := range capabilities {
+		for _, cmd := range cmds {
+			if cmd == args[0] && !s.hasCapability(name) {
+				return ""
+			}
+		}
+	}
+	switch args[0] {
+	case "get_capabilities":
+		var r []string
+		for name := range capabilities {
+			if s.hasCapability(name) {
+				r = append(r, name)
+			}
+		}
+		sort.Strings(r)
+		return strings.Join(r, ";")
+	case "get_time":
+		ev := s.rec.History[s.cur]
+		return fmt.Sprintf("%x,%x", ev.Bbcount, ev.PC)
+	case "goto_time":
+		i, ok := s.findEvent(strings.Join(args[1:], ","))
+		if !ok {
+			return "E01"
+		}
+		s.travel(i)
+		return "OK"
+	case "set_time_limits":
+		// Start and end limits as bbcount and pc pairs, both empty for a
+		// missing limit. Resuming stops at the limits as if they were the
+		// extremes of history.
+		if len(args) != 5 {
+			return "E01"
+		}
+		limits := []int{-1, -1}
+		for j := range limits {
+			if args[1+2*j] == "" {
+				continue
+			}
+			i, ok := s.findEvent(args[1+2*j] + "," + args[2+2*j])
+			if !ok {
+				return "E01"
+			}
+			limits[j] = i
+		}
+		s.limitStart, s.limitEnd = limits[0], limits[1]
+		return "OK"
+	case "goto_record_mode":
+		s.travel(len(s.rec.History) - 1)
+		return "OK"
+	case "get_wallclock":
+		// Wall-clock time of the last event at or before a bbcount, an
+		// empty response means that it is unknown.
+		var bbcount uint64
+		if len(args) < 2 {
+			return "E01"
+		}
+		if _, err := fmt.Sscanf(args[1], "%x", &bbcount); err != nil {
+			return "E01"
+		}
+		var wallclock int64
+		for _, ev := range s.rec.History {
+			if ev.Bbcount > bbcount {
+				break
+			}
+			wallclock = ev.Wallclock
+		}
+		if wallclock == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%x,%x", wallclock/1e9, wallclock%1e9)
+	case "find_wallclock":
+		// First event with a wall-clock time at or after the argument.
+		var sec, nsec int64
+		if _, err := fmt.Sscanf(strings.Join(args[1:], ","), "%x,%x", &sec, &nsec); err != nil {
+			return "E01"
+		}
+		for _, ev := range s.rec.History {
+			if ev.Wallclock != 0 && ev.Wallclock >= sec*1e9+nsec {
+				return fmt.Sprintf("%x,%x", ev.Bbcount, ev.PC)
+			}
+		}
+		return "E01"
+	case "save_recording":
+		// Saves the history between two bbcounts as a new recording, the
+		// response is the UUID of the new recording.
+		if len(args) != 4 {
+			return "E01"
+		}
+		path, err := hex.DecodeString(args[1])
+		if err != nil {
+			return "E01"
+		}
+		var start, end uint64
+		if _, err := fmt.Sscanf(args[2]+","+args[3], "%x,%x", &start, &end); err != nil {
+			return "E01"
+		}
+		rec, err := s.rec.slice(start, end)
+		if err != nil {
+			return "E01"
+		}
+		uuid := make([]byte, 16)
+		if _, err := rand.Read(uuid); err != nil {
+			return "E01"
+		}
+		rec.UUID = fmt.Sprintf("%x-%x-%x-%x-%x", uuid[:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+		if err := WriteRecording(string(path), rec); err != nil {
+			return "E01"
+		}
+		return rec.UUID
+	case "get_log_extent":
+		return fmt.Sprintf("%x,%x", s.rec.History[0].Bbcount, s.rec.History[len(s.rec.History)-1].Bbcount)
+	case "get_info":
+		if s.atLimit {
+			return "replaying;at_time_limit"
+		}
+		if s.cur != len(s.rec.History)-1 {
+			return "replaying"
+		}
+		if s.rec.ExitStatus != nil {
+			return fmt.Sprintf("replaying;has_exited,%x", *s.rec.ExitStatus)
+		}
+		return "replaying;at_event_log_end"
+	case "get_recording_ids":
+		return fmt.Sprintf("%x;%s;%s", s.rec.Pid, s.rec.UUID, s.rec.UUID)
+	case "set_debuggee_volatile":
+		s.volatile = len(args) > 1 && args[1] == "1"
+		if !s.volatile {
+			s.resetWrites()
+		}
+		return "OK"
+	case "clear_interrupt", "reset_progress_indicator":
+		return "OK"
+	case "get_load_exe_original":
+		return hex.EncodeToString([]byte(s.rec.Exe))
+	case "get_tmpdir":
+		return hex.EncodeToString([]byte(s.tmpdir))
+	}
+	return ""
+}
+
+// findEvent returns the index of the event at a time formatted like the
+// response of get_time. With a zero pc it is the first event at or after
+// the bbcount.
+func (s *Server) findEvent(time string) (int, bool) {
+	var bbcount, pc uint64
+	if _, err := fmt.Sscanf(time, "%x,%x", &bbcount, &pc); err != nil {
+		return 0, false
+	}
+	for i, ev := range s.rec.History {
+		if ev.Bbcount == bbcount && (pc == 0 || ev.PC == pc) || ev.Bbcount > bbcount && pc == 0 {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// travel moves the current position in history to the i-th event.
+func (s *Server) travel(i int) {
+	if i != s.cur {
+		s.resetWrites()
+	}
+	s.cur = i
+	s.atLimit = false
+}
+
+// stopAtLimit moves to the time limit at index limit and reports it.
+func (s *Server) stopAtLimit(limit int) string {
+	s.travel(limit)
+	s.atLimit = true
+	return s.stopPacket(5, 0)
+}
+
+func (s *Server) resetWrites() {
+	s.regWrites = make(map[int]map[string]uint64)
+	s.memWrites = make(map[uint64]byte)
+}
+
+// cont runs forward or backward until a breakpoint or watchpoint is hit or
+// the end of history, or a time limit, is reached.
+func (s *Server) cont(forward bool) string {
+	if s.volatile {
+		// Executing injected code would need a real CPU.
+		return "E01"
+	}
+	h := s.rec.History
+	if forward {
+		for i := s.cur + 1; i < len(h); i++ {
+			if s.limitEnd >= 0 && i > s.limitEnd {
+				return s.stopAtLimit(s.limitEnd)
+			}
+			if addr, ok := s.watchHit(h[i]); ok {
+				s.travel(i)
+				return s.stopPacket(5, addr)
+			}
+			if s.breakpoints[h[i].PC] {
+				s.travel(i)
+				return s.stopPacket(5, 0)
+			}
+		}
+		s.travel(len(h) - 1)
+		return s.stopPacket(5, 0)
+	}
+	for i := s.cur - 1; i >= 0; i-- {
+		if i < s.limitStart {
+			return s.stopAtLimit(s.limitStart)
+		}
+		// Running backwards a watchpoint triggers before the write is
+		// executed.
+		if addr, ok := s.watchHit(h[i+1]); ok {
+			s.travel(i)
+			return s.stopPacket(5, addr)
+		}
+		if s.breakpoints[h[i].PC] {
+			s.travel(i)
+			return s.stopPacket(5, 0)
+		}
+	}
+	s.travel(0)
+	// Signal 0 is how the start of history is reported in reverse execution.
+	return s.stopPacket(0, 0)
+}
+
+func (s *Server) step(forward bool) string {
+	switch {
+	case forward && s.limitEnd >= 0 && s.cur >= s.limitEnd:
+		return s.stopAtLimit(s.limitEnd)
+	case !forward && s.limitStart >= 0 && s.cur <= s.limitStart:
+		return s.stopAtLimit(s.limitStart)
+	case forward && s.cur < len(s.rec.History)-1:
+		s.travel(s.cur + 1)
+	case !forward && s.cur > 0:
+		s.travel(s.cur - 1)
+	}
+	return s.stopPacket(5, 0)
+}
+
+func (s *Server) watchHit(ev Event) (uint64, bool) {
+	for _, w := range ev.Writes {
+		for addr, wp := range s.watchpoints {
+			if w.Addr < addr+uint64(wp.size) && addr < w.Addr+uint64(len(w.Data)) {
+				return addr, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (s *Server) stopPacket(sig uint8, watchAddr uint64) string {
+	pkt := fmt.Sprintf("T%02xthread:%x;", sig, s.rec.History[s.cur].Thread)
+	if watchAddr != 0 {
+		pkt += fmt.Sprintf("watch:%x;", watchAddr)
+	}
+	return pkt
+}
+
+func (s *Server) breakpoint(insert bool, args string) string {
+	var typ int
+	var addr uint64
+	var kind int
+	if _, err := fmt.Sscanf(args, "%d,%x,%d", &typ, &addr, &kind); err != nil {
+		return "E01"
+	}
+	switch typ {
+	case 0, 1:
+		if insert {
+			s.breakpoints[addr] = true
+		} else {
+			delete(s.breakpoints, addr)
+		}
+	case 2, 3, 4:
+		// Read accesses are not part of the scripted history, all kinds of
+		// watchpoints trigger on writes.
+		if insert {
+			s.watchpoints[addr] = watchpoint{size: kind}
+		} else {
+			delete(s.watchpoints, addr)
+		}
+	default:
+		return ""
+	}
+	return "OK"
+}
+
+// threads returns the IDs of the threads that exist at the current
+// position in history.
+func (s *Server) threads() []int {
+	seen := map[int]bool{}
+	r := []int{}
+	for _, ev := range s.rec.History[:s.cur+1] {
+		if !seen[ev.Thread] {
+			seen[ev.Thread] = true
+			r = append(r, ev.Thread)
+		}
+	}
+	sort.Ints(r)
+	return r
+}
+
+func (s *Server) selectedThread() int {
+	if s.selected != 0 {
+		return s.selected
+	}
+	return s.rec.History[s.cur].Thread
+}
+
+// registerValues returns the registers of thread tid at the current
+// position in history.
+func (s *Server) registerValues(tid int) map[string]uint64 {
+	regs := map[string]uint64{}
+	found := false
+	for _, ev := range s.rec.History[:s.cur+1] {
+		if ev.Thread != tid {
+			continue
+		}
+		found = true
+		for name, val := range ev.Regs {
+			regs[name] = val
+		}
+		regs["rip"] = ev.PC
+	}
+	if !found {
+		return regs
+	}
+	for name, val := range s.regWrites[tid] {
+		regs[name] = val
+	}
+	return regs
+}
+
+func encodeRegister(buf *bytes.Buffer, reg register, val uint64) {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, val)
+	buf.WriteString(hex.EncodeToString(b[:reg.bitsize/8]))
+}
+
+func (s *Server) readRegisters() string {
+	regs := s.registerValues(s.selectedThread())
+	var buf bytes.Buffer
+	for _, reg := range registers {
+		encodeRegister(&buf, reg, regs[reg.name])
+	}
+	return buf.String()
+}
+
+func (s *Server) readRegister(args string) string {
+	regnum, err := strconv.ParseUint(args, 16, 64)
+	if err != nil {
+		return "E01"
+	}
+	regs := s.registerValues(s.selectedThread())
+	for _, reg := range registers {
+		if reg.regnum == int(regnum) {
+			var buf bytes.Buffer
+			encodeRegister(&buf, reg, regs[reg.name])
+			return buf.String()
+		}
+	}
+	return "E01"
+}
+
+func (s *Server) setRegister(tid int, reg register, data []byte) {
+	b := make([]byte, 8)
+	copy(b, data)
+	if s.regWrites[tid] == nil {
+		s.regWrites[tid] = make(map[string]uint64)
+	}
+	s.regWrites[tid][reg.name] = binary.LittleEndian.Uint64(b)
+}
+
+func (s *Server) writeRegisters(args string) string {
+	data, err := hex.DecodeString(args)
+	if err != nil {
+		return "E01"
+	}
+	tid := s.selectedThread()
+	for _, reg := range registers {
+		sz := reg.bitsize / 8
+		if len(data) < sz {
+			return "E01"
+		}
+		s.setRegister(tid, reg, data[:sz])
+		data = data[sz:]
+	}
+	return "OK"
+}
+
+func (s *Server) writeRegister(args string) string {
+	eq := strings.Index(args, "=")
+	if eq < 0 {
+		return "E01"
+	}
+	regnum, err := strconv.ParseUint(args[:eq], 16, 64)
+	if err != nil {
+		return "E01"
+	}
+	data, err := hex.DecodeString(args[eq+1:])
+	if err != nil {
+		return "E01"
+	}
+	for _, reg := range registers {
+		if reg.regnum == int(regnum) {
+			s.setRegister(s.selectedThread(), reg, data)
+			return "OK"
+		}
+	}
+	return "E01"
+}
+
+// readByte returns the contents of memory at addr at the current position
+// in history.
+func (s *Server) readByte(addr uint64) (byte, bool) {
+	if b, ok := s.memWrites[addr]; ok {
+		return b, true
+	}
+	for i := s.cur; i >= 0; i-- {
+		if b, ok := lookupByte(s.rec.History[i].Writes, addr); ok {
+			return b, true
+		}
+	}
+	return lookupByte(s.base, addr)
+}
+
+func lookupByte(mems []Memory, addr uint64) (byte, bool) {
+	for i := len(mems) - 1; i >= 0; i-- {
+		mem := mems[i]
+		if addr >= mem.Addr && addr < mem.Addr+uint64(len(mem.Data)) {
+			return mem.Data[addr-mem.Addr], true
+		}
+	}
+	return 0, false
+}
+
+func parseAddrLen(args string) (addr uint64, length int, rest string, err error) {
+	colon := strings.Index(args, ":")
+	if colon >= 0 {
+		rest = args[colon+1:]
+		args = args[:colon]
+	}
+	if _, err = fmt.Sscanf(args, "%x,%x", &addr, &length); err != nil {
+		return 0, 0, "", errors.New("malformed memory packet")
+	}
+	return addr, length, rest, nil
+}
+
+func (s *Server) readMemory(args string) string {
+	addr, length, _, err := parseAddrLen(args)
+	if err != nil {
+		return "E01"
+	}
+	data := make([]byte, length)
+	for i := range data {
+		b, ok := s.readByte(addr + uint64(i))
+		if !ok {
+			return errFault
+		}
+		data[i] = b
+	}
+	return hex.EncodeToString(data)
+}
+
+func (s *Server) writeMemory(args string) string {
+	addr, length, rest, err := parseAddrLen(args)
+	if err != nil {
+		return "E01"
+	}
+	data, err := hex.DecodeString(rest)
+	if err != nil || len(data) != length {
+		return "E01"
+	}
+	for i, b := range data {
+		if _, ok := s.readByte(addr + uint64(i)); !ok {
+			return errFault
+		}
+		s.memWrites[addr+uint64(i)] = b
+	}
+	return "OK"
+}
+
+// ServerMain implements the command line interface of udbserver used by
+// gdbserial.UndoReplay:
+//
+//	udbserver --load-file <recording> --connect-port <port>
+//
+// When ServerEnvVar is set to "live-record" it acts as the fake live-record
+// executable instead.
+func ServerMain(args []string) int {
+	if os.Getenv(ServerEnvVar) == "live-record" {
+		return liveRecordMain(args)
+	}
+	var recording, port string
+	for i := 0; i+1 < len(args); i++ {
+		switch args[i] {
+		case "--load-file":
+			recording = args[i+1]
+		case "--connect-port":
+			port = args[i+1]
+		}
+	}
+	if recording == "" || port == "" {
+		fmt.Fprintf(os.Stderr, "usage: udbserver --load-file <recording> --connect-port <port>\n")
+		return 1
+	}
+	if err := listenAndServe(recording, port); err != nil {
+		fmt.Fprintf(os.Stderr, "udbserver: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// liveRecordMain implements the fake live-record executable:
+//
+//	live-record --pid <pid> -o <recording>
+//
+// Rather than recording pid it waits until it is interrupted, or until pid
+// exits, and then copies the recording named by RecordingEnvVar to the
+// output file.
+func liveRecordMain(args []string) int {
+	var pid int
+	var out string
+	for i := 0; i+1 < len(args); i++ {
+		switch args[i] {
+		case "--pid":
+			pid, _ = strconv.Atoi(args[i+1])
+		case "-o":
+			out = args[i+1]
+		}
+	}
+	if pid <= 0 || out == "" {
+		fmt.Fprintf(os.Stderr, "live-record: only 'live-record --pid <pid> -o <recording>' is supported by a fake Undo installation\n")
+		return 1
+	}
+	src := os.Getenv(RecordingEnvVar)
+	if src == "" {
+		fmt.Fprintf(os.Stderr, "live-record: %s not set\n", RecordingEnvVar)
+		return 1
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+wait:
+	for {
+		select {
+		case <-sigs:
+			break wait
+		case <-ticker.C:
+			if syscall.Kill(pid, 0) != nil {
+				break wait
+			}
+		}
+	}
+
+	buf, err := ioutil.ReadFile(src)
+	if err == nil {
+		err = ioutil.WriteFile(out, buf, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "live-record: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func listenAndServe(recording, port string) error {
+	rec, err := ReadRecording(recording)
+	if err != nil {
+		return err
+	}
+	s, err := NewServer(rec)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+	conn, err := listener.Accept()
+	listener.Close()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return s.Serve(conn)
+}
@@ -0,0 +1,818 @@
+package proc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go/constant"
+	"reflect"
+
+	"github.com/undoio/delve/pkg/dwarf/godwarf"
+)
+
+const (
+	spanStateInUse = 1 // +rtype mSpanInUse
+
+	heapPageSize = 8192 // +rtype pageSize
+
+	// maxHeapRootFrames is the maximum depth of the stacktraces used to find
+	// the frames containing stack roots.
+	maxHeapRootFrames = 100
+
+	// maxHeapValues is the maximum number of values scanned by
+	// HeapReferences.
+	maxHeapValues = 1 << 20
+
+	// maxHeapCacheSize is the maximum size of a value that is read from the
+	// target in a single request.
+	maxHeapCacheSize = 1 << 20
+)
+
+// HeapObject is an object allocated on the garbage collected heap.
+type HeapObject struct {
+	Addr uint64
+	Size int64
+}
+
+// HeapRootKind is the kind of a GC root.
+type HeapRootKind uint8
+
+const (
+	// HeapRootGlobal is a package variable.
+	HeapRootGlobal HeapRootKind = iota
+	// HeapRootStack is a local variable or argument of a goroutine's frame.
+	HeapRootStack
+)
+
+func (kind HeapRootKind) String() string {
+	switch kind {
+	case HeapRootGlobal:
+		return "global"
+	case HeapRootStack:
+		return "stack"
+	default:
+		return "unknown"
+	}
+}
+
+// HeapRoot is a pointer to a heap object held by a variable that lives
+// outside of the heap.
+type HeapRoot struct {
+	Kind HeapRootKind
+	// Addr is the address of the pointer.
+	Addr uint64
+	// Name is the name of the variable containing Addr.
+	Name string
+	// Offset is the offset of Addr from the start of the variable.
+	Offset int64
+	// GoroutineID, Frame and Fn describe the stack frame of the variable,
+	// for stack roots.
+	GoroutineID int64
+	Frame       int
+	Fn          *Function
+}
+
+// HeapLink is a heap object holding a pointer to the next object in a
+// reference chain.
+type HeapLink struct {
+	Object HeapObject
+	// Offset is the offset of the pointer inside Object.
+	Offset int64
+}
+
+// HeapRefChain is a chain of pointers that goes from a GC root to a heap
+// object. Root points to the first object of Links, every object in Links
+// points to the following one and the last object in Links (or Root, if
+// Links is empty) points to the object.
+type HeapRefChain struct {
+	Root  HeapRoot
+	Links []HeapLink
+}
+
+// HeapReferences describes what keeps a heap object alive.
+type HeapReferences struct {
+	Object HeapObject
+	// Chains has one entry for every pointer to Object that can be reached
+	// from a GC root. Each chain is the shortest path from a root.
+	Chains []HeapRefChain
+}
+
+// heapRef is a pointer found while scanning a value.
+type heapRef struct {
+	slot uint64       // address of the pointer
+	p    uint64       // value of the pointer
+	typ  godwarf.Type // type of the value p points to, nil if it is not known
+}
+
+// HeapReferences returns the reference chains that keep alive the heap
+// object referred to by v: the object pointed to, if v is a pointer, map,
+// channel or function, the backing array, if v is a slice or a string, or
+// the object containing v otherwise.
+// GC roots are the package variables and the local variables and
+// arguments of the innermost frames of all goroutines. Values are scanned
+// using their DWARF types, starting from the types of the roots, so only
+// pointers are followed. Objects whose type is not known, like the ones
+// referred to by unsafe.Pointer values or by closures, are scanned
+// conservatively: every word that points into an allocated object is
+// considered a reference.
+// The scan stops with ErrCancelled when cancel is closed and fails if more
+// than maxHeapValues values are reachable.
+func (t *Target) HeapReferences(v *Variable, cancel <-chan struct{}) (*HeapReferences, error) {
+	addr, err := heapAddr(v)
+	if err != nil {
+		return nil, err
+	}
+	hs, err := newHeapScanner(t)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := hs.objectOf(addr)
+	if !ok {
+		return nil, fmt.Errorf("%#x is not the address of an object on the heap", addr)
+	}
+
+	gs, _, err := GoroutinesInfoWithCancel(t, 0, 0, cancel)
+	if err != nil {
+		return nil, err
+	}
+
+	// heapParent is the way an object was first reached, either through a
+	// root or through another object.
+	type heapParent struct {
+		root   int // index in roots, -1 if the object was reached from another object
+		from   HeapObject
+		offset int64
+	}
+
+	// heapValue is a value that was scanned, objects are scanned again
+	// when they are reached through a pointer to a different type.
+	type heapValue struct {
+		addr uint64
+		typ  godwarf.Type
+	}
+
+	var roots []HeapRoot
+	parents := map[uint64]heapParent{}
+	var referrers []heapParent
+	referrerSlots := map[uint64]bool{}
+	scanned := map[heapValue]bool{}
+	var queue []heapRef
+
+	reach := func(parent heapParent, ref heapRef) {
+		o, ok := hs.objectOf(ref.p)
+		if !ok {
+			return
+		}
+		if o.Addr == obj.Addr {
+			if parent.root >= 0 {
+				referrers = append(referrers, parent)
+			} else if parent.from.Addr != obj.Addr && !referrerSlots[ref.slot] {
+				referrerSlots[ref.slot] = true
+				referrers = append(referrers, parent)
+			}
+		}
+		if _, reached := parents[o.Addr]; !reached {
+			parents[o.Addr] = parent
+		}
+		val := heapValue{ref.p, ref.typ}
+		if ref.typ == nil {
+			val.addr = o.Addr
+		}
+		if !scanned[val] {
+			scanned[val] = true
+			queue = append(queue, ref)
+		}
+	}
+
+	err = hs.scanRoots(t, gs, cancel, func(root HeapRoot, ref heapRef) {
+		if _, ok := hs.objectOf(ref.p); ok {
+			roots = append(roots, root)
+			reach(heapParent{root: len(roots) - 1}, ref)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for len(queue) > 0 {
+		if cancelled(cancel) {
+			return nil, ErrCancelled
+		}
+		if len(scanned) > maxHeapValues {
+			return nil, fmt.Errorf("too many values reachable from GC roots, scan stopped after %d", maxHeapValues)
+		}
+		ref := queue[0]
+		queue = queue[1:]
+		o, _ := hs.objectOf(ref.p)
+		hs.scanObject(o, ref, func(ref2 heapRef) {
+			reach(heapParent{root: -1, from: o, offset: int64(ref2.slot - o.Addr)}, ref2)
+		})
+	}
+
+	r := &HeapReferences{Object: obj}
+	for _, parent := range referrers {
+		var chain HeapRefChain
+		for parent.root < 0 {
+			chain.Links = append(chain.Links, HeapLink{Object: parent.from, Offset: parent.offset})
+			parent = parents[parent.from.Addr]
+		}
+		for i, j := 0, len(chain.Links)-1; i < j; i, j = i+1, j-1 {
+			chain.Links[i], chain.Links[j] = chain.Links[j], chain.Links[i]
+		}
+		chain.Root = roots[parent.root]
+		r.Chains = append(r.Chains, chain)
+	}
+	return r, nil
+}
+
+// heapAddr returns the address of the heap object referred to by v, see
+// (*Target).HeapReferences.
+func heapAddr(v *Variable) (uint64, error) {
+	if v.Unreadable != nil {
+		return 0, v.Unreadable
+	}
+	switch v.Kind {
+	case reflect.Ptr:
+		return v.maybeDereference().Addr, nil
+	case reflect.UnsafePointer, reflect.Map, reflect.Chan, reflect.Func:
+		if v.Addr == 0 {
+			return 0, fmt.Errorf("can not determine the value of %s", v.Name)
+		}
+		return readUintRaw(v.mem, v.Addr, int64(v.bi.Arch.PtrSize()))
+	case reflect.Slice, reflect.String:
+		return v.Base, nil
+	default:
+		if v.Addr == 0 {
+			return 0, fmt.Errorf("can not take the address of %s", v.Name)
+		}
+		return v.Addr, nil
+	}
+}
+
+// heapScanner finds heap objects using the metadata the runtime keeps for
+// the garbage collector: the arena map in runtime.mheap_ points to a
+// runtime.heapArena for every arena of the heap, which has the
+// runtime.mspan of each of its pages. See runtime.spanOf.
+type heapScanner struct {
+	bi      *BinaryInfo
+	mem     MemoryReadWriter
+	ptrSize uint64
+
+	arenasAddr       uint64 // address of runtime.mheap_.arenas
+	arenaL1, arenaL2 uint64 // number of entries in each level of the arena map
+	arenaBaseOffset  uint64
+	heapArenaBytes   uint64
+	pagesPerArena    uint64
+	spansOffset      uint64 // offset of the spans field of runtime.heapArena
+
+	startAddrField, nelemsField, elemsizeField, freeindexField, allocBitsField, stateField, spanclassField *godwarf.StructField
+
+	arenas map[uint64]uint64 // address of the runtime.heapArena for each arena index
+	spans  map[uint64]*heapSpan
+
+	hasPtrs      map[godwarf.Type]bool
+	dynamicTypes map[uint64]heapDynamicType // DWARF types of the runtime._type at each address
+}
+
+// heapSpan is the part of runtime.mspan needed to find heap objects.
+type heapSpan struct {
+	base, elemsize, nelems, freeindex, allocBits uint64
+	inUse, noscan                                bool
+}
+
+func newHeapScanner(t *Target) (*heapScanner, error) {
+	bi := t.BinInfo()
+	scope := globalScope(t, bi, bi.Images[0], t.Memory())
+	// +rtype -var mheap_ mheap
+	// +rtype -field mheap.arenas anytype
+	mheap, err := scope.findGlobal("runtime", "mheap_")
+	if err != nil {
+		return nil, err
+	}
+	arenas, err := mheap.structMember("arenas")
+	if err != nil {
+		return nil, err
+	}
+
+	errLayout := errors.New("unsupported layout of the runtime heap metadata")
+
+	// arenas has type [1 << arenaL1Bits]*[1 << arenaL2Bits]*heapArena
+	arenaL1, l2typ := arrayOfPointers(arenas.RealType)
+	arenaL2, heapArenaTyp := arrayOfPointers(l2typ)
+	heapArena, ok := heapArenaTyp.(*godwarf.StructType)
+	if arenaL1 == 0 || arenaL2 == 0 || !ok {
+		return nil, errLayout
+	}
+	// +rtype -field heapArena.spans anytype
+	spansField := structField(heapArena, "spans")
+	if spansField == nil {
+		return nil, errLayout
+	}
+	pagesPerArena, mspanTyp := arrayOfPointers(spansField.Type)
+	mspan, ok := mspanTyp.(*godwarf.StructType)
+	if pagesPerArena == 0 || !ok {
+		return nil, errLayout
+	}
+
+	hs := &heapScanner{
+		bi:              bi,
+		mem:             t.Memory(),
+		ptrSize:         uint64(bi.Arch.PtrSize()),
+		arenasAddr:      arenas.Addr,
+		arenaL1:         uint64(arenaL1),
+		arenaL2:         uint64(arenaL2),
+		arenaBaseOffset: arenaBaseOffset(scope),
+		heapArenaBytes:  uint64(pagesPerArena) * heapPageSize,
+		pagesPerArena:   uint64(pagesPerArena),
+		spansOffset:     uint64(spansField.ByteOffset),
+		arenas:          make(map[uint64]uint64),
+		spans:           make(map[uint64]*heapSpan),
+		hasPtrs:         make(map[godwarf.Type]bool),
+		dynamicTypes:    make(map[uint64]heapDynamicType),
+	}
+
+	// +rtype -field mspan.startAddr uintptr
+	// +rtype -field mspan.nelems uint16|uintptr
+	// +rtype -field mspan.elemsize uintptr
+	// +rtype -field mspan.freeindex uint16|uintptr
+	// +rtype -field mspan.allocBits *gcBits
+	// +rtype -field mspan.state mSpanStateBox
+	// +rtype -field mspan.spanclass spanClass
+	for _, f := range []struct {
+		name string
+		dst  **godwarf.StructField
+	}{
+		{"startAddr", &hs.startAddrField},
+		{"nelems", &hs.nelemsField},
+		{"elemsize", &hs.elemsizeField},
+		{"freeindex", &hs.freeindexField},
+		{"allocBits", &hs.allocBitsField},
+		{"state", &hs.stateField},
+		{"spanclass", &hs.spanclassField},
+	} {
+		*f.dst = structField(mspan, f.name)
+		if *f.dst == nil {
+			return nil, errLayout
+		}
+	}
+	return hs, nil
+}
+
+// arrayOfPointers returns the length of typ, which must be an array of
+// pointers, and the type its elements point to.
+func arrayOfPointers(typ godwarf.Type) (int64, godwarf.Type) {
+	if ptyp, ok := resolveTypedef(typ).(*godwarf.PtrType); ok {
+		typ = ptyp.Type
+	}
+	atyp, ok := resolveTypedef(typ).(*godwarf.ArrayType)
+	if !ok {
+		return 0, nil
+	}
+	ptyp, ok := resolveTypedef(atyp.Type).(*godwarf.PtrType)
+	if !ok {
+		return 0, nil
+	}
+	return atyp.Count, resolveTypedef(ptyp.Type)
+}
+
+func structField(typ *godwarf.StructType, name string) *godwarf.StructField {
+	for _, field := range typ.Field {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// arenaBaseOffset returns the value of runtime.arenaBaseOffset, which is
+// only available as a typed constant in recent versions of Go.
+func arenaBaseOffset(scope *EvalScope) uint64 {
+	if v, err := scope.findGlobal("runtime", "arenaBaseOffsetUintptr"); err == nil && v.Value != nil {
+		n, _ := constant.Uint64Val(v.Value)
+		return n
+	}
+	if scope.BinInfo.Arch.Name == "amd64" {
+		return 0xffff800000000000
+	}
+	return 0
+}
+
+func (hs *heapScanner) readPtr(addr uint64) uint64 {
+	p, err := readUintRaw(hs.mem, addr, int64(hs.ptrSize))
+	if err != nil {
+		return 0
+	}
+	return p
+}
+
+// spanOf returns the in use span containing p, or nil if p does not point
+// into the heap.
+func (hs *heapScanner) spanOf(p uint64) *heapSpan {
+	ri := (p - hs.arenaBaseOffset) / hs.heapArenaBytes
+	if ri >= hs.arenaL1*hs.arenaL2 {
+		return nil
+	}
+	ha, cached := hs.arenas[ri]
+	if !cached {
+		if l2 := hs.readPtr(hs.arenasAddr + (ri/hs.arenaL2)*hs.ptrSize); l2 != 0 {
+			ha = hs.readPtr(l2 + (ri%hs.arenaL2)*hs.ptrSize)
+		}
+		hs.arenas[ri] = ha
+	}
+	if ha == 0 {
+		return nil
+	}
+	spanAddr := hs.readPtr(ha + hs.spansOffset + (p/heapPageSize%hs.pagesPerArena)*hs.ptrSize)
+	if spanAddr == 0 {
+		return nil
+	}
+	span := hs.span(spanAddr)
+	if span == nil || !span.inUse || p < span.base || p >= span.base+span.nelems*span.elemsize {
+		return nil
+	}
+	return span
+}
+
+func (hs *heapScanner) span(addr uint64) *heapSpan {
+	if span, cached := hs.spans[addr]; cached {
+		return span
+	}
+	var err error
+	field := func(f *godwarf.StructField) uint64 {
+		if err != nil {
+			return 0
+		}
+		var n uint64
+		n, err = readUintRaw(hs.mem, addr+uint64(f.ByteOffset), f.Type.Size())
+		return n
+	}
+	span := &heapSpan{
+		base:      field(hs.startAddrField),
+		elemsize:  field(hs.elemsizeField),
+		nelems:    field(hs.nelemsField),
+		freeindex: field(hs.freeindexField),
+		allocBits: field(hs.allocBitsField),
+		inUse:     field(hs.stateField) == spanStateInUse,
+		noscan:    field(hs.spanclassField)&1 != 0,
+	}
+	if err != nil || span.elemsize == 0 {
+		span = nil
+	}
+	hs.spans[addr] = span
+	return span
+}
+
+// objectOf returns the allocated heap object containing p.
+func (hs *heapScanner) objectOf(p uint64) (HeapObject, bool) {
+	span := hs.spanOf(p)
+	if span == nil {
+		return HeapObject{}, false
+	}
+	idx := (p - span.base) / span.elemsize
+	// Objects before freeindex are allocated, the others are allocated only
+	// if their bit in allocBits is set, see (*runtime.mspan).isFree.
+	if idx >= span.freeindex {
+		bits, err := readUintRaw(hs.mem, span.allocBits+idx/8, 1)
+		if err != nil || bits&(1<<(idx%8)) == 0 {
+			return HeapObject{}, false
+		}
+	}
+	return HeapObject{Addr: span.base + idx*span.elemsize, Size: int64(span.elemsize)}, true
+}
+
+// mayContainPointers returns false if the object at addr was allocated in
+// a span for objects without pointers.
+func (hs *heapScanner) mayContainPointers(addr uint64) bool {
+	span := hs.spanOf(addr)
+	return span != nil && !span.noscan
+}
+
+// scanRange calls fn for every pointer aligned word between start and end
+// with the address of the word and its value.
+func (hs *heapScanner) scanRange(start, end uint64, fn func(addr, p uint64)) error {
+	start = (start + hs.ptrSize - 1) &^ (hs.ptrSize - 1)
+	if end <= start {
+		return nil
+	}
+	buf := make([]byte, end-start)
+	if _, err := hs.mem.ReadMemory(buf, start); err != nil {
+		return err
+	}
+	for off := uint64(0); off+hs.ptrSize <= uint64(len(buf)); off += hs.ptrSize {
+		var p uint64
+		if hs.ptrSize == 4 {
+			p = uint64(binary.LittleEndian.Uint32(buf[off:]))
+		} else {
+			p = binary.LittleEndian.Uint64(buf[off:])
+		}
+		if p != 0 {
+			fn(start+off, p)
+		}
+	}
+	return nil
+}
+
+// scanObject calls fn for every pointer in the value ref points to, which
+// is part of the heap object o. If the type of the value is not known the
+// whole object is scanned conservatively.
+func (hs *heapScanner) scanObject(o HeapObject, ref heapRef, fn func(heapRef)) {
+	if !hs.mayContainPointers(o.Addr) {
+		return
+	}
+	end := o.Addr + uint64(o.Size)
+	if ref.typ == nil {
+		// Objects that can not be read are treated as if they did not contain
+		// any pointers.
+		_ = hs.scanRange(o.Addr, end, func(slot, p uint64) {
+			fn(heapRef{slot: slot, p: p})
+		})
+		return
+	}
+	hs.pointers(hs.cacheMemory(hs.mem, ref.p, end-ref.p, ref.typ), ref.p, end, ref.typ, fn)
+}
+
+// cacheMemory caches the part of the value of type typ at addr that comes
+// before limit bytes, unless it is too big.
+func (hs *heapScanner) cacheMemory(mem MemoryReadWriter, addr, limit uint64, typ godwarf.Type) MemoryReadWriter {
+	size := uint64(typ.Size())
+	if size > limit {
+		size = limit
+	}
+	if size > maxHeapCacheSize {
+		return mem
+	}
+	return cacheMemory(mem, addr, int(size))
+}
+
+func (hs *heapScanner) readPtrFrom(mem MemoryReadWriter, addr uint64) uint64 {
+	p, err := readUintRaw(mem, addr, int64(hs.ptrSize))
+	if err != nil {
+		return 0
+	}
+	return p
+}
+
+// pointers calls fn for every non-nil pointer in the value of type typ
+// stored at addr, ignoring the part of the value that comes after end.
+func (hs *heapScanner) pointers(mem MemoryReadWriter, addr, end uint64, typ godwarf.Type, fn func(heapRef)) {
+	if addr >= end || !hs.hasPointers(typ) {
+		return
+	}
+	switch t := resolveTypedef(typ).(type) {
+	case *godwarf.PtrType:
+		pointee := t.Type
+		if _, isvoid := pointee.(*godwarf.VoidType); isvoid {
+			// unsafe.Pointer
+			pointee = nil
+		}
+		if p := hs.readPtrFrom(mem, addr); p != 0 {
+			fn(heapRef{slot: addr, p: p, typ: pointee})
+		}
+	case *godwarf.FuncType:
+		// The type of the variables captured by a closure is not known.
+		if p := hs.readPtrFrom(mem, addr); p != 0 {
+			fn(heapRef{slot: addr, p: p})
+		}
+	case *godwarf.ChanType:
+		if p := hs.readPtrFrom(mem, addr); p != 0 {
+			fn(heapRef{slot: addr, p: p, typ: hs.chanType(t, p)})
+		}
+	case *godwarf.MapType:
+		if p := hs.readPtrFrom(mem, addr); p != 0 {
+			fn(heapRef{slot: addr, p: p, typ: hs.mapType(t, p)})
+		}
+	case *godwarf.SliceType:
+		var base, capacity uint64
+		var baseField *godwarf.StructField
+		for _, f := range t.Field {
+			switch f.Name {
+			case sliceArrayFieldName:
+				baseField = f
+				base = hs.readPtrFrom(mem, addr+uint64(f.ByteOffset))
+			case sliceCapFieldName:
+				capacity, _ = readUintRaw(mem, addr+uint64(f.ByteOffset), f.Type.Size())
+			}
+		}
+		if baseField != nil && base != 0 && capacity > 0 {
+			fn(heapRef{slot: addr + uint64(baseField.ByteOffset), p: base, typ: fakeArrayType(capacity, t.ElemType)})
+		}
+	case *godwarf.InterfaceType:
+		hs.interfacePointers(mem, addr, t, fn)
+	case *godwarf.StringType:
+		hs.structPointers(mem, addr, end, &t.StructType, fn)
+	case *godwarf.StructType:
+		hs.structPointers(mem, addr, end, t, fn)
+	case *godwarf.ArrayType:
+		stride := uint64(t.Type.Size())
+		if t.Count > 0 && t.ByteSize > 0 {
+			stride = uint64(t.ByteSize / t.Count)
+		}
+		for i := int64(0); i < t.Count; i++ {
+			elemAddr := addr + uint64(i)*stride
+			if elemAddr >= end {
+				break
+			}
+			hs.pointers(mem, elemAddr, end, t.Type, fn)
+		}
+	}
+}
+
+func (hs *heapScanner) structPointers(mem MemoryReadWriter, addr, end uint64, t *godwarf.StructType, fn func(heapRef)) {
+	for _, f := range t.Field {
+		hs.pointers(mem, addr+uint64(f.ByteOffset), end, f.Type, fn)
+	}
+}
+
+// hasPointers returns true if values of type typ can contain pointers.
+func (hs *heapScanner) hasPointers(typ godwarf.Type) bool {
+	if r, cached := hs.hasPtrs[typ]; cached {
+		return r
+	}
+	r := false
+	switch t := resolveTypedef(typ).(type) {
+	case *godwarf.PtrType, *godwarf.FuncType, *godwarf.ChanType, *godwarf.MapType, *godwarf.SliceType, *godwarf.StringType, *godwarf.InterfaceType:
+		r = true
+	case *godwarf.StructType:
+		for _, f := range t.Field {
+			if hs.hasPointers(f.Type) {
+				r = true
+				break
+			}
+		}
+	case *godwarf.ArrayType:
+		r = t.Count > 0 && hs.hasPointers(t.Type)
+	}
+	hs.hasPtrs[typ] = r
+	return r
+}
+
+// heapDynamicType is the DWARF type of a runtime._type.
+type heapDynamicType struct {
+	typ  godwarf.Type
+	kind int64
+}
+
+// interfacePointers calls fn for the pointers in the data of the interface
+// at addr, using its dynamic type.
+func (hs *heapScanner) interfacePointers(mem MemoryReadWriter, addr uint64, t *godwarf.InterfaceType, fn func(heapRef)) {
+	v := newVariable("", addr, t, hs.bi, mem)
+	_type, data, isnil := v.readInterface()
+	if isnil || data == nil || v.Unreadable != nil {
+		return
+	}
+	typeAddr := _type.maybeDereference().Addr
+	dt, cached := hs.dynamicTypes[typeAddr]
+	if !cached {
+		var err error
+		dt.typ, dt.kind, err = runtimeTypeToDIE(_type, data.Addr)
+		if err != nil {
+			dt.typ = nil
+		}
+		hs.dynamicTypes[typeAddr] = dt
+	}
+	if dt.typ == nil {
+		if p := hs.readPtrFrom(mem, data.Addr); p != 0 {
+			fn(heapRef{slot: data.Addr, p: p})
+		}
+		return
+	}
+	if _, isptr := resolveTypedef(dt.typ).(*godwarf.PtrType); isptr || dt.kind&kindDirectIface != 0 {
+		// The data word is the value itself, see (*Variable).loadInterface.
+		hs.pointers(mem, data.Addr, data.Addr+hs.ptrSize, dt.typ, fn)
+		return
+	}
+	if p := hs.readPtrFrom(mem, data.Addr); p != 0 {
+		fn(heapRef{slot: data.Addr, p: p, typ: dt.typ})
+	}
+}
+
+// chanType returns the type of the runtime.hchan at p, for a channel of
+// type t, with the buf field changed to a pointer to an array of the
+// channel elements, like (*Variable).loadChanInfo does.
+func (hs *heapScanner) chanType(t *godwarf.ChanType, p uint64) godwarf.Type {
+	ptyp, ok := resolveTypedef(&t.TypedefType).(*godwarf.PtrType)
+	if !ok {
+		return nil
+	}
+	st, ok := resolveTypedef(ptyp.Type).(*godwarf.StructType)
+	if !ok {
+		return nil
+	}
+	// +rtype -field hchan.dataqsiz uint
+	// +rtype -field hchan.buf unsafe.Pointer
+	sizeField := structField(st, "dataqsiz")
+	if sizeField == nil || structField(st, "buf") == nil {
+		return st
+	}
+	size, err := readUintRaw(hs.mem, p+uint64(sizeField.ByteOffset), sizeField.Type.Size())
+	if err != nil || size == 0 {
+		return st
+	}
+	return withFieldTypes(st, map[string]godwarf.Type{
+		"buf": pointerTo(fakeArrayType(size, t.ElemType), hs.bi.Arch),
+	})
+}
+
+// mapType returns the type of the map header at p, for a map of type t,
+// with the buckets and oldbuckets fields changed to pointers to arrays of
+// buckets.
+// Maps without buckets are scanned using the type of their header, which
+// does not describe the memory they point to.
+func (hs *heapScanner) mapType(t *godwarf.MapType, p uint64) godwarf.Type {
+	ptyp, ok := resolveTypedef(&t.TypedefType).(*godwarf.PtrType)
+	if !ok {
+		return nil
+	}
+	st, ok := resolveTypedef(ptyp.Type).(*godwarf.StructType)
+	if !ok {
+		return nil
+	}
+	// +rtype -field hmap.B uint8
+	// +rtype -field hmap.buckets unsafe.Pointer
+	// +rtype -field hmap.oldbuckets unsafe.Pointer
+	bField, bucketsField := structField(st, "B"), structField(st, "buckets")
+	if bField == nil || bucketsField == nil || structField(st, "oldbuckets") == nil {
+		return st
+	}
+	bucketPtr, ok := resolveTypedef(bucketsField.Type).(*godwarf.PtrType)
+	if !ok {
+		return st
+	}
+	b, err := readUintRaw(hs.mem, p+uint64(bField.ByteOffset), bField.Type.Size())
+	if err != nil || b >= 64 {
+		return st
+	}
+	fields := map[string]godwarf.Type{
+		"buckets": pointerTo(fakeArrayType(1<<b, bucketPtr.Type), hs.bi.Arch),
+	}
+	if b > 0 {
+		fields["oldbuckets"] = pointerTo(fakeArrayType(1<<(b-1), bucketPtr.Type), hs.bi.Arch)
+	}
+	return withFieldTypes(st, fields)
+}
+
+// withFieldTypes returns a copy of st with the types of some fields
+// replaced.
+func withFieldTypes(st *godwarf.StructType, types map[string]godwarf.Type) *godwarf.StructType {
+	r := &godwarf.StructType{}
+	*r = *st
+	r.Field = make([]*godwarf.StructField, len(st.Field))
+	for i := range st.Field {
+		field := &godwarf.StructField{}
+		*field = *st.Field[i]
+		if typ := types[field.Name]; typ != nil {
+			field.Type = typ
+		}
+		r.Field[i] = field
+	}
+	return r
+}
+
+// scanRoots calls fn for every pointer held by package variables and by
+// the local variables and arguments of the goroutines in gs.
+func (hs *heapScanner) scanRoots(t *Target, gs []*G, cancel <-chan struct{}, fn func(root HeapRoot, ref heapRef)) error {
+	bi := hs.bi
+	for i := range bi.packageVars {
+		pkgvar := &bi.packageVars[i]
+		scope := globalScope(t, bi, pkgvar.cu.image, hs.mem)
+		v, err := scope.packageVarVariable(pkgvar)
+		if err != nil {
+			continue
+		}
+		hs.variablePointers(v, func(ref heapRef) {
+			fn(HeapRoot{Kind: HeapRootGlobal, Addr: ref.slot, Name: pkgvar.name, Offset: int64(ref.slot - v.Addr)}, ref)
+		})
+	}
+	for _, g := range gs {
+		if cancelled(cancel) {
+			return ErrCancelled
+		}
+		if g.Status == Gdead {
+			continue
+		}
+		// A goroutine whose stack can not be unwound keeps alive only what its
+		// readable frames refer to.
+		frames, _ := g.Stacktrace(maxHeapRootFrames, 0)
+		for i := range frames {
+			vars, err := FrameToScope(t, hs.mem, g, frames[i:]...).Locals(0)
+			if err != nil {
+				continue
+			}
+			for _, v := range vars {
+				hs.variablePointers(v, func(ref heapRef) {
+					fn(HeapRoot{Kind: HeapRootStack, Addr: ref.slot, Name: v.Name, Offset: int64(ref.slot - v.Addr), GoroutineID: g.ID, Frame: i, Fn: frames[i].Call.Fn}, ref)
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// variablePointers calls fn for every pointer held by v.
+func (hs *heapScanner) variablePointers(v *Variable, fn func(heapRef)) {
+	if v.Unreadable != nil || v.RealType == nil || v.Addr == 0 {
+		return
+	}
+	end := v.Addr + uint64(v.RealType.Size())
+	hs.pointers(hs.cacheMemory(v.mem, v.Addr, end-v.Addr, v.RealType), v.Addr, end, v.RealType, fn)
+}
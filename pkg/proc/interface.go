@@ -65,6 +65,11 @@ type ProcessInternal interface {
 
 	// FollowExec enables (or disables) follow exec mode
 	FollowExec(bool) error
+
+	// CatchSyscalls makes the backend stop threads at the entry of the
+	// syscalls for which filter returns true, a nil filter disables
+	// syscall catching.
+	CatchSyscalls(filter func(nr int) bool) error
 }
 
 // RecordingManipulation is an interface for manipulating process recordings.
@@ -78,12 +83,36 @@ type RecordingManipulation interface {
 	GetDirection() Direction
 	// When returns current recording position.
 	When() (string, error)
+	// Position returns the current recording position in a form that can be
+	// passed to Restart.
+	Position() (string, error)
 	// Checkpoint sets a checkpoint at the current position.
 	Checkpoint(where string) (id int, err error)
 	// Checkpoints returns the list of currently set checkpoint.
 	Checkpoints() ([]Checkpoint, error)
 	// ClearCheckpoint removes a checkpoint.
 	ClearCheckpoint(id int) error
+	// SessionBreakpoints returns the breakpoints saved with the recording and
+	// the highest breakpoint ID ever allocated for it.
+	SessionBreakpoints() (bps []SessionBreakpoint, maxID int, err error)
+	// SaveSessionBreakpoints replaces the breakpoints saved with the recording.
+	SaveSessionBreakpoints(bps []SessionBreakpoint, maxID int) error
+	// TimeLimits returns the start and end of the portion of the recording
+	// that execution is limited to, empty strings mean there is no limit.
+	TimeLimits() (start, end string, err error)
+	// SetTimeLimits limits execution to the portion of the recording between
+	// start and end, specified like the position argument of Restart. An empty
+	// string removes the corresponding limit.
+	SetTimeLimits(start, end string) error
+	// ComparePositions compares two positions in the recording, specified
+	// like the position argument of Restart. The result is negative if a
+	// comes before b, positive if it comes after b and zero otherwise.
+	ComparePositions(a, b string) (int, error)
+	// SaveRecording saves the portion of the recording between from and to,
+	// specified like the position argument of Restart, as a new recording in
+	// path. An empty string means the corresponding end of the recording.
+	// Returns the checkpoints carried into the new recording.
+	SaveRecording(path, from, to string) ([]Checkpoint, error)
 }
 
 // RecordingManipulationInternal is an interface that a Delve backend can
@@ -116,6 +145,17 @@ type Checkpoint struct {
 	Where string
 }
 
+// SessionBreakpoint is a breakpoint saved with a recording, so that it can
+// be restored by other debuggers replaying the same recording.
+type SessionBreakpoint struct {
+	ID int
+	// Location is the location of the breakpoint as written by the debugger
+	// that saved it, usually file:line or a function name.
+	Location string
+	Cond     string
+	Enabled  bool
+}
+
 // ContinueOnceContext is an object passed to ContinueOnce that the backend
 // can use to communicate with the target layer.
 type ContinueOnceContext struct {
@@ -49,6 +49,10 @@ type osProcessDetails struct {
 	comm string
 
 	ebpf *ebpf.EBPFContext
+
+	// catchSyscalls returns true for the syscalls that should stop the
+	// target, it is nil when syscalls are not being caught.
+	catchSyscalls func(nr int) bool
 }
 
 func (os *osProcessDetails) Close() {
@@ -62,7 +66,7 @@ func (os *osProcessDetails) Close() {
 // to be supplied to that process. `wd` is working directory of the program.
 // If the DWARF information cannot be found in the binary, Delve will look
 // for external debug files in the directories passed in.
-func Launch(cmd []string, wd string, flags proc.LaunchFlags, debugInfoDirs []string, tty string, redirects [3]string) (*proc.TargetGroup, error) {
+func Launch(cmd []string, wd string, flags proc.LaunchFlags, debugInfoDirs []string, tty string, stdinPath string, stdoutOR proc.OutputRedirect, stderrOR proc.OutputRedirect) (*proc.TargetGroup, error) {
 	var (
 		process *exec.Cmd
 		err     error
@@ -70,7 +74,7 @@ func Launch(cmd []string, wd string, flags proc.LaunchFlags, debugInfoDirs []str
 
 	foreground := flags&proc.LaunchForeground != 0
 
-	stdin, stdout, stderr, closefn, err := openRedirects(redirects, foreground)
+	stdin, stdout, stderr, closefn, err := openRedirects(stdinPath, stdoutOR, stderrOR, foreground)
 	if err != nil {
 		return nil, err
 	}
@@ -238,8 +242,10 @@ func (dbp *nativeProcess) requestManualStop() (err error) {
 }
 
 const (
-	ptraceOptionsNormal     = syscall.PTRACE_O_TRACECLONE
-	ptraceOptionsFollowExec = syscall.PTRACE_O_TRACECLONE | syscall.PTRACE_O_TRACEVFORK | syscall.PTRACE_O_TRACEEXEC
+	// PTRACE_O_TRACESYSGOOD is always set so that syscall stops, which only
+	// happen while catching syscalls, can be told apart from SIGTRAPs.
+	ptraceOptionsNormal     = syscall.PTRACE_O_TRACECLONE | syscall.PTRACE_O_TRACESYSGOOD
+	ptraceOptionsFollowExec = ptraceOptionsNormal | syscall.PTRACE_O_TRACEVFORK | syscall.PTRACE_O_TRACEEXEC
 )
 
 // Attach to a newly created thread, and store that thread in our list of
@@ -485,6 +491,19 @@ func trapWaitInternal(procgrp *processGroup, pid int, options trapWaitOptions) (
 			// Sometimes we get an unknown thread, ignore it?
 			continue
 		}
+		if status.StopSignal() == sys.SIGTRAP|0x80 {
+			// Syscall stop, only the entry of a caught syscall stops the target.
+			if nr, ok := th.syscallEntryNr(); ok && dbp.os.catchSyscalls != nil && dbp.os.catchSyscalls(nr) {
+				th.os.running = false
+				th.common.SyscallEntry = true
+				th.common.SyscallNr = nr
+				return th, nil
+			}
+			if err := th.resumeWithSig(0); err != nil && err != sys.ESRCH {
+				return nil, err
+			}
+			continue
+		}
 		if (halt && status.StopSignal() == sys.SIGSTOP) || (status.StopSignal() == sys.SIGTRAP) {
 			th.os.running = false
 			if status.StopSignal() == sys.SIGTRAP {
@@ -595,7 +614,9 @@ func exitGuard(dbp *nativeProcess, procgrp *processGroup, err error) error {
 func (dbp *nativeProcess) resume() error {
 	// all threads stopped over a breakpoint are made to step over it
 	for _, thread := range dbp.threads {
-		if thread.CurrentBreakpoint.Breakpoint != nil {
+		// Threads stopped at the entry of a caught syscall are not on a
+		// breakpoint instruction and do not need to step over it.
+		if thread.CurrentBreakpoint.Breakpoint != nil && !thread.common.SyscallEntry {
 			if err := thread.StepInstruction(); err != nil {
 				return err
 			}
@@ -918,6 +939,13 @@ func (dbp *nativeProcess) FollowExec(v bool) error {
 	return err
 }
 
+// CatchSyscalls makes the target stop at the entry of the syscalls for
+// which filter returns true, a nil filter disables syscall catching.
+func (dbp *nativeProcess) CatchSyscalls(filter func(nr int) bool) error {
+	dbp.os.catchSyscalls = filter
+	return nil
+}
+
 func killProcess(pid int) error {
 	return sys.Kill(pid, sys.SIGINT)
 }
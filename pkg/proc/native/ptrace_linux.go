@@ -2,6 +2,7 @@ package native
 
 import (
 	"syscall"
+	"unsafe"
 
 	sys "golang.org/x/sys/unix"
 )
@@ -25,6 +26,31 @@ func ptraceCont(tid, sig int) error {
 	return sys.PtraceCont(tid, sig)
 }
 
+// ptraceSyscall executes ptrace PTRACE_SYSCALL
+func ptraceSyscall(tid, sig int) error {
+	return sys.PtraceSyscall(tid, sig)
+}
+
+// ptraceSyscallInfo is the beginning of struct ptrace_syscall_info, up to
+// the syscall number of the entry member of its union.
+type ptraceSyscallInfo struct {
+	op                 uint8
+	_                  [3]uint8
+	arch               uint32
+	instructionPointer uint64
+	stackPointer       uint64
+	nr                 uint64
+}
+
+// ptraceGetSyscallInfo executes ptrace PTRACE_GET_SYSCALL_INFO
+func ptraceGetSyscallInfo(tid int, info *ptraceSyscallInfo) error {
+	_, _, e1 := sys.Syscall6(sys.SYS_PTRACE, sys.PTRACE_GET_SYSCALL_INFO, uintptr(tid), unsafe.Sizeof(*info), uintptr(unsafe.Pointer(info)), 0, 0)
+	if e1 != 0 {
+		return e1
+	}
+	return nil
+}
+
 // ptraceSingleStep executes ptrace PTRACE_SINGLESTEP
 func ptraceSingleStep(pid, sig int) error {
 	_, _, e1 := sys.Syscall6(sys.SYS_PTRACE, uintptr(sys.PTRACE_SINGLESTEP), uintptr(pid), uintptr(0), uintptr(sig), 0, 0)
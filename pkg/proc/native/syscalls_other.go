@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package native
+
+import "errors"
+
+// CatchSyscalls makes the target stop at the entry of the syscalls for
+// which filter returns true, a nil filter disables syscall catching.
+func (*nativeProcess) CatchSyscalls(filter func(nr int) bool) error {
+	if filter == nil {
+		return nil
+	}
+	return errors.New("catching syscalls is not supported by this backend")
+}
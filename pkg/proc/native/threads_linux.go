@@ -46,11 +46,29 @@ func (t *nativeThread) resume() error {
 
 func (t *nativeThread) resumeWithSig(sig int) (err error) {
 	t.os.running = true
+	t.common.SyscallEntry = false
+	if t.dbp.os.catchSyscalls != nil {
+		t.dbp.execPtraceFunc(func() { err = ptraceSyscall(t.ID, sig) })
+		return
+	}
 	t.dbp.execPtraceFunc(func() { err = ptraceCont(t.ID, sig) })
 	return
 }
 
+// syscallEntryNr returns the number of the syscall the thread is about to
+// execute if it is stopped at a syscall entry stop.
+func (t *nativeThread) syscallEntryNr() (nr int, ok bool) {
+	var info ptraceSyscallInfo
+	var err error
+	t.dbp.execPtraceFunc(func() { err = ptraceGetSyscallInfo(t.ID, &info) })
+	if err != nil || info.op != sys.PTRACE_SYSCALL_INFO_ENTRY {
+		return 0, false
+	}
+	return int(info.nr), true
+}
+
 func (t *nativeThread) singleStep() (err error) {
+	t.common.SyscallEntry = false
 	sig := 0
 	for {
 		t.dbp.execPtraceFunc(func() { err = ptraceSingleStep(t.ID, sig) })
@@ -0,0 +1,68 @@
+package proc
+
+import (
+	"regexp"
+)
+
+// prettyPrinterValueName is the identifier the value being formatted is
+// bound to while the expression of a pretty printer is evaluated.
+const prettyPrinterValueName = "v"
+
+// PrettyPrinter formats the values of a type using a Delve expression.
+// The expression is evaluated with the identifier 'v' bound to the value
+// being formatted, its result is stored in the Display field of the
+// formatted variable.
+type PrettyPrinter struct {
+	// Type is the name of the type formatted by this printer.
+	Type string
+	// TypeRegexp, if not nil, is used instead of Type and matches the names
+	// of all the types formatted by this printer.
+	TypeRegexp *regexp.Regexp
+	// Expr is the expression evaluated to format a value.
+	Expr string
+}
+
+// Matches returns true if pp formats values of the type called typename.
+func (pp *PrettyPrinter) Matches(typename string) bool {
+	if pp.TypeRegexp != nil {
+		return pp.TypeRegexp.MatchString(typename)
+	}
+	return pp.Type == typename
+}
+
+// PrettyPrint sets the Display field of v, and of the variables contained
+// in v, to the result of the first printer matching their type. Errors
+// evaluating the expression of a printer are stored as an unreadable
+// Display value.
+func (scope *EvalScope) PrettyPrint(v *Variable, printers []PrettyPrinter, cfg LoadConfig) {
+	if len(printers) == 0 {
+		return
+	}
+	scope.prettyPrint(v, printers, cfg)
+}
+
+func (scope *EvalScope) prettyPrint(v *Variable, printers []PrettyPrinter, cfg LoadConfig) {
+	if v == nil || v.Unreadable != nil || v.OnlyAddr {
+		return
+	}
+	for i := range v.Children {
+		scope.prettyPrint(&v.Children[i], printers, cfg)
+	}
+	if v.DwarfType == nil {
+		return
+	}
+	typename := v.TypeString()
+	for i := range printers {
+		if !printers[i].Matches(typename) {
+			continue
+		}
+		ppscope := *scope
+		ppscope.prettyValue = v
+		display, err := ppscope.EvalExpression(printers[i].Expr, cfg)
+		if err != nil {
+			display = &Variable{Name: printers[i].Expr, Unreadable: err}
+		}
+		v.Display = display
+		return
+	}
+}
@@ -37,7 +37,7 @@ import (
 	"github.com/undoio/delve/service/api"
 )
 
-var normalLoadConfig = proc.LoadConfig{true, 1, 64, 64, -1, 0}
+var normalLoadConfig = proc.LoadConfig{true, 1, 64, 64, -1, 0, nil}
 var testBackend, buildMode string
 
 func init() {
@@ -104,18 +104,18 @@ func withTestProcessArgs(name string, t testing.TB, wd string, args []string, bu
 
 	switch testBackend {
 	case "native":
-		grp, err = native.Launch(append([]string{fixture.Path}, args...), wd, 0, []string{}, "", [3]string{})
+		grp, err = native.Launch(append([]string{fixture.Path}, args...), wd, 0, []string{}, "", "", proc.OutputRedirect{}, proc.OutputRedirect{})
 	case "lldb":
-		grp, err = gdbserial.LLDBLaunch(append([]string{fixture.Path}, args...), wd, 0, []string{}, "", [3]string{})
+		grp, err = gdbserial.LLDBLaunch(append([]string{fixture.Path}, args...), wd, 0, []string{}, "", "", proc.OutputRedirect{}, proc.OutputRedirect{})
 	case "rr":
 		protest.MustHaveRecordingAllowed(t)
 		t.Log("recording")
-		grp, tracedir, err = gdbserial.RecordAndReplay(append([]string{fixture.Path}, args...), wd, true, []string{}, [3]string{})
+		grp, tracedir, err = gdbserial.RecordAndReplay(append([]string{fixture.Path}, args...), wd, true, []string{}, "", proc.OutputRedirect{}, proc.OutputRedirect{})
 		t.Logf("replaying %q", tracedir)
 	case "undo":
 		protest.MustHaveRecordingAllowed(t)
 		t.Log("recording")
-		grp, recording, err = gdbserial.UndoRecordAndReplay(append([]string{fixture.Path}, args...), wd, true, []string{}, [3]string{})
+		grp, recording, err = gdbserial.UndoRecordAndReplay(append([]string{fixture.Path}, args...), wd, true, []string{}, "", proc.OutputRedirect{}, proc.OutputRedirect{})
 		t.Logf("replaying")
 	default:
 		t.Fatal("unknown backend")
@@ -2262,9 +2262,9 @@ func TestUnsupportedArch(t *testing.T) {
 
 	switch testBackend {
 	case "native":
-		p, err = native.Launch([]string{outfile}, ".", 0, []string{}, "", [3]string{})
+		p, err = native.Launch([]string{outfile}, ".", 0, []string{}, "", "", proc.OutputRedirect{}, proc.OutputRedirect{})
 	case "lldb":
-		p, err = gdbserial.LLDBLaunch([]string{outfile}, ".", 0, []string{}, "", [3]string{})
+		p, err = gdbserial.LLDBLaunch([]string{outfile}, ".", 0, []string{}, "", "", proc.OutputRedirect{}, proc.OutputRedirect{})
 	default:
 		t.Skip("test not valid for this backend")
 	}
@@ -2766,6 +2766,37 @@ func TestStepOutPanicAndDirectCall(t *testing.T) {
 		{contStepout, 6}})
 }
 
+func TestStepIntoCall(t *testing.T) {
+	// StepIntoCall should only step into the selected call of a line
+	// calling several functions.
+	protest.AllowRecording(t)
+	withTestProcess("stepintotargets", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture.Source, 19)
+		assertNoError(grp.Continue(), t, "Continue()")
+
+		targets, err := proc.StepIntoTargets(p, p.SelectedGoroutine())
+		assertNoError(err, t, "StepIntoTargets()")
+		names := []string{}
+		for _, target := range targets {
+			names = append(names, target.Fn.Name)
+		}
+		if want := []string{"main.g", "main.h", "main.f"}; !reflect.DeepEqual(names, want) {
+			t.Fatalf("wrong targets %q, expected %q", names, want)
+		}
+
+		assertNoError(grp.StepIntoCall(targets[1].CallPC), t, "StepIntoCall(main.h)")
+		assertFunctionName(p, t, "main.h", "StepIntoCall did not stop in main.h")
+
+		assertNoError(grp.StepOut(), t, "StepOut()")
+		assertLineNumber(p, t, 19, "StepOut()")
+		if err := grp.StepIntoCall(targets[0].CallPC); err != nil {
+			t.Fatalf("StepIntoCall(main.g) after the call: %v", err)
+		}
+		// The call of main.g was already executed, StepIntoCall behaves like Next.
+		assertLineNumber(p, t, 20, "StepIntoCall(main.g)")
+	})
+}
+
 func TestWorkDir(t *testing.T) {
 	wd := os.TempDir()
 	// For Darwin `os.TempDir()` returns `/tmp` which is symlink to `/private/tmp`.
@@ -2845,7 +2876,7 @@ func BenchmarkTrace(b *testing.B) {
 			assertNoError(grp.Continue(), b, "Continue()")
 			s, err := proc.GoroutineScope(p, p.CurrentThread())
 			assertNoError(err, b, "Scope()")
-			_, err = s.FunctionArguments(proc.LoadConfig{false, 0, 64, 0, 3, 0})
+			_, err = s.FunctionArguments(proc.LoadConfig{false, 0, 64, 0, 3, 0, nil})
 			assertNoError(err, b, "FunctionArguments()")
 		}
 		b.StopTimer()
@@ -6185,3 +6216,76 @@ func TestReadTargetArguments(t *testing.T) {
 		}
 	})
 }
+
+func TestHeapReferences(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("heaprefs", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Continue(), t, "Continue")
+
+		findChain := func(refs *proc.HeapReferences, rootName string) *proc.HeapRefChain {
+			for i := range refs.Chains {
+				if refs.Chains[i].Root.Name == rootName {
+					return &refs.Chains[i]
+				}
+			}
+			t.Fatalf("no reference chain from %s to %#x: %#v", rootName, refs.Object.Addr, refs.Chains)
+			return nil
+		}
+
+		leaf := evalVariable(p, t, "leaf")
+		refs, err := p.HeapReferences(leaf, nil)
+		assertNoError(err, t, "HeapReferences(leaf)")
+		if refs.Object.Addr != leaf.Children[0].Addr {
+			t.Fatalf("wrong object address %#x, expected %#x", refs.Object.Addr, leaf.Children[0].Addr)
+		}
+		chain := findChain(refs, "leaf")
+		if chain.Root.Kind != proc.HeapRootStack || chain.Root.Fn == nil || chain.Root.Fn.Name != "main.main" || len(chain.Links) != 0 {
+			t.Fatalf("wrong reference chain from leaf: %#v", chain)
+		}
+
+		// leaf is also referenced by the backing array of main.objs
+		chain = findChain(refs, "main.objs")
+		if chain.Root.Kind != proc.HeapRootGlobal || len(chain.Links) != 1 {
+			t.Fatalf("wrong reference chain from main.objs: %#v", chain)
+		}
+
+		// main.addr holds the address of hidden but it is not a pointer
+		hidden := evalVariable(p, t, "hidden")
+		refs, err = p.HeapReferences(hidden, nil)
+		assertNoError(err, t, "HeapReferences(hidden)")
+		findChain(refs, "hidden")
+		for _, chain := range refs.Chains {
+			if chain.Root.Name == "main.addr" {
+				t.Fatalf("main.addr reported as a reference to hidden: %#v", chain)
+			}
+		}
+
+		// head.next is referenced by the object pointed to by main.head
+		next := evalVariable(p, t, "head.next")
+		refs, err = p.HeapReferences(next, nil)
+		assertNoError(err, t, "HeapReferences(head.next)")
+		chain = findChain(refs, "main.head")
+		headv := evalVariable(p, t, "head")
+		if chain.Root.Kind != proc.HeapRootGlobal || len(chain.Links) != 1 || chain.Links[0].Object.Addr != headv.Children[0].Addr || chain.Links[0].Offset != 0 {
+			t.Fatalf("wrong reference chain from main.head: %#v", chain)
+		}
+
+		buf := evalVariable(p, t, "buf")
+		refs, err = p.HeapReferences(buf, nil)
+		assertNoError(err, t, "HeapReferences(buf)")
+		if refs.Object.Addr != buf.Base || refs.Object.Size < 16 {
+			t.Fatalf("wrong object for buf: %#v", refs.Object)
+		}
+
+		cancel := make(chan struct{})
+		close(cancel)
+		if _, err := p.HeapReferences(leaf, cancel); err != proc.ErrCancelled {
+			t.Fatalf("expected ErrCancelled, got %v", err)
+		}
+
+		_, err = p.HeapReferences(evalVariable(p, t, "&head"), nil)
+		if err == nil || !strings.Contains(err.Error(), "is not the address of an object on the heap") {
+			t.Fatalf("expected error for a global variable, got %v", err)
+		}
+	})
+}
@@ -587,7 +587,7 @@ func (g *G) readDefers(frames []Stackframe) {
 
 func (d *Defer) load() {
 	v := d.variable // +rtype _defer
-	v.loadValue(LoadConfig{false, 1, 0, 0, -1, 0})
+	v.loadValue(LoadConfig{false, 1, 0, 0, -1, 0, nil})
 	if v.Unreadable != nil {
 		d.Unreadable = v.Unreadable
 		return
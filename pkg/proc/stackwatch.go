@@ -146,6 +146,14 @@ func (t *Target) clearStackWatchBreakpoints(watchpoint *Breakpoint) error {
 // Its responsibility is to delete the watchpoint and make sure that the
 // user is notified of the watchpoint going out of scope.
 func watchpointOutOfScope(t *Target, watchpoint *Breakpoint) {
+	if !watchpoint.IsUser() {
+		// internal watchpoints are not reported to the user
+		if err := t.ClearInternalBreakpoint(watchpoint); err != nil {
+			log := logflags.DebuggerLogger()
+			log.Errorf("could not clear out-of-scope watchpoint: %v", err)
+		}
+		return
+	}
 	t.Breakpoints().WatchOutOfScope = append(t.Breakpoints().WatchOutOfScope, watchpoint)
 	err := t.ClearBreakpoint(watchpoint.Addr)
 	if err != nil {
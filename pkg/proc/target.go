@@ -34,6 +34,15 @@ const (
 	LaunchDisableASLR
 )
 
+// OutputRedirect specifies where the standard output or standard error of
+// a launched target should go. If File is set it is used directly and it is
+// not closed when the target exits, otherwise if Path is set a file is
+// created at Path. If neither is set the output goes to the output of Delve.
+type OutputRedirect struct {
+	Path string
+	File *os.File
+}
+
 // Target represents the process being debugged.
 type Target struct {
 	Process
@@ -605,6 +614,9 @@ func (t *Target) pluginOpenCallback(Thread, *Target) (bool, error) {
 }
 
 func isSuspended(t *Target, lbp *LogicalBreakpoint) bool {
+	if lbp.Set.Catch != nil {
+		return !t.hasCatchpoint(lbp.LogicalID)
+	}
 	for _, bp := range t.Breakpoints().M {
 		if bp.LogicalID() == lbp.LogicalID {
 			return false
@@ -634,6 +646,10 @@ func (*dummyRecordingManipulation) GetDirection() Direction { return Forward }
 // When will always return an empty string and nil, not supported on native proc backend.
 func (*dummyRecordingManipulation) When() (string, error) { return "", nil }
 
+// Position will always return an error on the native proc backend,
+// only supported for recorded traces.
+func (*dummyRecordingManipulation) Position() (string, error) { return "", ErrNotRecorded }
+
 // Checkpoint will always return an error on the native proc backend,
 // only supported for recorded traces.
 func (*dummyRecordingManipulation) Checkpoint(string) (int, error) { return -1, ErrNotRecorded }
@@ -646,6 +662,40 @@ func (*dummyRecordingManipulation) Checkpoints() ([]Checkpoint, error) { return
 // only supported in recorded traces.
 func (*dummyRecordingManipulation) ClearCheckpoint(int) error { return ErrNotRecorded }
 
+// SessionBreakpoints will always return an error on the native proc backend,
+// only supported for recorded traces.
+func (*dummyRecordingManipulation) SessionBreakpoints() ([]SessionBreakpoint, int, error) {
+	return nil, 0, ErrNotRecorded
+}
+
+// SaveSessionBreakpoints will always return an error on the native proc
+// backend, only supported for recorded traces.
+func (*dummyRecordingManipulation) SaveSessionBreakpoints([]SessionBreakpoint, int) error {
+	return ErrNotRecorded
+}
+
+// TimeLimits will always return an error on the native proc backend,
+// only supported for recorded traces.
+func (*dummyRecordingManipulation) TimeLimits() (string, string, error) {
+	return "", "", ErrNotRecorded
+}
+
+// SetTimeLimits will always return an error on the native proc backend,
+// only supported for recorded traces.
+func (*dummyRecordingManipulation) SetTimeLimits(string, string) error { return ErrNotRecorded }
+
+// ComparePositions will always return an error on the native proc backend,
+// only supported for recorded traces.
+func (*dummyRecordingManipulation) ComparePositions(string, string) (int, error) {
+	return 0, ErrNotRecorded
+}
+
+// SaveRecording will always return an error on the native proc backend,
+// only supported for recorded traces.
+func (*dummyRecordingManipulation) SaveRecording(string, string, string) ([]Checkpoint, error) {
+	return nil, ErrNotRecorded
+}
+
 // Restart will always return an error in the native proc backend, only for
 // recorded traces.
 func (*dummyRecordingManipulation) Restart(*ContinueOnceContext, string) (Thread, error) {
@@ -94,6 +94,7 @@ func (grp *TargetGroup) Continue() error {
 		it := ValidTargets{Group: grp}
 		for it.Next() {
 			for _, thread := range it.ThreadList() {
+				it.Target.setSyscallCatchpointHit(thread)
 				if thread.Breakpoint().Breakpoint != nil {
 					thread.Breakpoint().Breakpoint.checkCondition(it.Target, thread, thread.Breakpoint())
 				}
@@ -212,6 +213,10 @@ func (grp *TargetGroup) Continue() error {
 				dbp.StopReason = StopWatchpoint
 			}
 			return conditionErrors(grp)
+		case stopReason == StopLaunched && grp.GetDirection() == Backward:
+			// reached the start of the recording (or of the portion of the
+			// recording that execution is limited to)
+			return conditionErrors(grp)
 		default:
 			// not a manual stop, not on runtime.Breakpoint, not on a breakpoint, just repeat
 		}
@@ -443,6 +448,119 @@ func (grp *TargetGroup) Step() (err error) {
 	return grp.Continue()
 }
 
+// StepIntoCall resumes the processes in the group, continuing the selected
+// target until the next source line like Next, but steps into the function
+// called by the CALL instruction at callPC, which must be one of the
+// targets returned by StepIntoTargets for the selected goroutine. The other
+// calls made by the current line are stepped over.
+func (grp *TargetGroup) StepIntoCall(callPC uint64) (err error) {
+	if _, err := grp.Valid(); err != nil {
+		return err
+	}
+	if grp.HasSteppingBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+	if grp.GetDirection() == Backward {
+		return errors.New("can not step into a specific call backward")
+	}
+
+	dbp := grp.Selected
+	selg := dbp.SelectedGoroutine()
+	topframe, text, err := currentLineCalls(dbp, selg, dbp.CurrentThread())
+	if err != nil {
+		return err
+	}
+	var call *AsmInstruction
+	for i := range text {
+		if text[i].Loc.PC == callPC {
+			call = &text[i]
+			break
+		}
+	}
+	if call == nil {
+		return fmt.Errorf("no call at %#x on the current line", callPC)
+	}
+
+	if err = next(dbp, false, false); err != nil {
+		_ = dbp.ClearSteppingBreakpoints()
+		return err
+	}
+	if err = setStepIntoCallBreakpoint(dbp, topframe.Current.Fn, *call, sameGoroutineCondition(selg)); err != nil {
+		_ = dbp.ClearSteppingBreakpoints()
+		return err
+	}
+	return grp.Continue()
+}
+
+// StepIntoTarget is a function called by the current line of a goroutine.
+type StepIntoTarget struct {
+	// CallPC is the address of the CALL instruction.
+	CallPC uint64
+	// Fn is the function that stepping into the call arrives in,
+	// autogenerated wrappers are skipped.
+	Fn *Function
+}
+
+// StepIntoTargets returns the calls, made by the current line of g, that
+// can be stepped into with StepIntoCall, in the order they appear in the
+// function. If g is nil the current thread is used.
+// Calls before the current instruction, calls whose destination is not
+// known before they are executed and calls that Step would not step into
+// are not returned.
+func StepIntoTargets(t *Target, g *G) ([]StepIntoTarget, error) {
+	var thread Thread = t.CurrentThread()
+	if g != nil {
+		thread = g.Thread
+	}
+	topframe, text, err := currentLineCalls(t, g, thread)
+	if err != nil {
+		return nil, err
+	}
+	var r []StepIntoTarget
+	for _, instr := range text {
+		if instr.Loc.PC < topframe.Current.PC {
+			continue
+		}
+		fn, _, ok := stepIntoDestination(t, topframe.Current.Fn, instr)
+		if !ok || fn == nil {
+			continue
+		}
+		r = append(r, StepIntoTarget{CallPC: instr.Loc.PC, Fn: fn})
+	}
+	return r, nil
+}
+
+// currentLineCalls returns the topmost frame of g, or of thread if g is
+// nil, and the CALL instructions of its function that belong to its current
+// line.
+func currentLineCalls(t *Target, g *G, thread Thread) (Stackframe, []AsmInstruction, error) {
+	topframe, _, err := topframe(g, thread)
+	if err != nil {
+		return Stackframe{}, nil, err
+	}
+	if topframe.Current.Fn == nil {
+		return Stackframe{}, nil, &ErrNoSourceForPC{topframe.Current.PC}
+	}
+	var regs Registers
+	if thread != nil {
+		regs, err = thread.Registers()
+		if err != nil {
+			return Stackframe{}, nil, err
+		}
+	}
+	text, err := disassemble(t.Memory(), regs, t.Breakpoints(), t.BinInfo(), topframe.Current.Fn.Entry, topframe.Current.Fn.End, false)
+	if err != nil {
+		return Stackframe{}, nil, err
+	}
+	calls := text[:0]
+	for _, instr := range text {
+		if instr.Loc.File == topframe.Current.File && instr.Loc.Line == topframe.Current.Line && instr.IsCall() {
+			calls = append(calls, instr)
+		}
+	}
+	return topframe, calls, nil
+}
+
 // sameGoroutineCondition returns an expression that evaluates to true when
 // the current goroutine is g.
 func sameGoroutineCondition(g *G) ast.Expr {
@@ -533,6 +651,115 @@ func (grp *TargetGroup) StepOut() error {
 	return grp.Continue()
 }
 
+// StepBackToCallEntry resumes the processes in the group backward, until
+// the selected goroutine enters the function currently being executed.
+// Execution stops on the first instruction of the function.
+func (grp *TargetGroup) StepBackToCallEntry() error {
+	if grp.GetDirection() != Backward {
+		return errors.New("can only step back to the entry of a call while running backward")
+	}
+	if _, err := grp.Valid(); err != nil {
+		return err
+	}
+	if grp.HasSteppingBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+
+	dbp := grp.Selected
+	selg := dbp.SelectedGoroutine()
+
+	topframe, _, err := topframe(selg, dbp.CurrentThread())
+	if err != nil {
+		return err
+	}
+	fn := topframe.Current.Fn
+	if fn == nil {
+		return &ErrNoSourceForPC{topframe.Current.PC}
+	}
+	if topframe.Inlined {
+		return errors.New("can not step back to the entry of an inlined call")
+	}
+	if topframe.Current.PC == fn.Entry {
+		return fmt.Errorf("already at the entry of %s", fn.Name)
+	}
+
+	// The frame offset does not change between the entry of the function and
+	// the current instruction, it distinguishes the entry of this call from
+	// the entries of recursive calls made by it.
+	cond := astutil.And(sameGoroutineCondition(selg), frameoffCondition(&topframe))
+	if _, err := allowDuplicateBreakpoint(dbp.SetBreakpoint(0, fn.Entry, NextBreakpoint, cond)); err != nil {
+		dbp.ClearSteppingBreakpoints()
+		return err
+	}
+
+	return grp.Continue()
+}
+
+// StepBackToCallSite resumes the processes in the group backward, until
+// the start of the source line, in the caller, containing the call to the
+// function currently being executed by the selected goroutine. Execution
+// stops before the arguments of the call are evaluated.
+func (grp *TargetGroup) StepBackToCallSite() error {
+	if grp.GetDirection() != Backward {
+		return errors.New("can only step back to the site of a call while running backward")
+	}
+
+	// StepOut running backward uses stepOutReverse to stop on the CALL
+	// instruction.
+	if err := grp.StepOut(); err != nil {
+		return err
+	}
+	if grp.Selected.StopReason != StopNextFinished {
+		// stopped by a breakpoint or at the start of the recording
+		return nil
+	}
+
+	dbp := grp.Selected
+	selg := dbp.SelectedGoroutine()
+
+	topframe, _, err := topframe(selg, dbp.CurrentThread())
+	if err != nil {
+		return err
+	}
+	startpc, err := lineStartBefore(dbp, topframe)
+	if err != nil || startpc == topframe.Current.PC {
+		return err
+	}
+
+	cond := astutil.And(sameGoroutineCondition(selg), frameoffCondition(&topframe))
+	if _, err := allowDuplicateBreakpoint(dbp.SetBreakpoint(0, startpc, NextBreakpoint, cond)); err != nil {
+		dbp.ClearSteppingBreakpoints()
+		return err
+	}
+
+	return grp.Continue()
+}
+
+// lineStartBefore returns the address of the first instruction of the
+// contiguous range of instructions belonging to the same source line that
+// ends at the current instruction of frame.
+func lineStartBefore(p *Target, frame Stackframe) (uint64, error) {
+	pc := frame.Current.PC
+	fn := frame.Current.Fn
+	if fn == nil {
+		return pc, nil
+	}
+	text, err := disassemble(p.Memory(), nil, p.Breakpoints(), p.BinInfo(), fn.Entry, fn.End, false)
+	if err != nil {
+		return 0, err
+	}
+	for i := range text {
+		if text[i].Loc.PC != pc {
+			continue
+		}
+		for i > 0 && text[i-1].Loc.File == text[i].Loc.File && text[i-1].Loc.Line == text[i].Loc.Line {
+			i--
+		}
+		return text[i].Loc.PC, nil
+	}
+	return pc, nil
+}
+
 // StepInstruction will continue the current thread for exactly
 // one instruction. This method affects only the thread
 // associated with the selected goroutine. All other
@@ -778,24 +1005,29 @@ func setStepIntoBreakpoints(dbp *Target, curfn *Function, text []AsmInstruction,
 		if instr.Loc.File != topframe.Current.File || instr.Loc.Line != topframe.Current.Line || !instr.IsCall() {
 			continue
 		}
-
-		if instr.DestLoc != nil {
-			if err := setStepIntoBreakpoint(dbp, curfn, []AsmInstruction{instr}, sameGCond); err != nil {
-				return err
-			}
-		} else {
-			// Non-absolute call instruction, set a StepBreakpoint here
-			bp, err := allowDuplicateBreakpoint(dbp.SetBreakpoint(0, instr.Loc.PC, StepBreakpoint, sameGCond))
-			if err != nil {
-				return err
-			}
-			breaklet := bp.Breaklets[len(bp.Breaklets)-1]
-			breaklet.callback = stepIntoCallback
+		if err := setStepIntoCallBreakpoint(dbp, curfn, instr, sameGCond); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// setStepIntoCallBreakpoint sets a breakpoint inside the function called by
+// the CALL instruction instr.
+func setStepIntoCallBreakpoint(dbp *Target, curfn *Function, instr AsmInstruction, cond ast.Expr) error {
+	if instr.DestLoc != nil {
+		return setStepIntoBreakpoint(dbp, curfn, []AsmInstruction{instr}, cond)
+	}
+	// Non-absolute call instruction, set a StepBreakpoint here
+	bp, err := allowDuplicateBreakpoint(dbp.SetBreakpoint(0, instr.Loc.PC, StepBreakpoint, cond))
+	if err != nil {
+		return err
+	}
+	breaklet := bp.Breaklets[len(bp.Breaklets)-1]
+	breaklet.callback = stepIntoCallback
+	return nil
+}
+
 // stepIntoCallback is a callback called when a StepBreakpoint is hit, it
 // disassembles the current instruction to figure out its destination and
 // sets a breakpoint on it.
@@ -903,24 +1135,48 @@ func setStepIntoBreakpoint(dbp *Target, curfn *Function, text []AsmInstruction,
 		return nil
 	}
 
-	// If the current function is already a runtime function then
-	// setStepIntoBreakpoint is allowed to step into unexported runtime
-	// functions.
-	stepIntoUnexportedRuntime := curfn != nil && strings.HasPrefix(curfn.Name, "runtime.")
+	fn, pc, ok := stepIntoDestination(dbp, curfn, text[0])
+	if !ok {
+		return nil
+	}
+
+	// We want to skip the function prologue but we should only do it if the
+	// destination address of the CALL instruction is the entry point of the
+	// function.
+	// Calls to runtime.duffzero and duffcopy inserted by the compiler can
+	// sometimes point inside the body of those functions, well after the
+	// prologue.
+	if fn != nil && fn.Entry == pc {
+		pc, _ = FirstPCAfterPrologue(dbp, fn, false)
+	}
 
-	instr := text[0]
+	// Set a breakpoint after the function's prologue
+	if _, err := allowDuplicateBreakpoint(dbp.SetBreakpoint(0, pc, NextBreakpoint, cond)); err != nil {
+		return err
+	}
 
+	return nil
+}
+
+// stepIntoDestination returns the function, and the address in it, where
+// stepping into the CALL instruction instr should arrive. Returns false if
+// the destination can not be determined or should not be stepped into.
+func stepIntoDestination(dbp *Target, curfn *Function, instr AsmInstruction) (*Function, uint64, bool) {
 	if instr.DestLoc == nil {
 		// Call destination couldn't be resolved because this was not the
 		// current instruction, therefore the step-into breakpoint can not be set.
-		return nil
+		return nil, 0, false
 	}
 
+	// If the current function is already a runtime function then
+	// it is allowed to step into unexported runtime functions.
+	stepIntoUnexportedRuntime := curfn != nil && strings.HasPrefix(curfn.Name, "runtime.")
+
 	fn := instr.DestLoc.Fn
 
 	// Skip unexported runtime functions
 	if !stepIntoUnexportedRuntime && fn != nil && fn.privateRuntime() {
-		return nil
+		return nil, 0, false
 	}
 
 	//TODO(aarzilli): if we want to let users hide functions
@@ -931,27 +1187,11 @@ func setStepIntoBreakpoint(dbp *Target, curfn *Function, text []AsmInstruction,
 
 	// Skip InhibitStepInto functions for different arch.
 	if dbp.BinInfo().Arch.inhibitStepInto(dbp.BinInfo(), pc) {
-		return nil
+		return nil, 0, false
 	}
 
 	fn, pc = skipAutogeneratedWrappersIn(dbp, fn, pc)
-
-	// We want to skip the function prologue but we should only do it if the
-	// destination address of the CALL instruction is the entry point of the
-	// function.
-	// Calls to runtime.duffzero and duffcopy inserted by the compiler can
-	// sometimes point inside the body of those functions, well after the
-	// prologue.
-	if fn != nil && fn.Entry == pc {
-		pc, _ = FirstPCAfterPrologue(dbp, fn, false)
-	}
-
-	// Set a breakpoint after the function's prologue
-	if _, err := allowDuplicateBreakpoint(dbp.SetBreakpoint(0, pc, NextBreakpoint, cond)); err != nil {
-		return err
-	}
-
-	return nil
+	return fn, pc, true
 }
 
 func allowDuplicateBreakpoint(bp *Breakpoint, err error) (*Breakpoint, error) {
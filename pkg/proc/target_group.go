@@ -35,6 +35,9 @@ type TargetGroup struct {
 	KeepSteppingBreakpoints KeepSteppingBreakpoints
 
 	LogicalBreakpoints map[int]*LogicalBreakpoint
+	// deletedHits records the goroutines that triggered the logical
+	// breakpoints deleted by DeleteLogicalBreakpoint, by logical ID.
+	deletedHits map[int]*afterHit
 
 	cctx    *ContinueOnceContext
 	cfg     NewTargetGroupConfig
@@ -75,6 +78,8 @@ func Restart(grp, oldgrp *TargetGroup, discard func(*LogicalBreakpoint, error))
 		grp.LogicalBreakpoints[bp.LogicalID] = bp
 		bp.TotalHitCount = 0
 		bp.HitCount = make(map[int64]uint64)
+		bp.afterHit = nil
+		bp.hits = nil
 		bp.Set.PidAddrs = nil // breakpoints set through a list of addresses can not be restored after a restart
 		if bp.Enabled {
 			err := grp.EnableBreakpoint(bp)
@@ -262,6 +267,12 @@ targetLoop:
 	if err0 != nil {
 		it := ValidTargets{Group: grp}
 		for it.Next() {
+			if lbp.Set.Catch != nil {
+				if err1 := it.clearCatchpoint(lbp.LogicalID); err1 != nil {
+					return fmt.Errorf("error while creating breakpoint: %v, additionally the breakpoint could not be properly rolled back: %v", err0, err1)
+				}
+				continue
+			}
 			for _, bp := range it.Breakpoints().M {
 				if bp.LogicalID() == lbp.LogicalID {
 					if err1 := it.ClearBreakpoint(bp.Addr); err1 != nil {
@@ -276,10 +287,42 @@ targetLoop:
 	return nil
 }
 
+// DeleteLogicalBreakpoint removes lbp from LogicalBreakpoints. If lbp was
+// triggered breakpoints created later can still be made to depend on it,
+// see WasTriggered and InheritAfterHits.
+func (grp *TargetGroup) DeleteLogicalBreakpoint(lbp *LogicalBreakpoint) {
+	delete(grp.LogicalBreakpoints, lbp.LogicalID)
+	if lbp.hits != nil {
+		if grp.deletedHits == nil {
+			grp.deletedHits = make(map[int]*afterHit)
+		}
+		grp.deletedHits[lbp.LogicalID] = lbp.hits
+	}
+}
+
+// WasTriggered returns true if the logical breakpoint with ID id was
+// deleted after being triggered.
+func (grp *TargetGroup) WasTriggered(id int) bool {
+	return grp.deletedHits[id] != nil
+}
+
+// InheritAfterHits makes lbp remember that its After breakpoint was
+// triggered, if it was deleted after being triggered.
+func (grp *TargetGroup) InheritAfterHits(lbp *LogicalBreakpoint) {
+	if lbp.After == 0 || (lbp.afterHit != nil && lbp.afterHit.logicalID == lbp.After) {
+		return
+	}
+	if hits := grp.deletedHits[lbp.After]; hits != nil {
+		lbp.afterHit = hits.copy()
+	}
+}
+
 func enableBreakpointOnTarget(p *Target, lbp *LogicalBreakpoint) error {
 	var err error
 	var addrs []uint64
 	switch {
+	case lbp.Set.Catch != nil:
+		return p.setCatchpoint(lbp)
 	case lbp.Set.File != "":
 		addrs, err = FindFileLocation(p, lbp.Set.File, lbp.Set.Line)
 	case lbp.Set.FunctionName != "":
@@ -319,6 +362,13 @@ func (grp *TargetGroup) DisableBreakpoint(lbp *LogicalBreakpoint) error {
 	n := 0
 	it := ValidTargets{Group: grp}
 	for it.Next() {
+		if lbp.Set.Catch != nil {
+			n++
+			if err := it.clearCatchpoint(lbp.LogicalID); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
 		for _, bp := range it.Breakpoints().M {
 			if bp.LogicalID() == lbp.LogicalID {
 				n++
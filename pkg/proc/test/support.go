@@ -80,6 +80,8 @@ const (
 	AllNonOptimized
 	// LinkDisableDWARF enables '-ldflags="-w"'.
 	LinkDisableDWARF
+	// BuildTrimpath enables '-trimpath'.
+	BuildTrimpath
 )
 
 // TempFile makes a (good enough) random temporary file name
@@ -158,6 +160,9 @@ func BuildFixture(name string, flags BuildFlags) Fixture {
 	if flags&BuildModeExternalLinker != 0 {
 		buildFlags = append(buildFlags, "-ldflags=-linkmode=external")
 	}
+	if flags&BuildTrimpath != 0 {
+		buildFlags = append(buildFlags, "-trimpath")
+	}
 	if ver.IsDevel() || ver.AfterOrEqual(goversion.GoVersion{Major: 1, Minor: 11, Rev: -1}) {
 		if flags&EnableDWZCompression != 0 {
 			buildFlags = append(buildFlags, "-ldflags=-compressdwarf=false")
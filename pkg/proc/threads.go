@@ -57,6 +57,12 @@ type CommonThread struct {
 	CallReturn   bool // returnValues are the return values of a call injection
 	returnValues []*Variable
 	g            *G // cached g for this thread
+
+	// SyscallEntry is set by backends that support catching syscalls when
+	// the thread was stopped at the entry of a caught syscall, SyscallNr is
+	// the number of the syscall.
+	SyscallEntry bool
+	SyscallNr    int
 }
 
 // ReturnValues reads the return values from the function executing on
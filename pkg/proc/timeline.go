@@ -0,0 +1,323 @@
+package proc
+
+import (
+	"errors"
+	"go/constant"
+)
+
+// GoroutineEventKind is the kind of a goroutine scheduling event.
+type GoroutineEventKind uint8
+
+const (
+	// GoroutineCreated is recorded when runtime.newproc1 returns a new goroutine.
+	GoroutineCreated GoroutineEventKind = iota
+	// GoroutineRunning is recorded when runtime.execute schedules a goroutine
+	// on a thread.
+	GoroutineRunning
+	// GoroutineBlocked is recorded when runtime.gopark parks the current
+	// goroutine.
+	GoroutineBlocked
+	// GoroutineUnblocked is recorded when runtime.ready makes a parked
+	// goroutine runnable again.
+	GoroutineUnblocked
+)
+
+func (kind GoroutineEventKind) String() string {
+	switch kind {
+	case GoroutineCreated:
+		return "created"
+	case GoroutineRunning:
+		return "running"
+	case GoroutineBlocked:
+		return "blocked"
+	case GoroutineUnblocked:
+		return "unblocked"
+	default:
+		return "unknown"
+	}
+}
+
+// GoroutineEvent is a goroutine scheduling event that happened in a
+// recording.
+type GoroutineEvent struct {
+	Kind GoroutineEventKind
+	// Position is the position in the recording where the event happened,
+	// it can be passed to Restart.
+	Position string
+	ThreadID int
+	// GoroutineID is the goroutine the event is about, zero if it could not
+	// be determined.
+	GoroutineID int64
+	// CurrentGoroutineID is the goroutine that was running on the thread
+	// when the event happened, for example the creator of a new goroutine.
+	// Zero if the thread was not running a goroutine.
+	CurrentGoroutineID int64
+	// StartPC is the PC of the first function run by created goroutines.
+	StartPC uint64
+	// WaitReason is the wait reason of blocked goroutines.
+	WaitReason int64
+}
+
+// GoroutineTimeline replays the portion of the recording between from and
+// to, specified like the position argument of Restart, and returns the
+// goroutine scheduling events that happened in it. An empty string means
+// the corresponding end of the recording. The replay does not extend past
+// the time limits of the recording.
+// Events are collected by internal breakpoints on the scheduler functions
+// of the runtime, execution does not stop on them. Hit counts of user
+// breakpoints are not changed by the replay.
+// The position and direction of execution are restored before returning.
+func (grp *TargetGroup) GoroutineTimeline(from, to string) ([]GoroutineEvent, error) {
+	if recorded, _ := grp.Recorded(); !recorded {
+		return nil, ErrNotRecorded
+	}
+	if grp.Selected.Breakpoints().HasSteppingBreakpoints() {
+		return nil, errors.New("next while nexting")
+	}
+	pos, err := grp.Position()
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := grp.TimeLimits()
+	if err != nil {
+		// The replay relies on positions and time limits, rr recordings
+		// support neither.
+		return nil, errors.New("goroutine timelines are only supported by the undo backend")
+	}
+	dir := grp.GetDirection()
+	hitCounts := saveHitCounts(grp.LogicalBreakpoints)
+
+	var events []GoroutineEvent
+	err = grp.collectGoroutineEvents(from, to, start, end, &events)
+
+	restoreHitCounts(grp.LogicalBreakpoints, hitCounts)
+	if err1 := grp.Selected.clearTimelineBreakpoints(); err == nil {
+		err = err1
+	}
+	if err1 := grp.ChangeDirection(dir); err == nil {
+		err = err1
+	}
+	if to != "" {
+		// The time limits are removed before traveling back, the original
+		// position could be outside of the limits used for the replay.
+		if err1 := grp.SetTimeLimits("", ""); err == nil {
+			err = err1
+		}
+	}
+	if err1 := grp.Restart(pos); err == nil {
+		err = err1
+	}
+	if to != "" {
+		if err1 := grp.SetTimeLimits(start, end); err == nil {
+			err = err1
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (grp *TargetGroup) collectGoroutineEvents(from, to, start, end string, events *[]GoroutineEvent) error {
+	if err := grp.ChangeDirection(Forward); err != nil {
+		return err
+	}
+	if err := grp.Restart(from); err != nil {
+		return err
+	}
+	if to != "" {
+		if end != "" {
+			cmp, err := grp.ComparePositions(end, to)
+			if err != nil {
+				return err
+			}
+			if cmp < 0 {
+				to = end
+			}
+		}
+		// Reaching the end limit is reported like the end of the recording.
+		if err := grp.SetTimeLimits(start, to); err != nil {
+			return err
+		}
+	}
+	if err := grp.Selected.setTimelineBreakpoints(events); err != nil {
+		return err
+	}
+	// Continue steps over a breakpoint at the current position without
+	// triggering it, an event at the start of the replay must be recorded
+	// explicitly.
+	th := grp.Selected.CurrentThread()
+	if regs, err := th.Registers(); err == nil {
+		if bp := grp.Selected.Breakpoints().M[regs.PC()]; bp != nil {
+			for _, breaklet := range bp.Breaklets {
+				if breaklet.Kind == TimelineBreakpoint {
+					breaklet.callback(th, grp.Selected)
+				}
+			}
+		}
+	}
+	for {
+		err := grp.Continue()
+		if _, exited := err.(ErrProcessExited); exited {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if grp.Selected.StopReason == StopManual {
+			return nil
+		}
+		// Stopped by a user breakpoint, keep going.
+	}
+}
+
+// setTimelineBreakpoints sets breakpoints on the scheduler functions of
+// the runtime that append a GoroutineEvent to events every time they are
+// hit. Functions missing from the target are skipped.
+func (t *Target) setTimelineBreakpoints(events *[]GoroutineEvent) error {
+	type timelineBreakpoint struct {
+		addrs  []uint64
+		record func(th Thread, ev *GoroutineEvent)
+	}
+
+	var tbps []timelineBreakpoint
+	entry := func(fnname string, record func(th Thread, ev *GoroutineEvent)) {
+		if fn := t.BinInfo().lookupOneFunc(fnname); fn != nil {
+			tbps = append(tbps, timelineBreakpoint{[]uint64{fn.Entry}, record})
+		}
+	}
+
+	// The new goroutine is only known when runtime.newproc1 returns it.
+	retpcs, _ := findRetPC(t, "runtime.newproc1")
+	tbps = append(tbps, timelineBreakpoint{retpcs, func(th Thread, ev *GoroutineEvent) {
+		ev.Kind = GoroutineCreated
+		if g := t.newprocResult(th); g != nil {
+			ev.GoroutineID = g.ID
+			ev.StartPC = g.StartPC
+		}
+	}})
+	entry("runtime.execute", func(th Thread, ev *GoroutineEvent) {
+		ev.Kind = GoroutineRunning
+		ev.GoroutineID = t.evalThreadInt(th, "gp.goid")
+	})
+	entry("runtime.gopark", func(th Thread, ev *GoroutineEvent) {
+		ev.Kind = GoroutineBlocked
+		ev.GoroutineID = ev.CurrentGoroutineID
+		ev.WaitReason = t.evalThreadInt(th, "reason")
+	})
+	entry("runtime.ready", func(th Thread, ev *GoroutineEvent) {
+		ev.Kind = GoroutineUnblocked
+		ev.GoroutineID = t.evalThreadInt(th, "gp.goid")
+	})
+
+	for _, tbp := range tbps {
+		record := tbp.record
+		for _, addr := range tbp.addrs {
+			bp, err := t.SetBreakpoint(0, addr, TimelineBreakpoint, nil)
+			if err != nil {
+				return err
+			}
+			bp.Breaklets[len(bp.Breaklets)-1].callback = func(th Thread, p *Target) (bool, error) {
+				ev := GoroutineEvent{ThreadID: th.ThreadID()}
+				ev.Position, _ = p.recman.Position()
+				if g, _ := GetG(th); g != nil {
+					ev.CurrentGoroutineID = g.ID
+				}
+				record(th, &ev)
+				*events = append(*events, ev)
+				return false, nil
+			}
+		}
+	}
+	return nil
+}
+
+// newprocResult returns the goroutine returned by runtime.newproc1, th
+// must be stopped on one of its return instructions. Only supported with
+// the register based calling convention, where the result is in the first
+// integer register.
+func (t *Target) newprocResult(th Thread) *G {
+	if !t.BinInfo().regabi {
+		return nil
+	}
+	regs, err := th.Registers()
+	if err != nil {
+		return nil
+	}
+	dregs := t.BinInfo().Arch.RegistersToDwarfRegisters(0, regs)
+	gvar, err := newGVariable(th, dregs.Uint64Val(0), false)
+	if err != nil {
+		return nil
+	}
+	g, _ := gvar.parseG()
+	return g
+}
+
+// evalThreadInt evaluates expr on the topmost frame of th, returns zero
+// if it can not be evaluated.
+func (t *Target) evalThreadInt(th Thread, expr string) int64 {
+	scope, err := ThreadScope(t, th)
+	if err != nil {
+		return 0
+	}
+	v, err := scope.EvalExpression(expr, loadSingleValue)
+	if err != nil || v.Unreadable != nil || v.Value == nil {
+		return 0
+	}
+	n, _ := constant.Int64Val(constant.ToInt(v.Value))
+	return n
+}
+
+// clearTimelineBreakpoints removes all breakpoints set by
+// setTimelineBreakpoints.
+func (t *Target) clearTimelineBreakpoints() error {
+	for _, bp := range t.Breakpoints().M {
+		for i := range bp.Breaklets {
+			if bp.Breaklets[i].Kind == TimelineBreakpoint {
+				bp.Breaklets[i] = nil
+			}
+		}
+		cleared, err := t.finishClearBreakpoint(bp)
+		if err != nil {
+			return err
+		}
+		if cleared {
+			for _, thread := range t.ThreadList() {
+				if thread.Breakpoint().Breakpoint == bp {
+					thread.Breakpoint().Clear()
+				}
+			}
+		}
+	}
+	return nil
+}
+
+type hitCounts struct {
+	total    uint64
+	perG     map[int64]uint64
+	afterHit *afterHit
+	hits     *afterHit
+}
+
+func saveHitCounts(lbps map[int]*LogicalBreakpoint) map[int]hitCounts {
+	r := make(map[int]hitCounts, len(lbps))
+	for id, lbp := range lbps {
+		hc := hitCounts{total: lbp.TotalHitCount, perG: make(map[int64]uint64, len(lbp.HitCount)), afterHit: lbp.afterHit.copy(), hits: lbp.hits.copy()}
+		for goid, n := range lbp.HitCount {
+			hc.perG[goid] = n
+		}
+		r[id] = hc
+	}
+	return r
+}
+
+func restoreHitCounts(lbps map[int]*LogicalBreakpoint, saved map[int]hitCounts) {
+	for id, hc := range saved {
+		if lbp := lbps[id]; lbp != nil {
+			lbp.TotalHitCount = hc.total
+			lbp.HitCount = hc.perG
+			lbp.afterHit = hc.afterHit
+			lbp.hits = hc.hits
+		}
+	}
+}
@@ -134,6 +134,10 @@ type Variable struct {
 
 	LocationExpr *locationExpr // location expression
 	DeclLine     int64         // line number of this variable's declaration
+
+	// Display is the result of the pretty printer matching the type of this
+	// variable, nil if no pretty printer matched it. See PrettyPrint.
+	Display *Variable
 }
 
 // LoadConfig controls how variables are loaded from the targets memory.
@@ -177,11 +181,28 @@ type LoadConfig struct {
 	// sparse map is in scope, but evaluating a single variable will still work
 	// correctly, even if the variable in question is a very sparse map.
 	MaxMapBuckets int
+
+	// Cancel, if not nil, interrupts loading when it is closed, the values
+	// that were not loaded yet are marked unreadable with ErrCancelled.
+	Cancel <-chan struct{}
 }
 
-var loadSingleValue = LoadConfig{false, 0, 64, 0, 0, 0}
-var loadFullValue = LoadConfig{true, 1, 64, 64, -1, 0}
-var loadFullValueLongerStrings = LoadConfig{true, 1, 1024 * 1024, 64, -1, 0}
+var loadSingleValue = LoadConfig{false, 0, 64, 0, 0, 0, nil}
+var loadFullValue = LoadConfig{true, 1, 64, 64, -1, 0, nil}
+var loadFullValueLongerStrings = LoadConfig{true, 1, 1024 * 1024, 64, -1, 0, nil}
+
+// ErrCancelled is returned when an operation is interrupted by closing
+// its cancel channel.
+var ErrCancelled = errors.New("cancelled")
+
+func cancelled(cancel <-chan struct{}) bool {
+	select {
+	case <-cancel:
+		return true
+	default:
+		return false
+	}
+}
 
 // G status, from: src/runtime/runtime2.go
 const (
@@ -304,6 +325,12 @@ func GetG(thread Thread) (*G, error) {
 // while scanning for all available goroutines, or -1 if there was an error
 // or if the index already reached the last possible value.
 func GoroutinesInfo(dbp *Target, start, count int) ([]*G, int, error) {
+	return GoroutinesInfoWithCancel(dbp, start, count, nil)
+}
+
+// GoroutinesInfoWithCancel is like GoroutinesInfo but stops scanning and
+// returns ErrCancelled when cancel is closed.
+func GoroutinesInfoWithCancel(dbp *Target, start, count int, cancel <-chan struct{}) ([]*G, int, error) {
 	if _, err := dbp.Valid(); err != nil {
 		return nil, -1, err
 	}
@@ -336,6 +363,9 @@ func GoroutinesInfo(dbp *Target, start, count int) ([]*G, int, error) {
 		if count != 0 && len(allg) >= count {
 			return allg, int(i), nil
 		}
+		if cancelled(cancel) {
+			return nil, -1, ErrCancelled
+		}
 		gvar, err := newGVariable(dbp.CurrentThread(), allgptr+(i*uint64(dbp.BinInfo().Arch.PtrSize())), true)
 		if err != nil {
 			allg = append(allg, &G{Unreadable: err})
@@ -1296,6 +1326,10 @@ func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 	if v.Unreadable != nil || v.loaded || (v.Addr == 0 && v.Base == 0) {
 		return
 	}
+	if cancelled(cfg.Cancel) {
+		v.Unreadable = ErrCancelled
+		return
+	}
 
 	v.loaded = true
 	switch v.Kind {
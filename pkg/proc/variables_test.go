@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go/constant"
 	"io/ioutil"
+	"regexp"
 	"runtime"
 	"sort"
 	"strconv"
@@ -1678,3 +1679,78 @@ func TestBadUnsafePtr(t *testing.T) {
 		}
 	})
 }
+
+func TestCancelLoading(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testvariables2", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Continue(), t, "Continue() returned an error")
+
+		cancel := make(chan struct{})
+		cfg := pnormalLoadConfig
+		cfg.Cancel = cancel
+		s1, err := evalVariableWithCfg(p, "s1", cfg)
+		assertNoError(err, t, "EvalVariable(s1)")
+		assertVariable(t, s1, varTest{name: "s1", value: `[]string len: 5, cap: 5, ["one","two","three","four","five"]`, varType: "[]string"})
+
+		close(cancel)
+		v, err := evalVariableWithCfg(p, "s1", cfg)
+		assertNoError(err, t, "EvalVariable(s1) after cancel")
+		if v.Unreadable != proc.ErrCancelled {
+			t.Errorf("expected s1 to be unreadable with %v, got %v", proc.ErrCancelled, v.Unreadable)
+		}
+		resliced, err := s1.LoadResliced(1, cfg)
+		assertNoError(err, t, "LoadResliced(s1) after cancel")
+		if resliced.Unreadable != proc.ErrCancelled {
+			t.Errorf("expected s1[1:] to be unreadable with %v, got %v", proc.ErrCancelled, resliced.Unreadable)
+		}
+
+		if _, _, err := proc.GoroutinesInfoWithCancel(p, 0, 0, cancel); err != proc.ErrCancelled {
+			t.Errorf("expected GoroutinesInfoWithCancel to return %v, got %v", proc.ErrCancelled, err)
+		}
+		gs, _, err := proc.GoroutinesInfo(p, 0, 0)
+		assertNoError(err, t, "GoroutinesInfo()")
+		if len(gs) == 0 {
+			t.Errorf("no goroutines found")
+		}
+	})
+}
+
+func TestPrettyPrinters(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("prettyprinters", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Continue(), t, "Continue()")
+		scope, err := evalScope(p)
+		assertNoError(err, t, "evalScope()")
+		printers := []proc.PrettyPrinter{
+			{Type: "main.ID", Expr: "v.prefix"},
+			{TypeRegexp: regexp.MustCompile(`^main\.Mon`), Expr: "v.units"},
+			{Type: "main.Account", Expr: "v.nonexistent"},
+		}
+
+		for _, tc := range []struct {
+			expr, tgt string
+		}{
+			{"id", "main.ID(acct)"},
+			{"pid", "*main.ID(acct)"},
+			{"acct.balance", "main.Money(12)"},
+			{"ids", "[]main.ID len: 2, cap: 2, [a,b]"},
+			{"acct", "main.Account((pretty printer error: acct has no member nonexistent))"},
+			{"acct.owner", `"alice"`},
+		} {
+			v, err := scope.EvalExpression(tc.expr, pnormalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalExpression(%s)", tc.expr))
+			scope.PrettyPrint(v, printers, pnormalLoadConfig)
+			if out := api.ConvertVar(v).SinglelineString(); out != tc.tgt {
+				t.Errorf("%s: expected %q got %q", tc.expr, tc.tgt, out)
+			}
+		}
+
+		// Pretty printers do not change the value of the variable.
+		v, err := scope.EvalExpression("acct", pnormalLoadConfig)
+		assertNoError(err, t, "EvalExpression(acct)")
+		scope.PrettyPrint(v, printers, pnormalLoadConfig)
+		if id := api.ConvertVar(&v.Children[0]); id.Display != "acct" || len(id.Children) != 2 {
+			t.Errorf("wrong conversion of acct.id: %#v", id)
+		}
+	})
+}
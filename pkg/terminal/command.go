@@ -7,6 +7,7 @@ package terminal
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"go/parser"
@@ -123,17 +124,31 @@ func DebugCommands(client service.Client) *Commands {
 Type "help" followed by the name of a command for more information about it.`},
 		{aliases: []string{"break", "b"}, group: breakCmds, cmdFn: breakpoint, helpMsg: `Sets a breakpoint.
 
-	break [name] [locspec]
+	break [-once] [-after <breakpoint name or id>] [name] [locspec]
+	break [-once] [-per-g-after <breakpoint name or id>] [name] [locspec]
 
 See Documentation/cli/locspec.md for the syntax of locspec. If locspec is omitted a breakpoint will be set on the current line.
 
-See also: "help on", "help cond" and "help clear"`},
+With the -once option the breakpoint is cleared the first time it is hit.
+
+With the -after option the breakpoint will only stop after the specified breakpoint has been hit. The -per-g-after option works like -after, but the specified breakpoint must have been hit by the same goroutine. Hits that happen before that are not counted in the hit count of the breakpoint. A breakpoint that was cleared after being hit, for example by tbreak, can still be specified by its ID.
+
+See also: "help on", "help cond", "help tbreak" and "help clear"`},
+		{aliases: []string{"tbreak"}, group: breakCmds, cmdFn: tbreakpoint, helpMsg: `Sets a temporary breakpoint.
+
+	tbreak [-after <breakpoint name or id>] [name] [locspec]
+	tbreak [-per-g-after <breakpoint name or id>] [name] [locspec]
+
+Same as 'break -once', the breakpoint is cleared the first time it is hit.`},
 		{aliases: []string{"trace", "t"}, group: breakCmds, cmdFn: tracepoint, allowedPrefixes: onPrefix, helpMsg: `Set tracepoint.
 
-	trace [name] [locspec]
+	trace [-once] [-after <breakpoint name or id>] [name] [locspec]
+	trace [-once] [-per-g-after <breakpoint name or id>] [name] [locspec]
 
 A tracepoint is a breakpoint that does not stop the execution of the program, instead when the tracepoint is hit a notification is displayed. See Documentation/cli/locspec.md for the syntax of locspec. If locspec is omitted a tracepoint will be set on the current line.
 
+The -once, -after and -per-g-after options work like they do for 'break'.
+
 See also: "help on", "help cond" and "help clear"`},
 		{aliases: []string{"watch"}, group: breakCmds, cmdFn: watchpoint, helpMsg: `Set watchpoint.
 	
@@ -153,18 +168,39 @@ will watch the address of variable 'v' and writes to an int at addr '0x1400007c0
 Note that writes that do not change the value of the watched memory address might not be reported.
 
 See also: "help print".`},
+		{aliases: []string{"catch"}, group: breakCmds, cmdFn: catchpoint, helpMsg: `Sets a catchpoint.
+
+	catch [-once] [-after <breakpoint name or id>] <event> [<filter>]
+	catch [-once] [-per-g-after <breakpoint name or id>] <event> [<filter>]
+
+A catchpoint stops the program when an event happens, instead of when a location is reached. The events are:
+
+	signal [<signal>]		the program receives a signal, by name (SIGSEGV or SEGV) or number
+	syscall [<syscall>]		a thread enters a syscall, by name (openat) or number
+	goroutine-start [<regexp>]	a goroutine is created, whose start function matches regexp
+	goroutine-exit [<regexp>]	a goroutine exits, whose start function matches regexp
+	panic [<type>]			a goroutine panics with a value of the specified type, even if the panic is later recovered
+
+Without a filter every event of that kind stops the program. Catching syscalls is only supported by the native backend on Linux.
+
+Catchpoints are listed by 'breakpoints' and work like breakpoints with 'cond', 'on', 'toggle' and 'clear', the -once, -after and -per-g-after options work like they do for 'break'.
+
+See also: "help break", "help cond" and "help on"`},
 		{aliases: []string{"restart", "r"}, group: runCmds, cmdFn: restart, helpMsg: `Restart process.
 
 For recorded targets the command takes the following forms:
 
 	restart					resets to the start of the recording
 	restart [checkpoint]			resets the recording to the given checkpoint
+	restart @<wall-clock time>		resets the recording to the first instruction executed at or after the given wall-clock time (undo backend only)
 	restart -r [newargv...]	[redirects...]	re-records the target process
 	
 For live targets the command takes the following forms:
 
 	restart [newargv...] [redirects...]	restarts the process
 
+Wall-clock times use the RFC 3339 format, for example "restart @2026-10-16T10:03:11Z". If the time zone offset is omitted the time zone of the recording's session is used (UTC unless it was changed in UDB). Wall-clock times require a version of udbserver that supports them, rr recordings do not contain wall-clock times.
+
 If newargv is omitted the process is restarted (or re-recorded) with the same argument vector.
 If -noargs is specified instead, the argument vector is cleared.
 
@@ -316,8 +352,12 @@ Called with more arguments it will execute a command on the specified goroutine.
 		{aliases: []string{"breakpoints", "bp"}, group: breakCmds, cmdFn: breakpoints, helpMsg: `Print out info for active breakpoints.
 	
 	breakpoints [-a]
+	breakpoints save <file>
+	breakpoints load <file>
+
+Specifying -a prints all physical breakpoint, including internal breakpoints.
 
-Specifying -a prints all physical breakpoint, including internal breakpoints.`},
+The save subcommand writes the breakpoints and tracepoints, with their names, conditions and the commands executed when they are hit (see 'on'), to a file that the load subcommand reads to set them again, for example in a later debugging session. A breakpoint inside a function is saved relative to the start of the function, so that it is set at the same place after edits that moved the function. Watchpoints and breakpoints set on an address are not saved. The file is read and written by the headless instance of Delve when connected to one.`},
 		{aliases: []string{"print", "p"}, group: dataCmds, allowedPrefixes: onPrefix | deferredPrefix, cmdFn: printVar, helpMsg: `Evaluate an expression.
 
 	[goroutine <n>] [frame <m>] print [%format] <expression>
@@ -328,6 +368,15 @@ The optional format argument is a format specifier, like the ones used by the fm
 		{aliases: []string{"whatis"}, group: dataCmds, cmdFn: whatisCommand, helpMsg: `Prints type of an expression.
 
 	whatis <expression>`},
+		{aliases: []string{"refs"}, group: dataCmds, cmdFn: refsCommand, helpMsg: `Prints the chains of references that keep a heap object alive.
+
+	[goroutine <n>] [frame <m>] refs <expression>
+
+The object is the one pointed to by the expression, if it is a pointer, map, channel or function, the backing array of the expression, if it is a slice or a string, or the object containing the expression otherwise.
+
+Every reference to the object that can be reached from a GC root is printed, along with the shortest chain of heap objects leading to it from the root. GC roots are package variables and the local variables and arguments of all goroutines. Each object in a chain is followed by the offset of the pointer to the next one. Only pointers are followed, using the types of the values: objects whose type is not known, like the ones referred to by unsafe.Pointer values or by closures, are scanned conservatively and any word pointing into an allocated object is considered a reference.
+
+See Documentation/cli/expr.md for a description of supported expressions.`},
 		{aliases: []string{"set"}, group: dataCmds, cmdFn: setVar, helpMsg: `Changes the value of a variable.
 
 	[goroutine <n>] [frame <m>] set <variable> = <value>
@@ -474,6 +523,8 @@ The command 'on x -edit' can be used to edit the list of commands executed when
 	condition <breakpoint name or id> <boolean expression>.
 	condition -hitcount <breakpoint name or id> <operator> <argument>.
 	condition -per-g-hitcount <breakpoint name or id> <operator> <argument>.
+	condition -after <breakpoint name or id> <breakpoint name or id>.
+	condition -per-g-after <breakpoint name or id> <breakpoint name or id>.
 	condition -clear <breakpoint name or id>.
 
 Specifies that the breakpoint, tracepoint or watchpoint should break only if the boolean expression is true.
@@ -492,6 +543,8 @@ With the -hitcount option a condition on the breakpoint hit count can be set, th
 
 The -per-g-hitcount option works like -hitcount, but use per goroutine hitcount to compare with n.
 
+With the -after option the breakpoint will only stop after the second breakpoint has been hit, the -per-g-after option also requires it to have been hit by the same goroutine. Specifying 0 as the second breakpoint removes this condition.
+
 With the -clear option a condition on the breakpoint can removed.
 	
 The '% n' form means we should stop at the breakpoint when the hitcount is a multiple of n.
@@ -501,6 +554,7 @@ Examples:
 	cond 2 i == 10				breakpoint 2 will stop when variable i equals 10
 	cond name runtime.curg.goid == 5	breakpoint 'name' will stop only on goroutine 5
 	cond -clear 2				the condition on breakpoint 2 will be removed
+	cond -per-g-after 3 name		breakpoint 3 will stop only on goroutines that stopped at breakpoint 'name'
 `},
 		{aliases: []string{"config"}, cmdFn: configureCmd, helpMsg: `Changes configuration parameters.
 
@@ -631,7 +685,7 @@ The "note" is arbitrary text that can be used to identify the checkpoint, if it
 			command{
 				aliases: []string{"checkpoints"},
 				cmdFn:   checkpoints,
-				helpMsg: "Print out info for existing checkpoints.",
+				helpMsg: "Print out info for existing checkpoints. With the undo backend the wall-clock time of each checkpoint is also printed, if it is known.",
 			},
 			command{
 				aliases: []string{"clear-checkpoint", "clearcheck"},
@@ -639,13 +693,75 @@ The "note" is arbitrary text that can be used to identify the checkpoint, if it
 				helpMsg: `Deletes checkpoint.
 
 	clear-checkpoint <id>`,
+			},
+			command{
+				aliases: []string{"limit"},
+				cmdFn:   limit,
+				helpMsg: `Limits execution to a portion of the recording.
+
+	limit
+	limit start <time|checkpoint>
+	limit end <time|checkpoint>
+	limit clear
+
+Without arguments prints the current limits. Continue, rewind and restart will not go beyond the limits, the end limit is reported like the end of the recording. Times are specified like the argument of restart, "limit start start" and "limit end end" remove one of the limits and "limit clear" removes both. Limits are saved with the recording. Only supported by the undo backend.`,
+			},
+			command{
+				aliases: []string{"recording"},
+				cmdFn:   recording,
+				helpMsg: `Manages the recording.
+
+	recording save [--from <time|checkpoint>] [--to <time|checkpoint>] <file>
+
+Saves the portion of the recording between --from and --to as a new, smaller, recording in file. Times are specified like the argument of restart, by default the portion extends to the start and end of the recording. Checkpoints inside the saved portion are carried into the new recording, renumbered starting from c1. Only supported by the undo backend, with a version of udbserver that can save recordings.`,
+			},
+			command{
+				aliases: []string{"timeline"},
+				cmdFn:   timeline,
+				helpMsg: `Prints a timeline of events extracted from the recording.
+
+	timeline goroutines [--from <time|checkpoint>] [--to <time|checkpoint>] [--json <file>]
+
+Replays the portion of the recording between --from and --to with internal breakpoints on the scheduler of the runtime and prints when each goroutine was created, started running on a thread, blocked and was unblocked. Times are specified like the argument of restart, by default the whole recording is replayed. The position of every event can be passed to restart to travel to it. With --json the events are written to file, in JSON format, instead of being printed. The current position in the recording is restored at the end. Only supported by the undo backend.`,
+			},
+			command{
+				aliases: []string{"last"},
+				group:   runCmds,
+				cmdFn:   c.last,
+				helpMsg: `Run backwards to the most recent write to the memory of an expression.
+
+	last <expression>
+
+Prints the values of the expression before and after the write, and the goroutine and stack that executed it. Execution stops early if a breakpoint is hit or the start of recorded history is reached. See Documentation/cli/expr.md for a description of supported expressions.`,
+			},
+			command{
+				aliases:         []string{"call-entry"},
+				group:           runCmds,
+				allowedPrefixes: revPrefix,
+				cmdFn:           c.callEntry,
+				helpMsg: `Run backwards to the entry of the current function.
+
+	rev call-entry
+
+Stops on the first instruction of the current call of the current function, recursive calls are skipped. Can only be used with the rev prefix.`,
+			},
+			command{
+				aliases:         []string{"call-site"},
+				group:           runCmds,
+				allowedPrefixes: revPrefix,
+				cmdFn:           c.callSite,
+				helpMsg: `Run backwards to the line that called the current function.
+
+	rev call-site
+
+Stops at the start of the source line, in the caller, containing the call to the current function, before the arguments of the call are evaluated. Can only be used with the rev prefix.`,
 			},
 			command{
 				aliases: []string{"rev"},
 				group:   runCmds,
 				cmdFn:   c.revCmd,
 				helpMsg: `Reverses the execution of the target program for the command specified.
-Currently, rev next, step, step-instruction and stepout commands are supported.`,
+Currently, rev next, step, step-instruction, stepout, call-entry and call-site commands are supported.`,
 			})
 	}
 
@@ -1588,6 +1704,34 @@ func (c *Commands) stepout(t *Term, ctx callContext, args string) error {
 	return continueUntilCompleteNext(t, state, "stepout", true)
 }
 
+func (c *Commands) callEntry(t *Term, ctx callContext, args string) error {
+	return c.reverseCallCmd(t, ctx, "call-entry", t.client.ReverseCallEntry)
+}
+
+func (c *Commands) callSite(t *Term, ctx callContext, args string) error {
+	return c.reverseCallCmd(t, ctx, "call-site", t.client.ReverseCallSite)
+}
+
+func (c *Commands) reverseCallCmd(t *Term, ctx callContext, name string, fn func() (*api.DebuggerState, error)) error {
+	if ctx.Prefix != revPrefix {
+		return fmt.Errorf("%s can only be used with the rev prefix", name)
+	}
+	if err := scopePrefixSwitch(t, ctx); err != nil {
+		return err
+	}
+	if c.frame != 0 {
+		return errNotOnFrameZero
+	}
+
+	state, err := exitedToError(fn())
+	if err != nil {
+		printcontextNoState(t)
+		return err
+	}
+	printcontext(t, state)
+	return continueUntilCompleteNext(t, state, name, true)
+}
+
 func (c *Commands) call(t *Term, ctx callContext, args string) error {
 	if err := scopePrefixSwitch(t, ctx); err != nil {
 		return err
@@ -1693,17 +1837,28 @@ func (a byID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a byID) Less(i, j int) bool { return a[i].ID < a[j].ID }
 
 func breakpoints(t *Term, ctx callContext, args string) error {
+	if v := config.Split2PartsBySpace(args); len(v) == 2 {
+		switch v[0] {
+		case "save":
+			return saveBreakpoints(t, v[1])
+		case "load":
+			return loadBreakpoints(t, v[1])
+		}
+	}
 	breakPoints, err := t.client.ListBreakpoints(args == "-a")
 	if err != nil {
 		return err
 	}
 	sort.Sort(byID(breakPoints))
 	for _, bp := range breakPoints {
-		enabled := "(enabled)"
+		enabled := "enabled"
 		if bp.Disabled {
-			enabled = "(disabled)"
+			enabled = "disabled"
+		}
+		if bp.Once {
+			enabled += ", once"
 		}
-		fmt.Fprintf(t.stdout, "%s %s at %v (%d)\n", formatBreakpointName(bp, true), enabled, t.formatBreakpointLocation(bp), bp.TotalHitCount)
+		fmt.Fprintf(t.stdout, "%s (%s) at %v (%d)\n", formatBreakpointName(bp, true), enabled, t.formatBreakpointLocation(bp), bp.TotalHitCount)
 
 		attrs := formatBreakpointAttrs("\t", bp, false)
 
@@ -1714,6 +1869,42 @@ func breakpoints(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func saveBreakpoints(t *Term, path string) error {
+	sbps, err := t.client.SaveBreakpoints(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(t.stdout, "Saved %d breakpoints to %s\n", len(sbps), path)
+	return nil
+}
+
+func loadBreakpoints(t *Term, path string) error {
+	bps, discarded, err := t.client.LoadBreakpoints(path)
+	if err != nil {
+		return err
+	}
+	for _, bp := range bps {
+		fmt.Fprintf(t.stdout, "%s set at %s\n", formatBreakpointName(bp, true), t.formatBreakpointLocation(bp))
+	}
+	for _, d := range discarded {
+		fmt.Fprintf(t.stdout, "Could not set %s: %s\n", formatSavedBreakpointLocation(d.Breakpoint), d.Reason)
+	}
+	return nil
+}
+
+// formatSavedBreakpointLocation describes the location of a saved
+// breakpoint that could not be set.
+func formatSavedBreakpointLocation(bp *api.Breakpoint) string {
+	loc := bp.FunctionName
+	if bp.File != "" {
+		loc = fmt.Sprintf("%s:%d", bp.File, bp.Line)
+	}
+	if bp.Name != "" {
+		return fmt.Sprintf("breakpoint %s at %s", bp.Name, loc)
+	}
+	return "breakpoint at " + loc
+}
+
 func formatBreakpointAttrs(prefix string, bp *api.Breakpoint, includeTrace bool) []string {
 	var attrs []string
 	if bp.Cond != "" {
@@ -1726,6 +1917,13 @@ func formatBreakpointAttrs(prefix string, bp *api.Breakpoint, includeTrace bool)
 			attrs = append(attrs, fmt.Sprintf("%scond -hitcount %s", prefix, bp.HitCond))
 		}
 	}
+	if bp.After != 0 {
+		if bp.AfterPerG {
+			attrs = append(attrs, fmt.Sprintf("%scond -per-g-after %d", prefix, bp.After))
+		} else {
+			attrs = append(attrs, fmt.Sprintf("%scond -after %d", prefix, bp.After))
+		}
+	}
 	if bp.Stacktrace > 0 {
 		attrs = append(attrs, fmt.Sprintf("%sstack %d", prefix, bp.Stacktrace))
 	}
@@ -1758,10 +1956,44 @@ func formatBreakpointAttrs(prefix string, bp *api.Breakpoint, includeTrace bool)
 	return attrs
 }
 
+// parseBreakpointOptions parses the options at the start of the arguments
+// of break and trace into requestedBp and returns the remaining arguments.
+func parseBreakpointOptions(t *Term, requestedBp *api.Breakpoint, argstr string) (string, error) {
+	for {
+		args := config.Split2PartsBySpace(argstr)
+		switch args[0] {
+		case "-once":
+			requestedBp.Once = true
+		case "-after", "-per-g-after":
+			if len(args) < 2 || args[1] == "" {
+				return "", fmt.Errorf("%s requires a breakpoint name or id", args[0])
+			}
+			v := config.Split2PartsBySpace(args[1])
+			after, err := getAfterBreakpointID(t, v[0])
+			if err != nil {
+				return "", err
+			}
+			requestedBp.After = after
+			requestedBp.AfterPerG = args[0] == "-per-g-after"
+			args = v
+		default:
+			return argstr, nil
+		}
+		argstr = ""
+		if len(args) > 1 {
+			argstr = args[1]
+		}
+	}
+}
+
 func setBreakpoint(t *Term, ctx callContext, tracepoint bool, argstr string) ([]*api.Breakpoint, error) {
+	requestedBp := &api.Breakpoint{}
+	argstr, err := parseBreakpointOptions(t, requestedBp, argstr)
+	if err != nil {
+		return nil, err
+	}
 	args := config.Split2PartsBySpace(argstr)
 
-	requestedBp := &api.Breakpoint{}
 	spec := ""
 	switch len(args) {
 	case 1:
@@ -1872,11 +2104,34 @@ func setBreakpoint(t *Term, ctx callContext, tracepoint bool, argstr string) ([]
 	return created, nil
 }
 
+func catchpoint(t *Term, ctx callContext, args string) error {
+	requestedBp := &api.Breakpoint{}
+	args, err := parseBreakpointOptions(t, requestedBp, args)
+	if err != nil {
+		return err
+	}
+	if args == "" {
+		return errors.New("not enough arguments")
+	}
+	requestedBp.Catch = args
+	bp, err := t.client.CreateBreakpoint(requestedBp)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(t.stdout, "%s set at %s\n", formatBreakpointName(bp, true), t.formatBreakpointLocation(bp))
+	return nil
+}
+
 func breakpoint(t *Term, ctx callContext, args string) error {
 	_, err := setBreakpoint(t, ctx, false, args)
 	return err
 }
 
+func tbreakpoint(t *Term, ctx callContext, args string) error {
+	_, err := setBreakpoint(t, ctx, false, "-once "+args)
+	return err
+}
+
 func tracepoint(t *Term, ctx callContext, args string) error {
 	if ctx.Prefix == onPrefix {
 		if args != "" {
@@ -2092,6 +2347,7 @@ func printVar(t *Term, ctx callContext, args string) error {
 		return err
 	}
 
+	t.prettyPrint(val)
 	fmt.Fprintln(t.stdout, val.MultilineString("", fmtstr))
 	return nil
 }
@@ -2123,6 +2379,47 @@ func whatisCommand(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func refsCommand(t *Term, ctx callContext, args string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("not enough arguments")
+	}
+	refs, err := t.client.HeapReferences(ctx.Scope, args)
+	if err != nil {
+		return err
+	}
+	if len(refs.Chains) == 0 {
+		fmt.Fprintf(t.stdout, "Object %#x (%d bytes) is not referenced by any GC root\n", refs.Object.Addr, refs.Object.Size)
+		return nil
+	}
+	fmt.Fprintf(t.stdout, "Object %#x (%d bytes) is referenced by:\n", refs.Object.Addr, refs.Object.Size)
+	for _, chain := range refs.Chains {
+		fmt.Fprintf(t.stdout, "\t%s", formatHeapRoot(chain.Root))
+		for _, link := range chain.Links {
+			fmt.Fprintf(t.stdout, " -> %#x+%#x", link.Object.Addr, link.Offset)
+		}
+		fmt.Fprintf(t.stdout, " -> %#x\n", refs.Object.Addr)
+	}
+	return nil
+}
+
+func formatHeapRoot(root api.HeapRoot) string {
+	name := fmt.Sprintf("%#x", root.Addr)
+	if root.Name != "" {
+		name = root.Name
+		if root.Offset != 0 {
+			name += fmt.Sprintf("+%#x", root.Offset)
+		}
+	}
+	if root.Kind != "stack" {
+		return root.Kind + " " + name
+	}
+	fnname := "?"
+	if root.Function != nil {
+		fnname = root.Function.Name()
+	}
+	return fmt.Sprintf("goroutine %d frame %d %s: %s", root.GoroutineID, root.Frame, fnname, name)
+}
+
 func setVar(t *Term, ctx callContext, args string) error {
 	// HACK: in go '=' is not an operator, we detect the error and try to recover from it by splitting the input string
 	_, err := parser.ParseExpr(args)
@@ -2149,6 +2446,7 @@ func (t *Term) printFilteredVariables(varType string, vars []api.Variable, filte
 	for _, v := range vars {
 		if reg == nil || reg.Match([]byte(v.Name)) {
 			match = true
+			t.prettyPrint(&v)
 			name := v.Name
 			if v.Flags&api.VariableShadowed != 0 {
 				name = "(" + name + ")"
@@ -2680,6 +2978,8 @@ func printcontextThread(t *Term, th *api.Thread) {
 	bpname := ""
 	if th.Breakpoint.WatchExpr != "" {
 		bpname = fmt.Sprintf("watchpoint on [%s] ", th.Breakpoint.WatchExpr)
+	} else if th.Breakpoint.Catch != "" {
+		bpname = fmt.Sprintf("catchpoint on [%s] ", th.Breakpoint.Catch)
 	} else if th.Breakpoint.Name != "" {
 		bpname = fmt.Sprintf("[%s] ", th.Breakpoint.Name)
 	}
@@ -2917,6 +3217,20 @@ func getBreakpointByIDOrName(t *Term, arg string) (*api.Breakpoint, error) {
 	return t.client.GetBreakpointByName(arg)
 }
 
+// getAfterBreakpointID returns the ID of the breakpoint arg refers to as
+// the argument of -after, 0 means no breakpoint. IDs are not looked up,
+// they can belong to breakpoints that were cleared after being hit.
+func getAfterBreakpointID(t *Term, arg string) (int, error) {
+	if id, err := strconv.Atoi(arg); err == nil {
+		return id, nil
+	}
+	bp, err := t.client.GetBreakpointByName(arg)
+	if err != nil {
+		return 0, err
+	}
+	return bp.ID, nil
+}
+
 func (c *Commands) onCmd(t *Term, ctx callContext, argstr string) error {
 	args := config.Split2PartsBySpace(argstr)
 
@@ -2981,6 +3295,8 @@ func (c *Commands) parseBreakpointAttrs(t *Term, ctx callContext, r io.Reader) e
 	ctx.Breakpoint.Variables = ctx.Breakpoint.Variables[:0]
 	ctx.Breakpoint.Cond = ""
 	ctx.Breakpoint.HitCond = ""
+	ctx.Breakpoint.After = 0
+	ctx.Breakpoint.AfterPerG = false
 
 	scan := bufio.NewScanner(r)
 	lineno := 0
@@ -3027,6 +3343,36 @@ func conditionCmd(t *Term, ctx callContext, argstr string) error {
 		return t.client.AmendBreakpoint(bp)
 	}
 
+	afterPerG := args[0] == "-per-g-after"
+	if args[0] == "-after" || afterPerG {
+		if ctx.Prefix == onPrefix {
+			after, err := getAfterBreakpointID(t, args[1])
+			if err != nil {
+				return err
+			}
+			ctx.Breakpoint.After = after
+			ctx.Breakpoint.AfterPerG = afterPerG
+			return nil
+		}
+
+		args = config.Split2PartsBySpace(args[1])
+		if len(args) < 2 {
+			return fmt.Errorf("not enough arguments")
+		}
+
+		bp, err := getBreakpointByIDOrName(t, args[0])
+		if err != nil {
+			return err
+		}
+		bp.After, err = getAfterBreakpointID(t, args[1])
+		if err != nil {
+			return err
+		}
+		bp.AfterPerG = afterPerG
+
+		return t.client.AmendBreakpoint(bp)
+	}
+
 	if args[0] == "-clear" {
 		bp, err := getBreakpointByIDOrName(t, args[1])
 		if err != nil {
@@ -3092,6 +3438,31 @@ func (c *Commands) rewind(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func (c *Commands) last(t *Term, ctx callContext, args string) error {
+	if args == "" {
+		return errors.New("not enough arguments")
+	}
+	defer t.onStop()
+	c.frame = 0
+	lw, err := t.client.LastWrite(ctx.Scope, args, t.loadConfig(), 50)
+	if err != nil {
+		return err
+	}
+	printcontext(t, &lw.State)
+	if lw.Found {
+		fmt.Fprintf(t.stdout, "Old value: %s\n", lw.OldValue.MultilineString("", ""))
+		fmt.Fprintf(t.stdout, "New value: %s\n", lw.NewValue.MultilineString("", ""))
+		if lw.Goroutine != nil {
+			fmt.Fprintf(t.stdout, "Written by:\n")
+			writeGoroutineLong(t, t.stdout, lw.Goroutine, "\t")
+		}
+		fmt.Fprintf(t.stdout, "Stack:\n")
+		printStack(t, t.stdout, lw.Stacktrace, "\t", false)
+	}
+	printPos(t, lw.State.CurrentThread, printPosShowArrow)
+	return nil
+}
+
 func checkpoint(t *Term, ctx callContext, args string) error {
 	if args == "" {
 		state, err := t.client.GetState()
@@ -3143,6 +3514,160 @@ func clearCheckpoint(t *Term, ctx callContext, args string) error {
 	return t.client.ClearCheckpoint(id)
 }
 
+func limit(t *Term, ctx callContext, args string) error {
+	start, end, err := t.client.TimeLimits()
+	if err != nil {
+		return err
+	}
+	v := strings.SplitN(strings.TrimSpace(args), " ", 2)
+	switch v[0] {
+	case "":
+		if start == "" {
+			start = "start of recording"
+		}
+		if end == "" {
+			end = "end of recording"
+		}
+		fmt.Fprintf(t.stdout, "Execution limited to %s - %s\n", start, end)
+		return nil
+	case "start", "end":
+		if len(v) < 2 || strings.TrimSpace(v[1]) == "" {
+			return fmt.Errorf("not enough arguments to limit %s", v[0])
+		}
+		if v[0] == "start" {
+			start = strings.TrimSpace(v[1])
+		} else {
+			end = strings.TrimSpace(v[1])
+		}
+	case "clear":
+		start, end = "", ""
+	default:
+		return fmt.Errorf("unknown argument %q to limit", v[0])
+	}
+	return t.client.SetTimeLimits(start, end)
+}
+
+func recording(t *Term, ctx callContext, args string) error {
+	v := strings.Fields(args)
+	if len(v) == 0 {
+		return errors.New("not enough arguments to recording")
+	}
+	if v[0] != "save" {
+		return fmt.Errorf("unknown argument %q to recording", v[0])
+	}
+	var from, to, path string
+	for v = v[1:]; len(v) > 0; v = v[1:] {
+		switch v[0] {
+		case "--from", "--to":
+			if len(v) < 2 {
+				return fmt.Errorf("missing argument to %s", v[0])
+			}
+			if v[0] == "--from" {
+				from = v[1]
+			} else {
+				to = v[1]
+			}
+			v = v[1:]
+		default:
+			if path != "" {
+				return errors.New("too many arguments to recording save")
+			}
+			path = v[0]
+		}
+	}
+	if path == "" {
+		return errors.New("not enough arguments to recording save")
+	}
+	cps, err := t.client.SaveRecording(path, from, to)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(t.stdout, "Recording saved to %s\n", path)
+	for _, cp := range cps {
+		fmt.Fprintf(t.stdout, "Checkpoint c%d %q at %s in the new recording\n", cp.ID, cp.Where, cp.When)
+	}
+	return nil
+}
+
+func timeline(t *Term, ctx callContext, args string) error {
+	v := strings.Fields(args)
+	if len(v) == 0 {
+		return errors.New("not enough arguments to timeline")
+	}
+	if v[0] != "goroutines" {
+		return fmt.Errorf("unknown argument %q to timeline", v[0])
+	}
+	var from, to, path string
+	for v = v[1:]; len(v) > 0; v = v[1:] {
+		switch v[0] {
+		case "--from", "--to", "--json":
+			if len(v) < 2 {
+				return fmt.Errorf("missing argument to %s", v[0])
+			}
+			switch v[0] {
+			case "--from":
+				from = v[1]
+			case "--to":
+				to = v[1]
+			case "--json":
+				path = v[1]
+			}
+			v = v[1:]
+		default:
+			return fmt.Errorf("unknown argument %q to timeline goroutines", v[0])
+		}
+	}
+	events, err := t.client.GoroutineTimeline(from, to)
+	if err != nil {
+		return err
+	}
+	if path != "" {
+		buf, err := json.MarshalIndent(events, "", "\t")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, buf, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(t.stdout, "%d events written to %s\n", len(events), path)
+		return nil
+	}
+	w := new(tabwriter.Writer)
+	w.Init(t.stdout, 4, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Position\tThread\tGoroutine\tEvent")
+	for _, ev := range events {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", ev.Position, ev.ThreadID, formatTimelineGoroutine(ev.GoroutineID), describeGoroutineEvent(ev))
+	}
+	return w.Flush()
+}
+
+func formatTimelineGoroutine(id int64) string {
+	if id == 0 {
+		return "-"
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+func describeGoroutineEvent(ev api.GoroutineEvent) string {
+	switch ev.Kind {
+	case "created":
+		s := "created by goroutine " + formatTimelineGoroutine(ev.CurrentGoroutineID)
+		if ev.StartLoc != nil && ev.StartLoc.Function != nil {
+			s += ", start " + ev.StartLoc.Function.Name()
+		}
+		return s
+	case "blocked":
+		if ev.WaitReason > 0 && ev.WaitReason < int64(len(waitReasonStrings)) {
+			return fmt.Sprintf("blocked [%s]", waitReasonStrings[ev.WaitReason])
+		}
+	case "unblocked":
+		if ev.CurrentGoroutineID != 0 {
+			return "unblocked by goroutine " + formatTimelineGoroutine(ev.CurrentGoroutineID)
+		}
+	}
+	return ev.Kind
+}
+
 func display(t *Term, ctx callContext, args string) error {
 	const (
 		addOption = "-a "
@@ -3329,6 +3854,9 @@ func formatBreakpointName(bp *api.Breakpoint, upcase bool) string {
 	if bp.WatchExpr != "" {
 		thing = "watchpoint"
 	}
+	if bp.Catch != "" {
+		thing = "catchpoint"
+	}
 	if upcase {
 		thing = strings.Title(thing)
 	}
@@ -3343,6 +3871,10 @@ func formatBreakpointName(bp *api.Breakpoint, upcase bool) string {
 }
 
 func (t *Term) formatBreakpointLocation(bp *api.Breakpoint) string {
+	if bp.Catch != "" {
+		// Catchpoints are not set on a location.
+		return bp.Catch
+	}
 	var out bytes.Buffer
 	if len(bp.Addrs) > 0 {
 		for i, addr := range bp.Addrs {
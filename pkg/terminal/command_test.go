@@ -2,6 +2,7 @@ package terminal
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -12,6 +13,7 @@ import (
 	"reflect"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -1258,6 +1260,147 @@ func TestClearCondBreakpoint(t *testing.T) {
 	})
 }
 
+func TestAfterBreakpoint(t *testing.T) {
+	test.AllowRecording(t)
+	withTestTerminal("break", t, func(term *FakeTerminal) {
+		term.MustExec("break a main.main:4")
+		term.MustExec("condition a i == 3")
+		term.MustExec("break -after a b main.main:3")
+		out := term.MustExec("breakpoints")
+		if !strings.Contains(out, "\tcond -after 1\n") {
+			t.Fatalf("-after missing from breakpoints output: %q", out)
+		}
+		listIsAt(t, term, "continue", 7, -1, -1)
+		listIsAt(t, term, "continue", 6, -1, -1)
+		out = term.MustExec("print i")
+		if !strings.Contains(out, "3\n") {
+			t.Fatalf("wrong value of i: %q", out)
+		}
+		bp, err := term.client.GetBreakpointByName("b")
+		assertNoError(t, err, "GetBreakpointByName")
+		if bp.TotalHitCount != 1 {
+			t.Fatalf("wrong hit count for b, hits before a should not be counted: %d", bp.TotalHitCount)
+		}
+
+		term.MustExec("condition -after b 0")
+		bp, err = term.client.GetBreakpointByName("b")
+		assertNoError(t, err, "GetBreakpointByName")
+		if bp.After != 0 {
+			t.Fatalf("-after not removed: %d", bp.After)
+		}
+		term.AssertExecError("condition -after b b", "a breakpoint can not depend on itself")
+	})
+}
+
+func TestOnceBreakpoint(t *testing.T) {
+	test.AllowRecording(t)
+	withTestTerminal("condperghitcount", t, func(term *FakeTerminal) {
+		term.MustExec("tbreak a condperghitcount.go:13")
+		out := term.MustExec("breakpoints")
+		if !strings.Contains(out, "Breakpoint a (enabled, once)") {
+			t.Fatalf("wrong breakpoints output: %q", out)
+		}
+		term.MustExec("break -per-g-after a b condperghitcount.go:16")
+		term.MustExec("condition b j == 10")
+		listIsAt(t, term, "continue", 13, -1, -1)
+		if _, err := term.client.GetBreakpointByName("a"); err == nil {
+			t.Fatal("breakpoint a not cleared after it was hit")
+		}
+		listIsAt(t, term, "continue", 16, -1, -1)
+		out = term.MustExec("print j")
+		if !strings.Contains(out, "10\n") {
+			t.Fatalf("wrong value of j: %q", out)
+		}
+		// Breakpoints can depend on a after it was cleared.
+		term.MustExec("break -per-g-after 1 c condperghitcount.go:20")
+		listIsAt(t, term, "continue", 20, -1, -1)
+		// The second goroutine never hits a, b must not stop it.
+		_, err := term.Exec("continue")
+		if err == nil || !strings.Contains(err.Error(), " has exited with status ") {
+			t.Fatalf("expected the process to exit: %v", err)
+		}
+	})
+}
+
+func TestCatchpoints(t *testing.T) {
+	withTestTerminal("catchpoints", t, func(term *FakeTerminal) {
+		term.MustExec("break main.main")
+		term.MustExec("continue")
+
+		term.MustExec("catch signal SIGUSR1")
+		term.MustExec("catch goroutine-start ^main\\.worker$")
+		term.MustExec("catch goroutine-exit ^main\\.worker$")
+		term.MustExec("catch -once panic *main.myError")
+
+		out := term.MustExec("breakpoints")
+		if !strings.Contains(out, "Catchpoint 2 (enabled) at signal SIGUSR1 (0)") || !strings.Contains(out, "(enabled, once) at panic *main.myError") {
+			t.Fatalf("wrong breakpoints output: %q", out)
+		}
+
+		continueToCatchpoint := func(catch string) {
+			t.Helper()
+			out := term.MustExec("continue")
+			if !strings.Contains(out, "catchpoint on ["+catch+"]") {
+				t.Fatalf("expected stop on catchpoint %q: %q", catch, out)
+			}
+		}
+
+		continueToCatchpoint("signal SIGUSR1")
+		if runtime.GOOS == "linux" && testBackend == "native" {
+			term.MustExec("catch syscall openat")
+			continueToCatchpoint("syscall openat")
+		}
+		continueToCatchpoint("goroutine-start ^main\\.worker$")
+		continueToCatchpoint("goroutine-exit ^main\\.worker$")
+		// Only the panic catchpoint is of interest from here on.
+		term.MustExec("clear 3")
+		term.MustExec("clear 4")
+		continueToCatchpoint("panic *main.myError")
+		if out := term.MustExec("breakpoints"); strings.Contains(out, "at panic") {
+			t.Fatalf("once catchpoint not cleared: %q", out)
+		}
+
+		out, err := term.Exec("continue")
+		if err == nil || !strings.Contains(err.Error(), " has exited with status ") {
+			t.Fatalf("expected the process to exit: %v %q", err, out)
+		}
+	})
+}
+
+func TestCatchpointCond(t *testing.T) {
+	withTestTerminal("catchpoints", t, func(term *FakeTerminal) {
+		term.MustExec("break main.main")
+		term.MustExec("continue")
+		term.MustExec("catch goroutine-start")
+		term.MustExec("cond 2 main.stage == 3")
+		term.MustExec("on 2 print main.stage")
+		out := term.MustExec("continue")
+		if !strings.Contains(out, "catchpoint on [goroutine-start]") || !strings.Contains(out, "main.stage: 3") {
+			t.Fatalf("expected stop on catchpoint: %q", out)
+		}
+		term.AssertExecError("catch nothing", "unknown catchpoint kind \"nothing\"")
+		term.AssertExecError("catch signal SIGNOTHING", "unknown signal \"SIGNOTHING\"")
+	})
+}
+
+func TestHeapRefs(t *testing.T) {
+	withTestTerminal("heaprefs", t, func(term *FakeTerminal) {
+		term.MustExec("continue")
+		out := term.MustExec("refs leaf")
+		if !strings.Contains(out, "is referenced by:") || !strings.Contains(out, "\tgoroutine 1 frame 0 main.main: leaf -> 0x") {
+			t.Fatalf("wrong output for refs leaf: %q", out)
+		}
+		out = term.MustExec("refs head.next")
+		if !strings.Contains(out, "\tglobal main.head -> 0x") {
+			t.Fatalf("wrong output for refs head.next: %q", out)
+		}
+		_, err := term.Exec("refs &head")
+		if err == nil || !strings.HasSuffix(err.Error(), " is not the address of an object on the heap") {
+			t.Fatalf("expected error for refs &head, got %v", err)
+		}
+	})
+}
+
 func TestBreakpointEditing(t *testing.T) {
 	term := &FakeTerminal{
 		t:    t,
@@ -1414,3 +1557,82 @@ func TestCreateBreakpointByLocExpr(t *testing.T) {
 		}
 	})
 }
+
+func TestBreakpointsSaveLoad(t *testing.T) {
+	test.AllowRecording(t)
+	withTestTerminal("testnextprog", t, func(term *FakeTerminal) {
+		path := filepath.Join(t.TempDir(), "breakpoints.json")
+
+		term.MustExec("break bp1 testnextprog.go:26")
+		term.MustExec("cond bp1 i == 2")
+		term.MustExec("on bp1 print j")
+		term.MustExec("on bp1 stack 2")
+		term.MustExec("trace main.sleepytime")
+		term.MustExec("break hw main.helloworld")
+		term.MustExec("cond -hitcount hw > 1")
+		term.MustExec("on hw args")
+		term.MustExec("toggle hw")
+		term.MustExec("catch panic")
+		term.MustExec("cond 4 runtime.ncpu > 1")
+		term.AssertExec("breakpoints save "+path, fmt.Sprintf("Saved 4 breakpoints to %s\n", path))
+
+		// Moving the lines of the file does not move the breakpoints anchored
+		// to a function.
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sbps []api.SavedBreakpoint
+		if err := json.Unmarshal(buf, &sbps); err != nil {
+			t.Fatal(err)
+		}
+		for i := range sbps {
+			sbps[i].Line += 100
+		}
+		sbps = append(sbps, api.SavedBreakpoint{FunctionName: "main.nothere"})
+		// Breakpoints that depend on a breakpoint that is not loaded are
+		// discarded, with the breakpoints tracing their return values.
+		sbps = append(sbps, api.SavedBreakpoint{FunctionName: "main.helloworld", Tracepoint: true, TraceReturn: true, After: 100})
+		buf, _ = json.Marshal(sbps)
+		if err := ioutil.WriteFile(path, buf, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		before, err := term.client.ListBreakpoints(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		term.MustExec("clearall")
+		out := term.MustExec("breakpoints load " + path)
+		if !strings.Contains(out, "Could not set breakpoint at main.nothere:") {
+			t.Errorf("missing error for main.nothere in %q", out)
+		}
+		after, err := term.client.ListBreakpoints(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		summary := func(bps []*api.Breakpoint) []string {
+			r := []string{}
+			for _, bp := range bps {
+				if bp.ID < 0 {
+					continue
+				}
+				loadArgs, loadLocals := bp.LoadArgs, bp.LoadLocals
+				bp.ID, bp.Addr, bp.Addrs, bp.AddrPid, bp.HitCount = 0, 0, nil, nil, nil
+				bp.LoadArgs, bp.LoadLocals = nil, nil
+				r = append(r, fmt.Sprintf("%#v %#v %#v", bp, loadArgs, loadLocals))
+			}
+			sort.Strings(r)
+			return r
+		}
+		if b, a := summary(before), summary(after); !reflect.DeepEqual(b, a) {
+			t.Errorf("breakpoints changed\nbefore: %s\nafter:  %s", strings.Join(b, "\n\t"), strings.Join(a, "\n\t"))
+		}
+
+		out = term.MustExec("continue")
+		if !strings.Contains(out, "main.sleepytime") || !strings.Contains(out, "testnextprog.go:26") || !strings.Contains(out, "\tj: ") {
+			t.Errorf("wrong output for continue: %q", out)
+		}
+	})
+}
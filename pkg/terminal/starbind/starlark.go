@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"reflect"
+	"regexp"
 	"runtime"
 	"sort"
 	"strings"
@@ -29,6 +31,7 @@ const (
 	dlvContextName               = "dlv_context"
 	curScopeBuiltinName          = "cur_scope"
 	defaultLoadConfigBuiltinName = "default_load_config"
+	prettyPrinterBuiltinName     = "pretty_printer"
 	helpBuiltinName              = "help"
 )
 
@@ -47,6 +50,7 @@ func init() {
 type Context interface {
 	Client() service.Client
 	RegisterCommand(name, helpMsg string, cmdfn func(args string) error)
+	RegisterPrettyPrinter(typ string, re *regexp.Regexp, fn func(v *api.Variable) (string, error))
 	CallCommand(cmdstr string) error
 	Scope() api.EvalScope
 	LoadConfig() api.LoadConfig
@@ -139,6 +143,44 @@ func New(ctx Context, out EchoWriter) *Env {
 	})
 	builtindoc(defaultLoadConfigBuiltinName, "()", "returns the default load configuration.")
 
+	env.env[prettyPrinterBuiltinName] = starlark.NewBuiltin(prettyPrinterBuiltinName, func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var typ string
+		var fn starlark.Callable
+		var isRegexp bool
+		if err := starlark.UnpackArgs(prettyPrinterBuiltinName, args, kwargs, "Type", &typ, "Fn", &fn, "Regexp?", &isRegexp); err != nil {
+			return nil, decorateError(thread, err)
+		}
+		var re *regexp.Regexp
+		if isRegexp {
+			var err error
+			re, err = regexp.Compile(typ)
+			if err != nil {
+				return nil, decorateError(thread, err)
+			}
+		}
+		env.ctx.RegisterPrettyPrinter(typ, re, func(v *api.Variable) (string, error) {
+			switch v.Kind {
+			case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+				if v.Len != 0 && len(v.Children) == 0 && v.Addr != 0 {
+					// Loaded with a recursion limit that was too low, for
+					// example by the locals command.
+					v = env.autoLoad(varAddrExpr(v))
+				}
+			}
+			r, err := starlark.Call(env.newThread(), fn, starlark.Tuple{env.interfaceToStarlarkValue(*v)}, nil)
+			if err != nil {
+				return "", err
+			}
+			s, ok := r.(starlark.String)
+			if !ok {
+				return "", fmt.Errorf("pretty printer for %s returned a %s instead of a string", typ, r.Type())
+			}
+			return string(s), nil
+		})
+		return starlark.None, nil
+	})
+	builtindoc(prettyPrinterBuiltinName, "(Type, Fn, Regexp=False)", "registers Fn as the pretty printer of the variables of type Type, if Regexp is true Type is a regular expression matched against type names. Fn is called with the variable and must return a string.")
+
 	env.env[helpBuiltinName] = starlark.NewBuiltin(helpBuiltinName, func(_ *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 		switch len(args) {
 		case 0:
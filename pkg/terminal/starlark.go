@@ -1,6 +1,8 @@
 package terminal
 
 import (
+	"regexp"
+
 	"github.com/undoio/delve/pkg/terminal/starbind"
 	"github.com/undoio/delve/service"
 	"github.com/undoio/delve/service/api"
@@ -46,6 +48,17 @@ func (ctx starlarkContext) RegisterCommand(name, helpMsg string, fn func(args st
 	}
 }
 
+func (ctx starlarkContext) RegisterPrettyPrinter(typ string, re *regexp.Regexp, fn func(v *api.Variable) (string, error)) {
+	pp := prettyPrinter{typ: typ, re: re, fn: fn}
+	for i := range ctx.term.prettyPrinters {
+		if old := &ctx.term.prettyPrinters[i]; old.typ == typ && (old.re == nil) == (re == nil) {
+			*old = pp
+			return
+		}
+	}
+	ctx.term.prettyPrinters = append(ctx.term.prettyPrinters, pp)
+}
+
 func (ctx starlarkContext) CallCommand(cmdstr string) error {
 	return ctx.term.cmds.Call(cmdstr, ctx.term)
 }
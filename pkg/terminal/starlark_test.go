@@ -225,3 +225,36 @@ v.Children[0].Children[0].Value.XXX
 		}
 	})
 }
+
+func TestStarlarkPrettyPrinter(t *testing.T) {
+	withTestTerminal("prettyprinters", t, func(term *FakeTerminal) {
+		term.MustExec("continue")
+		term.MustExecStarlark(`pretty_printer("main.Money", lambda v: "$%d.%d%d" % (v.Value.units, v.Value.cents // 10, v.Value.cents % 10))`)
+		term.MustExecStarlark(`pretty_printer("^main\\.I[D]$", lambda v: "%s-%d" % (v.Value.prefix, v.Value.n), Regexp=True)`)
+
+		for _, tc := range []struct{ cmd, tgt string }{
+			{"print id", "main.ID(acct-42)\n"},
+			{"print pid", "*main.ID(acct-42)\n"},
+			{"print ids", "[]main.ID len: 2, cap: 2, [\n\ta-1,\n\tb-2,\n]\n"},
+			{"print acct.balance", "main.Money($12.05)\n"},
+			{"display -a acct.id", "0: acct.id = main.ID(acct-7)\n"},
+		} {
+			out := term.MustExec(tc.cmd)
+			if out != tc.tgt {
+				t.Errorf("%s: expected %q got %q", tc.cmd, tc.tgt, out)
+			}
+		}
+
+		out := term.MustExec("locals")
+		if !strings.Contains(out, "id = main.ID(acct-42)\n") || !strings.Contains(out, "balance: main.Money($12.05)") {
+			t.Errorf("pretty printers not used by locals")
+		}
+
+		// Registering a printer for the same type replaces it.
+		term.MustExecStarlark(`pretty_printer("main.Money", lambda v: v.Value.units)`)
+		out = term.MustExec("print acct.balance")
+		if out != "main.Money((pretty printer error: pretty printer for main.Money returned a int instead of a string))\n" {
+			t.Errorf("wrong output for failing pretty printer: %q", out)
+		}
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"net/rpc"
 	"os"
 	"os/signal"
+	"regexp"
 	"strings"
 	"sync"
 	"syscall"
@@ -64,6 +65,9 @@ type Term struct {
 
 	starlarkEnv *starbind.Env
 
+	// prettyPrinters are the pretty printers registered by starlark scripts.
+	prettyPrinters []prettyPrinter
+
 	substitutePathRulesCache [][2]string
 
 	// quitContinue is set to true by exitCommand to signal that the process
@@ -84,6 +88,19 @@ type displayEntry struct {
 	fmtstr string
 }
 
+type prettyPrinter struct {
+	typ string
+	re  *regexp.Regexp
+	fn  func(v *api.Variable) (string, error)
+}
+
+func (pp *prettyPrinter) matches(typename string) bool {
+	if pp.re != nil {
+		return pp.re.MatchString(typename)
+	}
+	return pp.typ == typename
+}
+
 // New returns a new Term.
 func New(client service.Client, conf *config.Config) *Term {
 	cmds := DebugCommands(client)
@@ -573,9 +590,33 @@ func (t *Term) printDisplay(i int) {
 		fmt.Fprintf(t.stdout, "%d: %s = error %v\n", i, expr, err)
 		return
 	}
+	t.prettyPrint(val)
 	fmt.Fprintf(t.stdout, "%d: %s = %s\n", i, val.Name, val.SinglelineStringFormatted(fmtstr))
 }
 
+// prettyPrint sets the Display field of v, and of the variables contained
+// in v, using the pretty printers registered by starlark scripts. They
+// take precedence over the pretty printers of the server.
+func (t *Term) prettyPrint(v *api.Variable) {
+	if len(t.prettyPrinters) == 0 || v.Unreadable != "" || v.OnlyAddr {
+		return
+	}
+	for i := range v.Children {
+		t.prettyPrint(&v.Children[i])
+	}
+	for i := range t.prettyPrinters {
+		if !t.prettyPrinters[i].matches(v.Type) {
+			continue
+		}
+		display, err := t.prettyPrinters[i].fn(v)
+		if err != nil {
+			display = fmt.Sprintf("(pretty printer error: %v)", err)
+		}
+		v.Display = display
+		return
+	}
+}
+
 func (t *Term) printDisplays() {
 	for i := range t.displays {
 		if t.displays[i].expr != "" {
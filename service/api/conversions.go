@@ -32,10 +32,17 @@ func ConvertLogicalBreakpoint(lbp *proc.LogicalBreakpoint) *Breakpoint {
 		LoadArgs:      LoadConfigFromProc(lbp.LoadArgs),
 		LoadLocals:    LoadConfigFromProc(lbp.LoadLocals),
 		TotalHitCount: lbp.TotalHitCount,
+		After:         lbp.After,
+		AfterPerG:     lbp.AfterPerG,
+		Once:          lbp.Once,
 		Disabled:      !lbp.Enabled,
 		UserData:      lbp.UserData,
 	}
 
+	if lbp.Set.Catch != nil {
+		b.Catch = lbp.Set.Catch.String()
+	}
+
 	b.HitCount = map[string]uint64{}
 	for idx := range lbp.HitCount {
 		b.HitCount[strconv.FormatInt(idx, 10)] = lbp.HitCount[idx]
@@ -183,6 +190,10 @@ func ConvertVar(v *proc.Variable) *Variable {
 
 	r.Value = VariableValueAsString(v)
 
+	if v.Display != nil {
+		r.Display = convertDisplay(ConvertVar(v.Display))
+	}
+
 	switch v.Kind {
 	case reflect.Complex64:
 		r.Children = make([]Variable, 2)
@@ -257,6 +268,19 @@ func VariableValueAsString(v *proc.Variable) string {
 	}
 }
 
+// convertDisplay returns the string used as the Display field of a
+// variable formatted by a pretty printer that returned v.
+func convertDisplay(v *Variable) string {
+	switch {
+	case v.Unreadable != "":
+		return fmt.Sprintf("(pretty printer error: %s)", v.Unreadable)
+	case v.Kind == reflect.String:
+		return v.Value
+	default:
+		return v.SinglelineString()
+	}
+}
+
 // ConvertVars converts from []*proc.Variable to []api.Variable.
 func ConvertVars(pv []*proc.Variable) []Variable {
 	if pv == nil {
@@ -322,6 +346,49 @@ func ConvertGoroutines(tgt *proc.Target, gs []*proc.G) []*Goroutine {
 	return goroutines
 }
 
+// ConvertGoroutineEvent converts from proc.GoroutineEvent to api.GoroutineEvent.
+func ConvertGoroutineEvent(tgt *proc.Target, ev proc.GoroutineEvent) GoroutineEvent {
+	r := GoroutineEvent{
+		Kind:               ev.Kind.String(),
+		Position:           ev.Position,
+		ThreadID:           ev.ThreadID,
+		GoroutineID:        ev.GoroutineID,
+		CurrentGoroutineID: ev.CurrentGoroutineID,
+		WaitReason:         ev.WaitReason,
+	}
+	if ev.StartPC != 0 {
+		file, line, fn := tgt.BinInfo().PCToLine(ev.StartPC)
+		loc := ConvertLocation(proc.Location{PC: ev.StartPC, File: file, Line: line, Fn: fn})
+		r.StartLoc = &loc
+	}
+	return r
+}
+
+// ConvertHeapReferences converts from proc.HeapReferences to api.HeapReferences.
+func ConvertHeapReferences(refs *proc.HeapReferences) *HeapReferences {
+	r := &HeapReferences{
+		Object: HeapObject{Addr: refs.Object.Addr, Size: refs.Object.Size},
+		Chains: make([]HeapRefChain, 0, len(refs.Chains)),
+	}
+	for _, chain := range refs.Chains {
+		root := chain.Root
+		c := HeapRefChain{Root: HeapRoot{
+			Kind:        root.Kind.String(),
+			Addr:        root.Addr,
+			Name:        root.Name,
+			Offset:      root.Offset,
+			GoroutineID: root.GoroutineID,
+			Frame:       root.Frame,
+			Function:    ConvertFunction(root.Fn),
+		}}
+		for _, link := range chain.Links {
+			c.Links = append(c.Links, HeapLink{Object: HeapObject{Addr: link.Object.Addr, Size: link.Object.Size}, Offset: link.Offset})
+		}
+		r.Chains = append(r.Chains, c)
+	}
+	return r
+}
+
 // ConvertLocation converts from proc.Location to api.Location.
 func ConvertLocation(loc proc.Location) Location {
 	return Location{
@@ -54,6 +54,15 @@ func (v *Variable) writeTo(buf io.Writer, top, newlines, includeType bool, inden
 		return
 	}
 
+	if v.Display != "" {
+		if includeType {
+			fmt.Fprintf(buf, "%s(%s)", v.Type, v.Display)
+		} else {
+			fmt.Fprint(buf, v.Display)
+		}
+		return
+	}
+
 	switch v.Kind {
 	case reflect.Slice:
 		v.writeSliceTo(buf, newlines, includeType, indent, fmtstr)
@@ -92,6 +92,9 @@ type Breakpoint struct {
 	// FunctionName is the name of the function at the current breakpoint, and
 	// may not always be available.
 	FunctionName string `json:"functionName,omitempty"`
+	// Catch is set for catchpoints to the event they stop on, for example
+	// "signal SIGSEGV" or "goroutine-start", see the catch command.
+	Catch string `json:"catch,omitempty"`
 
 	// Breakpoint condition
 	Cond string
@@ -100,6 +103,15 @@ type Breakpoint struct {
 	HitCond string
 	// HitCondPerG use per goroutine hitcount as HitCond operand, instead of total hitcount
 	HitCondPerG bool
+	// After is the ID of a breakpoint that must be hit before this
+	// breakpoint is triggered, zero if there is no such requirement.
+	After int `json:"after,omitempty"`
+	// AfterPerG requires the After breakpoint to be hit by the same
+	// goroutine that hits this breakpoint.
+	AfterPerG bool `json:"afterPerG,omitempty"`
+	// Once flag, signifying the breakpoint is cleared the first time it is
+	// hit.
+	Once bool `json:"once,omitempty"`
 
 	// Tracepoint flag, signifying this is a tracepoint.
 	Tracepoint bool `json:"continue"`
@@ -340,6 +352,10 @@ type Variable struct {
 	// Unreadable addresses will have this field set
 	Unreadable string `json:"unreadable"`
 
+	// Display is the value formatted by the pretty printer matching the type
+	// of the variable, empty if there is no such pretty printer
+	Display string `json:"display,omitempty"`
+
 	// LocationExpr describes the location expression of this variable's address
 	LocationExpr string
 	// DeclLine is the line number of this variable's declaration
@@ -403,6 +419,9 @@ type DebuggerCommand struct {
 	ReturnInfoLoadConfig *LoadConfig
 	// Expr is the expression argument for a Call command
 	Expr string `json:"expr,omitempty"`
+	// CallPC is the address of the CALL instruction to step into for the
+	// StepIntoCall command.
+	CallPC uint64 `json:"callPC,omitempty"`
 
 	// UnsafeCall disables parameter escape checking for function calls.
 	// Go objects can be allocated on the stack or on the heap. Heap objects
@@ -448,10 +467,16 @@ const (
 	Step = "step"
 	// ReverseStep continues backward to the previous line of source code, entering function calls.
 	ReverseStep = "reverseStep"
+	// StepIntoCall continues to the next source line, entering only the function called by the CALL instruction at CallPC.
+	StepIntoCall = "stepIntoCall"
 	// StepOut continues to the return address of the current function
 	StepOut = "stepOut"
 	// ReverseStepOut continues backward to the caller of the current function.
 	ReverseStepOut = "reverseStepOut"
+	// ReverseCallEntry continues backward to the entry of the current function.
+	ReverseCallEntry = "reverseCallEntry"
+	// ReverseCallSite continues backward to the start of the line, in the caller, that called the current function.
+	ReverseCallSite = "reverseCallSite"
 	// StepInstruction continues for exactly 1 cpu instruction.
 	StepInstruction = "stepInstruction"
 	// ReverseStepInstruction reverses execution for exactly 1 cpu instruction.
@@ -554,6 +579,52 @@ func (regs Registers) String() string {
 	return buf.String()
 }
 
+// SavedBreakpoint is a user breakpoint saved to a file by
+// SaveBreakpoints, that LoadBreakpoints recreates in a later debugging
+// session.
+type SavedBreakpoint struct {
+	// ID is the ID of the breakpoint when it was saved, it is only used to
+	// resolve the After field of other saved breakpoints.
+	ID int `json:"id,omitempty"`
+	// Name is the user defined name of the breakpoint.
+	Name string `json:"name,omitempty"`
+	// FunctionName and LineOffset anchor the breakpoint to the function
+	// containing it: LineOffset is the line of the breakpoint relative to
+	// the first line of the function, zero for the entry point of the
+	// function. Anchored breakpoints are restored at the same place in the
+	// function when edits shift the lines of the file.
+	FunctionName string `json:"functionName,omitempty"`
+	LineOffset   int    `json:"lineOffset,omitempty"`
+	// File and Line are the location of the breakpoint when it was saved,
+	// they are only used if the breakpoint can not be set in FunctionName.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	// Catch is the event a saved catchpoint stops on, catchpoints have no
+	// location.
+	Catch string `json:"catch,omitempty"`
+
+	Cond        string `json:"cond,omitempty"`
+	HitCond     string `json:"hitCond,omitempty"`
+	HitCondPerG bool   `json:"hitCondPerG,omitempty"`
+	// After is the ID of the saved breakpoint that must be hit before this
+	// breakpoint is triggered.
+	After     int  `json:"after,omitempty"`
+	AfterPerG bool `json:"afterPerG,omitempty"`
+	Once      bool `json:"once,omitempty"`
+
+	Tracepoint bool `json:"tracepoint,omitempty"`
+	// TraceReturn is set for tracepoints on the entry point of a function
+	// whose return values are traced too.
+	TraceReturn bool        `json:"traceReturn,omitempty"`
+	Goroutine   bool        `json:"goroutine,omitempty"`
+	Stacktrace  int         `json:"stacktrace,omitempty"`
+	Variables   []string    `json:"variables,omitempty"`
+	LoadArgs    *LoadConfig `json:"loadArgs,omitempty"`
+	LoadLocals  *LoadConfig `json:"loadLocals,omitempty"`
+
+	Disabled bool `json:"disabled,omitempty"`
+}
+
 // DiscardedBreakpoint is a breakpoint that is not
 // reinstated during a restart.
 type DiscardedBreakpoint struct {
@@ -569,6 +640,96 @@ type Checkpoint struct {
 	Where string
 }
 
+// LastWrite describes the most recent write to the memory of an
+// expression, found by travelling back in the execution history of a
+// recording.
+type LastWrite struct {
+	// State is the state of the target after travelling back.
+	State DebuggerState
+	// Found is true if the target stopped at a write to the memory of the
+	// expression. If it is false the target stopped for a different reason,
+	// for example because a breakpoint was hit or the start of the recording
+	// was reached, and none of the other fields are set.
+	Found bool
+	// OldValue is the value of the expression before the write.
+	OldValue *Variable
+	// NewValue is the value of the expression after the write.
+	NewValue *Variable
+	// Goroutine is the goroutine that executed the write, it is nil if the
+	// write was executed by a thread that was not running a goroutine.
+	Goroutine *Goroutine
+	// Stacktrace is the stack of the thread that executed the write.
+	Stacktrace []Stackframe
+}
+
+// HeapObject is an object allocated on the heap of the target.
+type HeapObject struct {
+	Addr uint64 `json:"addr"`
+	Size int64  `json:"size"`
+}
+
+// HeapRoot is a pointer to a heap object held by a variable that lives
+// outside of the heap.
+type HeapRoot struct {
+	// Kind is either "global" or "stack".
+	Kind string `json:"kind"`
+	// Addr is the address of the pointer.
+	Addr uint64 `json:"addr"`
+	// Name is the name of the variable containing Addr.
+	Name string `json:"name,omitempty"`
+	// Offset is the offset of Addr from the start of the variable.
+	Offset int64 `json:"offset,omitempty"`
+	// GoroutineID, Frame and Function describe the stack frame of the
+	// variable, for stack roots.
+	GoroutineID int64     `json:"goroutineID,omitempty"`
+	Frame       int       `json:"frame,omitempty"`
+	Function    *Function `json:"function,omitempty"`
+}
+
+// HeapLink is a heap object holding a pointer to the next object in a
+// reference chain.
+type HeapLink struct {
+	Object HeapObject `json:"object"`
+	// Offset is the offset of the pointer inside Object.
+	Offset int64 `json:"offset"`
+}
+
+// HeapRefChain is a chain of pointers that goes from a GC root, through
+// the objects in Links, to a heap object.
+type HeapRefChain struct {
+	Root  HeapRoot   `json:"root"`
+	Links []HeapLink `json:"links,omitempty"`
+}
+
+// HeapReferences describes what keeps a heap object alive.
+type HeapReferences struct {
+	Object HeapObject `json:"object"`
+	// Chains has one entry for every pointer to Object that can be reached
+	// from a GC root, each one is the shortest path from a root.
+	Chains []HeapRefChain `json:"chains"`
+}
+
+// GoroutineEvent is a goroutine scheduling event that happened in a
+// recording.
+type GoroutineEvent struct {
+	// Kind is one of "created", "running", "blocked" or "unblocked".
+	Kind string `json:"kind"`
+	// Position is the position in the recording where the event happened,
+	// it can be used as the argument of restart.
+	Position string `json:"position"`
+	ThreadID int    `json:"threadID"`
+	// GoroutineID is the goroutine the event is about, zero if it could not
+	// be determined.
+	GoroutineID int64 `json:"goroutineID"`
+	// CurrentGoroutineID is the goroutine that was running on the thread
+	// when the event happened, for example the creator of a new goroutine.
+	CurrentGoroutineID int64 `json:"currentGoroutineID"`
+	// StartLoc is the first function run by created goroutines.
+	StartLoc *Location `json:"startLoc,omitempty"`
+	// WaitReason is the wait reason of blocked goroutines.
+	WaitReason int64 `json:"waitReason,omitempty"`
+}
+
 // Image represents a loaded shared object (go plugin or shared library)
 type Image struct {
 	Path      string
@@ -49,6 +49,10 @@ type Client interface {
 	StepOut() (*api.DebuggerState, error)
 	// ReverseStepOut continues backward to the caller of the current function.
 	ReverseStepOut() (*api.DebuggerState, error)
+	// ReverseCallEntry continues backward to the entry of the current function.
+	ReverseCallEntry() (*api.DebuggerState, error)
+	// ReverseCallSite continues backward to the start of the line, in the caller, that called the current function.
+	ReverseCallSite() (*api.DebuggerState, error)
 	// Call resumes process execution while making a function call.
 	Call(goroutineID int64, expr string, unsafe bool) (*api.DebuggerState, error)
 
@@ -86,6 +90,10 @@ type Client interface {
 	// AmendBreakpoint allows user to update an existing breakpoint for example to change the information
 	// retrieved when the breakpoint is hit or to change, add or remove the break condition
 	AmendBreakpoint(*api.Breakpoint) error
+	// SaveBreakpoints writes the user breakpoints to a file.
+	SaveBreakpoints(path string) ([]api.SavedBreakpoint, error)
+	// LoadBreakpoints creates the breakpoints saved to a file by SaveBreakpoints.
+	LoadBreakpoints(path string) ([]*api.Breakpoint, []api.DiscardedBreakpoint, error)
 	// CancelNext cancels a Next or Step call that was interrupted by a manual stop or by another breakpoint
 	CancelNext() error
 
@@ -98,6 +106,8 @@ type Client interface {
 	ListPackageVariables(filter string, cfg api.LoadConfig) ([]api.Variable, error)
 	// EvalVariable returns a variable in the context of the current thread.
 	EvalVariable(scope api.EvalScope, symbol string, cfg api.LoadConfig) (*api.Variable, error)
+	// HeapReferences returns the chains of references that keep alive the heap object referred to by expr.
+	HeapReferences(scope api.EvalScope, expr string) (*api.HeapReferences, error)
 
 	// SetVariable sets the value of a variable
 	SetVariable(scope api.EvalScope, symbol, value string) error
@@ -160,6 +170,16 @@ type Client interface {
 	ListCheckpoints() ([]api.Checkpoint, error)
 	// ClearCheckpoint removes a checkpoint
 	ClearCheckpoint(id int) error
+	// TimeLimits returns the portion of the recording that execution is limited to.
+	TimeLimits() (start, end string, err error)
+	// SetTimeLimits limits execution to the portion of the recording between start and end.
+	SetTimeLimits(start, end string) error
+	// SaveRecording saves the portion of the recording between from and to as a new recording in path.
+	SaveRecording(path, from, to string) ([]api.Checkpoint, error)
+	// GoroutineTimeline returns the goroutine scheduling events that happened between from and to.
+	GoroutineTimeline(from, to string) ([]api.GoroutineEvent, error)
+	// LastWrite travels back to the most recent write to the memory of expr.
+	LastWrite(scope api.EvalScope, expr string, cfg api.LoadConfig, depth int) (*api.LastWrite, error)
 
 	// SetReturnValuesLoadConfig sets the load configuration for return values.
 	SetReturnValuesLoadConfig(*api.LoadConfig)
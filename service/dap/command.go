@@ -160,3 +160,21 @@ func (s *Session) sources(_, _ int, filter string) (string, error) {
 	sort.Strings(sources)
 	return strings.Join(sources, "\n"), nil
 }
+
+// commandCompletions returns the names of the dlv commands that start with
+// prefix.
+func (s *Session) commandCompletions(prefix string) []dap.CompletionItem {
+	var targets []dap.CompletionItem
+	for _, cmd := range debugCommands(s) {
+		for _, alias := range cmd.aliases {
+			if strings.HasPrefix(alias, prefix) {
+				h := cmd.helpMsg
+				if idx := strings.Index(h, "\n"); idx >= 0 {
+					h = h[:idx]
+				}
+				targets = append(targets, dap.CompletionItem{Label: alias, Detail: h, Type: "keyword"})
+			}
+		}
+	}
+	return targets
+}
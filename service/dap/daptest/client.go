@@ -6,6 +6,7 @@ package daptest
 
 import (
 	"bufio"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,6 +14,8 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/google/go-dap"
@@ -27,6 +30,18 @@ type Client struct {
 	// seq is used to track the sequence number of each
 	// requests that the client sends to the server
 	seq int
+	// launching is set while a launch request is in progress and launched
+	// once it succeeded. The output of the program is then collected in
+	// targetOutput instead of being returned by ReadMessage.
+	launching, launched bool
+	targetOutput        map[string]*strings.Builder
+	// held are the output events received while launching, they are
+	// returned by ReadMessage if the launch fails.
+	held []dap.Message
+	// loadedEvents are the module and loadedSource events received so far,
+	// they are sent when new images are found and are not returned by
+	// ReadMessage.
+	loadedEvents []dap.Message
 }
 
 // NewClient creates a new Client over a TCP connection.
@@ -43,7 +58,7 @@ func NewClient(addr string) *Client {
 // NewClientFromConn creates a new Client with the given TCP connection.
 // Call Close to close the connection.
 func NewClientFromConn(conn net.Conn) *Client {
-	c := &Client{conn: conn, reader: bufio.NewReader(conn)}
+	c := &Client{conn: conn, reader: bufio.NewReader(conn), targetOutput: make(map[string]*strings.Builder)}
 	c.seq = 1 // match VS Code numbering
 	return c
 }
@@ -54,22 +69,145 @@ func (c *Client) Close() {
 }
 
 func (c *Client) send(request dap.Message) {
+	if _, ok := request.(*dap.LaunchRequest); ok {
+		c.launching = true
+	}
 	dap.WriteProtocolMessage(c.conn, request)
 }
 
+// ReadMessage reads the next protocol message. Output events carrying the
+// output of the launched program arrive at any time, they are collected
+// and can be retrieved with TargetOutput. Module and loadedSource events
+// are collected as well and can be retrieved with LoadedEvents.
 func (c *Client) ReadMessage() (dap.Message, error) {
-	return dap.ReadProtocolMessage(c.reader)
+	if len(c.held) > 0 && !c.launching {
+		m := c.held[0]
+		c.held = c.held[1:]
+		return m, nil
+	}
+	for {
+		m, err := dap.ReadProtocolMessage(c.reader)
+		if err != nil {
+			return nil, err
+		}
+		switch m := m.(type) {
+		case *dap.ModuleEvent, *dap.LoadedSourceEvent:
+			c.loadedEvents = append(c.loadedEvents, m)
+			continue
+		case *dap.OutputEvent:
+			if (c.launching || c.launched) && m.Body.Source == nil && (m.Body.Category == "stdout" || m.Body.Category == "stderr") {
+				if c.launched {
+					c.collectTargetOutput(m)
+				} else {
+					c.held = append(c.held, m)
+				}
+				continue
+			}
+		case *dap.LaunchResponse:
+			c.launching, c.launched = false, true
+			for _, held := range c.held {
+				c.collectTargetOutput(held.(*dap.OutputEvent))
+			}
+			c.held = nil
+		case *dap.ErrorResponse:
+			if c.launching && m.Command == "launch" {
+				// The output events were not sent by the program, for
+				// example they report a build error.
+				c.launching = false
+				if len(c.held) > 0 {
+					c.held = append(c.held, m)
+					return c.ReadMessage()
+				}
+			}
+		}
+		return m, nil
+	}
+}
+
+func (c *Client) collectTargetOutput(m *dap.OutputEvent) {
+	if c.targetOutput[m.Body.Category] == nil {
+		c.targetOutput[m.Body.Category] = &strings.Builder{}
+	}
+	c.targetOutput[m.Body.Category].WriteString(m.Body.Output)
 }
 
 func (c *Client) ExpectMessage(t *testing.T) dap.Message {
 	t.Helper()
-	m, err := dap.ReadProtocolMessage(c.reader)
+	m, err := c.ReadMessage()
 	if err != nil {
 		t.Fatal(err)
 	}
 	return m
 }
 
+// TargetOutput returns the output of the launched program received so far
+// in output events of the given category, "stdout" or "stderr".
+func (c *Client) TargetOutput(category string) string {
+	if b := c.targetOutput[category]; b != nil {
+		return b.String()
+	}
+	return ""
+}
+
+// LoadedEvents returns the module and loadedSource events received so far.
+func (c *Client) LoadedEvents() []dap.Message {
+	return c.loadedEvents
+}
+
+// ExpectCustomMessage reads a protocol message that go-dap can not decode,
+// like the responses and events of Delve-specific requests, and unmarshals
+// it into v.
+func (c *Client) ExpectCustomMessage(t *testing.T, v interface{}) {
+	t.Helper()
+	content, err := dap.ReadBaseMessage(c.reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		t.Fatalf("%v: %s", err, content)
+	}
+}
+
+// ExpectRunInTerminalRequest reads a protocol message from the connection
+// and fails the test if the read message is not a runInTerminal request.
+func (c *Client) ExpectRunInTerminalRequest(t *testing.T) *dap.RunInTerminalRequest {
+	t.Helper()
+	m := c.ExpectMessage(t)
+	r, ok := m.(*dap.RunInTerminalRequest)
+	if !ok {
+		t.Fatalf("got %#v, want *dap.RunInTerminalRequest", m)
+	}
+	return r
+}
+
+// RunInTerminalResponse sends a successful response to the runInTerminal
+// request with sequence number requestSeq.
+func (c *Client) RunInTerminalResponse(requestSeq, processID int) {
+	response := &dap.RunInTerminalResponse{}
+	response.Seq = c.seq
+	c.seq++
+	response.Type = "response"
+	response.Command = "runInTerminal"
+	response.RequestSeq = requestSeq
+	response.Success = true
+	response.Body.ProcessId = processID
+	c.send(response)
+}
+
+// RunInTerminalErrorResponse sends a failed response to the runInTerminal
+// request with sequence number requestSeq.
+func (c *Client) RunInTerminalErrorResponse(requestSeq int, message string) {
+	response := &dap.RunInTerminalResponse{}
+	response.Seq = c.seq
+	c.seq++
+	response.Type = "response"
+	response.Command = "runInTerminal"
+	response.RequestSeq = requestSeq
+	response.Success = false
+	response.Message = message
+	c.send(response)
+}
+
 func (c *Client) ExpectInvisibleErrorResponse(t *testing.T) *dap.ErrorResponse {
 	t.Helper()
 	er := c.ExpectErrorResponse(t)
@@ -106,18 +244,32 @@ func (c *Client) ExpectInitializeResponseAndCapabilities(t *testing.T) *dap.Init
 	initResp := c.ExpectInitializeResponse(t)
 	wantCapabilities := dap.Capabilities{
 		// the values set by dap.(*Server).onInitializeRequest.
-		SupportsConfigurationDoneRequest: true,
-		SupportsConditionalBreakpoints:   true,
-		SupportsDelayedStackTraceLoading: true,
-		SupportsExceptionInfoRequest:     true,
-		SupportsSetVariable:              true,
-		SupportsFunctionBreakpoints:      true,
-		SupportsInstructionBreakpoints:   true,
-		SupportsEvaluateForHovers:        true,
-		SupportsClipboardContext:         true,
-		SupportsSteppingGranularity:      true,
-		SupportsLogPoints:                true,
-		SupportsDisassembleRequest:       true,
+		SupportsConfigurationDoneRequest:   true,
+		SupportsConditionalBreakpoints:     true,
+		SupportsDelayedStackTraceLoading:   true,
+		SupportsExceptionInfoRequest:       true,
+		SupportsSetVariable:                true,
+		SupportsFunctionBreakpoints:        true,
+		SupportsInstructionBreakpoints:     true,
+		SupportsDataBreakpoints:            true,
+		SupportsEvaluateForHovers:          true,
+		SupportsClipboardContext:           true,
+		SupportsSteppingGranularity:        true,
+		SupportsLogPoints:                  true,
+		SupportsDisassembleRequest:         true,
+		SupportsReadMemoryRequest:          true,
+		SupportsWriteMemoryRequest:         true,
+		SupportsCompletionsRequest:         true,
+		CompletionTriggerCharacters:        []string{"."},
+		SupportsStepInTargetsRequest:       true,
+		SupportsBreakpointLocationsRequest: true,
+		SupportsLoadedSourcesRequest:       true,
+		SupportsModulesRequest:             true,
+		AdditionalModuleColumns:            []dap.ColumnDescriptor{{AttributeName: "buildId", Label: "Build ID"}},
+		SupportsCancelRequest:              true,
+		SupportsSetExpression:              true,
+		SupportsTerminateRequest:           runtime.GOOS != "windows",
+		SupportsRestartRequest:             true,
 	}
 	if !reflect.DeepEqual(initResp.Body, wantCapabilities) {
 		t.Errorf("capabilities in initializeResponse: got %+v, want %v", pretty(initResp.Body), pretty(wantCapabilities))
@@ -225,6 +377,9 @@ func (c *Client) InitializeRequest() {
 		SupportsVariableType:         true,
 		SupportsVariablePaging:       true,
 		SupportsRunInTerminalRequest: true,
+		SupportsMemoryReferences:     true,
+		SupportsMemoryEvent:          true,
+		SupportsInvalidatedEvent:     true,
 		Locale:                       "en-us",
 	}
 	c.send(request)
@@ -359,6 +514,15 @@ func (c *Client) StepInRequest(thread int) {
 	c.send(request)
 }
 
+// StepInTargetRequest sends a 'stepIn' request for one of the targets
+// returned by a 'stepInTargets' request.
+func (c *Client) StepInTargetRequest(thread, targetID int) {
+	request := &dap.StepInRequest{Request: *c.newRequest("stepIn")}
+	request.Arguments.ThreadId = thread
+	request.Arguments.TargetId = targetID
+	c.send(request)
+}
+
 // StepInInstructionRequest sends a 'stepIn' request with granularity 'instruction'.
 func (c *Client) StepInInstructionRequest(thread int) {
 	request := &dap.StepInRequest{Request: *c.newRequest("stepIn")}
@@ -446,6 +610,15 @@ func (c *Client) RestartRequest() {
 	c.send(&dap.RestartRequest{Request: *c.newRequest("restart")})
 }
 
+// RestartRequestWithArgs sends a 'restart' request with the given
+// launch or attach configuration as its arguments.
+func (c *Client) RestartRequestWithArgs(arguments map[string]interface{}) {
+	c.send(&dap.RestartRequest{
+		Request:   *c.newRequest("restart"),
+		Arguments: &dap.RestartArguments{Arguments: arguments},
+	})
+}
+
 // SetFunctionBreakpointsRequest sends a 'setFunctionBreakpoints' request.
 func (c *Client) SetFunctionBreakpointsRequest(breakpoints []dap.FunctionBreakpoint) {
 	c.send(&dap.SetFunctionBreakpointsRequest{
@@ -471,6 +644,15 @@ func (c *Client) StepBackRequest() {
 	c.send(&dap.StepBackRequest{Request: *c.newRequest("stepBack")})
 }
 
+// StepBackRequestWithGranularity sends a 'stepBack' request with the
+// given granularity.
+func (c *Client) StepBackRequestWithGranularity(thread int, granularity dap.SteppingGranularity) {
+	request := &dap.StepBackRequest{Request: *c.newRequest("stepBack")}
+	request.Arguments.ThreadId = thread
+	request.Arguments.Granularity = granularity
+	c.send(request)
+}
+
 // ReverseContinueRequest sends a 'reverseContinue' request.
 func (c *Client) ReverseContinueRequest() {
 	c.send(&dap.ReverseContinueRequest{Request: *c.newRequest("reverseContinue")})
@@ -486,23 +668,36 @@ func (c *Client) SetVariableRequest(variablesRef int, name, value string) {
 }
 
 // RestartFrameRequest sends a 'restartFrame' request.
-func (c *Client) RestartFrameRequest() {
-	c.send(&dap.RestartFrameRequest{Request: *c.newRequest("restartFrame")})
+func (c *Client) RestartFrameRequest(frameID int) {
+	request := &dap.RestartFrameRequest{Request: *c.newRequest("restartFrame")}
+	request.Arguments.FrameId = frameID
+	c.send(request)
 }
 
 // GotoRequest sends a 'goto' request.
-func (c *Client) GotoRequest() {
-	c.send(&dap.GotoRequest{Request: *c.newRequest("goto")})
+func (c *Client) GotoRequest(threadID, targetID int) {
+	request := &dap.GotoRequest{Request: *c.newRequest("goto")}
+	request.Arguments.ThreadId = threadID
+	request.Arguments.TargetId = targetID
+	c.send(request)
 }
 
 // SetExpressionRequest sends a 'setExpression' request.
-func (c *Client) SetExpressionRequest() {
-	c.send(&dap.SetExpressionRequest{Request: *c.newRequest("setExpression")})
+func (c *Client) SetExpressionRequest(expression, value string, frameID int) {
+	request := &dap.SetExpressionRequest{Request: *c.newRequest("setExpression")}
+	request.Arguments.Expression = expression
+	request.Arguments.Value = value
+	request.Arguments.FrameId = frameID
+	c.send(request)
 }
 
-// SourceRequest sends a 'source' request.
-func (c *Client) SourceRequest() {
-	c.send(&dap.SourceRequest{Request: *c.newRequest("source")})
+// SourceRequest sends a 'source' request for the source with the given
+// path or, if it is not zero, sourceReference.
+func (c *Client) SourceRequest(path string, sourceReference int) {
+	request := &dap.SourceRequest{Request: *c.newRequest("source")}
+	request.Arguments.Source = &dap.Source{Path: path, SourceReference: sourceReference}
+	request.Arguments.SourceReference = sourceReference
+	c.send(request)
 }
 
 // TerminateThreadsRequest sends a 'terminateThreads' request.
@@ -520,18 +715,38 @@ func (c *Client) EvaluateRequest(expr string, fid int, context string) {
 }
 
 // StepInTargetsRequest sends a 'stepInTargets' request.
-func (c *Client) StepInTargetsRequest() {
-	c.send(&dap.StepInTargetsRequest{Request: *c.newRequest("stepInTargets")})
+func (c *Client) StepInTargetsRequest(frameID int) {
+	request := &dap.StepInTargetsRequest{Request: *c.newRequest("stepInTargets")}
+	request.Arguments.FrameId = frameID
+	c.send(request)
 }
 
 // GotoTargetsRequest sends a 'gotoTargets' request.
-func (c *Client) GotoTargetsRequest() {
-	c.send(&dap.GotoTargetsRequest{Request: *c.newRequest("gotoTargets")})
+func (c *Client) GotoTargetsRequest(source string, line int) {
+	request := &dap.GotoTargetsRequest{Request: *c.newRequest("gotoTargets")}
+	request.Arguments.Source.Path = source
+	request.Arguments.Line = line
+	c.send(request)
+}
+
+// CheckpointsRequest sends a Delve-specific 'checkpoints' request. The
+// response and events can be read with ExpectCustomMessage.
+func (c *Client) CheckpointsRequest(arguments map[string]interface{}) {
+	c.send(&struct {
+		dap.Request
+		Arguments map[string]interface{} `json:"arguments,omitempty"`
+	}{Request: *c.newRequest("checkpoints"), Arguments: arguments})
 }
 
 // CompletionsRequest sends a 'completions' request.
-func (c *Client) CompletionsRequest() {
-	c.send(&dap.CompletionsRequest{Request: *c.newRequest("completions")})
+func (c *Client) CompletionsRequest(frameID int, text string, column int) {
+	request := &dap.CompletionsRequest{Request: *c.newRequest("completions")}
+	request.Arguments = dap.CompletionsArguments{
+		FrameId: frameID,
+		Text:    text,
+		Column:  column,
+	}
+	c.send(request)
 }
 
 // ExceptionInfoRequest sends a 'exceptionInfo' request.
@@ -547,18 +762,46 @@ func (c *Client) LoadedSourcesRequest() {
 }
 
 // DataBreakpointInfoRequest sends a 'dataBreakpointInfo' request.
-func (c *Client) DataBreakpointInfoRequest() {
-	c.send(&dap.DataBreakpointInfoRequest{Request: *c.newRequest("dataBreakpointInfo")})
+func (c *Client) DataBreakpointInfoRequest(variablesReference int, name string, frameID int) {
+	request := &dap.DataBreakpointInfoRequest{Request: *c.newRequest("dataBreakpointInfo")}
+	request.Arguments.VariablesReference = variablesReference
+	request.Arguments.Name = name
+	request.Arguments.FrameId = frameID
+	c.send(request)
 }
 
 // SetDataBreakpointsRequest sends a 'setDataBreakpoints' request.
-func (c *Client) SetDataBreakpointsRequest() {
-	c.send(&dap.SetDataBreakpointsRequest{Request: *c.newRequest("setDataBreakpoints")})
+func (c *Client) SetDataBreakpointsRequest(breakpoints []dap.DataBreakpoint) {
+	c.send(&dap.SetDataBreakpointsRequest{
+		Request: *c.newRequest("setDataBreakpoints"),
+		Arguments: dap.SetDataBreakpointsArguments{
+			Breakpoints: breakpoints,
+		},
+	})
 }
 
 // ReadMemoryRequest sends a 'readMemory' request.
-func (c *Client) ReadMemoryRequest() {
-	c.send(&dap.ReadMemoryRequest{Request: *c.newRequest("readMemory")})
+func (c *Client) ReadMemoryRequest(memoryReference string, offset, count int) {
+	c.send(&dap.ReadMemoryRequest{
+		Request: *c.newRequest("readMemory"),
+		Arguments: dap.ReadMemoryArguments{
+			MemoryReference: memoryReference,
+			Offset:          offset,
+			Count:           count,
+		},
+	})
+}
+
+// WriteMemoryRequest sends a 'writeMemory' request.
+func (c *Client) WriteMemoryRequest(memoryReference string, offset int, data []byte) {
+	c.send(&dap.WriteMemoryRequest{
+		Request: *c.newRequest("writeMemory"),
+		Arguments: dap.WriteMemoryArguments{
+			MemoryReference: memoryReference,
+			Offset:          offset,
+			Data:            base64.StdEncoding.EncodeToString(data),
+		},
+	})
 }
 
 // DisassembleRequest sends a 'disassemble' request.
@@ -575,14 +818,36 @@ func (c *Client) DisassembleRequest(memoryReference string, instructionOffset, i
 	})
 }
 
-// CancelRequest sends a 'cancel' request.
-func (c *Client) CancelRequest() {
-	c.send(&dap.CancelRequest{Request: *c.newRequest("cancel")})
+// CancelRequest sends a 'cancel' request for the request with sequence
+// number requestID.
+func (c *Client) CancelRequest(requestID int) {
+	request := &dap.CancelRequest{Request: *c.newRequest("cancel")}
+	request.Arguments = &dap.CancelArguments{RequestId: requestID}
+	c.send(request)
+}
+
+// CancelProgressRequest sends a 'cancel' request for the request that
+// reported the progress progressID.
+func (c *Client) CancelProgressRequest(progressID string) {
+	request := &dap.CancelRequest{Request: *c.newRequest("cancel")}
+	request.Arguments = &dap.CancelArguments{ProgressId: progressID}
+	c.send(request)
+}
+
+// LastSeq returns the sequence number of the last request sent.
+func (c *Client) LastSeq() int {
+	return c.seq - 1
 }
 
 // BreakpointLocationsRequest sends a 'breakpointLocations' request.
-func (c *Client) BreakpointLocationsRequest() {
-	c.send(&dap.BreakpointLocationsRequest{Request: *c.newRequest("breakpointLocations")})
+func (c *Client) BreakpointLocationsRequest(source string, line, endLine int) {
+	request := &dap.BreakpointLocationsRequest{Request: *c.newRequest("breakpointLocations")}
+	request.Arguments = &dap.BreakpointLocationsArguments{
+		Source:  dap.Source{Path: source},
+		Line:    line,
+		EndLine: endLine,
+	}
+	c.send(request)
 }
 
 // ModulesRequest sends a 'modules' request.
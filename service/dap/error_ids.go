@@ -11,23 +11,35 @@ const (
 	// Where applicable and for consistency only,
 	// values below are inspired the original vscode-go debug adaptor.
 
-	FailedToLaunch             = 3000
-	FailedToAttach             = 3001
-	FailedToInitialize         = 3002
-	UnableToSetBreakpoints     = 2002
-	UnableToDisplayThreads     = 2003
-	UnableToProduceStackTrace  = 2004
-	UnableToListLocals         = 2005
-	UnableToListArgs           = 2006
-	UnableToListGlobals        = 2007
-	UnableToLookupVariable     = 2008
-	UnableToEvaluateExpression = 2009
-	UnableToHalt               = 2010
-	UnableToGetExceptionInfo   = 2011
-	UnableToSetVariable        = 2012
-	UnableToDisassemble        = 2013
-	UnableToListRegisters      = 2014
-	UnableToRunDlvCommand      = 2015
+	FailedToLaunch                  = 3000
+	FailedToAttach                  = 3001
+	FailedToInitialize              = 3002
+	UnableToSetBreakpoints          = 2002
+	UnableToDisplayThreads          = 2003
+	UnableToProduceStackTrace       = 2004
+	UnableToListLocals              = 2005
+	UnableToListArgs                = 2006
+	UnableToListGlobals             = 2007
+	UnableToLookupVariable          = 2008
+	UnableToEvaluateExpression      = 2009
+	UnableToHalt                    = 2010
+	UnableToGetExceptionInfo        = 2011
+	UnableToSetVariable             = 2012
+	UnableToDisassemble             = 2013
+	UnableToListRegisters           = 2014
+	UnableToRunDlvCommand           = 2015
+	UnableToGoto                    = 2016
+	UnableToRestartFrame            = 2017
+	UnableToManageCheckpoints       = 2018
+	UnableToReadMemory              = 2019
+	UnableToWriteMemory             = 2020
+	UnableToListStepInTargets       = 2021
+	UnableToListBreakpointLocations = 2022
+	UnableToListSources             = 2023
+	UnableToSetExpression           = 2024
+	UnableToTerminate               = 2025
+	UnableToRestart                 = 2026
+	UnableToGetSource               = 2027
 
 	// Add more codes as we support more requests
 
@@ -27,6 +27,9 @@ type fullyQualifiedVariable struct {
 	// startIndex is the index of the first child for an array or slice.
 	// This variable represents a chunk of the array, slice or map.
 	startIndex int
+	// frame is the stack frame the variable was loaded from, nil if it was
+	// not loaded from a specific frame (for example package variables).
+	frame *stackFrame
 }
 
 func newHandlesMap() *handlesMap {
@@ -70,6 +73,14 @@ func (hs *variablesHandlesMap) get(handle int) (*fullyQualifiedVariable, bool) {
 	return v.(*fullyQualifiedVariable), true
 }
 
+// setFrame records the stack frame that the variable with the given handle
+// was loaded from, if the handle exists.
+func (hs *variablesHandlesMap) setFrame(handle int, frame *stackFrame) {
+	if v, ok := hs.get(handle); ok {
+		v.frame = frame
+	}
+}
+
 func (hs *variablesHandlesMap) reset() {
 	hs.m.reset()
 }
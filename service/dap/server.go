@@ -11,9 +11,11 @@ package dap
 import (
 	"bufio"
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"go/ast"
 	"go/constant"
 	"go/parser"
 	"io"
@@ -30,13 +32,18 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
+	"unicode/utf16"
 
+	"github.com/undoio/delve/pkg/dwarf/godwarf"
 	"github.com/undoio/delve/pkg/gobuild"
 	"github.com/undoio/delve/pkg/goversion"
 	"github.com/undoio/delve/pkg/locspec"
 	"github.com/undoio/delve/pkg/logflags"
 	"github.com/undoio/delve/pkg/proc"
+	"github.com/undoio/delve/pkg/proc/debuginfod"
+	"github.com/undoio/delve/pkg/proc/gdbserial"
 
 	"github.com/google/go-dap"
 	"github.com/undoio/delve/service"
@@ -136,9 +143,29 @@ type Session struct {
 	debugger *debugger.Debugger
 	// binaryToRemove is the temp compiled binary to be removed on disconnect (if any).
 	binaryToRemove string
+	// launchConfig is the configuration of the launch request, used to
+	// rebuild the program and redirect its output again when it is
+	// restarted. Nil if the debug session was started by an attach request.
+	launchConfig *LaunchConfig
 	// noDebugProcess is set for the noDebug launch process.
 	noDebugProcess *process
 
+	// outputPipes are the pipes connected to the standard output and error
+	// of the launched program when its output is sent to the client.
+	outputPipes   []*outputPipe
+	outputPipesMu sync.Mutex
+	// terminal is the process started by a runInTerminal request to hold
+	// the terminal used by the launched program, nil if there is none.
+	terminal *os.Process
+	// terminalFile is the open terminal used by the launched program.
+	terminalFile *os.File
+	// runInTerminalResponse receives the response to the pending
+	// runInTerminal request, nil if there is none.
+	runInTerminalResponse chan *dap.RunInTerminalResponse
+	// terminalMu synchronizes access to terminal, terminalFile and
+	// runInTerminalResponse.
+	terminalMu sync.Mutex
+
 	// sendingMu synchronizes writing to conn
 	// to ensure that messages do not get interleaved
 	sendingMu sync.Mutex
@@ -147,7 +174,9 @@ type Session struct {
 	// command that resumes execution, which may not correspond to the actual
 	// running state of the process (e.g. if a command is temporarily interrupted).
 	runningCmd bool
-	runningMu  sync.Mutex
+	// runningDone is closed when runningCmd is cleared.
+	runningDone chan struct{}
+	runningMu   sync.Mutex
 
 	// haltRequested tracks whether a halt of the program has been requested, which may
 	// not correspond to whether a Halt Request has been sent to the target.
@@ -157,6 +186,50 @@ type Session struct {
 	// changeStateMu must be held for a request to protect itself from another goroutine
 	// changing the state of the running process at the same time.
 	changeStateMu sync.Mutex
+
+	// gotoTargets are the targets returned by the last gotoTargets request,
+	// the id of a target is its index plus one.
+	gotoTargets []gotoTarget
+
+	// internalBreakpoints are the breakpoints set by the goto or
+	// restartFrame request being executed, stops on them are reported with
+	// internalBreakpointsReason.
+	internalBreakpoints       []*proc.Breakpoint
+	internalBreakpointsReason string
+
+	// stepInTargets are the targets returned by the last stepInTargets
+	// request, the id of a target is its index plus one.
+	stepInTargets []stepInTarget
+	// stepIntoCallPC is the CALL instruction used by the next
+	// api.StepIntoCall command.
+	stepIntoCallPC uint64
+
+	// loadedImages is the number of images, and loadedSources the set of
+	// source files, of the target the client knows about. They are updated,
+	// sending module and loadedSource events, when execution stops.
+	loadedImages  int
+	loadedSources map[string]bool
+
+	// sourceReferences maps the paths of the sources that the client can
+	// not read to the sourceReference used to retrieve them with a source
+	// request, sourcePaths maps them back.
+	sourceReferences map[string]int
+	sourcePaths      map[int]string
+	// sourceExists caches whether the absolute paths of sources exist on
+	// disk.
+	sourceExists map[string]bool
+	// goEnvValues caches the values of go environment variables.
+	goEnvValues map[string]string
+
+	// cancelMu synchronizes access to cancels, that is also updated by the
+	// goroutine reading requests from the client.
+	cancelMu sync.Mutex
+	// cancels maps the sequence numbers of the cancellable requests that
+	// were read but not handled yet to channels closed by cancel requests.
+	cancels map[int]chan struct{}
+	// cancel is closed when the request being handled is cancelled, it is
+	// nil if the request can not be cancelled.
+	cancel chan struct{}
 }
 
 // Config is all the information needed to start the debugger, handle
@@ -199,6 +272,15 @@ type process struct {
 	exited chan struct{}
 }
 
+// outputPipe is a pipe connected to the standard output or error of the
+// launched program. Everything written to it is sent to the client as
+// output events.
+type outputPipe struct {
+	w *os.File
+	// done is closed when the read end of the pipe reaches EOF.
+	done chan struct{}
+}
+
 // launchAttachArgs captures arguments from launch/attach request that
 // impact handling of subsequent requests.
 // The fields with cfgName tag can be updated through an evaluation request.
@@ -244,6 +326,8 @@ type dapClientCapabilities struct {
 	supportsRunInTerminalRequest bool
 	supportsMemoryReferences     bool
 	supportsProgressReporting    bool
+	supportsMemoryEvent          bool
+	supportsInvalidatedEvent     bool
 }
 
 // DefaultLoadConfig controls how variables are loaded from the target's memory.
@@ -269,6 +353,8 @@ const (
 	// what is presented. A common use case of a call injection is to
 	// stringify complex data conveniently.
 	maxStringLenInCallRetVars = 1 << 10 // 1024
+	// How long to wait for the terminal started by a runInTerminal request.
+	runInTerminalTimeout = 30 * time.Second
 )
 
 var (
@@ -355,6 +441,7 @@ func (s *Session) setLaunchAttachArgs(args LaunchAttachCommonConfig) {
 		s.args.substitutePathClientToServer = clientToServer
 		s.args.substitutePathServerToClient = serverToClient
 	}
+	s.config.Debugger.SubstitutePath = s.args.substitutePathClientToServer
 }
 
 // Stop stops the DAP debugger service, closes the listener and the client
@@ -393,6 +480,8 @@ func (s *Session) Close() {
 	} else if s.noDebugProcess != nil {
 		s.stopNoDebugProcess()
 	}
+	s.closeOutputPipes()
+	s.closeTerminal()
 	// The binary is no longer in use by the debugger. It is safe to remove it.
 	if s.binaryToRemove != "" {
 		gobuild.Remove(s.binaryToRemove)
@@ -490,6 +579,25 @@ func (s *Server) RunWithClient(conn net.Conn) {
 	go s.runSession(conn)
 }
 
+// readProtocolMessage is like dap.ReadProtocolMessage but it also decodes
+// the Delve-specific requests.
+func readProtocolMessage(r *bufio.Reader) (dap.Message, error) {
+	content, err := dap.ReadBaseMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	var request dap.Request
+	if err := json.Unmarshal(content, &request); err == nil && request.Type == "request" {
+		switch request.Command {
+		case "checkpoints":
+			checkpoints := &CheckpointsRequest{}
+			err := json.Unmarshal(content, checkpoints)
+			return checkpoints, err
+		}
+	}
+	return dap.DecodeProtocolMessage(content)
+}
+
 func (s *Session) address() string {
 	if s.config.Listener != nil {
 		return s.config.Listener.Addr().String()
@@ -500,6 +608,53 @@ func (s *Session) address() string {
 	return ""
 }
 
+// maxQueuedRequests is the number of requests read from the client that
+// can wait to be handled before reading stops.
+const maxQueuedRequests = 100
+
+// protocolMessage is a message, or an error, read from the client.
+type protocolMessage struct {
+	msg dap.Message
+	err error
+}
+
+// readRequests reads and decodes requests from the client and queues them
+// to be handled, until it encounters an error other than an unsupported
+// request or done is closed.
+// Cancel requests are handled as soon as they are read, so that they can
+// interrupt the request being handled.
+func (s *Session) readRequests(reader *bufio.Reader, messages chan<- protocolMessage, done <-chan struct{}) {
+	for {
+		request, err := readProtocolMessage(reader)
+		if err == nil {
+			if request, ok := request.(*dap.CancelRequest); ok {
+				s.onCancelRequest(request)
+				continue
+			}
+			// The launch request waiting for this response is blocking
+			// the handling of requests.
+			if response, ok := request.(*dap.RunInTerminalResponse); ok {
+				s.onRunInTerminalResponse(response)
+				continue
+			}
+			// Failed responses are decoded as error responses.
+			if response, ok := request.(*dap.ErrorResponse); ok && response.Command == "runInTerminal" {
+				s.onRunInTerminalResponse(&dap.RunInTerminalResponse{Response: response.Response})
+				continue
+			}
+			s.addCancellable(request)
+		}
+		select {
+		case messages <- protocolMessage{request, err}:
+		case <-done:
+			return
+		}
+		if _, ok := err.(*dap.DecodeProtocolMessageFieldError); err != nil && !ok {
+			return
+		}
+	}
+}
+
 // ServeDAPCodec reads and decodes requests from the client
 // until it encounters an error or EOF, when it sends
 // a disconnect signal and returns.
@@ -507,9 +662,13 @@ func (s *Session) ServeDAPCodec() {
 	// Close conn, but not the debugger in case we are in AcceptMulti mode.
 	// If not, debugger will be shut down in Stop().
 	defer s.conn.Close()
-	reader := bufio.NewReader(s.conn)
+	messages := make(chan protocolMessage, maxQueuedRequests)
+	done := make(chan struct{})
+	defer close(done)
+	go s.readRequests(bufio.NewReader(s.conn), messages, done)
 	for {
-		request, err := dap.ReadProtocolMessage(reader)
+		message := <-messages
+		request, err := message.msg, message.err
 		// Handle dap.DecodeProtocolMessageFieldError errors gracefully by responding with an ErrorResponse.
 		// For example:
 		// -- "Request command 'foo' is not supported" means we
@@ -566,10 +725,14 @@ func (s *Session) handleRequest(request dap.Message) {
 		return
 	}
 
+	defer s.removeCancellable(request.GetSeq())
+
 	if s.isNoDebug() {
 		switch request := request.(type) {
 		case *dap.DisconnectRequest:
 			s.onDisconnectRequest(request)
+		case *dap.TerminateRequest:
+			s.onTerminateRequest(request, nil)
 		case *dap.RestartRequest:
 			s.sendUnsupportedErrorResponse(request.Request)
 		default:
@@ -596,12 +759,6 @@ func (s *Session) handleRequest(request dap.Message) {
 	case *dap.PauseRequest: // Required
 		s.onPauseRequest(request)
 		return
-	case *dap.TerminateRequest: // Optional (capability ‘supportsTerminateRequest‘)
-		/*TODO*/ s.onTerminateRequest(request) // not yet implemented
-		return
-	case *dap.RestartRequest: // Optional (capability ‘supportsRestartRequest’)
-		/*TODO*/ s.onRestartRequest(request) // not yet implemented
-		return
 	}
 
 	// Most requests cannot be processed while the debuggee is running.
@@ -655,6 +812,27 @@ func (s *Session) handleRequest(request dap.Message) {
 				return
 			}
 			s.onSetFunctionBreakpointsRequest(request)
+		case *dap.TerminateRequest: // Optional (capability ‘supportsTerminateRequest‘)
+			// The running program handles the signal right away.
+			s.onTerminateRequest(request, nil)
+		case *dap.RestartRequest: // Optional (capability ‘supportsRestartRequest’)
+			// Pause the program like a pause request, the restart can only
+			// start once the command that was running is done.
+			s.changeStateMu.Lock()
+			s.setHaltRequested(true)
+			_, err := s.halt()
+			s.changeStateMu.Unlock()
+			if err != nil {
+				s.sendErrorResponse(request.Request, UnableToRestart, "Unable to restart", err.Error())
+				return
+			}
+			s.waitForRunningCmd()
+			resumeRequestLoop := make(chan struct{})
+			go func() {
+				defer s.recoverPanic(request)
+				s.onRestartRequest(request, resumeRequestLoop)
+			}()
+			<-resumeRequestLoop
 		default:
 			r := request.(dap.RequestMessage).GetRequest()
 			s.sendErrorResponse(*r, DebuggeeIsRunning, fmt.Sprintf("Unable to process `%s`", r.Command), "debuggee is running")
@@ -669,6 +847,18 @@ func (s *Session) handleRequest(request dap.Message) {
 	// check above, there should be no more than one pending asynchronous
 	// request at a time.
 
+	if cancel := s.cancelChannel(request.GetSeq()); cancel != nil {
+		r := request.(dap.RequestMessage).GetRequest()
+		s.cancel = cancel
+		defer func() { s.cancel = nil }()
+		if s.isCancelled() {
+			s.sendCancelledResponse(*r)
+			return
+		}
+		endProgress := s.startProgress(*r)
+		defer endProgress()
+	}
+
 	// Non-blocking request handlers will signal when they are ready
 	// setting up for async execution, so more requests can be processed.
 	resumeRequestLoop := make(chan struct{})
@@ -717,6 +907,30 @@ func (s *Session) handleRequest(request dap.Message) {
 			s.onReverseContinueRequest(request, resumeRequestLoop)
 		}()
 		<-resumeRequestLoop
+	case *dap.GotoRequest: // Optional (capability ‘supportsGotoTargetsRequest’)
+		go func() {
+			defer s.recoverPanic(request)
+			s.onGotoRequest(request, resumeRequestLoop)
+		}()
+		<-resumeRequestLoop
+	case *dap.RestartFrameRequest: // Optional (capability ’supportsRestartFrame’)
+		go func() {
+			defer s.recoverPanic(request)
+			s.onRestartFrameRequest(request, resumeRequestLoop)
+		}()
+		<-resumeRequestLoop
+	case *dap.TerminateRequest: // Optional (capability ‘supportsTerminateRequest‘)
+		go func() {
+			defer s.recoverPanic(request)
+			s.onTerminateRequest(request, resumeRequestLoop)
+		}()
+		<-resumeRequestLoop
+	case *dap.RestartRequest: // Optional (capability ‘supportsRestartRequest’)
+		go func() {
+			defer s.recoverPanic(request)
+			s.onRestartRequest(request, resumeRequestLoop)
+		}()
+		<-resumeRequestLoop
 	//--- Synchronous requests ---
 	case *dap.SetBreakpointsRequest: // Required
 		s.onSetBreakpointsRequest(request)
@@ -726,6 +940,10 @@ func (s *Session) handleRequest(request dap.Message) {
 		s.onSetInstructionBreakpointsRequest(request)
 	case *dap.SetExceptionBreakpointsRequest: // Optional (capability ‘exceptionBreakpointFilters’)
 		s.onSetExceptionBreakpointsRequest(request)
+	case *dap.DataBreakpointInfoRequest: // Optional (capability ‘supportsDataBreakpoints’)
+		s.onDataBreakpointInfoRequest(request)
+	case *dap.SetDataBreakpointsRequest: // Optional (capability ‘supportsDataBreakpoints’)
+		s.onSetDataBreakpointsRequest(request)
 	case *dap.ThreadsRequest: // Required
 		s.onThreadsRequest(request)
 	case *dap.StackTraceRequest: // Required
@@ -738,41 +956,34 @@ func (s *Session) handleRequest(request dap.Message) {
 		s.onEvaluateRequest(request)
 	case *dap.SetVariableRequest: // Optional (capability ‘supportsSetVariable’)
 		s.onSetVariableRequest(request)
+	case *dap.SetExpressionRequest: // Optional (capability ‘supportsSetExpression’)
+		s.onSetExpressionRequest(request)
 	case *dap.ExceptionInfoRequest: // Optional (capability ‘supportsExceptionInfoRequest’)
 		s.onExceptionInfoRequest(request)
 	case *dap.DisassembleRequest: // Optional (capability ‘supportsDisassembleRequest’)
 		s.onDisassembleRequest(request)
-	//--- Requests that we may want to support ---
-	case *dap.SourceRequest: // Required
-		/*TODO*/ s.sendUnsupportedErrorResponse(request.Request) // https://github.com/go-delve/delve/issues/2851
-	case *dap.SetExpressionRequest: // Optional (capability ‘supportsSetExpression’)
-		/*TODO*/ s.onSetExpressionRequest(request) // Not yet implemented
-	case *dap.LoadedSourcesRequest: // Optional (capability ‘supportsLoadedSourcesRequest’)
-		/*TODO*/ s.onLoadedSourcesRequest(request) // Not yet implemented
 	case *dap.ReadMemoryRequest: // Optional (capability ‘supportsReadMemoryRequest‘)
-		/*TODO*/ s.onReadMemoryRequest(request) // Not yet implemented
-	case *dap.CancelRequest: // Optional (capability ‘supportsCancelRequest’)
-		/*TODO*/ s.onCancelRequest(request) // Not yet implemented (does this make sense?)
-	case *dap.ModulesRequest: // Optional (capability ‘supportsModulesRequest’)
-		/*TODO*/ s.sendUnsupportedErrorResponse(request.Request) // Not yet implemented (does this make sense?)
-	//--- Requests that we do not plan to support ---
-	case *dap.RestartFrameRequest: // Optional (capability ’supportsRestartFrame’)
-		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.GotoRequest: // Optional (capability ‘supportsGotoTargetsRequest’)
-		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.TerminateThreadsRequest: // Optional (capability ‘supportsTerminateThreadsRequest’)
-		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.StepInTargetsRequest: // Optional (capability ‘supportsStepInTargetsRequest’)
-		s.sendUnsupportedErrorResponse(request.Request)
+		s.onReadMemoryRequest(request)
+	case *dap.WriteMemoryRequest: // Optional (capability ‘supportsWriteMemoryRequest‘)
+		s.onWriteMemoryRequest(request)
 	case *dap.GotoTargetsRequest: // Optional (capability ‘supportsGotoTargetsRequest’)
-		s.sendUnsupportedErrorResponse(request.Request)
+		s.onGotoTargetsRequest(request)
 	case *dap.CompletionsRequest: // Optional (capability ‘supportsCompletionsRequest’)
-		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.DataBreakpointInfoRequest: // Optional (capability ‘supportsDataBreakpoints’)
-		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.SetDataBreakpointsRequest: // Optional (capability ‘supportsDataBreakpoints’)
-		s.sendUnsupportedErrorResponse(request.Request)
+		s.onCompletionsRequest(request)
+	case *dap.StepInTargetsRequest: // Optional (capability ‘supportsStepInTargetsRequest’)
+		s.onStepInTargetsRequest(request)
 	case *dap.BreakpointLocationsRequest: // Optional (capability ‘supportsBreakpointLocationsRequest’)
+		s.onBreakpointLocationsRequest(request)
+	case *dap.LoadedSourcesRequest: // Optional (capability ‘supportsLoadedSourcesRequest’)
+		s.onLoadedSourcesRequest(request)
+	case *dap.ModulesRequest: // Optional (capability ‘supportsModulesRequest’)
+		s.onModulesRequest(request)
+	case *dap.SourceRequest: // Required
+		s.onSourceRequest(request)
+	case *CheckpointsRequest: // Delve-specific
+		s.onCheckpointsRequest(request)
+	//--- Requests that we do not plan to support ---
+	case *dap.TerminateThreadsRequest: // Optional (capability ‘supportsTerminateThreadsRequest’)
 		s.sendUnsupportedErrorResponse(request.Request)
 	default:
 		// This is a DAP message that go-dap has a struct for, so
@@ -831,6 +1042,7 @@ func (s *Session) onInitializeRequest(request *dap.InitializeRequest) {
 	response.Body.SupportsDelayedStackTraceLoading = true
 	response.Body.SupportsFunctionBreakpoints = true
 	response.Body.SupportsInstructionBreakpoints = true
+	response.Body.SupportsDataBreakpoints = true
 	response.Body.SupportsExceptionInfoRequest = true
 	response.Body.SupportsSetVariable = true
 	response.Body.SupportsEvaluateForHovers = true
@@ -838,21 +1050,47 @@ func (s *Session) onInitializeRequest(request *dap.InitializeRequest) {
 	response.Body.SupportsSteppingGranularity = true
 	response.Body.SupportsLogPoints = true
 	response.Body.SupportsDisassembleRequest = true
+	response.Body.SupportsReadMemoryRequest = true
+	response.Body.SupportsWriteMemoryRequest = true
+	response.Body.SupportsCompletionsRequest = true
+	response.Body.CompletionTriggerCharacters = []string{"."}
+	response.Body.SupportsStepInTargetsRequest = true
+	response.Body.SupportsBreakpointLocationsRequest = true
+	response.Body.SupportsLoadedSourcesRequest = true
+	response.Body.SupportsModulesRequest = true
+	response.Body.AdditionalModuleColumns = moduleColumns
+	response.Body.SupportsCancelRequest = true
+	response.Body.SupportsSetExpression = true
+	// Terminating gracefully relies on SIGTERM, which Windows doesn't have.
+	response.Body.SupportsTerminateRequest = runtime.GOOS != "windows"
+	response.Body.SupportsRestartRequest = true
 	// To be enabled by CapabilitiesEvent based on launch configuration
 	response.Body.SupportsStepBack = false
+	response.Body.SupportsGotoTargetsRequest = false
+	response.Body.SupportsRestartFrame = false
 	response.Body.SupportTerminateDebuggee = false
-	// TODO(polina): support these requests in addition to vscode-go feature parity
-	response.Body.SupportsTerminateRequest = false
-	response.Body.SupportsRestartRequest = false
-	response.Body.SupportsSetExpression = false
-	response.Body.SupportsLoadedSourcesRequest = false
-	response.Body.SupportsReadMemoryRequest = false
-	response.Body.SupportsCancelRequest = false
 	s.send(response)
 }
 
+// sendReverseCapabilities enables the StepBack, goto and restartFrame
+// controls on the backends that replay a recording.
+func (s *Session) sendReverseCapabilities() {
+	switch s.config.Debugger.Backend {
+	case "rr", "undo":
+	default:
+		return
+	}
+	s.send(&dap.CapabilitiesEvent{Event: *newEvent("capabilities"), Body: dap.CapabilitiesEventBody{Capabilities: dap.Capabilities{
+		SupportsStepBack:           true,
+		SupportsGotoTargetsRequest: true,
+		SupportsRestartFrame:       true,
+	}}})
+}
+
 func (s *Session) setClientCapabilities(args dap.InitializeRequestArguments) {
 	s.clientCapabilities.supportsMemoryReferences = args.SupportsMemoryReferences
+	s.clientCapabilities.supportsMemoryEvent = args.SupportsMemoryEvent
+	s.clientCapabilities.supportsInvalidatedEvent = args.SupportsInvalidatedEvent
 	s.clientCapabilities.supportsProgressReporting = args.SupportsProgressReporting
 	s.clientCapabilities.supportsRunInTerminalRequest = args.SupportsRunInTerminalRequest
 	s.clientCapabilities.supportsVariablePaging = args.SupportsVariablePaging
@@ -913,6 +1151,12 @@ func (s *Session) onLaunchRequest(request *dap.LaunchRequest) {
 		return
 	}
 
+	if !isValidConsole(args.Console) {
+		s.sendShowUserErrorResponse(request.Request, FailedToLaunch, "Failed to launch",
+			fmt.Sprintf("invalid debug configuration - unsupported 'console' attribute %q", args.Console))
+		return
+	}
+
 	if args.Program == "" && args.Mode != "replay" { // Only fail on modes requiring a program
 		s.sendShowUserErrorResponse(request.Request, FailedToLaunch, "Failed to launch",
 			"The program attribute is missing in debug configuration.")
@@ -931,9 +1175,13 @@ func (s *Session) onLaunchRequest(request *dap.LaunchRequest) {
 			return
 		}
 
-		// Assign the rr trace directory path to debugger configuration
+		// Assign the rr trace directory path, or LiveRecorder recording, to
+		// debugger configuration
 		s.config.Debugger.CoreFile = args.TraceDirPath
 		args.Backend = "rr"
+		if isUndo, _ := gdbserial.UndoIsRecording(args.TraceDirPath); isUndo {
+			args.Backend = "undo"
+		}
 	}
 	if args.Mode == "core" {
 		// Validate core dump path
@@ -967,27 +1215,11 @@ func (s *Session) onLaunchRequest(request *dap.LaunchRequest) {
 		}
 		debugbinary = args.Output
 
-		var cmd string
-		var out []byte
-		var err error
-		switch args.Mode {
-		case "debug":
-			cmd, out, err = gobuild.GoBuildCombinedOutput(args.Output, []string{args.Program}, args.BuildFlags)
-		case "test":
-			cmd, out, err = gobuild.GoTestBuildCombinedOutput(args.Output, []string{args.Program}, args.BuildFlags)
-		}
 		args.DlvCwd, _ = filepath.Abs(args.DlvCwd)
-		s.config.log.Debugf("building from %q: [%s]", args.DlvCwd, cmd)
-		if err != nil {
+		if err := s.build(&args); err != nil {
 			if deleteOnError {
 				gobuild.Remove(args.Output)
 			}
-			s.send(&dap.OutputEvent{
-				Event: *newEvent("output"),
-				Body: dap.OutputEventBody{
-					Output:   fmt.Sprintf("Build Error: %s\n%s (%s)\n", cmd, strings.TrimSpace(string(out)), err.Error()),
-					Category: "stderr",
-				}})
 			// Users are used to checking the Debug Console for build errors.
 			// No need to bother them with a visible pop-up.
 			s.sendErrorResponse(request.Request, FailedToLaunch, "Failed to launch",
@@ -1020,6 +1252,17 @@ func (s *Session) onLaunchRequest(request *dap.LaunchRequest) {
 	argsToLog.Cwd, _ = filepath.Abs(args.Cwd)
 	s.config.log.Debugf("launching binary '%s' with config: %s", debugbinary, prettyPrint(argsToLog))
 
+	switch args.Mode {
+	case "debug", "test", "exec":
+		if err := s.redirectStdio(args.Console, argsToLog.Cwd); err != nil {
+			if s.binaryToRemove != "" {
+				gobuild.Remove(s.binaryToRemove)
+			}
+			s.sendShowUserErrorResponse(request.Request, FailedToLaunch, "Failed to launch", err.Error())
+			return
+		}
+	}
+
 	if args.NoDebug {
 		s.mu.Lock()
 		cmd, err := s.newNoDebugProcess(debugbinary, args.Args, s.config.Debugger.WorkingDir)
@@ -1038,6 +1281,7 @@ func (s *Session) onLaunchRequest(request *dap.LaunchRequest) {
 				s.config.log.Debugf("program exited with error: %v", err)
 			}
 			close(s.noDebugProcess.exited)
+			s.closeOutputPipes()
 			s.logToConsole(proc.ErrProcessExited{Pid: cmd.ProcessState.Pid(), Status: cmd.ProcessState.ExitCode()}.Error())
 			s.send(&dap.TerminatedEvent{Event: *newEvent("terminated")})
 		}()
@@ -1056,10 +1300,9 @@ func (s *Session) onLaunchRequest(request *dap.LaunchRequest) {
 		s.sendShowUserErrorResponse(request.Request, FailedToLaunch, "Failed to launch", err.Error())
 		return
 	}
-	// Enable StepBack controls on supported backends
-	if s.config.Debugger.Backend == "rr" {
-		s.send(&dap.CapabilitiesEvent{Event: *newEvent("capabilities"), Body: dap.CapabilitiesEventBody{Capabilities: dap.Capabilities{SupportsStepBack: true}}})
-	}
+	s.launchConfig = &args
+	s.sendReverseCapabilities()
+	s.updateLoaded(false)
 
 	// Notify the client that the debugger is ready to start accepting
 	// configuration requests for setting breakpoints, etc. The client
@@ -1068,6 +1311,30 @@ func (s *Session) onLaunchRequest(request *dap.LaunchRequest) {
 	s.send(&dap.LaunchResponse{Response: *newResponse(request.Request)})
 }
 
+// build builds the program of a launch configuration in "debug" or "test"
+// mode. Build errors are sent to the client as output events.
+func (s *Session) build(args *LaunchConfig) error {
+	var cmd string
+	var out []byte
+	var err error
+	switch args.Mode {
+	case "debug":
+		cmd, out, err = gobuild.GoBuildCombinedOutput(args.Output, []string{args.Program}, args.BuildFlags)
+	case "test":
+		cmd, out, err = gobuild.GoTestBuildCombinedOutput(args.Output, []string{args.Program}, args.BuildFlags)
+	}
+	s.config.log.Debugf("building from %q: [%s]", args.DlvCwd, cmd)
+	if err != nil {
+		s.send(&dap.OutputEvent{
+			Event: *newEvent("output"),
+			Body: dap.OutputEventBody{
+				Output:   fmt.Sprintf("Build Error: %s\n%s (%s)\n", cmd, strings.TrimSpace(string(out)), err.Error()),
+				Category: "stderr",
+			}})
+	}
+	return err
+}
+
 func (s *Session) getPackageDir(pkg string) string {
 	cmd := exec.Command("go", "list", "-f", "{{.Dir}}", pkg)
 	out, err := cmd.Output()
@@ -1086,6 +1353,17 @@ func (s *Session) newNoDebugProcess(program string, targetArgs []string, wd stri
 	}
 	cmd := exec.Command(program, targetArgs...)
 	cmd.Stdout, cmd.Stderr, cmd.Stdin, cmd.Dir = os.Stdout, os.Stderr, os.Stdin, wd
+	if f := s.config.Debugger.Stdout.File; f != nil {
+		cmd.Stdout = f
+	}
+	if f := s.config.Debugger.Stderr.File; f != nil {
+		cmd.Stderr = f
+	}
+	s.terminalMu.Lock()
+	if s.terminalFile != nil {
+		cmd.Stdin = s.terminalFile
+	}
+	s.terminalMu.Unlock()
 	if err := cmd.Start(); err != nil {
 		return nil, err
 	}
@@ -1121,6 +1399,257 @@ func (s *Session) stopNoDebugProcess() {
 	}
 }
 
+// redirectStdio configures the standard input, output and error of the
+// program that is about to be launched according to the console launch
+// attribute. With "internalConsole" the output of the program is sent to
+// the client as output events, otherwise the program uses a terminal
+// started by the client with a runInTerminal request.
+func (s *Session) redirectStdio(console, cwd string) error {
+	if console == "internalConsole" {
+		stdout, err := s.newOutputPipe("stdout")
+		if err != nil {
+			return err
+		}
+		stderr, err := s.newOutputPipe("stderr")
+		if err != nil {
+			return err
+		}
+		s.config.Debugger.Stdout = proc.OutputRedirect{File: stdout}
+		s.config.Debugger.Stderr = proc.OutputRedirect{File: stderr}
+		return nil
+	}
+
+	if !s.clientCapabilities.supportsRunInTerminalRequest {
+		return fmt.Errorf("console %q requires a client that supports the runInTerminal request", console)
+	}
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("console %q is not supported on %s", console, runtime.GOOS)
+	}
+	if !s.isLocalClient() {
+		// The program could not use a terminal started on another machine.
+		return fmt.Errorf("console %q requires the client to run on the same machine as the debugger", console)
+	}
+	tty, err := s.runInTerminal(console, cwd)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(tty, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	s.terminalMu.Lock()
+	s.terminalFile = f
+	s.terminalMu.Unlock()
+	s.config.Debugger.Stdin = tty
+	s.config.Debugger.Stdout = proc.OutputRedirect{File: f}
+	s.config.Debugger.Stderr = proc.OutputRedirect{File: f}
+	return nil
+}
+
+// newOutputPipe creates a pipe and starts a goroutine that sends everything
+// written to it to the client as output events of the given category.
+// Returns the write end of the pipe, which is closed by closeOutputPipes.
+func (s *Session) newOutputPipe(category string) (*os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	pipe := &outputPipe{w: w, done: make(chan struct{})}
+	s.outputPipesMu.Lock()
+	s.outputPipes = append(s.outputPipes, pipe)
+	s.outputPipesMu.Unlock()
+
+	go func() {
+		defer close(pipe.done)
+		defer r.Close()
+		buf := make([]byte, 4096)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				s.send(&dap.OutputEvent{
+					Event: *newEvent("output"),
+					Body: dap.OutputEventBody{
+						Output:   string(buf[:n]),
+						Category: category,
+					}})
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return w, nil
+}
+
+// closeOutputPipes closes the write ends of the output pipes and waits for
+// the output written to them before the program exited to be sent to the
+// client. Called once the program has exited, so that its output is
+// reported before the terminated event.
+func (s *Session) closeOutputPipes() {
+	s.closePipes(s.takeOutputPipes())
+}
+
+// takeOutputPipes returns the output pipes and forgets about them, so
+// that they are not closed by closeOutputPipes.
+func (s *Session) takeOutputPipes() []*outputPipe {
+	s.outputPipesMu.Lock()
+	defer s.outputPipesMu.Unlock()
+	pipes := s.outputPipes
+	s.outputPipes = nil
+	return pipes
+}
+
+// closePipes closes the write ends of pipes and waits for the output
+// written to them to be sent to the client.
+func (s *Session) closePipes(pipes []*outputPipe) {
+	for _, pipe := range pipes {
+		_ = pipe.w.Close()
+	}
+	timeout := time.After(time.Second)
+	for _, pipe := range pipes {
+		select {
+		case <-pipe.done:
+		case <-timeout:
+			// Some other process inherited the pipe and is still writing to it.
+			s.config.log.Debug("timed out waiting for the output of the program")
+			return
+		}
+	}
+}
+
+// terminalCommand is run in the terminal started by a runInTerminal
+// request. It holds the terminal open for the program until the debugger,
+// whose pid is passed as argument, exits.
+const terminalCommand = `while kill -0 "$1" 2>/dev/null; do sleep 1; done`
+
+// terminalTTY returns the path of the controlling terminal of a process.
+// This is a var for testing.
+var terminalTTY = func(pid int) (string, error) {
+	out, err := exec.Command("ps", "-o", "tty=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	tty := strings.TrimSpace(string(out))
+	if tty == "" || strings.HasPrefix(tty, "?") {
+		return "", fmt.Errorf("process %d has no controlling terminal", pid)
+	}
+	if !strings.HasPrefix(tty, "/") {
+		tty = "/dev/" + tty
+	}
+	return tty, nil
+}
+
+// isLocalClient reports whether the client is connected from the machine
+// the debugger runs on.
+func (s *Session) isLocalClient() bool {
+	conn, ok := s.conn.ReadWriteCloser.(net.Conn)
+	if !ok {
+		return true
+	}
+	switch addr := conn.RemoteAddr().(type) {
+	case *net.UnixAddr:
+		return true
+	case *net.TCPAddr:
+		return addr.IP.IsLoopback()
+	}
+	return false
+}
+
+// runInTerminal asks the client to start a terminal, of the kind specified
+// by console, and returns the path of its TTY, found from the process
+// reported in the response.
+// Called from onLaunchRequest while the handling of requests is blocked,
+// the response is passed on by onRunInTerminalResponse.
+func (s *Session) runInTerminal(console, cwd string) (string, error) {
+	responses := make(chan *dap.RunInTerminalResponse, 1)
+	s.terminalMu.Lock()
+	s.runInTerminalResponse = responses
+	s.terminalMu.Unlock()
+	defer func() {
+		s.terminalMu.Lock()
+		s.runInTerminalResponse = nil
+		s.terminalMu.Unlock()
+	}()
+
+	kind := "integrated"
+	if console == "externalTerminal" {
+		kind = "external"
+	}
+	s.send(&dap.RunInTerminalRequest{
+		Request: dap.Request{
+			ProtocolMessage: dap.ProtocolMessage{Seq: 0, Type: "request"},
+			Command:         "runInTerminal",
+		},
+		Arguments: dap.RunInTerminalRequestArguments{
+			Kind:  kind,
+			Title: "Go Debug Terminal",
+			Cwd:   cwd,
+			Args:  []string{"/bin/sh", "-c", terminalCommand, "dlv-terminal", strconv.Itoa(os.Getpid())},
+		},
+	})
+
+	var response *dap.RunInTerminalResponse
+	select {
+	case response = <-responses:
+	case <-time.After(runInTerminalTimeout):
+		return "", errors.New("timed out waiting for the terminal to start")
+	case <-s.config.StopTriggered:
+		return "", errors.New("debug session stopped while waiting for the terminal to start")
+	}
+	if !response.Success {
+		return "", fmt.Errorf("could not start the terminal: %s", response.Message)
+	}
+	// Clients that only report the shell of the terminal are fine to find
+	// its TTY, but the shell is not killed with the terminal process: that
+	// would close the terminal and hide the output of the program.
+	pid := response.Body.ProcessId
+	if pid == 0 {
+		pid = response.Body.ShellProcessId
+	}
+	if pid == 0 {
+		return "", errors.New("the client did not report the process started in the terminal")
+	}
+	tty, err := terminalTTY(pid)
+	if err != nil {
+		return "", fmt.Errorf("could not determine the TTY of the terminal: %v", err)
+	}
+	if response.Body.ProcessId != 0 {
+		s.terminalMu.Lock()
+		s.terminal, _ = os.FindProcess(response.Body.ProcessId)
+		s.terminalMu.Unlock()
+	}
+	return tty, nil
+}
+
+// onRunInTerminalResponse passes the response to a runInTerminal request
+// to runInTerminal.
+func (s *Session) onRunInTerminalResponse(response *dap.RunInTerminalResponse) {
+	s.terminalMu.Lock()
+	defer s.terminalMu.Unlock()
+	if s.runInTerminalResponse == nil {
+		s.config.log.Debug("unexpected runInTerminal response")
+		return
+	}
+	select {
+	case s.runInTerminalResponse <- response:
+	default:
+	}
+}
+
+// closeTerminal closes the terminal started by runInTerminal, if any.
+func (s *Session) closeTerminal() {
+	s.terminalMu.Lock()
+	defer s.terminalMu.Unlock()
+	if s.terminalFile != nil {
+		_ = s.terminalFile.Close()
+		s.terminalFile = nil
+	}
+	if s.terminal != nil {
+		_ = s.terminal.Kill()
+		s.terminal = nil
+	}
+}
+
 // onDisconnectRequest handles the DisconnectRequest. Per the DAP spec,
 // it disconnects the debuggee and signals that the debug adaptor
 // (in our case this TCP server) can be terminated.
@@ -1256,6 +1785,29 @@ func (s *Session) isNoDebug() bool {
 	return s.noDebugProcess != nil
 }
 
+// onBreakpointLocationsRequest handles 'breakpointLocations' requests.
+// This is an optional request enabled by capability ‘supportsBreakpointLocationsRequest’.
+// The locations are the lines in the range that have at least one
+// statement in the line table, columns are not reported.
+func (s *Session) onBreakpointLocationsRequest(request *dap.BreakpointLocationsRequest) {
+	args := request.Arguments
+	if args == nil || args.Source.Path == "" {
+		s.sendErrorResponse(request.Request, UnableToListBreakpointLocations, "Unable to list breakpoint locations", "empty file path")
+		return
+	}
+	endLine := args.EndLine
+	if endLine < args.Line {
+		endLine = args.Line
+	}
+
+	response := &dap.BreakpointLocationsResponse{Response: *newResponse(request.Request)}
+	response.Body.Breakpoints = []dap.BreakpointLocation{}
+	for _, line := range s.debugger.StatementLines(s.toServerPath(args.Source.Path), args.Line, endLine) {
+		response.Body.Breakpoints = append(response.Body.Breakpoints, dap.BreakpointLocation{Line: line})
+	}
+	s.send(response)
+}
+
 func (s *Session) onSetBreakpointsRequest(request *dap.SetBreakpointsRequest) {
 	if request.Arguments.Source.Path == "" {
 		s.sendErrorResponse(request.Request, UnableToSetBreakpoints, "Unable to set or clear breakpoints", "empty file path")
@@ -1302,6 +1854,16 @@ type bpLocation struct {
 	line  int
 	addr  uint64
 	addrs []uint64
+	// watch is set for data breakpoints, which are created as watchpoints
+	// instead of breakpoints.
+	watch *bpWatch
+}
+
+type bpWatch struct {
+	goid  int64
+	frame int
+	expr  string
+	wtype api.WatchType
 }
 
 // setBreakpoints is a helper function for setting source, function and instruction
@@ -1378,8 +1940,12 @@ func (s *Session) setBreakpoints(prefix string, totalBps int, metadataFunc func(
 				}
 				err = setLogMessage(bp, want.logMessage)
 				if err == nil {
-					// Create new breakpoints.
-					got, err = s.debugger.CreateBreakpoint(bp, "", nil, false)
+					if wantLoc.watch != nil {
+						got, err = s.createWatchpoint(wantLoc.watch, bp)
+					} else {
+						// Create new breakpoints.
+						got, err = s.debugger.CreateBreakpoint(bp, "", nil, false)
+					}
 				}
 			}
 		}
@@ -1389,6 +1955,23 @@ func (s *Session) setBreakpoints(prefix string, totalBps int, metadataFunc func(
 	return breakpoints
 }
 
+// createWatchpoint creates the watchpoint described by watch and gives it
+// the name and conditions of bp.
+func (s *Session) createWatchpoint(watch *bpWatch, bp *api.Breakpoint) (*api.Breakpoint, error) {
+	got, err := s.debugger.CreateWatchpoint(watch.goid, watch.frame, 0, watch.expr, watch.wtype)
+	if err != nil {
+		return nil, err
+	}
+	got.Name = bp.Name
+	got.Cond = bp.Cond
+	got.HitCond = bp.HitCond
+	if err := s.debugger.AmendBreakpoint(got); err != nil {
+		s.debugger.ClearBreakpoint(got)
+		return nil, err
+	}
+	return got, nil
+}
+
 func setLogMessage(bp *api.Breakpoint, msg string) error {
 	tracepoint, userdata, err := parseLogPoint(msg)
 	if err != nil {
@@ -1406,8 +1989,12 @@ func (s *Session) updateBreakpointsResponse(breakpoints []dap.Breakpoint, i int,
 	if err != nil {
 		breakpoints[i].Message = err.Error()
 	} else {
-		path := s.toClientPath(got.File)
 		breakpoints[i].Id = got.ID
+		if got.WatchExpr != "" {
+			// Watchpoints do not have a source location.
+			return
+		}
+		path := s.toClientPath(got.File)
 		breakpoints[i].Line = got.Line
 		breakpoints[i].Source = &dap.Source{Name: filepath.Base(path), Path: path}
 	}
@@ -1494,20 +2081,128 @@ func (s *Session) onSetInstructionBreakpointsRequest(request *dap.SetInstruction
 	s.send(response)
 }
 
-func (s *Session) clearBreakpoints(existingBps map[string]*api.Breakpoint, amendedBps map[string]struct{}) error {
-	for req, bp := range existingBps {
-		if _, ok := amendedBps[req]; ok {
-			continue
+// dataBpPrefix is the prefix of bp.Name for every watchpoint created by
+// a setDataBreakpoints request.
+const dataBpPrefix = "dataBreakpoint"
+
+// onDataBreakpointInfoRequest handles 'dataBreakpointInfo' requests.
+// The returned data id identifies the expression that evaluates to the
+// variable together with the goroutine and frame it must be evaluated in:
+// "<goroutine id> <frame> <expression>".
+func (s *Session) onDataBreakpointInfoRequest(request *dap.DataBreakpointInfoRequest) {
+	args := request.Arguments
+	goid, frame := int64(-1), 0
+	expr := args.Name
+	if args.VariablesReference != 0 {
+		v, ok := s.variableHandles.get(args.VariablesReference)
+		if !ok {
+			s.sendErrorResponse(request.Request, UnableToSetBreakpoints, "Unable to get data breakpoint info", fmt.Sprintf("unknown reference %d", args.VariablesReference))
+			return
 		}
-		_, err := s.debugger.ClearBreakpoint(bp)
+		if v.frame != nil {
+			goid, frame = int64(v.frame.goroutineID), v.frame.frameIndex
+		}
+		var err error
+		expr, err = s.computeEvaluateName(v, args.Name)
 		if err != nil {
-			return err
+			s.sendDataBreakpointInfoUnavailable(request, err.Error())
+			return
 		}
+	} else if sf, ok := s.stackFrameHandles.get(args.FrameId); ok {
+		goid = int64(sf.(stackFrame).goroutineID)
+		frame = sf.(stackFrame).frameIndex
 	}
-	return nil
-}
 
-func (s *Session) getMatchingBreakpoints(prefix string) map[string]*api.Breakpoint {
+	v, err := s.debugger.EvalVariableInScope(goid, frame, 0, expr, proc.LoadConfig{})
+	if err != nil {
+		s.sendDataBreakpointInfoUnavailable(request, err.Error())
+		return
+	}
+	if v.Addr == 0 {
+		s.sendDataBreakpointInfoUnavailable(request, fmt.Sprintf("%s is not addressable", expr))
+		return
+	}
+
+	response := &dap.DataBreakpointInfoResponse{Response: *newResponse(request.Request)}
+	response.Body.DataId = fmt.Sprintf("%d %d %s", goid, frame, expr)
+	response.Body.Description = expr
+	response.Body.AccessTypes = []dap.DataBreakpointAccessType{"write", "read", "readWrite"}
+	s.send(response)
+}
+
+// sendDataBreakpointInfoUnavailable tells the client that no data
+// breakpoint can be set for the requested data and why.
+func (s *Session) sendDataBreakpointInfoUnavailable(request *dap.DataBreakpointInfoRequest, reason string) {
+	response := &dap.DataBreakpointInfoResponse{Response: *newResponse(request.Request)}
+	response.Body.DataId = nil
+	response.Body.Description = reason
+	s.send(response)
+}
+
+// parseDataId parses a data id returned by onDataBreakpointInfoRequest.
+func parseDataId(dataId string) (*bpWatch, error) {
+	fields := strings.SplitN(dataId, " ", 3)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid data id %q", dataId)
+	}
+	goid, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data id %q: %v", dataId, err)
+	}
+	frame, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid data id %q: %v", dataId, err)
+	}
+	return &bpWatch{goid: goid, frame: frame, expr: fields[2]}, nil
+}
+
+func (s *Session) onSetDataBreakpointsRequest(request *dap.SetDataBreakpointsRequest) {
+	breakpoints := s.setBreakpoints(dataBpPrefix, len(request.Arguments.Breakpoints), func(i int) *bpMetadata {
+		want := request.Arguments.Breakpoints[i]
+		return &bpMetadata{
+			name:         fmt.Sprintf("%s DataId=%s AccessType=%s", dataBpPrefix, want.DataId, want.AccessType),
+			condition:    want.Condition,
+			hitCondition: want.HitCondition,
+			logMessage:   "",
+		}
+	}, func(i int) (*bpLocation, error) {
+		want := request.Arguments.Breakpoints[i]
+		watch, err := parseDataId(want.DataId)
+		if err != nil {
+			return nil, err
+		}
+		switch want.AccessType {
+		case "read":
+			watch.wtype = api.WatchRead
+		case "write", "":
+			watch.wtype = api.WatchWrite
+		case "readWrite":
+			watch.wtype = api.WatchRead | api.WatchWrite
+		default:
+			return nil, fmt.Errorf("unknown access type %q", want.AccessType)
+		}
+		return &bpLocation{watch: watch}, nil
+	})
+
+	response := &dap.SetDataBreakpointsResponse{Response: *newResponse(request.Request)}
+	response.Body.Breakpoints = breakpoints
+	s.send(response)
+}
+
+func (s *Session) clearBreakpoints(existingBps map[string]*api.Breakpoint, amendedBps map[string]struct{}) error {
+	for req, bp := range existingBps {
+		if _, ok := amendedBps[req]; ok {
+			continue
+		}
+		_, err := s.debugger.ClearBreakpoint(bp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Session) getMatchingBreakpoints(prefix string) map[string]*api.Breakpoint {
 	existing := s.debugger.Breakpoints(false)
 	matchingBps := make(map[string]*api.Breakpoint, len(existing))
 	for _, bp := range existing {
@@ -1603,7 +2298,7 @@ func (s *Session) onThreadsRequest(request *dap.ThreadsRequest) {
 	var gs []*proc.G
 	var next int
 	if s.debugger != nil {
-		gs, next, err = s.debugger.Goroutines(0, maxGoroutines)
+		gs, next, err = s.debugger.GoroutinesWithCancel(0, maxGoroutines, s.cancel)
 		if err == nil {
 			// Parse the goroutine arguments.
 			filters, _, _, _, _, _, _, parseErr := api.ParseGoroutineArgs(s.args.GoroutineFilters)
@@ -1619,6 +2314,10 @@ func (s *Session) onThreadsRequest(request *dap.ThreadsRequest) {
 			gs = s.debugger.FilterGoroutines(gs, filters)
 		}
 	}
+	if s.isCancelled() {
+		s.sendCancelledResponse(request.Request)
+		return
+	}
 
 	var threads []dap.Thread
 	if err != nil {
@@ -1714,6 +2413,10 @@ func (s *Session) onAttachRequest(request *dap.AttachRequest) {
 	}
 	s.config.log.Debug("parsed launch config: ", prettyPrint(args))
 
+	// The arguments are needed by the debugger to restore the breakpoints
+	// saved with a recording.
+	s.setLaunchAttachArgs(args.LaunchAttachCommonConfig)
+
 	switch args.Mode {
 	case "":
 		args.Mode = "local"
@@ -1763,10 +2466,7 @@ func (s *Session) onAttachRequest(request *dap.AttachRequest) {
 			s.sendShowUserErrorResponse(request.Request, FailedToAttach, "Failed to attach", err.Error())
 			return
 		}
-		// Enable StepBack controls on supported backends
-		if s.config.Debugger.Backend == "rr" {
-			s.send(&dap.CapabilitiesEvent{Event: *newEvent("capabilities"), Body: dap.CapabilitiesEventBody{Capabilities: dap.Capabilities{SupportsStepBack: true}}})
-		}
+		s.sendReverseCapabilities()
 		// Customize termination options for debugger and debuggee
 		if s.config.AcceptMulti {
 			// User can stop debugger with process or leave it running
@@ -1782,7 +2482,7 @@ func (s *Session) onAttachRequest(request *dap.AttachRequest) {
 		return
 	}
 
-	s.setLaunchAttachArgs(args.LaunchAttachCommonConfig)
+	s.updateLoaded(false)
 
 	// Notify the client that the debugger is ready to start accepting
 	// configuration requests for setting breakpoints, etc. The client
@@ -1800,9 +2500,62 @@ func (s *Session) onNextRequest(request *dap.NextRequest, allowNextStateChange c
 
 // onStepInRequest handles 'stepIn' request
 // This is a mandatory request to support.
+// If a target returned by the last stepInTargets request is specified
+// only the call of that target is stepped into.
 func (s *Session) onStepInRequest(request *dap.StepInRequest, allowNextStateChange chan struct{}) {
+	command := api.Step
+	if id := request.Arguments.TargetId; id != 0 {
+		if id < 0 || id > len(s.stepInTargets) || s.stepInTargets[id-1].goroutineID != request.Arguments.ThreadId {
+			closeIfOpen(allowNextStateChange)
+			s.sendErrorResponse(request.Request, UnableToListStepInTargets, "Unable to step in", fmt.Sprintf("unknown step in target %d", id))
+			return
+		}
+		command = api.StepIntoCall
+		s.stepIntoCallPC = s.stepInTargets[id-1].callPC
+	}
 	s.sendStepResponse(request.Arguments.ThreadId, &dap.StepInResponse{Response: *newResponse(request.Request)})
-	s.stepUntilStopAndNotify(api.Step, request.Arguments.ThreadId, request.Arguments.Granularity, allowNextStateChange)
+	s.stepUntilStopAndNotify(command, request.Arguments.ThreadId, request.Arguments.Granularity, allowNextStateChange)
+}
+
+type stepInTarget struct {
+	goroutineID int
+	callPC      uint64
+}
+
+// onStepInTargetsRequest handles 'stepInTargets' requests.
+// This is an optional request enabled by capability ‘supportsStepInTargetsRequest’.
+// The targets are the functions called by the current line of the frame,
+// found by disassembling it. Only the topmost frame of a goroutine has
+// targets, stepping into a call of a different frame is not possible.
+func (s *Session) onStepInTargetsRequest(request *dap.StepInTargetsRequest) {
+	sf, ok := s.stackFrameHandles.get(request.Arguments.FrameId)
+	if !ok {
+		s.sendErrorResponse(request.Request, UnableToListStepInTargets, "Unable to list step in targets", fmt.Sprintf("unknown frame id %d", request.Arguments.FrameId))
+		return
+	}
+	goid := sf.(stackFrame).goroutineID
+
+	response := &dap.StepInTargetsResponse{Response: *newResponse(request.Request)}
+	response.Body.Targets = []dap.StepInTarget{}
+	s.stepInTargets = nil
+	if sf.(stackFrame).frameIndex != 0 {
+		s.send(response)
+		return
+	}
+
+	targets, err := s.debugger.StepIntoTargets(int64(goid))
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToListStepInTargets, "Unable to list step in targets", err.Error())
+		return
+	}
+	for _, target := range targets {
+		s.stepInTargets = append(s.stepInTargets, stepInTarget{goroutineID: goid, callPC: target.CallPC})
+		response.Body.Targets = append(response.Body.Targets, dap.StepInTarget{
+			Id:    len(s.stepInTargets),
+			Label: target.Fn.Name,
+		})
+	}
+	s.send(response)
 }
 
 // onStepOutRequest handles 'stepOut' request
@@ -1884,6 +2637,17 @@ func (s *Session) stepUntilStopAndNotify(command string, threadId int, granulari
 		return
 	}
 
+	if command == api.ReverseNext {
+		// Non-standard granularities, only meaningful for stepBack, used to
+		// navigate the call stack backward.
+		switch granularity {
+		case "callEntry":
+			command = api.ReverseCallEntry
+		case "callSite":
+			command = api.ReverseCallSite
+		}
+	}
+
 	if granularity == "instruction" {
 		switch command {
 		case api.ReverseNext:
@@ -1957,6 +2721,10 @@ func (s *Session) onStackTraceRequest(request *dap.StackTraceRequest) {
 	if g, _ := s.debugger.FindGoroutine(int64(goroutineID)); g != nil {
 		isSystemGoroutine = g.System(s.debugger.Target())
 	}
+	if s.isCancelled() {
+		s.sendCancelledResponse(request.Request)
+		return
+	}
 
 	stackFrames := []dap.StackFrame{} // initialize to empty, since nil is not an accepted response.
 	for i := 0; i < levels && i+start < len(frames); i++ {
@@ -1966,7 +2734,7 @@ func (s *Session) onStackTraceRequest(request *dap.StackTraceRequest) {
 		stackFrame := dap.StackFrame{Id: uniqueStackFrameID, Line: loc.Line, Name: fnName(loc), InstructionPointerReference: fmt.Sprintf("%#x", loc.PC)}
 		if loc.File != "<autogenerated>" {
 			clientPath := s.toClientPath(loc.File)
-			stackFrame.Source = &dap.Source{Name: filepath.Base(clientPath), Path: clientPath}
+			stackFrame.Source = &dap.Source{Name: filepath.Base(clientPath), Path: clientPath, SourceReference: s.sourceReference(loc.File)}
 		}
 		stackFrame.Column = 0
 
@@ -2033,7 +2801,7 @@ func (s *Session) onScopesRequest(request *dap.ScopesRequest) {
 		s.sendErrorResponse(request.Request, UnableToListLocals, "Unable to list locals", err.Error())
 		return
 	}
-	locScope := &fullyQualifiedVariable{&proc.Variable{Name: fmt.Sprintf("Locals%s", suffix), Children: slicePtrVarToSliceVar(append(args, locals...))}, "", true, 0}
+	locScope := &fullyQualifiedVariable{&proc.Variable{Name: fmt.Sprintf("Locals%s", suffix), Children: slicePtrVarToSliceVar(append(args, locals...))}, "", true, 0, &stackFrame{goid, frame}}
 	scopeLocals := dap.Scope{Name: locScope.Name, VariablesReference: s.variableHandles.create(locScope)}
 	scopes := []dap.Scope{scopeLocals}
 
@@ -2067,7 +2835,7 @@ func (s *Session) onScopesRequest(request *dap.ScopesRequest) {
 		globScope := &fullyQualifiedVariable{&proc.Variable{
 			Name:     fmt.Sprintf("Globals (package %s)", currPkg),
 			Children: slicePtrVarToSliceVar(globals),
-		}, currPkg, true, 0}
+		}, currPkg, true, 0, nil}
 		scopeGlobals := dap.Scope{Name: globScope.Name, VariablesReference: s.variableHandles.create(globScope)}
 		scopes = append(scopes, scopeGlobals)
 	}
@@ -2088,7 +2856,7 @@ func (s *Session) onScopesRequest(request *dap.ScopesRequest) {
 				Kind:  reflect.Kind(proc.VariableConstant),
 			}
 		}
-		regsScope := &fullyQualifiedVariable{&proc.Variable{Name: "Registers", Children: regsVar}, "", true, 0}
+		regsScope := &fullyQualifiedVariable{&proc.Variable{Name: "Registers", Children: regsVar}, "", true, 0, nil}
 		scopeRegisters := dap.Scope{Name: regsScope.Name, VariablesReference: s.variableHandles.create(regsScope)}
 		scopes = append(scopes, scopeRegisters)
 	}
@@ -2142,6 +2910,10 @@ func (s *Session) onVariablesRequest(request *dap.VariablesRequest) {
 		indexed := s.childrenToDAPVariables(v)
 		children = append(children, indexed...)
 	}
+	if s.isCancelled() {
+		s.sendCancelledResponse(request.Request)
+		return
+	}
 	response := &dap.VariablesResponse{
 		Response: *newResponse(request.Request),
 		Body:     dap.VariablesResponseBody{Variables: children},
@@ -2162,13 +2934,13 @@ func (s *Session) maybeLoadResliced(v *fullyQualifiedVariable, start, count int)
 			return v, nil
 		}
 	}
-	indexedLoadConfig := DefaultLoadConfig
+	indexedLoadConfig := s.loadConfig()
 	indexedLoadConfig.MaxArrayValues = count
 	newV, err := s.debugger.LoadResliced(v.Variable, start, indexedLoadConfig)
 	if err != nil {
 		return nil, err
 	}
-	return &fullyQualifiedVariable{newV, v.fullyQualifiedNameOrExpr, false, start}, nil
+	return &fullyQualifiedVariable{newV, v.fullyQualifiedNameOrExpr, false, start, v.frame}, nil
 }
 
 // getIndexedVariableCount returns the number of indexed variables
@@ -2213,6 +2985,8 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 			}
 			key, keyref := s.convertVariable(keyv, keyexpr)
 			val, valref := s.convertVariable(valv, valexpr)
+			s.variableHandles.setFrame(keyref, v.frame)
+			s.variableHandles.setFrame(valref, v.frame)
 			keyType := s.getTypeIfSupported(keyv)
 			valType := s.getTypeIfSupported(valv)
 			// If key or value or both are scalars, we can use
@@ -2227,6 +3001,7 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 					VariablesReference: keyref,
 					IndexedVariables:   getIndexedVariableCount(keyv),
 					NamedVariables:     getNamedVariableCount(keyv),
+					MemoryReference:    s.getMemoryReferenceIfSupported(keyv),
 				}
 				valvar := dap.Variable{
 					Name:               fmt.Sprintf("[val %d]", v.startIndex+kvIndex),
@@ -2236,6 +3011,7 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 					VariablesReference: valref,
 					IndexedVariables:   getIndexedVariableCount(valv),
 					NamedVariables:     getNamedVariableCount(valv),
+					MemoryReference:    s.getMemoryReferenceIfSupported(valv),
 				}
 				children = append(children, keyvar, valvar)
 			} else { // At least one is a scalar
@@ -2244,10 +3020,11 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 					keyValType = fmt.Sprintf("%s: %s", keyType, valType)
 				}
 				kvvar := dap.Variable{
-					Name:         key,
-					EvaluateName: valexpr,
-					Type:         keyValType,
-					Value:        val,
+					Name:            key,
+					EvaluateName:    valexpr,
+					Type:            keyValType,
+					Value:           val,
+					MemoryReference: s.getMemoryReferenceIfSupported(valv),
 				}
 				if keyref != 0 { // key is a type to be expanded
 					if len(key) > maxMapKeyValueLen {
@@ -2271,6 +3048,7 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 			idx := v.startIndex + i
 			cfqname := fmt.Sprintf("%s[%d]", v.fullyQualifiedNameOrExpr, idx)
 			cvalue, cvarref := s.convertVariable(&v.Children[i], cfqname)
+			s.variableHandles.setFrame(cvarref, v.frame)
 			children[i] = dap.Variable{
 				Name:               fmt.Sprintf("[%d]", idx),
 				EvaluateName:       cfqname,
@@ -2279,6 +3057,7 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 				VariablesReference: cvarref,
 				IndexedVariables:   getIndexedVariableCount(&v.Children[i]),
 				NamedVariables:     getNamedVariableCount(&v.Children[i]),
+				MemoryReference:    s.getMemoryReferenceIfSupported(&v.Children[i]),
 			}
 		}
 	default:
@@ -2301,6 +3080,7 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 				cfqname = "" // complex children are not struct fields and can't be accessed directly
 			}
 			cvalue, cvarref := s.convertVariable(c, cfqname)
+			s.variableHandles.setFrame(cvarref, v.frame)
 
 			// Annotate any shadowed variables to "(name)" in order
 			// to distinguish from non-shadowed variables.
@@ -2330,6 +3110,7 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 				VariablesReference: cvarref,
 				IndexedVariables:   getIndexedVariableCount(c),
 				NamedVariables:     getNamedVariableCount(c),
+				MemoryReference:    s.getMemoryReferenceIfSupported(c),
 			}
 		}
 	}
@@ -2371,7 +3152,7 @@ func (s *Session) metadataToDAPVariables(v *fullyQualifiedVariable) ([]dap.Varia
 
 		s.config.log.Debugf("loading %s (type %s) with %s", v.fullyQualifiedNameOrExpr, typeName, loadExpr)
 		// We know that this is an array/slice of Uint8 or Int32, so we will load up to MaxStringLen.
-		config := DefaultLoadConfig
+		config := s.loadConfig()
 		config.MaxArrayValues = config.MaxStringLen
 		vLoaded, err := s.debugger.EvalVariableInScope(-1, 0, 0, loadExpr, config)
 		if err == nil {
@@ -2405,6 +3186,31 @@ func (s *Session) getTypeIfSupported(v *proc.Variable) string {
 	return v.TypeString()
 }
 
+// getMemoryReferenceIfSupported returns the address of the memory that
+// pointer, slice, string and array variables refer to, for use in
+// readMemory and writeMemory requests. Other variables have no memory
+// reference.
+func (s *Session) getMemoryReferenceIfSupported(v *proc.Variable) string {
+	if !s.clientCapabilities.supportsMemoryReferences || v.Unreadable != nil {
+		return ""
+	}
+	var addr uint64
+	switch v.Kind {
+	case reflect.Ptr:
+		if len(v.Children) > 0 {
+			addr = v.Children[0].Addr
+		}
+	case reflect.Slice, reflect.String:
+		addr = v.Base
+	case reflect.Array:
+		addr = v.Addr
+	}
+	if addr == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%#x", addr)
+}
+
 // convertVariable converts proc.Variable to dap.Variable value and reference
 // while keeping track of the full qualified name or load expression.
 // Variable reference is used to keep track of the children associated with each
@@ -2449,7 +3255,7 @@ func (s *Session) convertVariableWithOpts(v *proc.Variable, qualifiedNameOrExpr
 		if opts&skipRef != 0 {
 			return 0
 		}
-		return s.variableHandles.create(&fullyQualifiedVariable{v, qualifiedNameOrExpr, false /*not a scope*/, 0})
+		return s.variableHandles.create(&fullyQualifiedVariable{v, qualifiedNameOrExpr, false /*not a scope*/, 0, nil})
 	}
 	value = api.ConvertVar(v).SinglelineString()
 	if v.Unreadable != nil {
@@ -2471,7 +3277,7 @@ func (s *Session) convertVariableWithOpts(v *proc.Variable, qualifiedNameOrExpr
 		s.config.log.Debugf("loading %s (type %s) with %s", qualifiedNameOrExpr, typeName, loadExpr)
 		// Make sure we can load the pointers directly, not by updating just the child
 		// This is not really necessary now because users have no way of setting FollowPointers to false.
-		config := DefaultLoadConfig
+		config := s.loadConfig()
 		config.FollowPointers = true
 		vLoaded, err := s.debugger.EvalVariableInScope(-1, 0, 0, loadExpr, config)
 		if err != nil {
@@ -2479,6 +3285,7 @@ func (s *Session) convertVariableWithOpts(v *proc.Variable, qualifiedNameOrExpr
 		} else {
 			v.Children = vLoaded.Children
 			v.Value = vLoaded.Value
+			v.Display = vLoaded.Display
 			value = api.ConvertVar(v).SinglelineString()
 		}
 		return value
@@ -2505,7 +3312,7 @@ func (s *Session) convertVariableWithOpts(v *proc.Variable, qualifiedNameOrExpr
 					cTypeName := api.PrettyTypeName(v.Children[0].DwarfType)
 					cLoadExpr := fmt.Sprintf("*(*%q)(%#x)", cTypeName, v.Children[0].Addr)
 					s.config.log.Debugf("loading *(%s) (type %s) with %s", qualifiedNameOrExpr, cTypeName, cLoadExpr)
-					cLoaded, err := s.debugger.EvalVariableInScope(-1, 0, 0, cLoadExpr, DefaultLoadConfig)
+					cLoaded, err := s.debugger.EvalVariableInScope(-1, 0, 0, cLoadExpr, s.loadConfig())
 					if err != nil {
 						value += fmt.Sprintf(" - FAILED TO LOAD: %s", err)
 					} else {
@@ -2637,6 +3444,10 @@ func (s *Session) onEvaluateRequest(request *dap.EvaluateRequest) {
 	} else if isCall, err := regexp.MatchString(`^\s*call\s+\S+`, expr); err == nil && isCall { // call {expression}
 		expr := strings.Replace(expr, "call ", "", 1)
 		_, retVars, err := s.doCall(goid, frame, expr)
+		if err != nil && s.isCancelled() {
+			s.sendCancelledResponse(request.Request)
+			return
+		}
 		if err != nil {
 			s.sendErrorResponseWithOpts(request.Request, UnableToEvaluateExpression, "Unable to evaluate expression", err.Error(), showErrorToUser)
 			return
@@ -2653,11 +3464,11 @@ func (s *Session) onEvaluateRequest(request *dap.EvaluateRequest) {
 			}
 			response.Body = dap.EvaluateResponseBody{
 				Result:             strings.TrimRight(retVarsAsStr, ", "),
-				VariablesReference: s.variableHandles.create(&fullyQualifiedVariable{retVarsAsVar, "", false /*not a scope*/, 0}),
+				VariablesReference: s.variableHandles.create(&fullyQualifiedVariable{retVarsAsVar, "", false /*not a scope*/, 0, nil}),
 			}
 		}
 	} else { // {expression}
-		exprVar, err := s.debugger.EvalVariableInScope(int64(goid), frame, 0, expr, DefaultLoadConfig)
+		exprVar, err := s.debugger.EvalVariableInScope(int64(goid), frame, 0, expr, s.loadConfig())
 		if err != nil {
 			s.sendErrorResponseWithOpts(request.Request, UnableToEvaluateExpression, "Unable to evaluate expression", err.Error(), showErrorToUser)
 			return
@@ -2669,7 +3480,7 @@ func (s *Session) onEvaluateRequest(request *dap.EvaluateRequest) {
 			if exprVar.Kind == reflect.String {
 				if strVal := constant.StringVal(exprVar.Value); exprVar.Len > int64(len(strVal)) {
 					// Reload the string value with a bigger limit.
-					loadCfg := DefaultLoadConfig
+					loadCfg := s.loadConfig()
 					loadCfg.MaxStringLen = maxSingleStringLen
 					if v, err := s.debugger.EvalVariableInScope(int64(goid), frame, 0, request.Arguments.Expression, loadCfg); err != nil {
 						s.config.log.Debugf("Failed to load more for %v: %v", request.Arguments.Expression, err)
@@ -2686,7 +3497,12 @@ func (s *Session) onEvaluateRequest(request *dap.EvaluateRequest) {
 			opts |= showFullValue
 		}
 		exprVal, exprRef := s.convertVariableWithOpts(exprVar, fmt.Sprintf("(%s)", request.Arguments.Expression), opts)
-		response.Body = dap.EvaluateResponseBody{Result: exprVal, Type: s.getTypeIfSupported(exprVar), VariablesReference: exprRef, IndexedVariables: getIndexedVariableCount(exprVar), NamedVariables: getNamedVariableCount(exprVar)}
+		s.variableHandles.setFrame(exprRef, &stackFrame{goid, frame})
+		response.Body = dap.EvaluateResponseBody{Result: exprVal, Type: s.getTypeIfSupported(exprVar), VariablesReference: exprRef, IndexedVariables: getIndexedVariableCount(exprVar), NamedVariables: getNamedVariableCount(exprVar), MemoryReference: s.getMemoryReferenceIfSupported(exprVar)}
+	}
+	if s.isCancelled() {
+		s.sendCancelledResponse(request.Request)
+		return
 	}
 	s.send(response)
 }
@@ -2713,6 +3529,23 @@ func (s *Session) doCall(goid, frame int, expr string) (*api.DebuggerState, []*p
 	loadCfg := DefaultLoadConfig
 	loadCfg.MaxStringLen = maxStringLenInCallRetVars
 
+	// Cancelling the request interrupts the call the same way a pause request
+	// would.
+	if s.cancel != nil {
+		callDone := make(chan struct{})
+		defer close(callDone)
+		go func(cancel <-chan struct{}) {
+			select {
+			case <-cancel:
+				s.config.log.Debug("halting cancelled call")
+				if _, err := s.halt(); err != nil {
+					s.config.log.Debug("Unable to halt cancelled call: ", err)
+				}
+			case <-callDone:
+			}
+		}(s.cancel)
+	}
+
 	// TODO(polina): since call will resume execution of all goroutines,
 	// we should do this asynchronously and send a continued event to the
 	// editor, followed by a stop event when the call completes.
@@ -2724,6 +3557,7 @@ func (s *Session) doCall(goid, frame int, expr string) (*api.DebuggerState, []*p
 		GoroutineID:          int64(goid),
 	}, nil)
 	if processExited(state, err) {
+		s.closeOutputPipes()
 		e := &dap.TerminatedEvent{Event: *newEvent("terminated")}
 		s.send(e)
 		return nil, nil, errors.New("terminated")
@@ -2795,16 +3629,252 @@ func (s *Session) sendStoppedEvent(state *api.DebuggerState) {
 	s.send(stopped)
 }
 
-// onTerminateRequest sends a not-yet-implemented error response.
-// Capability 'supportsTerminateRequest' is not set in 'initialize' response.
-func (s *Session) onTerminateRequest(request *dap.TerminateRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+// onTerminateRequest handles 'terminate' requests.
+// Capability 'supportsTerminateRequest' is set in 'initialize' response.
+// The program is sent SIGTERM, so that it can exit gracefully, and resumed
+// if it is stopped. The terminated event is sent when it exits, a program
+// that handles the signal without exiting keeps running.
+// Recordings and core files are not running programs, the debug session
+// ends right away.
+// Not supported on Windows, clients disconnect instead.
+// allowNextStateChange is nil if the program is already running.
+func (s *Session) onTerminateRequest(request *dap.TerminateRequest, allowNextStateChange chan struct{}) {
+	defer closeIfOpen(allowNextStateChange)
+
+	if runtime.GOOS == "windows" {
+		s.sendUnsupportedErrorResponse(request.Request)
+		return
+	}
+
+	var pid int
+	switch {
+	case s.noDebugProcess != nil:
+		pid = s.noDebugProcess.Process.Pid
+	case s.debugger == nil:
+		s.sendErrorResponse(request.Request, UnableToTerminate, "Unable to terminate", "no program is being debugged")
+		return
+	case s.config.Debugger.Backend == "rr" || s.config.Debugger.Backend == "undo" || s.config.Debugger.Backend == "core":
+		s.send(&dap.TerminateResponse{Response: *newResponse(request.Request)})
+		s.send(&dap.TerminatedEvent{Event: *newEvent("terminated")})
+		return
+	default:
+		// The debugger is busy while the program is running, the pid of
+		// the target does not change until it is restarted.
+		pid = s.debugger.Target().Pid()
+	}
+
+	if err := terminateProcess(pid); err != nil {
+		s.sendErrorResponse(request.Request, UnableToTerminate, "Unable to terminate", err.Error())
+		return
+	}
+	s.logToConsole(fmt.Sprintf("Sent SIGTERM to process %d", pid))
+	s.send(&dap.TerminateResponse{Response: *newResponse(request.Request)})
+
+	if allowNextStateChange != nil {
+		s.runUntilStopAndNotify(api.Continue, allowNextStateChange)
+	}
+}
+
+// terminateProcess asks the process with the given pid to exit by sending
+// it SIGTERM.
+func terminateProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(syscall.SIGTERM)
+}
+
+// onRestartRequest handles 'restart' requests.
+// Capability 'supportsRestartRequest' is set in 'initialize' response.
+// The program is restarted with Debugger.Restart, keeping the breakpoints.
+// Programs launched in "debug" or "test" mode are rebuilt first.
+// Recordings restart from the checkpoint in the Delve-specific checkpoint
+// attribute of the arguments, see RestartConfig, and stop there, or from
+// their start. Otherwise execution stops on entry or continues like after
+// the configurationDone request.
+func (s *Session) onRestartRequest(request *dap.RestartRequest, allowNextStateChange chan struct{}) {
+	defer closeIfOpen(allowNextStateChange)
+
+	if s.debugger == nil {
+		s.sendErrorResponse(request.Request, UnableToRestart, "Unable to restart", "no program is being debugged")
+		return
+	}
+
+	var args RestartConfig
+	if request.Arguments != nil && request.Arguments.Arguments != nil {
+		input, err := json.Marshal(request.Arguments.Arguments)
+		if err == nil {
+			err = unmarshalLaunchAttachArgs(input, &args)
+		}
+		if err == nil {
+			err = s.applyRestartArgs(input)
+		}
+		if err != nil {
+			s.sendErrorResponse(request.Request, UnableToRestart, "Unable to restart", err.Error())
+			return
+		}
+	}
+
+	recorded := s.isRecorded()
+	pos := ""
+	if args.Checkpoint != 0 {
+		if !recorded {
+			s.sendErrorResponse(request.Request, UnableToRestart, "Unable to restart", "checkpoints are only supported when debugging a recording")
+			return
+		}
+		pos = fmt.Sprintf("c%d", args.Checkpoint)
+	}
+
+	oldImages := s.images()
+	rebuilt := false
+	var oldPipes []*outputPipe
+	if !recorded && s.launchConfig != nil {
+		switch s.launchConfig.Mode {
+		case "debug", "test":
+			if err := s.build(s.launchConfig); err != nil {
+				s.sendErrorResponse(request.Request, UnableToRestart, "Unable to restart",
+					"Build error: Check the debug console for details.")
+				return
+			}
+			rebuilt = true
+		}
+		switch s.launchConfig.Mode {
+		case "debug", "test", "exec":
+			if s.launchConfig.Console == "internalConsole" {
+				// The pipes of the old process are closed once it is
+				// killed, so that its output is sent to the client.
+				oldPipes = s.takeOutputPipes()
+				if err := s.redirectStdio(s.launchConfig.Console, s.launchConfig.Cwd); err != nil {
+					s.closePipes(oldPipes)
+					s.sendErrorResponse(request.Request, UnableToRestart, "Unable to restart", err.Error())
+					return
+				}
+			}
+		}
+	}
+
+	discarded, err := func() ([]api.DiscardedBreakpoint, error) {
+		s.changeStateMu.Lock()
+		defer s.changeStateMu.Unlock()
+		return s.debugger.Restart(false, pos, false, nil, [3]string{}, false)
+	}()
+	s.closePipes(oldPipes)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToRestart, "Unable to restart", err.Error())
+		return
+	}
+	for _, bp := range discarded {
+		s.logToConsole(fmt.Sprintf("Discarded breakpoint %d at %s:%d: %s", bp.Breakpoint.ID, bp.Breakpoint.File, bp.Breakpoint.Line, bp.Reason))
+		s.send(&dap.BreakpointEvent{
+			Event: *newEvent("breakpoint"),
+			Body:  dap.BreakpointEventBody{Reason: "removed", Breakpoint: dap.Breakpoint{Id: bp.Breakpoint.ID}},
+		})
+	}
+	if !recorded {
+		s.updateLoadedAfterRestart(oldImages, rebuilt)
+	}
+	s.resetHandlesForStoppedEvent()
+	s.send(&dap.RestartResponse{Response: *newResponse(request.Request)})
+
+	switch {
+	case pos != "":
+		state, err := s.debugger.State(false)
+		if err != nil {
+			s.config.log.Errorf("Error retrieving state: %v", err)
+			return
+		}
+		stopped := &dap.StoppedEvent{Event: *newEvent("stopped")}
+		stopped.Body.AllThreadsStopped = true
+		stopped.Body.ThreadId = int(stoppedGoroutineID(state))
+		stopped.Body.Reason = "goto"
+		s.send(stopped)
+	case s.args.stopOnEntry:
+		s.send(&dap.StoppedEvent{
+			Event: *newEvent("stopped"),
+			Body:  dap.StoppedEventBody{Reason: "entry", ThreadId: 1, AllThreadsStopped: true},
+		})
+	default:
+		s.runUntilStopAndNotify(api.Continue, allowNextStateChange)
+	}
+}
+
+// applyRestartArgs applies the launch or attach configuration passed to a
+// restart request, which is the latest version of the configuration of the
+// session. Attributes missing from input keep their values. Attributes that
+// can only be used when the session starts, like the program or the mode,
+// can not be changed.
+func (s *Session) applyRestartArgs(input json.RawMessage) error {
+	if s.launchConfig == nil {
+		args := defaultAttachConfig
+		if err := unmarshalLaunchAttachArgs(input, &args); err != nil {
+			return err
+		}
+		if args.ProcessID != 0 && args.ProcessID != s.debugger.AttachPid() {
+			return errors.New("the 'processId' attribute can not be changed by a restart request")
+		}
+		s.setLaunchAttachArgs(args.LaunchAttachCommonConfig)
+		return nil
+	}
+
+	cur := s.launchConfig
+	args := *cur
+	if err := unmarshalLaunchAttachArgs(input, &args); err != nil {
+		return err
+	}
+	if args.Output != cur.Output && args.Output != "" {
+		output, err := filepath.Abs(cleanExeName(args.Output))
+		if err != nil {
+			return err
+		}
+		args.Output = output
+	}
+	if args.DlvCwd != cur.DlvCwd && args.DlvCwd != "" {
+		args.DlvCwd, _ = filepath.Abs(args.DlvCwd)
+	}
+	// The backend of replay and core sessions is determined by the file
+	// that is opened.
+	backendChanged := args.Backend != cur.Backend && args.Mode != "replay" && args.Mode != "core"
+	for _, attr := range []struct {
+		name    string
+		changed bool
+	}{
+		{"mode", args.Mode != cur.Mode},
+		{"program", args.Program != cur.Program},
+		{"output", args.Output != cur.Output},
+		{"cwd", args.Cwd != cur.Cwd},
+		{"noDebug", args.NoDebug != cur.NoDebug},
+		{"traceDirPath", args.TraceDirPath != cur.TraceDirPath},
+		{"coreFilePath", args.CoreFilePath != cur.CoreFilePath},
+		{"dlvCwd", args.DlvCwd != cur.DlvCwd},
+		{"console", args.Console != cur.Console},
+		{"args", (args.Mode == "replay" || args.Mode == "core") && !reflect.DeepEqual(args.Args, cur.Args)},
+		{"env", !reflect.DeepEqual(args.Env, cur.Env)},
+		{"backend", backendChanged},
+	} {
+		if attr.changed {
+			return fmt.Errorf("the '%s' attribute can not be changed by a restart request", attr.name)
+		}
+	}
+
+	if !reflect.DeepEqual(args.Args, cur.Args) {
+		s.debugger.SetProcessArgs(args.Args)
+	}
+	// The build flags are used when the program is rebuilt.
+	s.launchConfig = &args
+	s.setLaunchAttachArgs(args.LaunchAttachCommonConfig)
+	return nil
 }
 
-// onRestartRequest sends a not-yet-implemented error response
-// Capability 'supportsRestartRequest' is not set in 'initialize' response.
-func (s *Session) onRestartRequest(request *dap.RestartRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+// waitForRunningCmd waits for the asynchronous command that resumed
+// execution, if any, to be done after the program was halted.
+func (s *Session) waitForRunningCmd() {
+	s.runningMu.Lock()
+	done := s.runningDone
+	s.runningMu.Unlock()
+	if done != nil {
+		<-done
+	}
 }
 
 // onStepBackRequest handles 'stepBack' request.
@@ -2824,66 +3894,288 @@ func (s *Session) onReverseContinueRequest(request *dap.ReverseContinueRequest,
 	s.runUntilStopAndNotify(api.Rewind, allowNextStateChange)
 }
 
-// computeEvaluateName finds the named child, and computes its evaluate name.
-func (s *Session) computeEvaluateName(v *fullyQualifiedVariable, cname string) (string, error) {
-	children := s.childrenToDAPVariables(v)
-	for _, c := range children {
-		if c.Name == cname {
-			if c.EvaluateName != "" {
-				return c.EvaluateName, nil
-			}
-			return "", errors.New("cannot set the variable without evaluate name")
-		}
-	}
-	return "", errors.New("failed to find the named variable")
+// gotoTarget is a source line that a goto request can move execution to.
+type gotoTarget struct {
+	pcs      []uint64
+	backward bool
 }
 
-// onSetVariableRequest handles 'setVariable' requests.
-func (s *Session) onSetVariableRequest(request *dap.SetVariableRequest) {
-	arg := request.Arguments
+// isRecorded returns true if the target is a recording that can be run
+// backward.
+func (s *Session) isRecorded() bool {
+	if s.debugger == nil {
+		return false
+	}
+	recorded, _ := s.debugger.Recorded()
+	return recorded
+}
 
-	v, ok := s.variableHandles.get(arg.VariablesReference)
-	if !ok {
-		s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to lookup variable", fmt.Sprintf("unknown reference %d", arg.VariablesReference))
+// onGotoTargetsRequest handles 'gotoTargets' requests.
+// This is an optional request enabled by capability ‘supportsGotoTargetsRequest’.
+// For recorded targets every executable line has two targets: its next
+// and its previous execution.
+func (s *Session) onGotoTargetsRequest(request *dap.GotoTargetsRequest) {
+	if !s.isRecorded() {
+		s.sendErrorResponse(request.Request, UnableToGoto, "Unable to find goto targets", "only supported when debugging a recording")
 		return
 	}
-	// We need to translate the arg.Name to its evaluateName if the name
-	// refers to a field or element of a variable.
-	// https://github.com/microsoft/vscode/issues/120774
-	evaluateName, err := s.computeEvaluateName(v, arg.Name)
+	if request.Arguments.Source.Path == "" {
+		s.sendErrorResponse(request.Request, UnableToGoto, "Unable to find goto targets", "empty file path")
+		return
+	}
+	file := s.toServerPath(request.Arguments.Source.Path)
+	locs, err := s.debugger.FindLocation(-1, 0, 0, fmt.Sprintf("%s:%d", file, request.Arguments.Line), false, nil)
+	if err == nil && len(locs) == 0 {
+		err = fmt.Errorf("no code at line %d", request.Arguments.Line)
+	}
 	if err != nil {
-		s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to set variable", err.Error())
+		s.sendErrorResponse(request.Request, UnableToGoto, "Unable to find goto targets", err.Error())
 		return
 	}
+	line := locs[0].Line
+	var pcs []uint64
+	for _, loc := range locs {
+		pcs = append(pcs, loc.PCs...)
+	}
 
-	// By running EvalVariableInScope, we get the type info of the variable
-	// that can be accessed with the evaluateName, and ensure the variable we are
-	// trying to update is valid and accessible from the top most frame & the
-	// current goroutine.
-	goid, frame := -1, 0
-	evaluated, err := s.debugger.EvalVariableInScope(int64(goid), frame, 0, evaluateName, DefaultLoadConfig)
+	s.gotoTargets = []gotoTarget{{pcs: pcs}, {pcs: pcs, backward: true}}
+	response := &dap.GotoTargetsResponse{Response: *newResponse(request.Request)}
+	response.Body.Targets = []dap.GotoTarget{
+		{Id: 1, Label: fmt.Sprintf("Next execution of line %d", line), Line: line},
+		{Id: 2, Label: fmt.Sprintf("Previous execution of line %d", line), Line: line},
+	}
+	s.send(response)
+}
+
+// onGotoRequest handles 'goto' requests.
+// This is an optional request enabled by capability ‘supportsGotoTargetsRequest’.
+// Execution is resumed, forward or backward, until the line of the target
+// is reached. Like for continue, stops on user breakpoints end the request
+// early.
+func (s *Session) onGotoRequest(request *dap.GotoRequest, allowNextStateChange chan struct{}) {
+	defer closeIfOpen(allowNextStateChange)
+	id := request.Arguments.TargetId
+	if id <= 0 || id > len(s.gotoTargets) {
+		s.sendErrorResponse(request.Request, UnableToGoto, "Unable to goto", fmt.Sprintf("unknown goto target %d", id))
+		return
+	}
+	target := s.gotoTargets[id-1]
+
+	clear, err := s.setInternalBreakpoint(target.pcs, "", "goto")
 	if err != nil {
-		s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to lookup variable", err.Error())
+		s.sendErrorResponse(request.Request, UnableToGoto, "Unable to goto", err.Error())
 		return
 	}
+	defer clear()
 
-	useFnCall := false
-	switch evaluated.Kind {
-	case reflect.String:
-		useFnCall = true
-	default:
-		// TODO(hyangah): it's possible to set a non-string variable using (`call i = fn()`)
-		// and we don't support it through the Set Variable request yet.
-		// If we want to support it for non-string types, we need to parse arg.Value.
+	s.send(&dap.GotoResponse{Response: *newResponse(request.Request)})
+	command := api.Continue
+	if target.backward {
+		command = api.Rewind
 	}
+	s.runUntilStopAndNotify(command, allowNextStateChange)
+}
 
-	if useFnCall {
-		// TODO(hyangah): function call injection currently allows to assign return values of
+// onRestartFrameRequest handles 'restartFrame' requests.
+// This is an optional request enabled by capability ’supportsRestartFrame’.
+// For recorded targets execution is resumed backward until the entry of the
+// call of the frame.
+func (s *Session) onRestartFrameRequest(request *dap.RestartFrameRequest, allowNextStateChange chan struct{}) {
+	defer closeIfOpen(allowNextStateChange)
+	if !s.isRecorded() {
+		s.sendErrorResponse(request.Request, UnableToRestartFrame, "Unable to restart frame", "only supported when debugging a recording")
+		return
+	}
+	sf, ok := s.stackFrameHandles.get(request.Arguments.FrameId)
+	if !ok {
+		s.sendErrorResponse(request.Request, UnableToRestartFrame, "Unable to restart frame", fmt.Sprintf("unknown frame id %d", request.Arguments.FrameId))
+		return
+	}
+	goid := sf.(stackFrame).goroutineID
+	frame := sf.(stackFrame).frameIndex
+
+	frames, err := s.debugger.Stacktrace(int64(goid), frame, 0)
+	if err == nil && len(frames) <= frame {
+		err = fmt.Errorf("frame %d does not exist", frame)
+	}
+	if err == nil {
+		switch {
+		case frames[frame].Current.Fn == nil:
+			err = errors.New("no function for the frame")
+		case frames[frame].Inlined:
+			err = errors.New("can not restart the frame of an inlined call")
+		case frame == 0 && frames[0].Current.PC == frames[0].Current.Fn.Entry:
+			err = fmt.Errorf("already at the entry of %s", frames[0].Current.Fn.Name)
+		}
+	}
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToRestartFrame, "Unable to restart frame", err.Error())
+		return
+	}
+
+	// The frame offset is the same at the entry of the function, it
+	// distinguishes the entry of this call from the entries of other calls of
+	// the same function.
+	cond := fmt.Sprintf("runtime.frameoff == %d", frames[frame].FrameOffset())
+	if goid > 0 {
+		cond = fmt.Sprintf("runtime.curg.goid == %d && %s", goid, cond)
+	}
+	clear, err := s.setInternalBreakpoint([]uint64{frames[frame].Current.Fn.Entry}, cond, "restart")
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToRestartFrame, "Unable to restart frame", err.Error())
+		return
+	}
+	defer clear()
+
+	s.send(&dap.RestartFrameResponse{Response: *newResponse(request.Request)})
+	s.runUntilStopAndNotify(api.Rewind, allowNextStateChange)
+}
+
+// setInternalBreakpoint sets an internal breakpoint at each of pcs,
+// stopping only when cond is true, and returns a function that clears them.
+// Internal breakpoints are not visible to the user, a stop on one of them is
+// reported with the given reason.
+func (s *Session) setInternalBreakpoint(pcs []uint64, cond, reason string) (clear func(), err error) {
+	bps, err := s.debugger.CreateInternalBreakpoint(pcs, cond)
+	if err != nil {
+		return nil, err
+	}
+	s.internalBreakpoints, s.internalBreakpointsReason = bps, reason
+	return func() {
+		s.internalBreakpoints, s.internalBreakpointsReason = nil, ""
+		if err := s.debugger.ClearInternalBreakpoints(bps); err != nil {
+			s.config.log.Errorf("Error clearing %s breakpoint: %v", reason, err)
+		}
+	}, nil
+}
+
+// stoppedOnInternalBreakpoint returns true if the target is stopped on one
+// of the breakpoints set by setInternalBreakpoint.
+func (s *Session) stoppedOnInternalBreakpoint() bool {
+	return s.internalBreakpoints != nil && s.debugger.StoppedOnInternalBreakpoint(s.internalBreakpoints)
+}
+
+// onCheckpointsRequest handles 'checkpoints' requests.
+// This is a Delve-specific request, see CheckpointsRequest.
+func (s *Session) onCheckpointsRequest(request *CheckpointsRequest) {
+	args := request.Arguments
+	changed := false
+	var err error
+	switch {
+	case !s.isRecorded():
+		err = errors.New("only supported when debugging a recording")
+	case args.Create != nil:
+		where := *args.Create
+		if where == "" {
+			if state, err := s.debugger.State(false); err == nil && state.CurrentThread != nil {
+				where = fmt.Sprintf("%s:%d", state.CurrentThread.File, state.CurrentThread.Line)
+			}
+		}
+		_, err = s.debugger.Checkpoint(where)
+		changed = true
+	case args.Clear != 0:
+		err = s.debugger.ClearCheckpoint(args.Clear)
+		changed = true
+	case args.Goto != 0:
+		s.changeStateMu.Lock()
+		defer s.changeStateMu.Unlock()
+		_, err = s.debugger.Restart(false, fmt.Sprintf("c%d", args.Goto), false, nil, [3]string{}, false)
+	}
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToManageCheckpoints, "Unable to manage checkpoints", err.Error())
+		return
+	}
+
+	checkpoints, err := s.checkpoints()
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToManageCheckpoints, "Unable to list checkpoints", err.Error())
+		return
+	}
+	s.send(&CheckpointsResponse{Response: *newResponse(request.Request), Body: CheckpointsBody{Checkpoints: checkpoints}})
+	if changed {
+		s.send(&CheckpointsEvent{Event: *newEvent("checkpoints"), Body: CheckpointsBody{Checkpoints: checkpoints}})
+	}
+	if args.Goto != 0 {
+		state, err := s.debugger.State(false)
+		if err != nil {
+			s.config.log.Errorf("Error retrieving state: %v", err)
+			return
+		}
+		s.resetHandlesForStoppedEvent()
+		stopped := &dap.StoppedEvent{Event: *newEvent("stopped")}
+		stopped.Body.AllThreadsStopped = true
+		stopped.Body.ThreadId = int(stoppedGoroutineID(state))
+		stopped.Body.Reason = "goto"
+		s.send(stopped)
+	}
+}
+
+func (s *Session) checkpoints() ([]Checkpoint, error) {
+	cps, err := s.debugger.Checkpoints()
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := make([]Checkpoint, len(cps))
+	for i, cp := range cps {
+		checkpoints[i] = Checkpoint{ID: cp.ID, When: cp.When, Where: cp.Where}
+	}
+	return checkpoints, nil
+}
+
+// computeEvaluateName finds the named child, and computes its evaluate name.
+func (s *Session) computeEvaluateName(v *fullyQualifiedVariable, cname string) (string, error) {
+	children := s.childrenToDAPVariables(v)
+	for i, c := range children {
+		if c.Name == cname {
+			if c.EvaluateName != "" {
+				return c.EvaluateName, nil
+			}
+			// Children without an evaluate name, for example the parts of a
+			// complex number or the fields of a return value, can still be
+			// set through their address. Map children are not one-to-one
+			// with the DAP variables, but they always have evaluate names.
+			if v.Kind != reflect.Map && i < len(v.Children) {
+				if cv := &v.Children[i]; cv.Addr != 0 && cv.Addr < proc.FakeAddressBase && cv.DwarfType != nil {
+					return fmt.Sprintf("*(*%q)(%#x)", api.PrettyTypeName(cv.DwarfType), cv.Addr), nil
+				}
+			}
+			return "", errors.New("cannot set the variable without evaluate name")
+		}
+	}
+	return "", errors.New("failed to find the named variable")
+}
+
+// setExpression assigns value to the assignable expression expr, evaluated
+// in frame of goroutine goid, and returns its new value.
+// Strings, and slices with values that need an allocation, are assigned
+// with an injected function call, so that string literals can be allocated
+// and functions returning them can be called.
+func (s *Session) setExpression(goid, frame int, expr, value string) (*proc.Variable, error) {
+	// By evaluating the expression first, we get the type info of the variable
+	// and ensure the variable we are trying to update is valid and accessible
+	// from the frame.
+	evaluated, err := s.debugger.EvalVariableInScope(int64(goid), frame, 0, expr, DefaultLoadConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	useFnCall := false
+	switch evaluated.Kind {
+	case reflect.String:
+		useFnCall = true
+	case reflect.Slice:
+		// Values like nil or a slice of another variable can be assigned
+		// without a call, which is not possible everywhere, for example
+		// in core files.
+		useFnCall = valueNeedsAllocation(value)
+	}
+
+	if useFnCall {
+		// TODO(hyangah): function call injection currently allows to assign return values of
 		// a function call to variables. So, curious users would find set variable
 		// on string would accept expression like `fn()`.
-		if state, retVals, err := s.doCall(goid, frame, fmt.Sprintf("%v=%v", evaluateName, arg.Value)); err != nil {
-			s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to set variable", err.Error())
-			return
+		if state, retVals, err := s.doCall(goid, frame, fmt.Sprintf("%v=%v", expr, value)); err != nil {
+			return nil, err
 		} else if retVals != nil {
 			// The assignment expression isn't supposed to return values, but we got them.
 			// That indicates something went wrong (e.g. panic).
@@ -2899,14 +4191,11 @@ func (s *Session) onSetVariableRequest(request *dap.SetVariableRequest) {
 			if len(r) > 0 {
 				msg = "interrupted:" + strings.Join(r, ", ")
 			}
-
-			s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to set variable", msg)
-			return
+			return nil, errors.New(msg)
 		}
 	} else {
-		if err := s.debugger.SetVariableInScope(int64(goid), frame, 0, evaluateName, arg.Value); err != nil {
-			s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to set variable", err.Error())
-			return
+		if err := s.debugger.SetVariableInScope(int64(goid), frame, 0, expr, value); err != nil {
+			return nil, err
 		}
 	}
 	// * Note on inconsistent state after set variable:
@@ -2926,29 +4215,794 @@ func (s *Session) onSetVariableRequest(request *dap.SetVariableRequest) {
 	// invalidate this state hoping that the editors will refetch the state
 	// as soon as the user resumes debugging.
 
+	return s.debugger.EvalVariableInScope(int64(goid), frame, 0, expr, DefaultLoadConfig)
+}
+
+// valueNeedsAllocation returns true if the expression value contains a
+// function call or a composite literal, which can only be evaluated with an
+// injected function call.
+func valueNeedsAllocation(value string) bool {
+	expr, err := parser.ParseExpr(value)
+	if err != nil {
+		return false
+	}
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.CallExpr, *ast.CompositeLit:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// onSetVariableRequest handles 'setVariable' requests.
+// Capability 'supportsSetVariable' is set in 'initialize' response.
+// The response contains the new value of the variable.
+func (s *Session) onSetVariableRequest(request *dap.SetVariableRequest) {
+	arg := request.Arguments
+
+	v, ok := s.variableHandles.get(arg.VariablesReference)
+	if !ok {
+		s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to lookup variable", fmt.Sprintf("unknown reference %d", arg.VariablesReference))
+		return
+	}
+	// We need to translate the arg.Name to its evaluateName if the name
+	// refers to a field or element of a variable.
+	// https://github.com/microsoft/vscode/issues/120774
+	evaluateName, err := s.computeEvaluateName(v, arg.Name)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to set variable", err.Error())
+		return
+	}
+
+	// Variables are set in the frame they were loaded from, the topmost
+	// frame of the current goroutine if it is not known.
+	goid, frame := -1, 0
+	if v.frame != nil {
+		goid, frame = v.frame.goroutineID, v.frame.frameIndex
+	}
+	newv, err := s.setExpression(goid, frame, evaluateName, arg.Value)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to set variable", err.Error())
+		return
+	}
+
 	response := &dap.SetVariableResponse{Response: *newResponse(request.Request)}
-	response.Body.Value = arg.Value
-	// TODO(hyangah): instead of arg.Value, reload the variable and return
-	// the presentation of the new value.
+	value, ref := s.convertVariable(newv, evaluateName)
+	s.variableHandles.setFrame(ref, &stackFrame{goid, frame})
+	response.Body = dap.SetVariableResponseBody{
+		Value:              value,
+		Type:               s.getTypeIfSupported(newv),
+		VariablesReference: ref,
+		IndexedVariables:   getIndexedVariableCount(newv),
+		NamedVariables:     getNamedVariableCount(newv),
+	}
+	s.send(response)
+}
+
+// onCompletionsRequest handles 'completions' requests.
+// Capability 'supportsCompletionsRequest' is set in 'initialize' response.
+// Completes the word before the cursor with:
+//   - the names of dlv commands, after "dlv " or "dlv help ";
+//   - the names of fields and methods, after "<expression>.";
+//   - the names of package variables, after "<package>.";
+//   - the names of local variables and of the package variables of the
+//     current package otherwise.
+//
+// Errors evaluating the text result in no targets rather than an error.
+func (s *Session) onCompletionsRequest(request *dap.CompletionsRequest) {
+	response := &dap.CompletionsResponse{Response: *newResponse(request.Request)}
+	response.Body.Targets = []dap.CompletionItem{}
+
+	goid, frame := -1, 0
+	if sf, ok := s.stackFrameHandles.get(request.Arguments.FrameId); ok {
+		goid = sf.(stackFrame).goroutineID
+		frame = sf.(stackFrame).frameIndex
+	}
+
+	text := completionsPrefix(request.Arguments.Text, request.Arguments.Line, request.Arguments.Column)
+
+	var (
+		word    string
+		targets []dap.CompletionItem
+	)
+	if m := dlvCommandPrefixRe.FindStringSubmatch(text); m != nil {
+		word = m[1]
+		targets = s.commandCompletions(word)
+	} else {
+		expr := trailingExpression(text)
+		word = expr[strings.LastIndex(expr, ".")+1:]
+		if s.debugger != nil {
+			targets = s.expressionCompletions(goid, frame, expr)
+		}
+	}
+
+	// All targets replace the word before the cursor.
+	start := len(utf16.Encode([]rune(text[:len(text)-len(word)]))) + 1
+	length := len(utf16.Encode([]rune(word)))
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Label < targets[j].Label })
+	for i := range targets {
+		if i > 0 && targets[i].Label == targets[i-1].Label {
+			continue
+		}
+		targets[i].Start = start
+		targets[i].Length = length
+		response.Body.Targets = append(response.Body.Targets, targets[i])
+	}
 	s.send(response)
 }
 
-// onSetExpression sends a not-yet-implemented error response.
-// Capability 'supportsSetExpression' is not set 'initialize' response.
+// dlvCommandPrefixRe matches text that ends with the name of a dlv
+// command being typed, the name is captured.
+var dlvCommandPrefixRe = regexp.MustCompile(`^\s*dlv\s+(?:(?:help|h)\s+)?(\w*)$`)
+
+// completionsPrefix returns the part of the line of text before column,
+// line and column are 1-based and measured in UTF-16 code units as
+// specified by the DAP, zero means the last line and the end of the line.
+func completionsPrefix(text string, line, column int) string {
+	lines := strings.Split(text, "\n")
+	if line <= 0 || line > len(lines) {
+		line = len(lines)
+	}
+	units := utf16.Encode([]rune(lines[line-1]))
+	if column > 0 && column-1 < len(units) {
+		units = units[:column-1]
+	}
+	return string(utf16.Decode(units))
+}
+
+// trailingExpression returns the selector expression at the end of text,
+// for example "cfg.Servers[i].Na" for "x + cfg.Servers[i].Na".
+func trailingExpression(text string) string {
+	isIdent := func(r byte) bool {
+		return r == '_' || r == '.' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r >= 0x80
+	}
+	i := len(text)
+	for i > 0 {
+		switch c := text[i-1]; {
+		case isIdent(c):
+			i--
+		case c == ']' || c == ')':
+			// Skip to the matching bracket.
+			depth := 0
+			j := i - 1
+			for ; j >= 0; j-- {
+				switch text[j] {
+				case ']', ')':
+					depth++
+				case '[', '(':
+					depth--
+				}
+				if depth == 0 {
+					break
+				}
+			}
+			if j < 0 {
+				return text[i:]
+			}
+			i = j
+		default:
+			return text[i:]
+		}
+	}
+	return text
+}
+
+// expressionCompletions returns the completions for the last word of expr.
+func (s *Session) expressionCompletions(goid, frame int, expr string) []dap.CompletionItem {
+	var targets []dap.CompletionItem
+	dot := strings.LastIndex(expr, ".")
+	if dot < 0 {
+		args, _ := s.debugger.FunctionArguments(int64(goid), frame, 0, proc.LoadConfig{})
+		locals, _ := s.debugger.LocalVariables(int64(goid), frame, 0, proc.LoadConfig{})
+		for _, v := range append(args, locals...) {
+			if strings.HasPrefix(v.Name, expr) {
+				targets = append(targets, dap.CompletionItem{Label: v.Name, Type: "variable"})
+			}
+		}
+		if fn, _ := s.debugger.Function(int64(goid), frame, 0, proc.LoadConfig{}); fn != nil {
+			pkg := fn.PackageName()
+			globals, _ := s.debugger.PackageVariableNames("^" + regexp.QuoteMeta(pkg+"."+expr))
+			for _, global := range globals {
+				if name := strings.TrimPrefix(global, pkg+"."); !strings.Contains(name, ".") {
+					targets = append(targets, dap.CompletionItem{Label: name, Type: "variable"})
+				}
+			}
+		}
+		return targets
+	}
+
+	base, word := expr[:dot], expr[dot+1:]
+	v, err := s.debugger.EvalVariableInScope(int64(goid), frame, 0, base, proc.LoadConfig{})
+	if err != nil {
+		// The base could be the name of a package, variables are named
+		// after the import path of their package.
+		paths := []string{base}
+		for _, path := range s.debugger.Target().BinInfo().PackageMap[base] {
+			if path != base {
+				paths = append(paths, path)
+			}
+		}
+		for _, path := range paths {
+			globals, _ := s.debugger.PackageVariableNames("^" + regexp.QuoteMeta(path+"."+word))
+			for _, global := range globals {
+				if name := strings.TrimPrefix(global, path+"."); !strings.Contains(name, ".") {
+					targets = append(targets, dap.CompletionItem{Label: name, Type: "variable"})
+				}
+			}
+		}
+		return targets
+	}
+	if v.Unreadable != nil || v.DwarfType == nil {
+		return nil
+	}
+
+	typ := v.DwarfType
+	if ptr, ok := resolveTypedef(typ).(*godwarf.PtrType); ok {
+		typ = ptr.Type
+	}
+	if st, ok := resolveTypedef(typ).(*godwarf.StructType); ok {
+		for _, name := range structFieldNames(st, 0) {
+			if strings.HasPrefix(name, word) {
+				targets = append(targets, dap.CompletionItem{Label: name, Type: "field"})
+			}
+		}
+	}
+	for _, name := range s.methodNames(typ.Common().Name) {
+		if strings.HasPrefix(name, word) {
+			targets = append(targets, dap.CompletionItem{Label: name, Type: "method"})
+		}
+	}
+	return targets
+}
+
+func resolveTypedef(typ godwarf.Type) godwarf.Type {
+	for {
+		td, ok := typ.(*godwarf.TypedefType)
+		if !ok {
+			return typ
+		}
+		typ = td.Type
+	}
+}
+
+// structFieldNames returns the names of the fields of st, including the
+// fields promoted from embedded structs.
+func structFieldNames(st *godwarf.StructType, depth int) []string {
+	const maxEmbeddingDepth = 5
+	var names []string
+	for _, field := range st.Field {
+		names = append(names, field.Name)
+		if !field.Embedded || depth >= maxEmbeddingDepth {
+			continue
+		}
+		typ := resolveTypedef(field.Type)
+		if ptr, ok := typ.(*godwarf.PtrType); ok {
+			typ = resolveTypedef(ptr.Type)
+		}
+		if est, ok := typ.(*godwarf.StructType); ok {
+			names = append(names, structFieldNames(est, depth+1)...)
+		}
+	}
+	return names
+}
+
+// methodNames returns the names of the methods of the named type typename
+// that are present in the binary, with value or pointer receivers.
+func (s *Session) methodNames(typename string) []string {
+	dot := strings.LastIndex(typename, ".")
+	if dot < 0 || strings.ContainsAny(typename, "[*") {
+		return nil
+	}
+	pkg, name := regexp.QuoteMeta(typename[:dot]), regexp.QuoteMeta(typename[dot+1:])
+	fns, err := s.debugger.Functions(fmt.Sprintf(`^%s\.(%s|\(\*%s\))\.[^.]+$`, pkg, name, name))
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(fns))
+	for _, fn := range fns {
+		names = append(names, fn[strings.LastIndex(fn, ".")+1:])
+	}
+	return names
+}
+
+// onSetExpressionRequest handles 'setExpression' requests.
+// Capability 'supportsSetExpression' is set in 'initialize' response.
+// The expression must be assignable, for example a variable, a field, an
+// element of a map, slice or array or a dereferenced pointer. It is
+// evaluated in the selected frame, the topmost frame of the current
+// goroutine if no frame is specified. The response contains the new value
+// of the expression.
 func (s *Session) onSetExpressionRequest(request *dap.SetExpressionRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	goid, frame := -1, 0
+	if sf, ok := s.stackFrameHandles.get(request.Arguments.FrameId); ok {
+		goid = sf.(stackFrame).goroutineID
+		frame = sf.(stackFrame).frameIndex
+	}
+
+	expr := request.Arguments.Expression
+	v, err := s.setExpression(goid, frame, expr, request.Arguments.Value)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToSetExpression, "Unable to set expression", err.Error())
+		return
+	}
+
+	response := &dap.SetExpressionResponse{Response: *newResponse(request.Request)}
+	value, ref := s.convertVariable(v, fmt.Sprintf("(%s)", expr))
+	s.variableHandles.setFrame(ref, &stackFrame{goid, frame})
+	response.Body = dap.SetExpressionResponseBody{
+		Value:              value,
+		Type:               s.getTypeIfSupported(v),
+		VariablesReference: ref,
+		IndexedVariables:   getIndexedVariableCount(v),
+		NamedVariables:     getNamedVariableCount(v),
+	}
+	s.send(response)
 }
 
-// onLoadedSourcesRequest sends a not-yet-implemented error response.
-// Capability 'supportsLoadedSourcesRequest' is not set 'initialize' response.
+// onLoadedSourcesRequest handles 'loadedSources' requests.
+// Capability 'supportsLoadedSourcesRequest' is set in 'initialize' response.
+// The sources are the files listed in the line tables of the executable
+// and of the dynamic libraries loaded so far.
 func (s *Session) onLoadedSourcesRequest(request *dap.LoadedSourcesRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	response := &dap.LoadedSourcesResponse{Response: *newResponse(request.Request)}
+	response.Body.Sources = []dap.Source{}
+	sources, err := s.loadedSourceFiles()
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToListSources, "Unable to list loaded sources", err.Error())
+		return
+	}
+	for _, path := range sources {
+		response.Body.Sources = append(response.Body.Sources, s.loadedSource(path))
+	}
+	s.send(response)
+}
+
+// loadedSourceFiles returns the source files of the target, leaving out
+// placeholders like "<autogenerated>".
+func (s *Session) loadedSourceFiles() ([]string, error) {
+	files, err := s.debugger.Sources("")
+	if err != nil {
+		return nil, err
+	}
+	sources := files[:0]
+	for _, file := range files {
+		if file != "" && !strings.HasPrefix(file, "<") {
+			sources = append(sources, file)
+		}
+	}
+	return sources, nil
+}
+
+func (s *Session) loadedSource(path string) dap.Source {
+	clientPath := s.toClientPath(path)
+	return dap.Source{Name: filepath.Base(clientPath), Path: clientPath}
+}
+
+// onSourceRequest handles 'source' requests.
+// The contents of sources that are not on disk, for example because the
+// binary was built on another machine, are looked for with debuginfod, in
+// the module cache and in GOROOT and finally in the debug information.
+func (s *Session) onSourceRequest(request *dap.SourceRequest) {
+	ref := request.Arguments.SourceReference
+	if request.Arguments.Source != nil && request.Arguments.Source.SourceReference > 0 {
+		ref = request.Arguments.Source.SourceReference
+	}
+	var path string
+	switch {
+	case ref > 0:
+		var ok bool
+		path, ok = s.sourcePaths[ref]
+		if !ok {
+			s.sendErrorResponse(request.Request, UnableToGetSource, "Unable to get source", fmt.Sprintf("unknown source reference %d", ref))
+			return
+		}
+	case request.Arguments.Source != nil && request.Arguments.Source.Path != "":
+		path = s.toServerPath(request.Arguments.Source.Path)
+	default:
+		s.sendErrorResponse(request.Request, UnableToGetSource, "Unable to get source", "no source specified")
+		return
+	}
+	content, err := s.sourceContents(path)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToGetSource, "Unable to get source", err.Error())
+		return
+	}
+	s.send(&dap.SourceResponse{Response: *newResponse(request.Request), Body: dap.SourceResponseBody{Content: content}})
+}
+
+// sourceReference returns the reference the client must use to retrieve
+// the contents of path with a source request, or 0 if the client can read
+// path from disk.
+func (s *Session) sourceReference(path string) int {
+	if s.toClientPath(path) != path {
+		// The substitutePath rules tell the client where the file is.
+		return 0
+	}
+	if filepath.IsAbs(path) {
+		exists, ok := s.sourceExists[path]
+		if !ok {
+			_, err := os.Stat(path)
+			exists = err == nil
+			if s.sourceExists == nil {
+				s.sourceExists = make(map[string]bool)
+			}
+			s.sourceExists[path] = exists
+		}
+		if exists {
+			return 0
+		}
+	}
+	if ref, ok := s.sourceReferences[path]; ok {
+		return ref
+	}
+	if s.sourceReferences == nil {
+		s.sourceReferences = make(map[string]int)
+		s.sourcePaths = make(map[int]string)
+	}
+	ref := len(s.sourceReferences) + 1
+	s.sourceReferences[path] = ref
+	s.sourcePaths[ref] = path
+	return ref
+}
+
+// sourceContents returns the contents of the source file path. If path
+// does not exist, which is usually the case for binaries built on other
+// machines and for -trimpath builds, the file is retrieved with
+// debuginfod, it is looked for in the module cache and, if the binary
+// was built with the same version of Go, in GOROOT. Lastly the contents
+// embedded in the DWARF 5 line table are used.
+func (s *Session) sourceContents(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		if buf, err := os.ReadFile(path); err == nil {
+			return string(buf), nil
+		}
+	}
+	if buildID := s.debugger.BuildID(); buildID != "" {
+		if found, err := debuginfod.GetSource(buildID, path); err == nil {
+			if buf, err := os.ReadFile(found); err == nil {
+				return string(buf), nil
+			}
+		}
+	}
+	var candidates []string
+	if modcache := s.goEnv("GOMODCACHE"); modcache != "" {
+		if found := moduleCachePath(path, modcache); found != "" {
+			candidates = append(candidates, found)
+		}
+	}
+	if goroot := s.localGoroot(); goroot != "" {
+		if found := gorootPath(path, s.targetGoroot(), goroot); found != "" {
+			candidates = append(candidates, found)
+		}
+	}
+	for _, found := range candidates {
+		if buf, err := os.ReadFile(found); err == nil {
+			return string(buf), nil
+		}
+	}
+	if src, ok := s.debugger.EmbeddedSource(path); ok {
+		return src, nil
+	}
+	return "", fmt.Errorf("could not find %s", path)
+}
+
+// localGoroot returns the GOROOT of the go command, if it is the same
+// version of Go that built the target, an empty string otherwise.
+func (s *Session) localGoroot() string {
+	ver := goversion.ParseProducer(s.debugger.TargetGoVersion())
+	if ver.Major <= 0 {
+		return ""
+	}
+	if installed, ok := goversion.Installed(); !ok || installed != ver {
+		return ""
+	}
+	return s.goEnv("GOROOT")
+}
+
+// targetGoroot returns the GOROOT of the source files of the standard
+// library in the debug information of the target, including the trailing
+// src directory. It is empty for binaries built with -trimpath.
+func (s *Session) targetGoroot() string {
+	for _, pkg := range s.debugger.ListPackagesBuildInfo(false) {
+		if pkg.ImportPath == "runtime" {
+			return strings.TrimSuffix(pkg.DirectoryPath, "runtime")
+		}
+	}
+	return ""
 }
 
-// onReadMemoryRequest sends a not-yet-implemented error response.
-// Capability 'supportsReadMemoryRequest' is not set 'initialize' response.
+// moduleCachePath returns the path in the module cache modcache of the
+// source file path, as it appears in the debug information, of a module
+// dependency. It returns an empty string if path does not belong to the
+// module cache.
+func moduleCachePath(path, modcache string) string {
+	const modDir = "/pkg/mod/"
+	if i := strings.LastIndex(path, modDir); i >= 0 {
+		return filepath.Join(modcache, filepath.FromSlash(path[i+len(modDir):]))
+	}
+	if !strings.HasPrefix(path, "/") && strings.Contains(path, "@") {
+		// Built with -trimpath, the path starts with the module path and
+		// version.
+		return filepath.Join(modcache, filepath.FromSlash(path))
+	}
+	return ""
+}
+
+// gorootPath returns the path in the src directory of goroot of the
+// source file path, as it appears in the debug information, if it is
+// under targetGoroot. See targetGoroot.
+func gorootPath(path, targetGoroot, goroot string) string {
+	if targetGoroot == "" && (strings.HasPrefix(path, "/") || filepath.IsAbs(path)) {
+		return ""
+	}
+	if !strings.HasPrefix(path, targetGoroot) {
+		return ""
+	}
+	return filepath.Join(goroot, "src", filepath.FromSlash(path[len(targetGoroot):]))
+}
+
+// goEnv returns goEnv(name), running the go command once per variable in
+// the session.
+func (s *Session) goEnv(name string) string {
+	if v, ok := s.goEnvValues[name]; ok {
+		return v
+	}
+	v := goEnv(name)
+	if s.goEnvValues == nil {
+		s.goEnvValues = make(map[string]string)
+	}
+	s.goEnvValues[name] = v
+	return v
+}
+
+// goEnv returns the value of the go environment variable name, as reported
+// by 'go env'.
+func goEnv(name string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	out, err := exec.Command("go", "env", name).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// moduleColumns describes the Delve-specific attributes of Module
+// displayed in the modules view.
+var moduleColumns = []dap.ColumnDescriptor{{AttributeName: "buildId", Label: "Build ID"}}
+
+// onModulesRequest handles 'modules' requests.
+// Capability 'supportsModulesRequest' is set in 'initialize' response.
+// The modules are the executable, which is always the first, and the
+// dynamic libraries loaded so far.
+func (s *Session) onModulesRequest(request *dap.ModulesRequest) {
+	images := s.images()
+	response := &ModulesResponse{Response: *newResponse(request.Request)}
+	response.Body.Modules = []Module{}
+	response.Body.TotalModules = len(images)
+	start := request.Arguments.StartModule
+	if start < 0 {
+		start = 0
+	}
+	end := len(images)
+	if count := request.Arguments.ModuleCount; count > 0 && start+count < end {
+		end = start + count
+	}
+	for i := start; i < end; i++ {
+		response.Body.Modules = append(response.Body.Modules, convertModule(i, images[i]))
+	}
+	s.send(response)
+}
+
+// images returns the executable of the target followed by its dynamic
+// libraries.
+func (s *Session) images() []*proc.Image {
+	exe := s.debugger.Target().BinInfo().Images[0]
+	return append([]*proc.Image{exe}, s.debugger.ListDynamicLibraries()...)
+}
+
+func convertModule(id int, image *proc.Image) Module {
+	m := Module{
+		Module: dap.Module{
+			Id:         id,
+			Name:       filepath.Base(image.Path),
+			Path:       image.Path,
+			IsUserCode: id == 0,
+		},
+		BuildID: image.BuildID,
+	}
+	if start, end := image.AddressRange(); end != 0 {
+		m.AddressRange = fmt.Sprintf("%#x-%#x", start, end)
+	}
+	switch {
+	case image.Stripped():
+		m.SymbolStatus = "Symbols not found."
+	case image.LoadError() != nil:
+		m.SymbolStatus = fmt.Sprintf("Symbols partially loaded: %v.", image.LoadError())
+	case image.DebugInfoDownloaded:
+		m.SymbolStatus = "Symbols downloaded with debuginfod."
+	default:
+		m.SymbolStatus = "Symbols loaded."
+	}
+	if !image.Stripped() {
+		m.SymbolFilePath = image.DebugInfoPath
+	}
+	return m
+}
+
+// updateLoadedAfterRestart updates the images and source files the client
+// knows about after the target was restarted: the dynamic libraries of the
+// old process are removed and, if the program was rebuilt, the executable
+// has changed. oldImages are the images of the target before the restart.
+func (s *Session) updateLoadedAfterRestart(oldImages []*proc.Image, rebuilt bool) {
+	for i := 1; i < s.loadedImages && i < len(oldImages); i++ {
+		s.send(&ModuleEvent{Event: *newEvent("module"), Body: ModuleEventBody{Reason: "removed", Module: convertModule(i, oldImages[i])}})
+	}
+	s.loadedImages = 1
+	if rebuilt {
+		s.send(&ModuleEvent{Event: *newEvent("module"), Body: ModuleEventBody{Reason: "changed", Module: convertModule(0, s.images()[0])}})
+		// The source files of the old executable are not removed, they
+		// can still be opened.
+		s.updateLoadedSources(true)
+	}
+	s.updateLoaded(true)
+}
+
+// updateLoaded records the images and source files of the target. If
+// notify is true a module event is sent for each image, and a
+// loadedSource event for each source file, added since the last call.
+func (s *Session) updateLoaded(notify bool) {
+	images := s.images()
+	if len(images) == s.loadedImages {
+		return
+	}
+	if notify {
+		for i := s.loadedImages; i < len(images); i++ {
+			s.send(&ModuleEvent{Event: *newEvent("module"), Body: ModuleEventBody{Reason: "new", Module: convertModule(i, images[i])}})
+		}
+	}
+	s.loadedImages = len(images)
+	s.updateLoadedSources(notify)
+}
+
+// updateLoadedSources records the source files of the target. If notify
+// is true a loadedSource event is sent for each source file added since
+// the last call.
+func (s *Session) updateLoadedSources(notify bool) {
+	sources, err := s.loadedSourceFiles()
+	if err != nil {
+		s.config.log.Errorf("Error listing loaded sources: %v", err)
+		return
+	}
+	if s.loadedSources == nil {
+		s.loadedSources = make(map[string]bool, len(sources))
+	}
+	for _, path := range sources {
+		if s.loadedSources[path] {
+			continue
+		}
+		s.loadedSources[path] = true
+		if notify {
+			s.send(&dap.LoadedSourceEvent{Event: *newEvent("loadedSource"), Body: dap.LoadedSourceEventBody{Reason: "new", Source: s.loadedSource(path)}})
+		}
+	}
+}
+
+// onReadMemoryRequest handles 'readMemory' requests.
+// Capability 'supportsReadMemoryRequest' is set in 'initialize' response.
 func (s *Session) onReadMemoryRequest(request *dap.ReadMemoryRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	addr, err := parseMemoryReference(request.Arguments.MemoryReference, request.Arguments.Offset)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToReadMemory, "Unable to read memory", err.Error())
+		return
+	}
+	count := request.Arguments.Count
+	if count < 0 {
+		s.sendErrorResponse(request.Request, UnableToReadMemory, "Unable to read memory", fmt.Sprintf("invalid count %d", count))
+		return
+	}
+	// Bytes past maxReadMemoryCount are reported as unreadable, the client
+	// can request them separately.
+	n := count
+	if n > maxReadMemoryCount {
+		n = maxReadMemoryCount
+	}
+
+	data, err := s.debugger.ExamineMemory(addr, n)
+	if err != nil {
+		// Return the readable part of the requested range, the client
+		// is told how many bytes after it could not be read.
+		data = s.readMemoryPrefix(addr, n)
+	}
+
+	response := &dap.ReadMemoryResponse{Response: *newResponse(request.Request)}
+	response.Body.Address = fmt.Sprintf("%#x", addr)
+	response.Body.Data = base64.StdEncoding.EncodeToString(data)
+	response.Body.UnreadableBytes = count - len(data)
+	s.send(response)
+}
+
+// maxReadMemoryCount is the maximum number of bytes returned by a single
+// 'readMemory' request.
+const maxReadMemoryCount = 1 << 20
+
+// memoryPageSize is the granularity used to find the readable prefix of a
+// memory range.
+const memoryPageSize = 0x1000
+
+// readMemoryPrefix reads count bytes starting at addr, one page at a time,
+// and stops at the first page that can not be read.
+func (s *Session) readMemoryPrefix(addr uint64, count int) []byte {
+	data := []byte{}
+	for len(data) < count {
+		cur := addr + uint64(len(data))
+		n := int(memoryPageSize - cur%memoryPageSize)
+		if rem := count - len(data); n > rem {
+			n = rem
+		}
+		chunk, err := s.debugger.ExamineMemory(cur, n)
+		if err != nil {
+			break
+		}
+		data = append(data, chunk...)
+	}
+	return data
+}
+
+// onWriteMemoryRequest handles 'writeMemory' requests.
+// Capability 'supportsWriteMemoryRequest' is set in 'initialize' response.
+func (s *Session) onWriteMemoryRequest(request *dap.WriteMemoryRequest) {
+	addr, err := parseMemoryReference(request.Arguments.MemoryReference, request.Arguments.Offset)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToWriteMemory, "Unable to write memory", err.Error())
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(request.Arguments.Data)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToWriteMemory, "Unable to write memory", err.Error())
+		return
+	}
+
+	n, err := s.debugger.WriteMemory(addr, data)
+	if err == nil && n < len(data) {
+		err = fmt.Errorf("only %d of %d bytes written", n, len(data))
+	}
+	if err != nil && (!request.Arguments.AllowPartial || n == 0) {
+		s.sendErrorResponse(request.Request, UnableToWriteMemory, "Unable to write memory", err.Error())
+		return
+	}
+
+	response := &dap.WriteMemoryResponse{Response: *newResponse(request.Request)}
+	response.Body.BytesWritten = n
+	s.send(response)
+
+	// Memory views and variables showing the written bytes are stale now.
+	if s.clientCapabilities.supportsMemoryEvent {
+		s.send(&dap.MemoryEvent{Event: *newEvent("memory"), Body: dap.MemoryEventBody{
+			MemoryReference: request.Arguments.MemoryReference,
+			Offset:          request.Arguments.Offset,
+			Count:           n,
+		}})
+	}
+	if s.clientCapabilities.supportsInvalidatedEvent {
+		s.send(&dap.InvalidatedEvent{Event: *newEvent("invalidated"), Body: dap.InvalidatedEventBody{
+			Areas: []dap.InvalidatedAreas{"variables"},
+		}})
+	}
+}
+
+// parseMemoryReference returns the address of the byte at offset from the
+// memory reference ref, as set in the memoryReference field of variables.
+func parseMemoryReference(ref string, offset int) (uint64, error) {
+	addr, err := strconv.ParseUint(ref, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory reference %q", ref)
+	}
+	return addr + uint64(offset), nil
 }
 
 var invalidInstruction = dap.DisassembledInstruction{
@@ -3173,10 +5227,155 @@ func checkOutOfAddressSpace(pc uint64, bi *proc.BinaryInfo) (bool, uint64) {
 	return false, pc
 }
 
-// onCancelRequest sends a not-yet-implemented error response.
-// Capability 'supportsCancelRequest' is not set 'initialize' response.
+// cancellableRequests maps the commands of the requests that can be
+// cancelled to the title of the progress reported while they are handled.
+var cancellableRequests = map[string]string{
+	"threads":    "Loading goroutines",
+	"stackTrace": "Loading stack trace",
+	"variables":  "Loading variables",
+	"evaluate":   "Evaluating expression",
+}
+
+// progressDelay is how long a cancellable request runs before a
+// progressStart event is sent for it.
+var progressDelay = 500 * time.Millisecond
+
+// addCancellable registers request, if it can be cancelled, so that a
+// cancel request can interrupt it while it is queued or being handled.
+func (s *Session) addCancellable(request dap.Message) {
+	r, ok := request.(dap.RequestMessage)
+	if !ok {
+		return
+	}
+	if _, ok := cancellableRequests[r.GetRequest().Command]; !ok {
+		return
+	}
+	s.cancelMu.Lock()
+	defer s.cancelMu.Unlock()
+	if s.cancels == nil {
+		s.cancels = make(map[int]chan struct{})
+	}
+	s.cancels[r.GetRequest().Seq] = make(chan struct{})
+}
+
+// cancelChannel returns the channel closed when the request with
+// sequence number seq is cancelled, nil if it can not be cancelled.
+func (s *Session) cancelChannel(seq int) chan struct{} {
+	s.cancelMu.Lock()
+	defer s.cancelMu.Unlock()
+	return s.cancels[seq]
+}
+
+// removeCancellable is called when the request with sequence number seq
+// has been handled.
+func (s *Session) removeCancellable(seq int) {
+	s.cancelMu.Lock()
+	defer s.cancelMu.Unlock()
+	delete(s.cancels, seq)
+}
+
+// isCancelled returns true if the request being handled was cancelled.
+func (s *Session) isCancelled() bool {
+	select {
+	case <-s.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// loadConfig returns DefaultLoadConfig set to stop loading when the
+// request being handled is cancelled.
+func (s *Session) loadConfig() proc.LoadConfig {
+	cfg := DefaultLoadConfig
+	cfg.Cancel = s.cancel
+	return cfg
+}
+
+// startProgress sends a progressStart event for request, if the client
+// supports progress reporting and the request is still being handled after
+// progressDelay. The returned function must be called when the request has
+// been handled, it sends the matching progressEnd event.
+// The progress id is the sequence number of the request.
+func (s *Session) startProgress(request dap.Request) func() {
+	if !s.clientCapabilities.supportsProgressReporting {
+		return func() {}
+	}
+	progressID := strconv.Itoa(request.Seq)
+	var mu sync.Mutex
+	started, ended := false, false
+	timer := time.AfterFunc(progressDelay, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if ended {
+			return
+		}
+		started = true
+		s.send(&dap.ProgressStartEvent{
+			Event: *newEvent("progressStart"),
+			Body: dap.ProgressStartEventBody{
+				ProgressId:  progressID,
+				Title:       cancellableRequests[request.Command],
+				RequestId:   request.Seq,
+				Cancellable: true,
+			},
+		})
+	})
+	return func() {
+		timer.Stop()
+		mu.Lock()
+		defer mu.Unlock()
+		ended = true
+		if started {
+			s.send(&dap.ProgressEndEvent{
+				Event: *newEvent("progressEnd"),
+				Body:  dap.ProgressEndEventBody{ProgressId: progressID},
+			})
+		}
+	}
+}
+
+// onCancelRequest handles 'cancel' requests.
+// This is an optional request enabled by capability ‘supportsCancelRequest’.
+// It is handled by the goroutine reading requests as soon as it is read,
+// threads, stackTrace, variables and evaluate requests that are queued or
+// being handled can be cancelled, either by their sequence number or by
+// the id of the progress reported for them. A cancelled request responds
+// with a 'cancelled' error. Cancelling an evaluate request that calls a
+// function halts the program.
 func (s *Session) onCancelRequest(request *dap.CancelRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	defer s.recoverPanic(request)
+	jsonmsg, _ := json.Marshal(request)
+	s.config.log.Debug("[<- from client]", string(jsonmsg))
+
+	if args := request.Arguments; args != nil {
+		seq := args.RequestId
+		if args.ProgressId != "" {
+			seq, _ = strconv.Atoi(args.ProgressId)
+		}
+		s.cancelMu.Lock()
+		if cancel := s.cancels[seq]; cancel != nil {
+			select {
+			case <-cancel:
+			default:
+				close(cancel)
+			}
+		}
+		s.cancelMu.Unlock()
+	}
+	s.send(&dap.CancelResponse{Response: *newResponse(request.Request)})
+}
+
+// sendCancelledResponse sends the error response of a cancelled request.
+func (s *Session) sendCancelledResponse(request dap.Request) {
+	er := &dap.ErrorResponse{}
+	er.Type = "response"
+	er.Command = request.Command
+	er.RequestSeq = request.Seq
+	er.Success = false
+	er.Message = "cancelled"
+	s.config.log.Debugf("%s request %d cancelled", request.Command, request.Seq)
+	s.send(er)
 }
 
 // onExceptionInfoRequest handles 'exceptionInfo' requests.
@@ -3362,11 +5561,6 @@ func (s *Session) sendUnsupportedErrorResponse(request dap.Request) {
 		fmt.Sprintf("cannot process %q request", request.Command))
 }
 
-func (s *Session) sendNotYetImplementedErrorResponse(request dap.Request) {
-	s.sendErrorResponse(request, NotYetImplemented, "Not yet implemented",
-		fmt.Sprintf("cannot process %q request", request.Command))
-}
-
 func newResponse(request dap.Request) *dap.Response {
 	return &dap.Response{
 		ProtocolMessage: dap.ProtocolMessage{
@@ -3409,6 +5603,13 @@ func (s *Session) setRunningCmd(running bool) {
 	s.runningMu.Lock()
 	defer s.runningMu.Unlock()
 	s.runningCmd = running
+	switch {
+	case running && s.runningDone == nil:
+		s.runningDone = make(chan struct{})
+	case !running && s.runningDone != nil:
+		close(s.runningDone)
+		s.runningDone = nil
+	}
 }
 
 func (s *Session) isRunningCmd() bool {
@@ -3451,7 +5652,7 @@ func (s *Session) resumeOnce(command string, allowNextStateChange chan struct{})
 		state, err := s.debugger.State(false)
 		return false, state, err
 	}
-	state, err := s.debugger.Command(&api.DebuggerCommand{Name: command}, asyncSetupDone)
+	state, err := s.debugger.Command(&api.DebuggerCommand{Name: command, CallPC: s.stepIntoCallPC}, asyncSetupDone)
 	return true, state, err
 }
 
@@ -3470,6 +5671,7 @@ func (s *Session) runUntilStopAndNotify(command string, allowNextStateChange cha
 	}
 
 	if processExited(state, err) {
+		s.closeOutputPipes()
 		s.send(&dap.TerminatedEvent{Event: *newEvent("terminated")})
 		return
 	}
@@ -3481,6 +5683,7 @@ func (s *Session) runUntilStopAndNotify(command string, allowNextStateChange cha
 	}
 	s.config.log.Debugf("%q command stopped - reason %q, location %s:%d", command, stopReason, file, line)
 
+	s.updateLoaded(true)
 	s.resetHandlesForStoppedEvent()
 	stopped := &dap.StoppedEvent{Event: *newEvent("stopped")}
 	stopped.Body.AllThreadsStopped = true
@@ -3504,6 +5707,11 @@ func (s *Session) runUntilStopAndNotify(command string, allowNextStateChange cha
 			stopped.Body.Reason = "unknown"
 		case proc.StopWatchpoint:
 			stopped.Body.Reason = "data breakpoint"
+			goid, bp := s.stoppedOnBreakpointGoroutineID(state)
+			stopped.Body.ThreadId = int(goid)
+			if bp != nil {
+				stopped.Body.HitBreakpointIds = []int{bp.ID}
+			}
 		default:
 			stopped.Body.Reason = "breakpoint"
 			goid, bp := s.stoppedOnBreakpointGoroutineID(state)
@@ -3526,9 +5734,24 @@ func (s *Session) runUntilStopAndNotify(command string, allowNextStateChange cha
 					stopped.Body.Reason = "instruction breakpoint"
 				}
 				stopped.Body.HitBreakpointIds = []int{bp.ID}
+			} else if s.stoppedOnInternalBreakpoint() {
+				stopped.Body.Reason = s.internalBreakpointsReason
+				stopped.Body.ThreadId = int(stoppedGoroutineID(state))
 			}
 		}
 
+		// Watchpoints on stack variables are cleared when the variable goes
+		// out of scope, execution stops so that the user notices.
+		for _, bp := range state.WatchOutOfScope {
+			s.logToConsole(fmt.Sprintf("Data breakpoint on %s went out of scope and was cleared", bp.WatchExpr))
+			s.send(&dap.BreakpointEvent{
+				Event: *newEvent("breakpoint"),
+				Body:  dap.BreakpointEventBody{Reason: "removed", Breakpoint: dap.Breakpoint{Id: bp.ID}},
+			})
+			stopped.Body.Reason = "data breakpoint"
+			stopped.Body.Description = "out of scope"
+		}
+
 		// Override the stop reason if there was a manual stop request.
 		// TODO(suzmue): move this logic into the runUntilStop command
 		// so that the stop reason is determined by that function which
@@ -3607,8 +5830,10 @@ func (s *Session) resumeOnceAndCheckStop(command string, allowNextStateChange ch
 
 	switch s.debugger.StopReason() {
 	case proc.StopBreakpoint, proc.StopManual:
-		// Make sure a real manual stop was requested or a real breakpoint was hit.
-		if len(gsOnBp) > 0 || s.checkHaltRequested() {
+		// Make sure a real manual stop was requested, a real breakpoint was hit
+		// (including the internal breakpoints of goto and restartFrame) or a
+		// watchpoint went out of scope.
+		if len(gsOnBp) > 0 || len(state.WatchOutOfScope) > 0 || s.checkHaltRequested() || s.stoppedOnInternalBreakpoint() {
 			s.setRunningCmd(false)
 		}
 	default:
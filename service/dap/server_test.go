@@ -2,6 +2,9 @@ package dap
 
 import (
 	"bufio"
+	"debug/elf"
+	"encoding/base64"
+	"encoding/binary"
 	"flag"
 	"fmt"
 	"io"
@@ -24,11 +27,13 @@ import (
 	"github.com/undoio/delve/pkg/goversion"
 	"github.com/undoio/delve/pkg/logflags"
 	"github.com/undoio/delve/pkg/proc"
+	"github.com/undoio/delve/pkg/proc/gdbserial/undotest"
 	protest "github.com/undoio/delve/pkg/proc/test"
 	"github.com/undoio/delve/service"
 	"github.com/undoio/delve/service/api"
 	"github.com/undoio/delve/service/dap/daptest"
 	"github.com/undoio/delve/service/debugger"
+	"golang.org/x/arch/x86/x86asm"
 )
 
 const stopOnEntry bool = true
@@ -41,6 +46,9 @@ const globalsScope = 1001
 var testBackend string
 
 func TestMain(m *testing.M) {
+	if os.Getenv(undotest.ServerEnvVar) != "" {
+		os.Exit(undotest.ServerMain(os.Args[1:]))
+	}
 	logOutputVal := ""
 	if _, isTeamCityTest := os.LookupEnv("TEAMCITY_VERSION"); isTeamCityTest {
 		logOutputVal = "debugger,dap"
@@ -1947,6 +1955,50 @@ func TestScopesRequestsOptimized(t *testing.T) {
 		protest.EnableOptimization, false)
 }
 
+// TestVariablesPrettyPrinters tests that the values of variables are
+// formatted by the pretty printers of the debugger configuration.
+func TestVariablesPrettyPrinters(t *testing.T) {
+	fixture := protest.BuildFixture("prettyprinters", protest.AllNonOptimized)
+	serverStopped := make(chan struct{})
+	server, _ := startDAPServer(t, false, serverStopped)
+	server.config.Debugger.PrettyPrinters = []proc.PrettyPrinter{{Type: "main.ID", Expr: "v.prefix"}}
+	client := daptest.NewClient(server.config.Listener.Addr().String())
+	defer client.Close()
+
+	runDebugSessionWithBPs(t, client, "launch",
+		// Launch
+		func() {
+			client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+		},
+		// Breakpoints are set within the program
+		fixture.Source, []int{},
+		[]onBreakpoint{{
+			execute: func() {
+				client.StackTraceRequest(1, 0, 20)
+				client.ExpectStackTraceResponse(t)
+
+				client.ScopesRequest(1000)
+				client.ExpectScopesResponse(t)
+
+				client.VariablesRequest(localsScope)
+				locals := client.ExpectVariablesResponse(t)
+				checkVarExact(t, locals, -1, "id", "id", "main.ID(acct)", "main.ID", hasChildren)
+				ref := checkVarExact(t, locals, -1, "acct", "acct", `main.Account {id: main.ID(acct), balance: main.Money {units: 12, cents: 5}, owner: "alice"}`, "main.Account", hasChildren)
+
+				client.VariablesRequest(ref)
+				acct := client.ExpectVariablesResponse(t)
+				ref = checkVarExact(t, acct, 0, "id", "acct.id", "main.ID(acct)", "main.ID", hasChildren)
+
+				// The fields of values with a pretty printer can still be inspected.
+				client.VariablesRequest(ref)
+				id := client.ExpectVariablesResponse(t)
+				checkVarExact(t, id, 1, "n", "acct.id.n", "7", "int", noChildren)
+			},
+			disconnect: true,
+		}})
+	<-serverStopped
+}
+
 // TestVariablesLoading exposes test cases where variables might be partially or
 // fully unloaded.
 func TestVariablesLoading(t *testing.T) {
@@ -2860,6 +2912,93 @@ func TestSetInstructionBreakpoint(t *testing.T) {
 	})
 }
 
+func TestSetDataBreakpoints(t *testing.T) {
+	if runtime.GOOS == "freebsd" || runtime.GOOS == "windows" || runtime.GOARCH == "386" {
+		t.Skip("watchpoints not supported")
+	}
+	position1 := 17
+	if runtime.GOARCH == "arm64" {
+		position1 = 16
+	}
+	runTest(t, "databpstack", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{11}, // Position 0
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.f", 11)
+
+					// Data ids are returned for both locals and expressions
+					// evaluated in a frame.
+					client.DataBreakpointInfoRequest(localsScope, "w", 0)
+					info := client.ExpectDataBreakpointInfoResponse(t)
+					if info.Body.DataId != "1 0 w" || info.Body.Description != "w" || len(info.Body.AccessTypes) != 3 {
+						t.Errorf("\ngot  %#v\nwant DataId=\"1 0 w\" Description=\"w\" and 3 access types", info)
+					}
+					client.DataBreakpointInfoRequest(0, "w", 1000)
+					info = client.ExpectDataBreakpointInfoResponse(t)
+					if info.Body.DataId != "1 0 w" {
+						t.Errorf("\ngot  %#v\nwant DataId=\"1 0 w\"", info)
+					}
+					for _, expr := range []string{"w + 1", "nosuchvar"} {
+						client.DataBreakpointInfoRequest(0, expr, 1000)
+						info = client.ExpectDataBreakpointInfoResponse(t)
+						if info.Body.DataId != nil || info.Body.Description == "" {
+							t.Errorf("%s:\ngot  %#v\nwant DataId=nil and a description", expr, info)
+						}
+					}
+
+					// Stack variables can not be watched for reads.
+					client.SetDataBreakpointsRequest([]dap.DataBreakpoint{{DataId: "1 0 w", AccessType: "read"}})
+					got := client.ExpectSetDataBreakpointsResponse(t)
+					if len(got.Body.Breakpoints) != 1 || got.Body.Breakpoints[0].Verified || !strings.Contains(got.Body.Breakpoints[0].Message, "for reads") {
+						t.Errorf("\ngot  %#v\nwant one unverified breakpoint", got)
+					}
+
+					client.SetDataBreakpointsRequest([]dap.DataBreakpoint{{DataId: "1 0 w", AccessType: "write"}})
+					got = client.ExpectSetDataBreakpointsResponse(t)
+					if len(got.Body.Breakpoints) != 1 || !got.Body.Breakpoints[0].Verified || got.Body.Breakpoints[0].Source != nil {
+						t.Fatalf("\ngot  %#v\nwant one verified breakpoint without source", got)
+					}
+					id := got.Body.Breakpoints[0].Id
+
+					client.ContinueRequest(1)
+					client.ExpectContinueResponse(t)
+					se := client.ExpectStoppedEvent(t)
+					if se.Body.Reason != "data breakpoint" || len(se.Body.HitBreakpointIds) != 1 || se.Body.HitBreakpointIds[0] != id {
+						t.Errorf("\ngot  %#v\nwant Reason=\"data breakpoint\" HitBreakpointIds=[%d]", se, id)
+					}
+					checkStop(t, client, 1, "main.g", position1)
+
+					// The watchpoint is removed when f returns.
+					client.ContinueRequest(1)
+					client.ExpectContinueResponse(t)
+					client.ExpectOutputEventRegex(t, `Data breakpoint on w went out of scope and was cleared\n`)
+					be := client.ExpectBreakpointEvent(t)
+					if be.Body.Reason != "removed" || be.Body.Breakpoint.Id != id {
+						t.Errorf("\ngot  %#v\nwant Reason=\"removed\" Id=%d", be, id)
+					}
+					se = client.ExpectStoppedEvent(t)
+					if se.Body.Reason != "data breakpoint" {
+						t.Errorf("\ngot  %#v\nwant Reason=\"data breakpoint\"", se)
+					}
+					checkStop(t, client, 1, "main.main", 24)
+
+					client.SetDataBreakpointsRequest([]dap.DataBreakpoint{})
+					got = client.ExpectSetDataBreakpointsResponse(t)
+					if len(got.Body.Breakpoints) != 0 {
+						t.Errorf("\ngot  %#v\nwant no breakpoints", got)
+					}
+				},
+				disconnect: false,
+			}})
+	})
+}
+
 func TestPauseAtStop(t *testing.T) {
 	runTest(t, "loopprog", func(client *daptest.Client, fixture protest.Fixture) {
 		runDebugSessionWithBPs(t, client, "launch",
@@ -4473,6 +4612,111 @@ func TestNextAndStep(t *testing.T) {
 	})
 }
 
+func TestStepInTargets(t *testing.T) {
+	runTest(t, "stepintotargets", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{19},
+			[]onBreakpoint{{ // Stop at line 19
+				execute: func() {
+					checkStop(t, client, 1, "main.main", 19)
+
+					client.StepInTargetsRequest(1000)
+					got := client.ExpectStepInTargetsResponse(t)
+					labels := []string{}
+					for _, target := range got.Body.Targets {
+						labels = append(labels, target.Label)
+					}
+					if want := []string{"main.g", "main.h", "main.f"}; !reflect.DeepEqual(labels, want) {
+						t.Fatalf("got targets %q, want %q", labels, want)
+					}
+
+					// Only the topmost frame has targets.
+					client.StepInTargetsRequest(1001)
+					if got := client.ExpectStepInTargetsResponse(t); len(got.Body.Targets) != 0 {
+						t.Errorf("got targets %#v for frame 1001, want none", got.Body.Targets)
+					}
+
+					client.StepInTargetRequest(1, 42)
+					client.ExpectErrorResponseWith(t, UnableToListStepInTargets, "unknown step in target 42", false)
+
+					// Stepping into h steps over the call of g.
+					client.StepInTargetsRequest(1000)
+					got = client.ExpectStepInTargetsResponse(t)
+					client.StepInTargetRequest(1, got.Body.Targets[1].Id)
+					client.ExpectStepInResponse(t)
+					if se := client.ExpectStoppedEvent(t); se.Body.Reason != "step" || se.Body.ThreadId != 1 {
+						t.Errorf("got %#v, want Reason=\"step\", ThreadId=1", se)
+					}
+					checkStop(t, client, 1, "main.h", 9)
+
+					client.StepOutRequest(1)
+					client.ExpectStepOutResponse(t)
+					client.ExpectStoppedEvent(t)
+					checkStop(t, client, 1, "main.main", 19)
+
+					// The calls already made are not targets anymore.
+					client.StepInTargetsRequest(1000)
+					got = client.ExpectStepInTargetsResponse(t)
+					if len(got.Body.Targets) != 1 || got.Body.Targets[0].Label != "main.f" {
+						t.Fatalf("got targets %#v, want main.f", got.Body.Targets)
+					}
+					client.StepInTargetRequest(1, got.Body.Targets[0].Id)
+					client.ExpectStepInResponse(t)
+					client.ExpectStoppedEvent(t)
+					checkStop(t, client, 1, "main.f", 13)
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+func TestBreakpointLocations(t *testing.T) {
+	runTest(t, "stepintotargets", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{19},
+			[]onBreakpoint{{ // Stop at line 19
+				execute: func() {
+					checkStop(t, client, 1, "main.main", 19)
+
+					client.BreakpointLocationsRequest(fixture.Source, 1, 22)
+					got := client.ExpectBreakpointLocationsResponse(t)
+					lines := []int{}
+					for _, loc := range got.Body.Breakpoints {
+						lines = append(lines, loc.Line)
+					}
+					if want := []int{5, 6, 9, 10, 13, 14, 17, 18, 19, 20, 21}; !reflect.DeepEqual(lines, want) {
+						t.Errorf("got lines %v, want %v", lines, want)
+					}
+
+					client.BreakpointLocationsRequest(fixture.Source, 19, 0)
+					got = client.ExpectBreakpointLocationsResponse(t)
+					if len(got.Body.Breakpoints) != 1 || got.Body.Breakpoints[0].Line != 19 {
+						t.Errorf("got %#v, want line 19", got.Body.Breakpoints)
+					}
+
+					client.BreakpointLocationsRequest(fixture.Source, 3, 4)
+					if got := client.ExpectBreakpointLocationsResponse(t); len(got.Body.Breakpoints) != 0 {
+						t.Errorf("got %#v, want no locations", got.Body.Breakpoints)
+					}
+
+					client.BreakpointLocationsRequest("", 1, 2)
+					client.ExpectErrorResponseWith(t, UnableToListBreakpointLocations, "empty file path", false)
+				},
+				disconnect: true,
+			}})
+	})
+}
+
 func TestHardCodedBreakpoints(t *testing.T) {
 	runTest(t, "consts", func(client *daptest.Client, fixture protest.Fixture) {
 		runDebugSessionWithBPs(t, client, "launch",
@@ -5366,6 +5610,166 @@ func runNoDebugSession(t *testing.T, client *daptest.Client, launchRequest func(
 	client.ExpectTerminatedEvent(t)
 }
 
+func TestLaunchRequestOutput(t *testing.T) {
+	checkOutput := func(t *testing.T, client *daptest.Client) {
+		t.Helper()
+		if got := client.TargetOutput("stdout"); got != "hello stdout\n" {
+			t.Errorf("got stdout %q, want \"hello stdout\\n\"", got)
+		}
+		if got := client.TargetOutput("stderr"); got != "hello stderr\n" {
+			t.Errorf("got stderr %q, want \"hello stderr\\n\"", got)
+		}
+	}
+	runTest(t, "stdoutstderr", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSession(t, client, "launch", func() {
+			client.LaunchRequestWithArgs(map[string]interface{}{
+				"mode": "exec", "program": fixture.Path})
+		})
+		checkOutput(t, client)
+	})
+	runTest(t, "stdoutstderr", func(client *daptest.Client, fixture protest.Fixture) {
+		runNoDebugSession(t, client, func() {
+			client.LaunchRequestWithArgs(map[string]interface{}{
+				"noDebug": true, "mode": "exec", "program": fixture.Path})
+		}, 0)
+		checkOutput(t, client)
+	})
+}
+
+func TestLaunchRequestIntegratedTerminal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("terminal consoles are not supported on windows")
+	}
+	runTest(t, "stdoutstderr", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+
+		client.LaunchRequestWithArgs(map[string]interface{}{
+			"mode": "exec", "program": fixture.Path, "console": "integratedTerminal"})
+		req := client.ExpectRunInTerminalRequest(t)
+		args := req.Arguments.Args
+		if req.Arguments.Kind != "integrated" || len(args) < 2 || args[0] != "/bin/sh" {
+			t.Fatalf("got %#v, want Kind=\"integrated\" Args=[/bin/sh ...]", req)
+		}
+
+		// Play the part of the terminal: a regular file stands in for its
+		// TTY and a sleep process for the process holding it.
+		terminal := exec.Command("sleep", "60")
+		if err := terminal.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer terminal.Process.Kill()
+		ttyPath := filepath.Join(t.TempDir(), "tty")
+		if err := os.WriteFile(ttyPath, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		defer func(f func(int) (string, error)) { terminalTTY = f }(terminalTTY)
+		terminalTTY = func(pid int) (string, error) {
+			if pid != terminal.Process.Pid {
+				return "", fmt.Errorf("unexpected terminal process %d", pid)
+			}
+			return ttyPath, nil
+		}
+		client.RunInTerminalResponse(req.Seq, terminal.Process.Pid)
+
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+		client.ExpectTerminatedEvent(t)
+
+		buf, err := os.ReadFile(ttyPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf); got != "hello stdout\nhello stderr\n" {
+			t.Errorf("got terminal output %q, want \"hello stdout\\nhello stderr\\n\"", got)
+		}
+		if got := client.TargetOutput("stdout") + client.TargetOutput("stderr"); got != "" {
+			t.Errorf("got output events %q, want none", got)
+		}
+
+		client.DisconnectRequestWithKillOption(true)
+		client.ExpectOutputEventProcessExitedAnyStatus(t)
+		client.ExpectOutputEventDetaching(t)
+		client.ExpectDisconnectResponse(t)
+		client.ExpectTerminatedEvent(t)
+
+		// The process holding the terminal is killed at the end of the session.
+		waitErr := make(chan error)
+		go func() { waitErr <- terminal.Wait() }()
+		select {
+		case <-waitErr:
+		case <-time.After(5 * time.Second):
+			t.Error("terminal process still running after the end of the session")
+		}
+	})
+	runTest(t, "stdoutstderr", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequestWithArgs(dap.InitializeRequestArguments{
+			AdapterID:       "go",
+			PathFormat:      "path",
+			LinesStartAt1:   true,
+			ColumnsStartAt1: true,
+		})
+		client.ExpectInitializeResponse(t)
+		client.LaunchRequestWithArgs(map[string]interface{}{
+			"mode": "exec", "program": fixture.Path, "console": "integratedTerminal"})
+		er := client.ExpectVisibleErrorResponse(t)
+		errmsg := "Failed to launch: console \"integratedTerminal\" requires a client that supports the runInTerminal request"
+		if er.Body.Error == nil || er.Body.Error.Id != FailedToLaunch || !checkErrorMessageFormat(er.Body.Error, errmsg) {
+			t.Errorf("\ngot  %#v\nwant Id=%d Format=%q", er, FailedToLaunch, errmsg)
+		}
+		client.DisconnectRequest()
+		client.ExpectDisconnectResponse(t)
+	})
+	runTest(t, "stdoutstderr", func(client *daptest.Client, fixture protest.Fixture) {
+		// A failed runInTerminal request fails the launch without waiting
+		// for the terminal.
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+		client.LaunchRequestWithArgs(map[string]interface{}{
+			"mode": "exec", "program": fixture.Path, "console": "externalTerminal"})
+		req := client.ExpectRunInTerminalRequest(t)
+		if req.Arguments.Kind != "external" {
+			t.Fatalf("got %#v, want Kind=\"external\"", req)
+		}
+		client.RunInTerminalErrorResponse(req.Seq, "no terminal emulator")
+		er := client.ExpectVisibleErrorResponse(t)
+		errmsg := "Failed to launch: could not start the terminal: no terminal emulator"
+		if er.Body.Error == nil || er.Body.Error.Id != FailedToLaunch || !checkErrorMessageFormat(er.Body.Error, errmsg) {
+			t.Errorf("\ngot  %#v\nwant Id=%d Format=%q", er, FailedToLaunch, errmsg)
+		}
+		client.DisconnectRequest()
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
+func TestIsLocalClient(t *testing.T) {
+	// Terminal consoles are rejected for clients that could be on another
+	// machine.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	local, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+	pipe, _ := net.Pipe()
+	defer pipe.Close()
+	for _, tc := range []struct {
+		conn net.Conn
+		want bool
+	}{{local, true}, {pipe, false}} {
+		s := &Session{conn: &connection{tc.conn, make(chan struct{})}}
+		if got := s.isLocalClient(); got != tc.want {
+			t.Errorf("isLocalClient() with remote address %v = %v, want %v", tc.conn.RemoteAddr(), got, tc.want)
+		}
+	}
+}
+
 func TestNoDebug_AcceptNoRequestsButDisconnect(t *testing.T) {
 	runTest(t, "http_server", func(client *daptest.Client, fixture protest.Fixture) {
 		client.InitializeRequest()
@@ -5785,39 +6189,9 @@ func TestUnsupportedCommandResponses(t *testing.T) {
 			seqCnt++
 		}
 
-		client.RestartFrameRequest()
-		expectUnsupportedCommand("restartFrame")
-
-		client.GotoRequest()
-		expectUnsupportedCommand("goto")
-
-		client.SourceRequest()
-		expectUnsupportedCommand("source")
-
 		client.TerminateThreadsRequest()
 		expectUnsupportedCommand("terminateThreads")
 
-		client.StepInTargetsRequest()
-		expectUnsupportedCommand("stepInTargets")
-
-		client.GotoTargetsRequest()
-		expectUnsupportedCommand("gotoTargets")
-
-		client.CompletionsRequest()
-		expectUnsupportedCommand("completions")
-
-		client.DataBreakpointInfoRequest()
-		expectUnsupportedCommand("dataBreakpointInfo")
-
-		client.SetDataBreakpointsRequest()
-		expectUnsupportedCommand("setDataBreakpoints")
-
-		client.BreakpointLocationsRequest()
-		expectUnsupportedCommand("breakpointLocations")
-
-		client.ModulesRequest()
-		expectUnsupportedCommand("modules")
-
 		client.DisconnectRequest()
 		client.ExpectDisconnectResponse(t)
 	})
@@ -5830,7 +6204,19 @@ type helperForSetVariable struct {
 
 func (h *helperForSetVariable) expectSetVariable(ref int, name, value string) {
 	h.t.Helper()
-	h.expectSetVariable0(ref, name, value, false)
+	h.expectSetVariable0(ref, name, value, regexp.QuoteMeta(value), false)
+}
+
+// expectSetVariableValue is like expectSetVariable, but for values that are
+// displayed differently than they are typed, like expressions.
+func (h *helperForSetVariable) expectSetVariableValue(ref int, name, value, want string) {
+	h.t.Helper()
+	h.expectSetVariable0(ref, name, value, regexp.QuoteMeta(want), false)
+}
+
+func (h *helperForSetVariable) expectSetVariableRegex(ref int, name, value, want string) {
+	h.t.Helper()
+	h.expectSetVariable0(ref, name, value, want, false)
 }
 
 func (h *helperForSetVariable) failSetVariable(ref int, name, value, wantErrInfo string) {
@@ -5857,15 +6243,16 @@ func (h *helperForSetVariable) evaluateRegex(expr, want string, hasRef bool) {
 	checkEvalRegex(h.t, got, want, hasRef)
 }
 
-func (h *helperForSetVariable) expectSetVariable0(ref int, name, value string, wantStop bool) {
+func (h *helperForSetVariable) expectSetVariable0(ref int, name, value, want string, wantStop bool) {
 	h.t.Helper()
 
 	h.c.SetVariableRequest(ref, name, value)
 	if wantStop {
 		h.c.ExpectStoppedEvent(h.t)
 	}
-	if got, want := h.c.ExpectSetVariableResponse(h.t), value; got.Success != true || got.Body.Value != want {
-		h.t.Errorf("SetVariableRequest(%v, %v)=%#v, want {Success=true, Body.Value=%q", name, value, got, want)
+	got := h.c.ExpectSetVariableResponse(h.t)
+	if matched, _ := regexp.MatchString("^"+want+"$", got.Body.Value); !got.Success || !matched {
+		h.t.Errorf("SetVariableRequest(%v, %v)=%#v, want {Success=true, Body.Value=%q}", name, value, got, want)
 	}
 }
 
@@ -5959,7 +6346,7 @@ func TestSetVariable(t *testing.T) {
 
 					// pointer
 					checkVarExact(t, locals, -1, "a9", "a9", `*main.FooBar nil`, "*main.FooBar", noChildren)
-					tester.expectSetVariable(localsScope, "a9", "&a6")
+					tester.expectSetVariableValue(localsScope, "a9", "&a6", `*main.FooBar {Baz: 8, Bur: "word"}`)
 					tester.evaluate("a9", `*main.FooBar {Baz: 8, Bur: "word"}`, hasChildren)
 
 					// slice of pointers
@@ -6009,17 +6396,17 @@ func TestSetVariable(t *testing.T) {
 
 					// channel
 					tester.evaluate("chnil", "chan int nil", noChildren)
-					tester.expectSetVariable(localsScope, "chnil", "ch1")
+					tester.expectSetVariableValue(localsScope, "chnil", "ch1", "chan int 4/11")
 					tester.evaluate("chnil", "chan int 4/11", hasChildren)
 
 					// func
 					tester.evaluate("fn2", "nil", noChildren)
-					tester.expectSetVariable(localsScope, "fn2", "fn1")
+					tester.expectSetVariableValue(localsScope, "fn2", "fn1", "main.afunc")
 					tester.evaluate("fn2", "main.afunc", noChildren)
 
 					// interface
 					tester.evaluate("ifacenil", "interface {} nil", noChildren)
-					tester.expectSetVariable(localsScope, "ifacenil", "iface1")
+					tester.expectSetVariableValue(localsScope, "ifacenil", "iface1", "interface {}(*main.astruct) *{A: 1, B: 2}")
 					tester.evaluate("ifacenil", "interface {}(*main.astruct) *{A: 1, B: 2}", hasChildren)
 
 					// interface.(data)
@@ -6162,7 +6549,7 @@ func TestSetVariableWithCall(t *testing.T) {
 					_ = tester.variables(localsScope)
 
 					// successful variable set using a function call.
-					tester.expectSetVariable(localsScope, "str", `callstacktrace()`)
+					tester.expectSetVariableRegex(localsScope, "str", `callstacktrace()`, `.*in main.callstacktrace at.*`)
 					tester.evaluateRegex("str", `.*in main.callstacktrace at.*`, noChildren)
 
 					tester.failSetVariableAndStop(localsScope, "str", `callpanic()`, `callpanic panicked`)
@@ -6181,42 +6568,6 @@ func TestSetVariableWithCall(t *testing.T) {
 	})
 }
 
-func TestOptionalNotYetImplementedResponses(t *testing.T) {
-	var got *dap.ErrorResponse
-	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
-		seqCnt := 1
-		expectNotYetImplemented := func(cmd string) {
-			t.Helper()
-			got = client.ExpectNotYetImplementedErrorResponse(t)
-			if got.RequestSeq != seqCnt || got.Command != cmd {
-				t.Errorf("\ngot  %#v\nwant RequestSeq=%d Command=%s", got, seqCnt, cmd)
-			}
-			seqCnt++
-		}
-
-		client.TerminateRequest()
-		expectNotYetImplemented("terminate")
-
-		client.RestartRequest()
-		expectNotYetImplemented("restart")
-
-		client.SetExpressionRequest()
-		expectNotYetImplemented("setExpression")
-
-		client.LoadedSourcesRequest()
-		expectNotYetImplemented("loadedSources")
-
-		client.ReadMemoryRequest()
-		expectNotYetImplemented("readMemory")
-
-		client.CancelRequest()
-		expectNotYetImplemented("cancel")
-
-		client.DisconnectRequest()
-		client.ExpectDisconnectResponse(t)
-	})
-}
-
 func TestBadLaunchRequests(t *testing.T) {
 	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
 		seqCnt := 1
@@ -6272,6 +6623,10 @@ func TestBadLaunchRequests(t *testing.T) {
 		checkFailedToLaunchWithMessage(client.ExpectVisibleErrorResponse(t),
 			"Failed to launch: invalid debug configuration - unsupported 'mode' attribute \"notamode\"")
 
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path, "console": "notaconsole"})
+		checkFailedToLaunchWithMessage(client.ExpectVisibleErrorResponse(t),
+			"Failed to launch: invalid debug configuration - unsupported 'console' attribute \"notaconsole\"")
+
 		client.LaunchRequestWithArgs(map[string]interface{}{"mode": 12345, "program": fixture.Path})
 		checkFailedToLaunchWithMessage(client.ExpectVisibleErrorResponse(t),
 			"Failed to launch: invalid debug configuration - cannot unmarshal number into \"mode\" of type string")
@@ -7003,37 +7358,208 @@ func TestParseLogPoint(t *testing.T) {
 	}
 }
 
-func TestDisassemble(t *testing.T) {
-	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+func TestCompletionsRequest(t *testing.T) {
+	runTest(t, "completions", func(client *daptest.Client, fixture protest.Fixture) {
 		runDebugSessionWithBPs(t, client, "launch",
 			// Launch
 			func() {
 				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
 			},
-			// Set breakpoints
-			fixture.Source, []int{17},
-			[]onBreakpoint{{
-				// Stop at line 17
+			fixture.Source, []int{}, // Breakpoint set in the program
+			[]onBreakpoint{{ // Stop at runtime.Breakpoint
 				execute: func() {
-					checkStop(t, client, 1, "main.main", 17)
+					client.CheckStopLocation(t, 1, "main.main", -1)
 
-					client.StackTraceRequest(1, 0, 1)
-					st := client.ExpectStackTraceResponse(t)
-					if len(st.Body.StackFrames) < 1 {
-						t.Fatalf("\ngot  %#v\nwant len(stackframes) => 1", st)
+					tests := []struct {
+						text       string
+						column     int // zero means the end of text
+						want       []string
+						wantType   dap.CompletionItemType
+						wantStart  int
+						wantLength int
+					}{
+						{"conf", 0, []string{"config", "configCount", "configName", "configPtr"}, "variable", 1, 4},
+						{"x + config.Ser", 0, []string{"Servers"}, "field", 12, 3},
+						{"config.Ser + 1", 11, []string{"Servers"}, "field", 8, 3},
+						{"config.", 0, []string{"Describe", "Primary", "Retries", "RetryMax", "Servers", "retryBase"}, "", 8, 0},
+						{"configPtr.Retries", 0, []string{"Retries"}, "method", 11, 7},
+						{"config.Servers[len(config.Servers)-1].Ho", 0, []string{"Host"}, "field", 39, 2},
+						{"config.Primary.N", 0, []string{"Name"}, "field", 16, 1},
+						{"runtime.MemProf", 0, []string{"MemProfileRate"}, "variable", 9, 7},
+						{"fs.ErrNot", 0, []string{"ErrNotExist"}, "variable", 4, 6},
+						{"nosuchvar.", 0, []string{}, "", 0, 0},
+						{"dlv so", 0, []string{"sources"}, "keyword", 5, 2},
+						{"dlv help con", 0, []string{"config"}, "keyword", 10, 3},
+						{"dlv ", 0, []string{"config", "h", "help", "s", "sources"}, "keyword", 5, 0},
 					}
-					// Request the single instruction that the program is stopped at.
-					pc := st.Body.StackFrames[0].InstructionPointerReference
-					client.DisassembleRequest(pc, 0, 1)
-					dr := client.ExpectDisassembleResponse(t)
-					if len(dr.Body.Instructions) != 1 {
-						t.Errorf("\ngot %#v\nwant len(instructions) = 1", dr)
-					} else if dr.Body.Instructions[0].Address != pc {
-						t.Errorf("\ngot %#v\nwant instructions[0].Address = %s", dr, pc)
+					for _, tc := range tests {
+						column := tc.column
+						if column == 0 {
+							column = len(tc.text) + 1
+						}
+						client.CompletionsRequest(1000, tc.text, column)
+						got := client.ExpectCompletionsResponse(t)
+						labels := []string{}
+						for _, target := range got.Body.Targets {
+							labels = append(labels, target.Label)
+							if tc.wantType != "" && target.Type != tc.wantType {
+								t.Errorf("%q: got %#v, want Type=%q", tc.text, target, tc.wantType)
+							}
+							if target.Start != tc.wantStart || target.Length != tc.wantLength {
+								t.Errorf("%q: got %#v, want Start=%d Length=%d", tc.text, target, tc.wantStart, tc.wantLength)
+							}
+						}
+						if !reflect.DeepEqual(labels, tc.want) {
+							t.Errorf("%q: got %v, want %v", tc.text, labels, tc.want)
+						}
 					}
-
-					// Request the instruction that the program is stopped at, and the two
-					// surrounding it.
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+func TestReadWriteMemory(t *testing.T) {
+	if runtime.GOARCH == "386" {
+		t.Skip("test assumes 64-bit ints")
+	}
+	runTest(t, "testvariables", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			fixture.Source, []int{}, // Breakpoint set in the program
+			[]onBreakpoint{{ // Stop at first breakpoint
+				execute: func() {
+					// Skip the line check, the line of the stop after
+					// runtime.Breakpoint depends on the Go version.
+					client.CheckStopLocation(t, 1, "main.foobar", -1)
+
+					memoryReference := func(expr string) string {
+						t.Helper()
+						client.EvaluateRequest(expr, 1000, "repl")
+						return client.ExpectEvaluateResponse(t).Body.MemoryReference
+					}
+					readMemory := func(ref string, offset, count int) *dap.ReadMemoryResponse {
+						t.Helper()
+						client.ReadMemoryRequest(ref, offset, count)
+						got := client.ExpectReadMemoryResponse(t)
+						if got.Body.Address == "" {
+							t.Errorf("\ngot  %#v\nwant Address", got)
+						}
+						return got
+					}
+					readInt := func(ref string, offset int) uint64 {
+						t.Helper()
+						data, err := base64.StdEncoding.DecodeString(readMemory(ref, offset, 8).Body.Data)
+						if err != nil || len(data) != 8 {
+							t.Fatalf("could not read int at %s+%d: %v %v", ref, offset, data, err)
+						}
+						return binary.LittleEndian.Uint64(data)
+					}
+
+					if ref := memoryReference("a2"); ref != "" {
+						t.Errorf("got memory reference %q for int, want none", ref)
+					}
+
+					// Strings refer to their contents.
+					a1 := memoryReference("a1")
+					got := readMemory(a1, 3, 6)
+					if data, _ := base64.StdEncoding.DecodeString(got.Body.Data); string(data) != "foofoo" || got.Body.UnreadableBytes != 0 {
+						t.Errorf("\ngot  %#v (%q)\nwant \"foofoo\"", got, data)
+					}
+
+					// Slices and arrays refer to their elements, pointers
+					// to the value they point to.
+					a5 := memoryReference("a5")
+					if n := readInt(a5, 4*8); n != 5 {
+						t.Errorf("got a5[4] = %d, want 5", n)
+					}
+					if n := readInt(memoryReference("a4"), 8); n != 2 {
+						t.Errorf("got a4[1] = %d, want 2", n)
+					}
+					if n := readInt(memoryReference("a7"), 0); n != 5 {
+						t.Errorf("got a7.Baz = %d, want 5", n)
+					}
+
+					// Children of variables have memory references too.
+					client.EvaluateRequest("a13", 1000, "repl")
+					a13 := client.ExpectEvaluateResponse(t)
+					client.VariablesRequest(a13.Body.VariablesReference)
+					children := client.ExpectVariablesResponse(t)
+					if len(children.Body.Variables) != 3 || children.Body.Variables[0].MemoryReference != memoryReference("a13[0]") || children.Body.Variables[0].MemoryReference == "" {
+						t.Errorf("\ngot  %#v\nwant memory references for the elements of a13", children)
+					}
+
+					client.WriteMemoryRequest(a5, 8, []byte{42, 0, 0, 0, 0, 0, 0, 0})
+					if got := client.ExpectWriteMemoryResponse(t); got.Body.BytesWritten != 8 {
+						t.Errorf("\ngot  %#v\nwant BytesWritten=8", got)
+					}
+					if got := client.ExpectMemoryEvent(t); got.Body.MemoryReference != a5 || got.Body.Offset != 8 || got.Body.Count != 8 {
+						t.Errorf("\ngot  %#v\nwant MemoryReference=%s Offset=8 Count=8", got, a5)
+					}
+					if got := client.ExpectInvalidatedEvent(t); len(got.Body.Areas) != 1 || got.Body.Areas[0] != "variables" {
+						t.Errorf("\ngot  %#v\nwant Areas=[variables]", got)
+					}
+					client.EvaluateRequest("a5[1]", 1000, "repl")
+					checkEval(t, client.ExpectEvaluateResponse(t), "42", noChildren)
+
+					// Large reads are truncated, the rest is reported as
+					// unreadable.
+					got = readMemory(a5, 0, 1<<40)
+					if data, _ := base64.StdEncoding.DecodeString(got.Body.Data); len(data) > maxReadMemoryCount || len(data)+got.Body.UnreadableBytes != 1<<40 {
+						t.Errorf("\ngot  %d bytes and UnreadableBytes=%d\nwant at most %d bytes", len(data), got.Body.UnreadableBytes, maxReadMemoryCount)
+					}
+
+					// Unreadable memory is reported as such.
+					got = readMemory("0x0", 0, 16)
+					if got.Body.Data != "" || got.Body.UnreadableBytes != 16 {
+						t.Errorf("\ngot  %#v\nwant UnreadableBytes=16", got)
+					}
+
+					client.ReadMemoryRequest("a5", 0, 8)
+					er := client.ExpectErrorResponse(t)
+					if er.Body.Error == nil || er.Body.Error.Id != UnableToReadMemory {
+						t.Errorf("\ngot  %#v\nwant Id=%d", er, UnableToReadMemory)
+					}
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+func TestDisassemble(t *testing.T) {
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{17},
+			[]onBreakpoint{{
+				// Stop at line 17
+				execute: func() {
+					checkStop(t, client, 1, "main.main", 17)
+
+					client.StackTraceRequest(1, 0, 1)
+					st := client.ExpectStackTraceResponse(t)
+					if len(st.Body.StackFrames) < 1 {
+						t.Fatalf("\ngot  %#v\nwant len(stackframes) => 1", st)
+					}
+					// Request the single instruction that the program is stopped at.
+					pc := st.Body.StackFrames[0].InstructionPointerReference
+					client.DisassembleRequest(pc, 0, 1)
+					dr := client.ExpectDisassembleResponse(t)
+					if len(dr.Body.Instructions) != 1 {
+						t.Errorf("\ngot %#v\nwant len(instructions) = 1", dr)
+					} else if dr.Body.Instructions[0].Address != pc {
+						t.Errorf("\ngot %#v\nwant instructions[0].Address = %s", dr, pc)
+					}
+
+					// Request the instruction that the program is stopped at, and the two
+					// surrounding it.
 					client.DisassembleRequest(pc, -1, 3)
 					dr = client.ExpectDisassembleResponse(t)
 					if len(dr.Body.Instructions) != 3 {
@@ -7366,6 +7892,442 @@ func TestDisassembleCgo(t *testing.T) {
 		protest.AllNonOptimized, true)
 }
 
+func TestModulesAndLoadedSources(t *testing.T) {
+	runTestBuildFlags(t, "cgodisass", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{11},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.main", 11)
+
+					client.ModulesRequest()
+					var modules ModulesResponse
+					client.ExpectCustomMessage(t, &modules)
+					if !modules.Success || len(modules.Body.Modules) < 2 || modules.Body.TotalModules != len(modules.Body.Modules) {
+						t.Fatalf("got %#v, want at least the executable and libc", modules)
+					}
+					exe := modules.Body.Modules[0]
+					if exe.Path != fixture.Path || !exe.IsUserCode || exe.SymbolStatus != "Symbols loaded." || exe.AddressRange == "" {
+						t.Errorf("got %#v, want the executable with symbols", exe)
+					}
+					var libc *Module
+					for i := range modules.Body.Modules[1:] {
+						if m := &modules.Body.Modules[i+1]; strings.HasPrefix(m.Name, "libc.") {
+							libc = m
+						}
+					}
+					if libc == nil {
+						t.Fatalf("libc not found in %#v", modules.Body.Modules)
+					}
+					if libc.IsUserCode || libc.BuildID == "" || libc.AddressRange == "" {
+						t.Errorf("got %#v, want libc with a build ID", libc)
+					}
+
+					// The dynamic libraries were loaded after the launch.
+					found := false
+					for _, m := range client.LoadedEvents() {
+						if me, ok := m.(*dap.ModuleEvent); ok && me.Body.Reason == "new" && me.Body.Module.Path == libc.Path {
+							found = true
+						}
+					}
+					if !found {
+						t.Errorf("no module event for %s in %#v", libc.Path, client.LoadedEvents())
+					}
+
+					client.LoadedSourcesRequest()
+					sources := client.ExpectLoadedSourcesResponse(t)
+					found = false
+					for _, source := range sources.Body.Sources {
+						if source.Path == fixture.Source && source.Name == filepath.Base(fixture.Source) {
+							found = true
+						}
+						if strings.HasPrefix(source.Path, "<") {
+							t.Errorf("got placeholder source %#v", source)
+						}
+					}
+					if !found {
+						t.Errorf("%s not found in loaded sources", fixture.Source)
+					}
+				},
+				disconnect: true,
+			}},
+		)
+	},
+		protest.AllNonOptimized, true)
+}
+
+func TestSourceRequest(t *testing.T) {
+	// Built with -trimpath the sources in the debug information are not on
+	// disk, the standard library is found in GOROOT.
+	runTestBuildFlags(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+		client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+		client.SetFunctionBreakpointsRequest([]dap.FunctionBreakpoint{{Name: "main.Increment"}})
+		client.ExpectSetFunctionBreakpointsResponse(t)
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+		client.ExpectStoppedEvent(t)
+
+		client.StackTraceRequest(1, 0, 20)
+		st := client.ExpectStackTraceResponse(t)
+		var mainSource, runtimeSource *dap.Source
+		for i := range st.Body.StackFrames {
+			switch frame := &st.Body.StackFrames[i]; frame.Name {
+			case "main.Increment":
+				mainSource = frame.Source
+			case "runtime.main":
+				runtimeSource = frame.Source
+			}
+		}
+		if mainSource == nil || mainSource.SourceReference <= 0 {
+			t.Fatalf("got %#v, want main.Increment with a source reference", st.Body.StackFrames)
+		}
+		if runtimeSource == nil || runtimeSource.Path != "runtime/proc.go" || runtimeSource.SourceReference <= 0 || runtimeSource.SourceReference == mainSource.SourceReference {
+			t.Fatalf("got %#v, want runtime.main with a source reference", st.Body.StackFrames)
+		}
+
+		// The same reference is used every time.
+		client.StackTraceRequest(1, 0, 1)
+		st = client.ExpectStackTraceResponse(t)
+		if got := st.Body.StackFrames[0].Source; got.SourceReference != mainSource.SourceReference {
+			t.Errorf("got source %#v, want reference %d", got, mainSource.SourceReference)
+		}
+
+		client.SourceRequest(runtimeSource.Path, runtimeSource.SourceReference)
+		src := client.ExpectSourceResponse(t)
+		want, err := os.ReadFile(filepath.Join(goEnv("GOROOT"), "src", "runtime", "proc.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if src.Body.Content != string(want) {
+			t.Errorf("got %d bytes of runtime/proc.go, want %d", len(src.Body.Content), len(want))
+		}
+
+		// The main package can not be found.
+		client.SourceRequest(mainSource.Path, mainSource.SourceReference)
+		er := client.ExpectErrorResponse(t)
+		if er.Body.Error == nil || er.Body.Error.Id != UnableToGetSource {
+			t.Errorf("got %#v, want error %d", er, UnableToGetSource)
+		}
+
+		client.SourceRequest("", 1000)
+		er = client.ExpectErrorResponse(t)
+		if er.Body.Error == nil || er.Body.Error.Format != "Unable to get source: unknown source reference 1000" {
+			t.Errorf("got %#v, want unknown source reference", er)
+		}
+
+		// Sources on disk are read by path.
+		client.SourceRequest(fixture.Source, 0)
+		src = client.ExpectSourceResponse(t)
+		want, err = os.ReadFile(fixture.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if src.Body.Content != string(want) {
+			t.Errorf("got %q, want %q", src.Body.Content, want)
+		}
+
+		client.DisconnectRequestWithKillOption(true)
+		client.ExpectOutputEventDetachingKill(t)
+		client.ExpectDisconnectResponse(t)
+		client.ExpectTerminatedEvent(t)
+	}, protest.AllNonOptimized|protest.BuildTrimpath, false)
+}
+
+func TestSourcePaths(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/home/ci/go/pkg/mod/github.com/pkg/errors@v0.9.1/errors.go", "/modcache/github.com/pkg/errors@v0.9.1/errors.go"},
+		{"github.com/pkg/errors@v0.9.1/errors.go", "/modcache/github.com/pkg/errors@v0.9.1/errors.go"},
+		{"/home/ci/src/main.go", ""},
+		{"main/main.go", ""},
+	}
+	for _, tc := range tests {
+		if got := moduleCachePath(tc.path, "/modcache"); got != filepath.FromSlash(tc.want) {
+			t.Errorf("moduleCachePath(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+
+	tests = []struct {
+		path, want string
+	}{
+		{"/opt/go/src/runtime/proc.go", "/goroot/src/runtime/proc.go"},
+		{"/home/ci/src/main.go", ""},
+	}
+	for _, tc := range tests {
+		if got := gorootPath(tc.path, "/opt/go/src/", "/goroot"); got != filepath.FromSlash(tc.want) {
+			t.Errorf("gorootPath(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+	// Built with -trimpath.
+	if got := gorootPath("runtime/proc.go", "", "/goroot"); got != filepath.FromSlash("/goroot/src/runtime/proc.go") {
+		t.Errorf("gorootPath(\"runtime/proc.go\") = %q", got)
+	}
+	if got := gorootPath("/home/ci/src/main.go", "", "/goroot"); got != "" {
+		t.Errorf("gorootPath(\"/home/ci/src/main.go\") = %q", got)
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	runTest(t, "cancelrequest", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequestWithArgs(dap.InitializeRequestArguments{
+			AdapterID:                 "go",
+			PathFormat:                "path",
+			LinesStartAt1:             true,
+			ColumnsStartAt1:           true,
+			SupportsProgressReporting: true,
+		})
+		client.ExpectInitializeResponse(t)
+		client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+		client.ExpectStoppedEvent(t)
+		client.CheckStopLocation(t, 1, "main.main", -1)
+
+		expectCancelled := func(seq int) {
+			t.Helper()
+			er := client.ExpectErrorResponse(t)
+			if er.RequestSeq != seq || er.Message != "cancelled" {
+				t.Errorf("\ngot  %#v\nwant RequestSeq=%d Message=\"cancelled\"", er, seq)
+			}
+		}
+
+		// Cancelling a request that is not pending does nothing.
+		client.CancelRequest(1000)
+		client.ExpectCancelResponse(t)
+
+		client.EvaluateRequest("call slow()", 1000, "repl")
+		callSeq := client.LastSeq()
+		progress := client.ExpectProgressStartEvent(t)
+		if progress.Body.RequestId != callSeq || !progress.Body.Cancellable {
+			t.Errorf("\ngot  %#v\nwant RequestId=%d Cancellable=true", progress, callSeq)
+		}
+
+		// Requests waiting for the call to complete can be cancelled too.
+		client.ThreadsRequest()
+		threadsSeq := client.LastSeq()
+		client.CancelRequest(threadsSeq)
+		client.ExpectCancelResponse(t)
+
+		// Cancelling the call halts the program.
+		client.CancelProgressRequest(progress.Body.ProgressId)
+		client.ExpectCancelResponse(t)
+		client.ExpectStoppedEvent(t)
+		expectCancelled(callSeq)
+		if pe := client.ExpectProgressEndEvent(t); pe.Body.ProgressId != progress.Body.ProgressId {
+			t.Errorf("\ngot  %#v\nwant ProgressId=%q", pe, progress.Body.ProgressId)
+		}
+		expectCancelled(threadsSeq)
+
+		client.DisconnectRequest()
+		client.ExpectOutputEventDetachingKill(t)
+		client.ExpectDisconnectResponse(t)
+		client.ExpectTerminatedEvent(t)
+	})
+}
+
+func TestSetExpression(t *testing.T) {
+	protest.MustSupportFunctionCalls(t, testBackend)
+
+	runTest(t, "setexpression", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			fixture.Source, []int{}, // breakpoints are set within the program.
+			[]onBreakpoint{{
+				execute: func() {
+					client.CheckStopLocation(t, 1, "main.main", -1)
+					tester := &helperForSetVariable{t, client}
+
+					setExpression := func(expr, value, want, wantType string) {
+						t.Helper()
+						client.SetExpressionRequest(expr, value, 1000)
+						got := client.ExpectSetExpressionResponse(t)
+						if !got.Success || got.Body.Value != want || got.Body.Type != wantType {
+							t.Errorf("SetExpressionRequest(%v, %v)=%#v, want {Success=true, Body.Value=%q, Body.Type=%q}", expr, value, got, want, wantType)
+						}
+					}
+
+					setExpression("pts[1].X", "42", "42", "int")
+					tester.evaluate("pts", "[]main.Point len: 2, cap: 2, [{X: 1, Y: 2},{X: 42, Y: 4}]", hasChildren)
+					setExpression("*p", "pts[0]", "main.Point {X: 1, Y: 2}", "main.Point")
+					tester.evaluate("p", "*main.Point {X: 1, Y: 2}", hasChildren)
+					setExpression("s", "s[1:]", "[]int len: 2, cap: 2, [2,3]", "[]int")
+
+					// Strings, and slices with values that need an
+					// allocation, are assigned with function calls.
+					setExpression("str", `"bye"`, `"bye"`, "string")
+					setExpression("s", "getPrimes()", "[]int len: 4, cap: 4, [2,3,5,7]", "[]int")
+					setExpression("s", "nil", "[]int len: 0, cap: 0, nil", "[]int")
+
+					client.SetExpressionRequest("1+2", "3", 1000)
+					er := client.ExpectErrorResponse(t)
+					if er.Body.Error == nil || er.Body.Error.Id != UnableToSetExpression {
+						t.Errorf("\ngot  %#v\nwant Id=%d", er, UnableToSetExpression)
+					}
+
+					// Children without evaluate names, like the values returned by
+					// a call, are set through their address.
+					client.EvaluateRequest("call getOrigin()", 1000, "repl")
+					ref := client.ExpectEvaluateResponse(t).Body.VariablesReference
+					ret := tester.variables(ref)
+					checkChildren(t, ret, "call getOrigin()", 1)
+					ptr := tester.variables(ret.Body.Variables[0].VariablesReference)
+					checkChildren(t, ptr, "~r0", 1)
+					tester.expectSetVariable(ptr.Body.Variables[0].VariablesReference, "X", "9")
+					tester.evaluate("origin", "main.Point {X: 9, Y: 0}", hasChildren)
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+func TestTerminateRequest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("terminate requests are not supported on windows")
+	}
+	// The program loops forever, it only exits because of the signal.
+	t.Run("stopped", func(t *testing.T) {
+		runTest(t, "loopprog", func(client *daptest.Client, fixture protest.Fixture) {
+			client.InitializeRequest()
+			client.ExpectInitializeResponseAndCapabilities(t)
+			client.LaunchRequest("exec", fixture.Path, stopOnEntry)
+			client.ExpectInitializedEvent(t)
+			client.ExpectLaunchResponse(t)
+			client.ConfigurationDoneRequest()
+			client.ExpectStoppedEvent(t)
+			client.ExpectConfigurationDoneResponse(t)
+
+			client.TerminateRequest()
+			client.ExpectOutputEventRegex(t, `Sent SIGTERM to process [0-9]+\n`)
+			client.ExpectTerminateResponse(t)
+			client.ExpectTerminatedEvent(t)
+
+			client.DisconnectRequestWithKillOption(true)
+			client.ExpectOutputEventProcessExitedAnyStatus(t)
+			client.ExpectOutputEventDetaching(t)
+			client.ExpectDisconnectResponse(t)
+			client.ExpectTerminatedEvent(t)
+		})
+	})
+	t.Run("running", func(t *testing.T) {
+		runTest(t, "loopprog", func(client *daptest.Client, fixture protest.Fixture) {
+			client.InitializeRequest()
+			client.ExpectInitializeResponseAndCapabilities(t)
+			client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			client.ExpectInitializedEvent(t)
+			client.ExpectLaunchResponse(t)
+			client.ConfigurationDoneRequest()
+			client.ExpectConfigurationDoneResponse(t)
+
+			client.TerminateRequest()
+			client.ExpectOutputEventRegex(t, `Sent SIGTERM to process [0-9]+\n`)
+			client.ExpectTerminateResponse(t)
+			client.ExpectTerminatedEvent(t)
+
+			client.DisconnectRequestWithKillOption(true)
+			client.ExpectOutputEventProcessExitedAnyStatus(t)
+			client.ExpectOutputEventDetaching(t)
+			client.ExpectDisconnectResponse(t)
+			client.ExpectTerminatedEvent(t)
+		})
+	})
+}
+
+func TestRestartRequest(t *testing.T) {
+	runTest(t, "loopprog", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+		client.LaunchRequest("debug", fixture.Source, !stopOnEntry)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+		client.SetBreakpointsRequest(fixture.Source, []int{8})
+		client.ExpectSetBreakpointsResponse(t)
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+		client.ExpectStoppedEvent(t)
+		client.CheckStopLocation(t, 1, "main.loop", 8)
+
+		// The program is rebuilt, with the new arguments, and stops at the
+		// same breakpoint.
+		client.RestartRequestWithArgs(map[string]interface{}{"mode": "debug", "program": fixture.Source, "args": []string{"arg1"}})
+		client.ExpectRestartResponse(t)
+		se := client.ExpectStoppedEvent(t)
+		if se.Body.Reason != "breakpoint" {
+			t.Errorf("got %#v, want Reason=\"breakpoint\"", se)
+		}
+		client.CheckStopLocation(t, 1, "main.loop", 8)
+		client.EvaluateRequest("os.Args[1]", 1000, "repl")
+		checkEval(t, client.ExpectEvaluateResponse(t), `"arg1"`, noChildren)
+
+		// Attributes that are only used when the session starts can not be
+		// changed.
+		client.RestartRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path})
+		er := client.ExpectErrorResponse(t)
+		if er.Body.Error == nil || er.Body.Error.Id != UnableToRestart || !strings.Contains(er.Body.Error.Format, "'mode'") {
+			t.Errorf("\ngot  %#v\nwant Id=%d", er, UnableToRestart)
+		}
+
+		// The response to a request that halts the program and the stopped
+		// event can be received in any order.
+		expectPaused := func(isResponse func(m dap.Message) bool) {
+			t.Helper()
+			var responded, paused bool
+			for !responded || !paused {
+				m := client.ExpectMessage(t)
+				switch {
+				case isResponse(m):
+					responded = true
+				default:
+					se, ok := m.(*dap.StoppedEvent)
+					if !ok || se.Body.Reason != "pause" {
+						t.Fatalf("got %#v, want response and stopped event with Reason=\"pause\"", m)
+					}
+					paused = true
+				}
+			}
+		}
+
+		// The program can be restarted while it is running, it is paused
+		// first.
+		client.SetBreakpointsRequest(fixture.Source, []int{})
+		client.ExpectSetBreakpointsResponse(t)
+		client.ContinueRequest(1)
+		client.ExpectContinueResponse(t)
+		client.RestartRequest()
+		expectPaused(func(m dap.Message) bool {
+			_, ok := m.(*dap.RestartResponse)
+			return ok
+		})
+
+		// The restarted program runs.
+		client.SetBreakpointsRequest(fixture.Source, []int{8})
+		client.ExpectSetBreakpointsResponse(t)
+		se = client.ExpectStoppedEvent(t)
+		if se.Body.Reason != "breakpoint" {
+			t.Errorf("got %#v, want Reason=\"breakpoint\"", se)
+		}
+		client.CheckStopLocation(t, 1, "main.loop", 8)
+
+		client.DisconnectRequestWithKillOption(true)
+		client.ExpectOutputEventDetachingKill(t)
+		client.ExpectDisconnectResponse(t)
+		client.ExpectTerminatedEvent(t)
+	})
+}
+
 // Helper functions for checking ErrorMessage field values.
 
 func checkErrorMessageId(er *dap.ErrorMessage, id int) bool {
@@ -7375,3 +8337,279 @@ func checkErrorMessageId(er *dap.ErrorMessage, id int) bool {
 func checkErrorMessageFormat(er *dap.ErrorMessage, fmt string) bool {
 	return er != nil && er.Format == fmt
 }
+
+func TestUndoReplayGotoAndCheckpoints(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("the undo backend is only supported on linux/amd64")
+	}
+	runTest(t, "continuetestprog", func(client *daptest.Client, fixture protest.Fixture) {
+		bi := proc.NewBinaryInfo(runtime.GOOS, runtime.GOARCH)
+		if err := bi.LoadBinaryInfo(fixture.Path, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+		// Stops before the prologue of main.sayhi have the same frame offset,
+		// without having to know the stack pointer.
+		var sayhi2 uint64
+		recording, _ := undotest.SetupExe(t, fixture.Path, func(syms map[string]uint64) []undotest.Event {
+			event := func(bbcount, pc uint64) undotest.Event {
+				return undotest.Event{Bbcount: bbcount, Thread: undotest.ExePid, PC: pc}
+			}
+			start := event(100, syms["runtime.rt0_go"])
+			start.Regs = map[string]uint64{"rsp": undotest.ExeStack + 0x800}
+			history := []undotest.Event{start, event(1000, syms["main.main"])}
+			pcs := bi.AllPCsForFileLines(fixture.Source, []int{9, 13})
+			for i, pc := range pcs[9] {
+				history = append(history, event(2000+uint64(i), pc))
+			}
+			sayhi2 = syms["main.sayhi"] + uint64(firstInstructionLen(t, fixture.Path, syms["main.sayhi"]))
+			history = append(history, event(2900, syms["main.sayhi"]), event(2901, sayhi2))
+			for i, pc := range pcs[13] {
+				history = append(history, event(3000+uint64(i), pc))
+			}
+			return append(history, event(4000, syms["runtime.main"]))
+		})
+
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "replay", "traceDirPath": recording, "stopOnEntry": true})
+		capabilities := client.ExpectCapabilitiesEvent(t).Body.Capabilities
+		if !capabilities.SupportsStepBack || !capabilities.SupportsGotoTargetsRequest || !capabilities.SupportsRestartFrame {
+			t.Errorf("got %#v, want SupportsStepBack, SupportsGotoTargetsRequest and SupportsRestartFrame", capabilities)
+		}
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+		client.ConfigurationDoneRequest()
+		client.ExpectStoppedEvent(t)
+		client.ExpectConfigurationDoneResponse(t)
+
+		expectGoto := func(reason string, line int) {
+			t.Helper()
+			se := client.ExpectStoppedEvent(t)
+			if se.Body.Reason != reason {
+				t.Errorf("got %#v, want Reason=%q", se, reason)
+			}
+			client.StackTraceRequest(se.Body.ThreadId, 0, 1)
+			st := client.ExpectStackTraceResponse(t)
+			if len(st.Body.StackFrames) == 0 || st.Body.StackFrames[0].Line != line {
+				t.Errorf("got %#v, want stopped at line %d", st, line)
+			}
+		}
+
+		client.GotoTargetsRequest(fixture.Source, 13)
+		targets := client.ExpectGotoTargetsResponse(t).Body.Targets
+		if len(targets) != 2 || targets[0].Line != 13 || targets[1].Line != 13 {
+			t.Fatalf("got %#v, want next and previous execution of line 13", targets)
+		}
+		client.GotoRequest(1, targets[0].Id)
+		client.ExpectGotoResponse(t)
+		expectGoto("goto", 13)
+
+		var cps struct {
+			dap.Response
+			Body CheckpointsBody `json:"body"`
+		}
+		client.CheckpointsRequest(map[string]interface{}{"create": "hi"})
+		client.ExpectCustomMessage(t, &cps)
+		if !cps.Success || len(cps.Body.Checkpoints) != 1 || cps.Body.Checkpoints[0].Where != "hi" {
+			t.Fatalf("got %#v, want one checkpoint", cps)
+		}
+		var cpsEvent CheckpointsEvent
+		client.ExpectCustomMessage(t, &cpsEvent)
+		if cpsEvent.Event.Event != "checkpoints" || len(cpsEvent.Body.Checkpoints) != 1 {
+			t.Errorf("got %#v, want checkpoints event", cpsEvent)
+		}
+		id := cps.Body.Checkpoints[0].ID
+
+		client.GotoTargetsRequest(fixture.Source, 9)
+		targets = client.ExpectGotoTargetsResponse(t).Body.Targets
+		client.GotoRequest(1, targets[1].Id)
+		client.ExpectGotoResponse(t)
+		expectGoto("goto", 9)
+
+		client.RestartRequestWithArgs(map[string]interface{}{"mode": "replay", "traceDirPath": recording, "checkpoint": id})
+		client.ExpectRestartResponse(t)
+		expectGoto("goto", 13)
+		client.GotoRequest(1, targets[1].Id)
+		client.ExpectGotoResponse(t)
+		expectGoto("goto", 9)
+
+		client.CheckpointsRequest(map[string]interface{}{"goto": id})
+		client.ExpectCustomMessage(t, &cps)
+		if !cps.Success {
+			t.Fatalf("got %#v, want success", cps)
+		}
+		expectGoto("goto", 13)
+
+		client.CheckpointsRequest(map[string]interface{}{"clear": id})
+		client.ExpectCustomMessage(t, &cps)
+		client.ExpectCustomMessage(t, &cpsEvent)
+		if !cps.Success || len(cps.Body.Checkpoints) != 0 || len(cpsEvent.Body.Checkpoints) != 0 {
+			t.Errorf("got %#v and %#v, want no checkpoints", cps, cpsEvent)
+		}
+
+		// The breakpoints used by goto are internal, they don't use up
+		// breakpoint IDs.
+		client.SetInstructionBreakpointsRequest([]dap.InstructionBreakpoint{{InstructionReference: fmt.Sprintf("%#x", sayhi2)}})
+		if bps := client.ExpectSetInstructionBreakpointsResponse(t).Body.Breakpoints; len(bps) != 1 || bps[0].Id != 1 {
+			t.Errorf("got %#v, want breakpoint with Id=1", bps)
+		}
+		client.ReverseContinueRequest()
+		client.ExpectReverseContinueResponse(t)
+		client.ExpectStoppedEvent(t)
+		client.StackTraceRequest(0, 0, 1)
+		frameID := client.ExpectStackTraceResponse(t).Body.StackFrames[0].Id
+		client.RestartFrameRequest(frameID)
+		client.ExpectRestartFrameResponse(t)
+		expectGoto("restart", 12)
+
+		client.StackTraceRequest(0, 0, 1)
+		frameID = client.ExpectStackTraceResponse(t).Body.StackFrames[0].Id
+		client.RestartFrameRequest(frameID)
+		client.ExpectErrorResponseWith(t, UnableToRestartFrame, "already at the entry of main.sayhi", false)
+		client.RestartFrameRequest(frameID + 1000)
+		client.ExpectErrorResponseWith(t, UnableToRestartFrame, "unknown frame id", false)
+
+		// Without a checkpoint the recording restarts from its start.
+		client.RestartRequest()
+		client.ExpectRestartResponse(t)
+		if se := client.ExpectStoppedEvent(t); se.Body.Reason != "entry" {
+			t.Errorf("got %#v, want Reason=\"entry\"", se)
+		}
+
+		client.DisconnectRequest()
+		client.ExpectOutputEventDetachingKill(t)
+		client.ExpectDisconnectResponse(t)
+		client.ExpectTerminatedEvent(t)
+	})
+}
+
+func TestUndoReplayStepBackCallEntryAndSite(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("the undo backend is only supported on linux/amd64")
+	}
+	runTest(t, "continuetestprog", func(client *daptest.Client, fixture protest.Fixture) {
+		bi := proc.NewBinaryInfo(runtime.GOOS, runtime.GOARCH)
+		if err := bi.LoadBinaryInfo(fixture.Path, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+		// main.main calls main.sleepytime, the breakpoint is after its first
+		// instruction. The return address on the stack is what identifies
+		// the call site.
+		var callpc, sleepytime2 uint64
+		recording, _ := undotest.SetupExe(t, fixture.Path, func(syms map[string]uint64) []undotest.Event {
+			event := func(bbcount, pc uint64) undotest.Event {
+				return undotest.Event{Bbcount: bbcount, Thread: undotest.ExePid, PC: pc}
+			}
+			const mainsp = undotest.ExeStack + 0x800
+			mainfn := bi.LookupFunc()["main.main"][0]
+			callpc = findCall(t, fixture.Path, mainfn.Entry, mainfn.End, syms["main.sleepytime"])
+			sleepytime2 = syms["main.sleepytime"] + uint64(firstInstructionLen(t, fixture.Path, syms["main.sleepytime"]))
+			retaddr := make([]byte, 8)
+			binary.LittleEndian.PutUint64(retaddr, callpc+5)
+
+			start := event(100, syms["runtime.rt0_go"])
+			start.Regs = map[string]uint64{"rsp": mainsp}
+			call := event(1500, callpc)
+			entry := event(2000, syms["main.sleepytime"])
+			entry.Regs = map[string]uint64{"rsp": mainsp - 8}
+			entry.Writes = []undotest.Memory{{Addr: mainsp - 8, Data: retaddr}}
+			return []undotest.Event{start, event(1000, syms["main.main"]), call, entry, event(2100, sleepytime2), event(3000, syms["main.sayhi"]), event(4000, syms["runtime.main"])}
+		})
+
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "replay", "traceDirPath": recording, "stopOnEntry": true})
+		client.ExpectCapabilitiesEvent(t)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+		client.SetInstructionBreakpointsRequest([]dap.InstructionBreakpoint{{InstructionReference: fmt.Sprintf("%#x", sleepytime2)}})
+		client.ExpectSetInstructionBreakpointsResponse(t)
+		client.ConfigurationDoneRequest()
+		client.ExpectStoppedEvent(t)
+		client.ExpectConfigurationDoneResponse(t)
+
+		for _, tc := range []struct {
+			granularity dap.SteppingGranularity
+			name        string
+			line        int
+			pc          uint64
+		}{
+			{"callEntry", "main.sleepytime", 8, bi.LookupFunc()["main.sleepytime"][0].Entry},
+			{"callSite", "main.main", 17, callpc},
+		} {
+			client.ContinueRequest(1)
+			client.ExpectContinueResponse(t)
+			// The recording has no goroutine 1, step the one that stopped.
+			threadID := client.ExpectStoppedEvent(t).Body.ThreadId
+
+			client.StepBackRequestWithGranularity(threadID, tc.granularity)
+			client.ExpectContinuedEvent(t)
+			client.ExpectStepBackResponse(t)
+			se := client.ExpectStoppedEvent(t)
+			if se.Body.Reason != "step" {
+				t.Errorf("%s: got %#v, want Reason=\"step\"", tc.granularity, se)
+			}
+			client.StackTraceRequest(se.Body.ThreadId, 0, 1)
+			st := client.ExpectStackTraceResponse(t)
+			if len(st.Body.StackFrames) == 0 {
+				t.Fatalf("%s: got %#v, want a stack frame", tc.granularity, st)
+			}
+			frame := st.Body.StackFrames[0]
+			if frame.Name != tc.name || frame.Line != tc.line || frame.InstructionPointerReference != fmt.Sprintf("%#x", tc.pc) {
+				t.Errorf("%s: got %#v, want stopped in %s at line %d and pc %#x", tc.granularity, frame, tc.name, tc.line, tc.pc)
+			}
+		}
+
+		client.DisconnectRequest()
+		client.ExpectOutputEventDetachingKill(t)
+		client.ExpectDisconnectResponse(t)
+		client.ExpectTerminatedEvent(t)
+	})
+}
+
+// findCall returns the address of the first call to target by the code
+// between start and end in the executable at path.
+func findCall(t *testing.T, path string, start, end, target uint64) uint64 {
+	f, err := elf.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	text := f.Section(".text")
+	buf := make([]byte, end-start)
+	if _, err := text.ReadAt(buf, int64(start-text.Addr)); err != nil {
+		t.Fatal(err)
+	}
+	for pc := start; pc < end; {
+		inst, err := x86asm.Decode(buf[pc-start:], 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rel, ok := inst.Args[0].(x86asm.Rel); ok && inst.Op == x86asm.CALL && pc+uint64(inst.Len)+uint64(int64(rel)) == target {
+			return pc
+		}
+		pc += uint64(inst.Len)
+	}
+	t.Fatalf("could not find call to %#x", target)
+	return 0
+}
+
+// firstInstructionLen returns the length of the instruction at addr in
+// the executable at path.
+func firstInstructionLen(t *testing.T, path string, addr uint64) int {
+	f, err := elf.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	text := f.Section(".text")
+	buf := make([]byte, 16)
+	if _, err := text.ReadAt(buf, int64(addr-text.Addr)); err != nil {
+		t.Fatal(err)
+	}
+	inst, err := x86asm.Decode(buf, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inst.Len
+}
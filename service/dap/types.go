@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+
+	"github.com/google/go-dap"
 )
 
 // Launch debug sessions support the following modes:
@@ -11,7 +13,7 @@ import (
 //	-- [DEFAULT] "debug" - builds and launches debugger for specified program (similar to 'dlv debug')
 //
 //	   Required args: program
-//	   Optional args with default: output, cwd, noDebug
+//	   Optional args with default: output, cwd, noDebug, console
 //	   Optional args: buildFlags, args
 //
 //	-- "test" - builds and launches debugger for specified test (similar to 'dlv test')
@@ -21,10 +23,11 @@ import (
 //	-- "exec" - launches debugger for precompiled binary (similar to 'dlv exec')
 //
 //	   Required args: program
-//	   Optional args with default: cwd, noDebug
+//	   Optional args with default: cwd, noDebug, console
 //	   Optional args: args
 //
-//	-- "replay" - replays a trace generated by mozilla rr. Mozilla rr must be installed.
+//	-- "replay" - replays a trace generated by mozilla rr or a LiveRecorder recording.
+//	   Mozilla rr or UDB, respectively, must be installed.
 //
 //	   Required args: traceDirPath
 //	   Optional args: args
@@ -44,6 +47,14 @@ func isValidLaunchMode(mode string) bool {
 	return false
 }
 
+func isValidConsole(console string) bool {
+	switch console {
+	case "internalConsole", "integratedTerminal", "externalTerminal":
+		return true
+	}
+	return false
+}
+
 // Default values for Launch/Attach configs.
 // Used to initialize configuration variables before decoding
 // arguments in launch/attach requests.
@@ -54,6 +65,7 @@ var (
 	}
 	defaultLaunchConfig = LaunchConfig{
 		Mode:                     "debug",
+		Console:                  "internalConsole",
 		LaunchAttachCommonConfig: defaultLaunchAttachCommonConfig,
 	}
 	defaultAttachConfig = AttachConfig{
@@ -68,7 +80,7 @@ type LaunchConfig struct {
 	//   "debug": compiles your program with optimizations disabled, starts and attaches to it.
 	//   "test": compiles your unit test program with optimizations disabled, starts and attaches to it.
 	//   "exec": executes a precompiled binary and begins a debug session.
-	//   "replay": replays an rr trace.
+	//   "replay": replays an rr trace or a LiveRecorder recording.
 	//   "core": examines a core dump.
 	//
 	// Default is "debug".
@@ -115,7 +127,8 @@ type LaunchConfig struct {
 	// NoDebug is used to run the program without debugging.
 	NoDebug bool `json:"noDebug,omitempty"`
 
-	// TraceDirPath is the trace directory path for replay mode.
+	// TraceDirPath is the trace directory path, or the path of the
+	// LiveRecorder recording, for replay mode.
 	// Relative path is interpreted as a path relative to Delve's
 	// current working directory.
 	// This is required for "replay" mode but unused in other modes.
@@ -139,6 +152,21 @@ type LaunchConfig struct {
 	// directory.
 	DlvCwd string `json:"dlvCwd,omitempty"`
 
+	// Console specifies where the standard input, output and error of the
+	// program are connected in "debug", "test" and "exec" modes.
+	// Acceptable values are:
+	//   "internalConsole": the output of the program is sent to the client
+	//                      as output events with category stdout/stderr.
+	//   "integratedTerminal": the program uses a terminal started by the
+	//                      client in its integrated terminal.
+	//   "externalTerminal": the program uses a terminal started by the
+	//                      client in an external terminal window.
+	// The terminal consoles require a client that supports the
+	// runInTerminal request.
+	//
+	// Default is "internalConsole".
+	Console string `json:"console,omitempty"`
+
 	// Env specifies optional environment variables for Delve server
 	// in addition to the environment variables Delve initially
 	// started with.
@@ -236,6 +264,16 @@ type AttachConfig struct {
 	LaunchAttachCommonConfig
 }
 
+// RestartConfig is the collection of Delve-specific attributes recognized
+// in the arguments of restart requests, in addition to the launch or attach
+// configuration.
+type RestartConfig struct {
+	// Checkpoint is the ID of the checkpoint the debug session of a
+	// recording restarts from.
+	// Default is the start of the recording.
+	Checkpoint int `json:"checkpoint,omitempty"`
+}
+
 // unmarshalLaunchAttachArgs wraps unmarshalling of launch/attach request's
 // arguments attribute. Upon unmarshal failure, it returns an error massaged
 // to be suitable for end-users.
@@ -258,6 +296,103 @@ func unmarshalLaunchAttachArgs(input json.RawMessage, config interface{}) error
 	return nil
 }
 
+// CheckpointsRequest is a Delve-specific request, with command
+// "checkpoints", to manage the checkpoints of a recorded target.
+// Without arguments it only lists the checkpoints. The response lists the
+// checkpoints after the request was carried out.
+type CheckpointsRequest struct {
+	dap.Request
+
+	Arguments CheckpointsArguments `json:"arguments,omitempty"`
+}
+
+func (r *CheckpointsRequest) GetRequest() *dap.Request { return &r.Request }
+
+// CheckpointsArguments are the arguments of a checkpoints request, at
+// most one of them should be set.
+type CheckpointsArguments struct {
+	// Create, if not nil, creates a checkpoint at the current position
+	// with this note. If empty the note defaults to the current
+	// filename:line position.
+	Create *string `json:"create,omitempty"`
+	// Clear, if not zero, deletes the checkpoint with this ID.
+	Clear int `json:"clear,omitempty"`
+	// Goto, if not zero, moves the target to the checkpoint with this ID.
+	// A stopped event with reason "goto" follows the response.
+	Goto int `json:"goto,omitempty"`
+}
+
+// CheckpointsResponse is the response to a checkpoints request.
+type CheckpointsResponse struct {
+	dap.Response
+
+	Body CheckpointsBody `json:"body"`
+}
+
+func (r *CheckpointsResponse) GetResponse() *dap.Response { return &r.Response }
+
+// CheckpointsEvent is a Delve-specific event, with event name
+// "checkpoints", sent when the checkpoints of the target change.
+type CheckpointsEvent struct {
+	dap.Event
+
+	Body CheckpointsBody `json:"body"`
+}
+
+func (e *CheckpointsEvent) GetEvent() *dap.Event { return &e.Event }
+
+// CheckpointsBody is the body of checkpoints responses and events.
+type CheckpointsBody struct {
+	Checkpoints []Checkpoint `json:"checkpoints"`
+}
+
+// Checkpoint is a position of a recorded target that can be returned to.
+type Checkpoint struct {
+	ID int `json:"id"`
+	// When is the position of the checkpoint in the recording.
+	When string `json:"when"`
+	// Where is the note of the checkpoint.
+	Where string `json:"where"`
+}
+
+// Module is a dap.Module with the Delve-specific attributes shown in the
+// additional columns of the modules view, see moduleColumns.
+type Module struct {
+	dap.Module
+
+	// BuildID is the GNU build ID of the module.
+	BuildID string `json:"buildId,omitempty"`
+}
+
+// ModulesResponse is the response to a modules request, it is a
+// dap.ModulesResponse with Delve-specific module attributes.
+type ModulesResponse struct {
+	dap.Response
+
+	Body ModulesResponseBody `json:"body"`
+}
+
+func (r *ModulesResponse) GetResponse() *dap.Response { return &r.Response }
+
+type ModulesResponseBody struct {
+	Modules      []Module `json:"modules"`
+	TotalModules int      `json:"totalModules,omitempty"`
+}
+
+// ModuleEvent is a dap.ModuleEvent with Delve-specific module attributes.
+type ModuleEvent struct {
+	dap.Event
+
+	Body ModuleEventBody `json:"body"`
+}
+
+func (e *ModuleEvent) GetEvent() *dap.Event { return &e.Event }
+
+type ModuleEventBody struct {
+	Reason string `json:"reason"`
+	Module Module `json:"module"`
+}
+
 func prettyPrint(config interface{}) string {
 	pretty, err := json.MarshalIndent(config, "", "\t")
 	if err != nil {
@@ -5,11 +5,14 @@ import (
 	"debug/elf"
 	"debug/macho"
 	"debug/pe"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"go/ast"
 	"go/parser"
 	"go/token"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -80,6 +83,13 @@ type Debugger struct {
 	dumpState proc.DumpState
 
 	breakpointIDCounter int
+
+	// foreignBreakpoints are the breakpoints saved with the recording that
+	// could not be restored, they are kept so that they can be saved again.
+	foreignBreakpoints []proc.SessionBreakpoint
+	// sessionLocations are the locations of the breakpoints restored from
+	// the recording, as they were saved.
+	sessionLocations map[int]string
 }
 
 type ExecuteKind int
@@ -136,12 +146,26 @@ type Config struct {
 	// ExecuteKind contains the kind of the executed program.
 	ExecuteKind ExecuteKind
 
-	// Redirects specifies redirect rules for stdin, stdout and stderr
-	Redirects [3]string
+	// Stdin is the path of a file to use as the standard input of the
+	// target process.
+	Stdin string
+	// Stdout and Stderr specify where the standard output and standard error
+	// of the target process are redirected.
+	Stdout proc.OutputRedirect
+	Stderr proc.OutputRedirect
 
 	// DisableASLR disables ASLR
 	DisableASLR bool
 
+	// PrettyPrinters are used to format the values of variables returned by
+	// EvalVariableInScope, LocalVariables, FunctionArguments and
+	// PackageVariables.
+	PrettyPrinters []proc.PrettyPrinter
+
+	// SubstitutePath are the path substitution rules used to resolve the
+	// locations of the breakpoints saved with a recording.
+	SubstitutePath [][2]string
+
 	RrOnProcessPid int
 }
 
@@ -192,6 +216,7 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 			d.target.Detach(true)
 			return nil, err
 		}
+		d.loadSessionBreakpoints()
 
 	default:
 		d.log.Infof("launching process with args: %v", d.processArgs)
@@ -262,61 +287,69 @@ func (d *Debugger) Launch(processArgs []string, wd string) (*proc.TargetGroup, e
 
 	switch d.config.Backend {
 	case "native":
-		return native.Launch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Redirects)
+		return native.Launch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Stdin, d.config.Stdout, d.config.Stderr)
 	case "lldb":
-		return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Redirects))
+		return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Stdin, d.config.Stdout, d.config.Stderr))
 	case "rr":
 		if d.target != nil {
 			// restart should not call us if the backend is 'rr'
 			panic("internal error: call to Launch with rr backend and target already exists")
 		}
 
-		run, stop, err := gdbserial.RecordAsync(processArgs, wd, false, d.config.Redirects)
+		run, stop, err := gdbserial.RecordAsync(processArgs, wd, false, d.config.Stdin, d.config.Stdout, d.config.Stderr)
 		if err != nil {
 			return nil, err
 		}
 
-		// let the initialization proceed but hold the targetMutex lock so that
-		// any other request to debugger will block except State(nowait=true) and
-		// Command(halt).
-		d.targetMutex.Lock()
-		d.recordingStart(stop)
-
-		go func() {
-			defer d.targetMutex.Unlock()
-
-			grp, err := d.recordingRun(run)
-			if err != nil {
-				d.log.Errorf("could not record target: %v", err)
-				// this is ugly but we can't respond to any client requests at this
-				// point so it's better if we die.
-				os.Exit(1)
-			}
-			d.recordingDone()
-			d.target = grp
-			if err := d.checkGoVersion(); err != nil {
-				d.log.Error(err)
-				err := d.target.Detach(true)
-				if err != nil {
-					d.log.Errorf("Error detaching from target: %v", err)
-				}
-			}
-		}()
+		d.recordAsync(run, stop)
 		return nil, nil
 	case "undo":
-		tgt, _, err := gdbserial.UndoRecordAndReplay(processArgs, wd, false, d.config.DebugInfoDirectories, d.config.Redirects)
+		tgt, _, err := gdbserial.UndoRecordAndReplay(processArgs, wd, false, d.config.DebugInfoDirectories, d.config.Stdin, d.config.Stdout, d.config.Stderr)
 		return tgt, err
 
 	case "default":
 		if runtime.GOOS == "darwin" {
-			return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Redirects))
+			return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Stdin, d.config.Stdout, d.config.Stderr))
 		}
-		return native.Launch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Redirects)
+		return native.Launch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Stdin, d.config.Stdout, d.config.Stderr)
 	default:
 		return nil, fmt.Errorf("unknown backend %q", d.config.Backend)
 	}
 }
 
+// recordAsync records the target in the background using run and then
+// replays the recording.
+// It lets the initialization proceed but holds the targetMutex lock until
+// the replay starts, so that any other request to debugger will block
+// except State(nowait=true) and Command(halt).
+func (d *Debugger) recordAsync(run func() (string, error), stop func() error) {
+	d.targetMutex.Lock()
+	d.recordingStart(stop)
+
+	go func() {
+		defer d.targetMutex.Unlock()
+
+		grp, err := d.recordingRun(run)
+		if err != nil {
+			d.log.Errorf("could not record target: %v", err)
+			// this is ugly but we can't respond to any client requests at this
+			// point so it's better if we die.
+			os.Exit(1)
+		}
+		d.recordingDone()
+		d.target = grp
+		if err := d.checkGoVersion(); err != nil {
+			d.log.Error(err)
+			err := d.target.Detach(true)
+			if err != nil {
+				d.log.Errorf("Error detaching from target: %v", err)
+			}
+			return
+		}
+		d.loadSessionBreakpoints()
+	}()
+}
+
 func (d *Debugger) recordingStart(stop func() error) {
 	d.recordMutex.Lock()
 	d.stopRecording = stop
@@ -341,6 +374,9 @@ func (d *Debugger) recordingRun(run func() (string, error)) (*proc.TargetGroup,
 		return nil, err
 	}
 
+	if d.config.Backend == "undo" {
+		return gdbserial.UndoReplay(tracedir, false, d.config.DebugInfoDirectories, strings.Join(d.processArgs, " "))
+	}
 	return gdbserial.Replay(tracedir, false, true, d.config.DebugInfoDirectories, 0, strings.Join(d.processArgs, " "))
 }
 
@@ -356,6 +392,14 @@ func (d *Debugger) Attach(pid int, path string) (*proc.TargetGroup, error) {
 			return betterGdbserialLaunchError(gdbserial.LLDBAttach(pid, path, d.config.DebugInfoDirectories))
 		}
 		return native.Attach(pid, d.config.DebugInfoDirectories)
+	case "undo":
+		run, stop, err := gdbserial.UndoAttachAsync(pid, false)
+		if err != nil {
+			return nil, err
+		}
+
+		d.recordAsync(run, stop)
+		return nil, nil
 	default:
 		return nil, fmt.Errorf("unknown backend %q", d.config.Backend)
 	}
@@ -396,7 +440,10 @@ func (d *Debugger) LastModified() time.Time {
 func (d *Debugger) FunctionReturnLocations(fnName string) ([]uint64, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
+	return d.functionReturnLocations(fnName)
+}
 
+func (d *Debugger) functionReturnLocations(fnName string) ([]uint64, error) {
 	if len(d.target.Targets()) > 1 {
 		return nil, ErrNotImplementedWithMultitarget
 	}
@@ -483,7 +530,9 @@ func (d *Debugger) Restart(rerecord bool, pos string, resetArgs bool, newArgs []
 	}
 	if resetArgs {
 		d.processArgs = append([]string{d.processArgs[0]}, newArgs...)
-		d.config.Redirects = newRedirects
+		d.config.Stdin = newRedirects[0]
+		d.config.Stdout = proc.OutputRedirect{Path: newRedirects[1]}
+		d.config.Stderr = proc.OutputRedirect{Path: newRedirects[2]}
 	}
 	var grp *proc.TargetGroup
 	var err error
@@ -507,7 +556,7 @@ func (d *Debugger) Restart(rerecord bool, pos string, resetArgs bool, newArgs []
 	}
 
 	if recorded && d.config.Backend == "rr" {
-		run, stop, err2 := gdbserial.RecordAsync(d.processArgs, d.config.WorkingDir, false, d.config.Redirects)
+		run, stop, err2 := gdbserial.RecordAsync(d.processArgs, d.config.WorkingDir, false, d.config.Stdin, d.config.Stdout, d.config.Stderr)
 		if err2 != nil {
 			return nil, err2
 		}
@@ -516,7 +565,7 @@ func (d *Debugger) Restart(rerecord bool, pos string, resetArgs bool, newArgs []
 		grp, err = d.recordingRun(run)
 		d.recordingDone()
 	} else if recorded && d.config.Backend == "undo" {
-		grp, _, err = gdbserial.UndoRecordAndReplay(d.processArgs, d.config.WorkingDir, false, d.config.DebugInfoDirectories, d.config.Redirects)
+		grp, _, err = gdbserial.UndoRecordAndReplay(d.processArgs, d.config.WorkingDir, false, d.config.DebugInfoDirectories, d.config.Stdin, d.config.Stdout, d.config.Stderr)
 	} else {
 		grp, err = d.Launch(d.processArgs, d.config.WorkingDir)
 	}
@@ -628,6 +677,9 @@ func (d *Debugger) state(retLoadCfg *proc.LoadConfig, withBreakpointInfo bool) (
 // The ways of specifying a breakpoint are listed below in the order they are considered by
 // this function:
 //
+// - If requestedBp.Catch is not an empty string a catchpoint will be
+// created for the specified event, see proc.ParseCatchpoint.
+//
 // - If requestedBp.TraceReturn is true then it is expected that
 // requestedBp.Addrs will contain the list of return addresses
 // supplied by the caller.
@@ -658,6 +710,15 @@ func (d *Debugger) CreateBreakpoint(requestedBp *api.Breakpoint, locExpr string,
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
+	createdBp, err := d.createBreakpoint(requestedBp, locExpr, substitutePathRules, suspended)
+	if err != nil {
+		return nil, err
+	}
+	d.saveSessionBreakpoints()
+	return createdBp, nil
+}
+
+func (d *Debugger) createBreakpoint(requestedBp *api.Breakpoint, locExpr string, substitutePathRules [][2]string, suspended bool) (*api.Breakpoint, error) {
 	var (
 		setbp proc.SetBreakpoint
 		err   error
@@ -674,7 +735,15 @@ func (d *Debugger) CreateBreakpoint(requestedBp *api.Breakpoint, locExpr string,
 		return abp, proc.BreakpointExistsError{File: lbp.File, Line: lbp.Line}
 	}
 
+	if requestedBp.After != 0 {
+		if err := d.checkAfterBreakpoint(requestedBp.ID, requestedBp.After); err != nil {
+			return nil, err
+		}
+	}
+
 	switch {
+	case requestedBp.Catch != "":
+		setbp.Catch, err = proc.ParseCatchpoint(requestedBp.Catch)
 	case requestedBp.TraceReturn:
 		if len(d.target.Targets()) != 1 {
 			return nil, ErrNotImplementedWithMultitarget
@@ -755,8 +824,10 @@ func (d *Debugger) CreateBreakpoint(requestedBp *api.Breakpoint, locExpr string,
 
 	err = copyLogicalBreakpointInfo(lbp, requestedBp)
 	if err != nil {
+		delete(d.target.LogicalBreakpoints, id)
 		return nil, err
 	}
+	d.target.InheritAfterHits(lbp)
 
 	lbp.Set = setbp
 
@@ -805,7 +876,11 @@ func (d *Debugger) convertBreakpoint(lbp *proc.LogicalBreakpoint) *api.Breakpoin
 }
 
 func (d *Debugger) ConvertThreadBreakpoint(thread proc.Thread) *api.Breakpoint {
-	if b := thread.Breakpoint(); b.Active && b.Breakpoint.Logical != nil {
+	b := thread.Breakpoint()
+	if b.Active && b.Catchpoint != nil {
+		return d.convertBreakpoint(b.Catchpoint)
+	}
+	if b.Active && b.Breakpoint.Logical != nil {
 		return d.convertBreakpoint(b.Breakpoint.Logical)
 	}
 	return nil
@@ -829,11 +904,17 @@ func (d *Debugger) amendBreakpoint(amend *api.Breakpoint) error {
 	if original == nil {
 		return fmt.Errorf("no breakpoint with ID %d", amend.ID)
 	}
+	if amend.After != 0 && amend.After != original.After {
+		if err := d.checkAfterBreakpoint(amend.ID, amend.After); err != nil {
+			return err
+		}
+	}
 	enabledBefore := original.Enabled
 	err := copyLogicalBreakpointInfo(original, amend)
 	if err != nil {
 		return err
 	}
+	d.target.InheritAfterHits(original)
 	original.Enabled = !amend.Disabled
 
 	switch {
@@ -860,6 +941,19 @@ func (d *Debugger) amendBreakpoint(amend *api.Breakpoint) error {
 	return nil
 }
 
+// checkAfterBreakpoint checks that the breakpoint with ID id can be made
+// to depend on the breakpoint with ID after, which must exist or have been
+// cleared after being hit.
+func (d *Debugger) checkAfterBreakpoint(id, after int) error {
+	if after == id {
+		return errors.New("a breakpoint can not depend on itself")
+	}
+	if d.target.LogicalBreakpoints[after] == nil && !d.target.WasTriggered(after) {
+		return fmt.Errorf("no breakpoint with ID %d", after)
+	}
+	return nil
+}
+
 func (d *Debugger) isWatchpoint(lbp *proc.LogicalBreakpoint) bool {
 	t := proc.ValidTargets{Group: d.target}
 	for t.Next() {
@@ -878,7 +972,9 @@ func (d *Debugger) AmendBreakpoint(amend *api.Breakpoint) error {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
-	return d.amendBreakpoint(amend)
+	err := d.amendBreakpoint(amend)
+	d.saveSessionBreakpoints()
+	return err
 }
 
 // CancelNext will clear internal breakpoints, thus cancelling the 'next',
@@ -921,6 +1017,10 @@ func copyLogicalBreakpointInfo(lbp *proc.LogicalBreakpoint, requested *api.Break
 		lbp.HitCondPerG = requested.HitCondPerG
 	}
 
+	lbp.After = requested.After
+	lbp.AfterPerG = requested.After != 0 && requested.AfterPerG
+	lbp.Once = requested.Once
+
 	return nil
 }
 
@@ -965,6 +1065,71 @@ func parseHitCondition(hitCond string) (token.Token, int, error) {
 	return opTok, val, nil
 }
 
+// CreateInternalBreakpoint sets a breakpoint of proc.InternalBreakpoint
+// kind at each of addrs in the selected target, stopping only when cond (if
+// not empty) is true. Internal breakpoints have no ID, are not listed by
+// Breakpoints and are not saved with the recording, they must be removed
+// with ClearInternalBreakpoints.
+func (d *Debugger) CreateInternalBreakpoint(addrs []uint64, cond string) ([]*proc.Breakpoint, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	var condExpr ast.Expr
+	if cond != "" {
+		var err error
+		condExpr, err = parser.ParseExpr(cond)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p := d.target.Selected
+	bps := make([]*proc.Breakpoint, 0, len(addrs))
+	for _, addr := range addrs {
+		bp, err := p.SetBreakpoint(0, addr, proc.InternalBreakpoint, condExpr)
+		if err != nil {
+			d.clearInternalBreakpoints(bps)
+			return nil, err
+		}
+		bps = append(bps, bp)
+	}
+	return bps, nil
+}
+
+// ClearInternalBreakpoints removes breakpoints created by
+// CreateInternalBreakpoint.
+func (d *Debugger) ClearInternalBreakpoints(bps []*proc.Breakpoint) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.clearInternalBreakpoints(bps)
+}
+
+func (d *Debugger) clearInternalBreakpoints(bps []*proc.Breakpoint) error {
+	var err error
+	for _, bp := range bps {
+		if err1 := d.target.Selected.ClearInternalBreakpoint(bp); err1 != nil && err == nil {
+			err = err1
+		}
+	}
+	return err
+}
+
+// StoppedOnInternalBreakpoint returns true if the current thread of the
+// selected target is stopped on one of bps.
+func (d *Debugger) StoppedOnInternalBreakpoint(bps []*proc.Breakpoint) bool {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	bpstate := d.target.Selected.CurrentThread().Breakpoint()
+	if !bpstate.Active {
+		return false
+	}
+	for _, bp := range bps {
+		if bpstate.Breakpoint == bp {
+			return true
+		}
+	}
+	return false
+}
+
 // ClearBreakpoint clears a breakpoint.
 func (d *Debugger) ClearBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoint, error) {
 	d.targetMutex.Lock()
@@ -985,12 +1150,334 @@ func (d *Debugger) ClearBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoint
 		return nil, err
 	}
 
-	delete(d.target.LogicalBreakpoints, requestedBp.ID)
+	d.target.DeleteLogicalBreakpoint(lbp)
+	d.saveSessionBreakpoints()
 
 	d.log.Infof("cleared breakpoint: %#v", clearedBp)
 	return clearedBp, nil
 }
 
+// loadSessionBreakpoints creates the breakpoints saved with the recording,
+// possibly by a different debugger. Breakpoints whose location can not be
+// found or set are kept in d.foreignBreakpoints.
+func (d *Debugger) loadSessionBreakpoints() {
+	sbps, maxID, err := d.target.SessionBreakpoints()
+	if err != nil {
+		d.log.Errorf("could not load breakpoints saved with the recording: %v", err)
+		return
+	}
+	sort.Slice(sbps, func(i, j int) bool { return sbps[i].ID < sbps[j].ID })
+	d.sessionLocations = make(map[int]string)
+	for _, sbp := range sbps {
+		bp, err := d.createSessionBreakpoint(sbp)
+		if err != nil {
+			d.log.Warnf("could not restore breakpoint %d saved with the recording: %v", sbp.ID, err)
+			d.foreignBreakpoints = append(d.foreignBreakpoints, sbp)
+			continue
+		}
+		d.sessionLocations[bp.ID] = sbp.Location
+		if !sbp.Enabled {
+			lbp := d.target.LogicalBreakpoints[bp.ID]
+			if err := d.target.DisableBreakpoint(lbp); err != nil {
+				d.log.Warnf("could not disable breakpoint %d saved with the recording: %v", bp.ID, err)
+			}
+		}
+	}
+	if maxID > d.breakpointIDCounter {
+		d.breakpointIDCounter = maxID
+	}
+}
+
+// createSessionBreakpoint creates a breakpoint saved with the recording,
+// its location is resolved as if it had been typed by the user.
+func (d *Debugger) createSessionBreakpoint(sbp proc.SessionBreakpoint) (*api.Breakpoint, error) {
+	loc, err := locspec.Parse(sbp.Location)
+	if err != nil {
+		return nil, err
+	}
+	locs, err := d.findLocation(-1, 0, 0, sbp.Location, loc, false, d.config.SubstitutePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(locs) != 1 {
+		return nil, fmt.Errorf("location %q matches %d locations", sbp.Location, len(locs))
+	}
+	requestedBp := &api.Breakpoint{ID: sbp.ID, Cond: sbp.Cond, Addr: locs[0].PC, Addrs: locs[0].PCs, AddrPid: locs[0].PCPids}
+	return d.createBreakpoint(requestedBp, sbp.Location, d.config.SubstitutePath, false)
+}
+
+// saveSessionBreakpoints saves the user breakpoints with the recording, so
+// that they are restored the next time it is replayed. Watchpoints,
+// tracepoints and breakpoints set on an address are not saved.
+func (d *Debugger) saveSessionBreakpoints() {
+	if recorded, _ := d.target.Recorded(); !recorded {
+		return
+	}
+	sbps := append([]proc.SessionBreakpoint(nil), d.foreignBreakpoints...)
+	for _, lbp := range d.target.LogicalBreakpoints {
+		if lbp.LogicalID <= 0 || lbp.Tracepoint || lbp.TraceReturn || d.isWatchpoint(lbp) {
+			continue
+		}
+		sbp := proc.SessionBreakpoint{ID: lbp.LogicalID, Enabled: lbp.Enabled}
+		switch {
+		case d.sessionLocations[lbp.LogicalID] != "":
+			sbp.Location = d.sessionLocations[lbp.LogicalID]
+		case lbp.Set.File != "":
+			sbp.Location = fmt.Sprintf("%s:%d", lbp.Set.File, lbp.Set.Line)
+		case lbp.Set.FunctionName != "" && lbp.Set.Line == 0:
+			sbp.Location = lbp.Set.FunctionName
+		case lbp.File != "":
+			sbp.Location = fmt.Sprintf("%s:%d", lbp.File, lbp.Line)
+		default:
+			continue
+		}
+		if lbp.Cond != nil {
+			sbp.Cond = api.ConvertLogicalBreakpoint(lbp).Cond
+		}
+		sbps = append(sbps, sbp)
+	}
+	sort.Slice(sbps, func(i, j int) bool { return sbps[i].ID < sbps[j].ID })
+	if err := d.target.SaveSessionBreakpoints(sbps, d.breakpointIDCounter); err != nil {
+		d.log.Errorf("could not save breakpoints with the recording: %v", err)
+	}
+}
+
+// SaveBreakpoints writes the user breakpoints to the file path, as a JSON
+// list of api.SavedBreakpoint, and returns them. Watchpoints, breakpoints
+// set on addresses and the return breakpoints of tracepoints, which are
+// recorded with the tracepoint, are not saved.
+func (d *Debugger) SaveBreakpoints(path string) ([]api.SavedBreakpoint, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	lbps := make([]*proc.LogicalBreakpoint, 0, len(d.target.LogicalBreakpoints))
+	returnTraced := make(map[string]bool)
+	for _, lbp := range d.target.LogicalBreakpoints {
+		if lbp.TraceReturn {
+			returnTraced[lbp.FunctionName] = true
+		}
+		lbps = append(lbps, lbp)
+	}
+	sort.Slice(lbps, func(i, j int) bool { return lbps[i].LogicalID < lbps[j].LogicalID })
+
+	sbps := []api.SavedBreakpoint{}
+	for _, lbp := range lbps {
+		if lbp.LogicalID <= 0 || lbp.TraceReturn || d.isWatchpoint(lbp) {
+			continue
+		}
+		sbp, ok := d.savedBreakpoint(lbp)
+		if !ok {
+			continue
+		}
+		sbp.TraceReturn = sbp.Tracepoint && sbp.LineOffset == 0 && returnTraced[sbp.FunctionName]
+		sbps = append(sbps, sbp)
+	}
+
+	buf, err := json.MarshalIndent(sbps, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, append(buf, '\n'), 0644); err != nil {
+		return nil, err
+	}
+	return sbps, nil
+}
+
+// savedBreakpoint converts lbp to an api.SavedBreakpoint, anchoring it to
+// the function containing it when possible. It returns false if the
+// location of lbp can not be saved.
+func (d *Debugger) savedBreakpoint(lbp *proc.LogicalBreakpoint) (api.SavedBreakpoint, bool) {
+	abp := api.ConvertLogicalBreakpoint(lbp)
+	sbp := api.SavedBreakpoint{
+		ID:          lbp.LogicalID,
+		Name:        lbp.Name,
+		Cond:        abp.Cond,
+		HitCond:     abp.HitCond,
+		HitCondPerG: abp.HitCondPerG,
+		After:       lbp.After,
+		AfterPerG:   lbp.AfterPerG,
+		Once:        lbp.Once,
+		Tracepoint:  lbp.Tracepoint,
+		Goroutine:   lbp.Goroutine,
+		Stacktrace:  lbp.Stacktrace,
+		Variables:   lbp.Variables,
+		LoadArgs:    abp.LoadArgs,
+		LoadLocals:  abp.LoadLocals,
+		Disabled:    !lbp.Enabled,
+	}
+
+	if lbp.Set.Catch != nil {
+		sbp.Catch = lbp.Set.Catch.String()
+		return sbp, true
+	}
+
+	switch {
+	case lbp.Set.FunctionName != "":
+		sbp.FunctionName, sbp.LineOffset = lbp.Set.FunctionName, lbp.Set.Line
+	case lbp.FunctionName != "" && lbp.Line > 0:
+		// Only breakpoints in the same file as the start of the function can
+		// be anchored to it, this is not the case for inlined calls.
+		fns, err := d.target.Selected.BinInfo().FindFunction(lbp.FunctionName)
+		if err != nil {
+			break
+		}
+		file, line := d.target.Selected.BinInfo().EntryLineForFunc(fns[0])
+		if file == lbp.File && lbp.Line >= line {
+			sbp.FunctionName, sbp.LineOffset = lbp.FunctionName, lbp.Line-line
+		}
+	}
+
+	switch {
+	case lbp.Set.File != "":
+		sbp.File, sbp.Line = lbp.Set.File, lbp.Set.Line
+	case lbp.File != "" && lbp.Line > 0:
+		sbp.File, sbp.Line = lbp.File, lbp.Line
+	}
+
+	return sbp, sbp.FunctionName != "" || sbp.File != ""
+}
+
+// LoadBreakpoints creates the breakpoints saved to the file path by
+// SaveBreakpoints. Breakpoints anchored to a function are set at the same
+// line relative to the start of the function, their file and line are
+// used if that fails. Saved breakpoints that can not be created are
+// returned as discarded breakpoints.
+func (d *Debugger) LoadBreakpoints(path string) ([]*api.Breakpoint, []api.DiscardedBreakpoint, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sbps []api.SavedBreakpoint
+	if err := json.Unmarshal(buf, &sbps); err != nil {
+		return nil, nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	type loadedBreakpoint struct {
+		sbp     api.SavedBreakpoint
+		lbp     *proc.LogicalBreakpoint
+		returns []*proc.LogicalBreakpoint // breakpoints tracing the return values
+	}
+	var loaded []loadedBreakpoint
+	discarded := []api.DiscardedBreakpoint{}
+	discard := func(sbp api.SavedBreakpoint, err error) {
+		discarded = append(discarded, api.DiscardedBreakpoint{
+			Breakpoint: &api.Breakpoint{Name: sbp.Name, FunctionName: sbp.FunctionName, File: sbp.File, Line: sbp.Line, Catch: sbp.Catch},
+			Reason:     err.Error(),
+		})
+	}
+	newIDs := make(map[int]int)
+	for _, sbp := range sbps {
+		bp, returns, err := d.loadBreakpoint(sbp)
+		if err != nil {
+			discard(sbp, err)
+			continue
+		}
+		if sbp.ID > 0 {
+			newIDs[sbp.ID] = bp.ID
+		}
+		loaded = append(loaded, loadedBreakpoint{sbp, d.target.LogicalBreakpoints[bp.ID], returns})
+	}
+
+	// Dependencies between breakpoints are resolved once all of them are
+	// created, since the IDs of the loaded breakpoints differ from the saved
+	// ones.
+	created := []*api.Breakpoint{}
+	for _, lb := range loaded {
+		if lb.sbp.After != 0 {
+			if newIDs[lb.sbp.After] == 0 {
+				// Without its dependency the breakpoint would be triggered too
+				// early.
+				for _, lbp := range append([]*proc.LogicalBreakpoint{lb.lbp}, lb.returns...) {
+					if err := d.target.DisableBreakpoint(lbp); err != nil {
+						return nil, nil, err
+					}
+					delete(d.target.LogicalBreakpoints, lbp.LogicalID)
+				}
+				discard(lb.sbp, fmt.Errorf("breakpoint %d it depends on was not loaded", lb.sbp.After))
+				continue
+			}
+			lb.lbp.After, lb.lbp.AfterPerG = newIDs[lb.sbp.After], lb.sbp.AfterPerG
+		}
+		created = append(created, d.convertBreakpoint(lb.lbp))
+	}
+
+	d.saveSessionBreakpoints()
+	return created, discarded, nil
+}
+
+// loadBreakpoint creates the saved breakpoint sbp, it also returns the
+// breakpoints created to trace its return values.
+func (d *Debugger) loadBreakpoint(sbp api.SavedBreakpoint) (*api.Breakpoint, []*proc.LogicalBreakpoint, error) {
+	requestedBp := &api.Breakpoint{
+		Name:        sbp.Name,
+		Cond:        sbp.Cond,
+		HitCond:     sbp.HitCond,
+		HitCondPerG: sbp.HitCondPerG,
+		Once:        sbp.Once,
+		Tracepoint:  sbp.Tracepoint,
+		Goroutine:   sbp.Goroutine,
+		Stacktrace:  sbp.Stacktrace,
+		Variables:   sbp.Variables,
+		LoadArgs:    sbp.LoadArgs,
+		LoadLocals:  sbp.LoadLocals,
+	}
+
+	if err := api.ValidBreakpointName(sbp.Name); err != nil {
+		return nil, nil, err
+	}
+	if sbp.FunctionName == "" && sbp.File == "" && sbp.Catch == "" {
+		return nil, nil, errors.New("no location")
+	}
+	var (
+		bp  *api.Breakpoint
+		err error
+	)
+	if sbp.Catch != "" {
+		requestedBp.Catch = sbp.Catch
+		bp, err = d.createBreakpoint(requestedBp, "", nil, false)
+	}
+	if sbp.FunctionName != "" {
+		requestedBp.FunctionName, requestedBp.Line = sbp.FunctionName, sbp.LineOffset
+		bp, err = d.createBreakpoint(requestedBp, "", nil, false)
+	}
+	if (bp == nil || err != nil) && sbp.File != "" {
+		requestedBp.FunctionName = ""
+		requestedBp.File, requestedBp.Line = sbp.File, sbp.Line
+		bp, err = d.createBreakpoint(requestedBp, "", nil, false)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var returns []*proc.LogicalBreakpoint
+	if sbp.TraceReturn && requestedBp.FunctionName != "" {
+		addrs, err := d.functionReturnLocations(sbp.FunctionName)
+		if err != nil {
+			d.log.Warnf("could not trace the return values of %s: %v", sbp.FunctionName, err)
+		}
+		for _, addr := range addrs {
+			rbp, err := d.createBreakpoint(&api.Breakpoint{Addr: addr, TraceReturn: true, Line: -1, LoadArgs: sbp.LoadArgs}, "", nil, false)
+			if err != nil {
+				d.log.Warnf("could not trace the return values of %s: %v", sbp.FunctionName, err)
+				continue
+			}
+			returns = append(returns, d.target.LogicalBreakpoints[rbp.ID])
+		}
+	}
+
+	if sbp.Disabled {
+		lbp := d.target.LogicalBreakpoints[bp.ID]
+		if err := d.target.DisableBreakpoint(lbp); err != nil {
+			return nil, nil, err
+		}
+		bp = d.convertBreakpoint(lbp)
+	}
+	return bp, returns, nil
+}
+
 // isBpHitCondNotSatisfiable returns true if the breakpoint bp has a hit
 // condition that is no more satisfiable.
 // The hit condition is considered no more satisfiable if it can no longer be
@@ -1233,6 +1720,12 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 			return nil, err
 		}
 		err = d.target.Step()
+	case api.StepIntoCall:
+		d.log.Debugf("stepping into call at %#x", command.CallPC)
+		if err := d.target.ChangeDirection(proc.Forward); err != nil {
+			return nil, err
+		}
+		err = d.target.StepIntoCall(command.CallPC)
 	case api.StepInstruction:
 		d.log.Debug("single stepping")
 		if err := d.target.ChangeDirection(proc.Forward); err != nil {
@@ -1257,6 +1750,18 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 			return nil, err
 		}
 		err = d.target.StepOut()
+	case api.ReverseCallEntry:
+		d.log.Debug("reverse to call entry")
+		if err := d.target.ChangeDirection(proc.Backward); err != nil {
+			return nil, err
+		}
+		err = d.target.StepBackToCallEntry()
+	case api.ReverseCallSite:
+		d.log.Debug("reverse to call site")
+		if err := d.target.ChangeDirection(proc.Backward); err != nil {
+			return nil, err
+		}
+		err = d.target.StepBackToCallSite()
 	case api.SwitchThread:
 		d.log.Debugf("switching to thread %d", command.ThreadID)
 		t := proc.ValidTargets{Group: d.target}
@@ -1309,9 +1814,36 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 		bp.Disabled = true
 		d.amendBreakpoint(bp)
 	}
+	d.clearOnceBreakpoints(state)
 	return state, err
 }
 
+// clearOnceBreakpoints clears the breakpoints with the Once flag that
+// were triggered by the threads of state. The breakpoints reported in state
+// are not changed.
+func (d *Debugger) clearOnceBreakpoints(state *api.DebuggerState) {
+	cleared := false
+	for _, th := range state.Threads {
+		if th.Breakpoint == nil || !th.Breakpoint.Once {
+			continue
+		}
+		lbp := d.target.LogicalBreakpoints[th.Breakpoint.ID]
+		if lbp == nil {
+			continue
+		}
+		if err := d.target.DisableBreakpoint(lbp); err != nil {
+			d.log.Errorf("could not clear breakpoint %d: %v", lbp.LogicalID, err)
+			continue
+		}
+		d.target.DeleteLogicalBreakpoint(lbp)
+		d.log.Infof("cleared breakpoint %d after it was hit once", lbp.LogicalID)
+		cleared = true
+	}
+	if cleared {
+		d.saveSessionBreakpoints()
+	}
+}
+
 func (d *Debugger) collectBreakpointInformation(apiThread *api.Thread, thread proc.Thread) error {
 	if apiThread.Breakpoint == nil || apiThread.BreakpointInfo != nil {
 		return nil
@@ -1363,20 +1895,26 @@ func (d *Debugger) collectBreakpointInformation(apiThread *api.Thread, thread pr
 		bpi.Variables = make([]api.Variable, len(bp.Variables))
 	}
 	for i := range bp.Variables {
-		v, err := s.EvalExpression(bp.Variables[i], proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1})
+		cfg := proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+		v, err := s.EvalExpression(bp.Variables[i], cfg)
 		if err != nil {
 			bpi.Variables[i] = api.Variable{Name: bp.Variables[i], Unreadable: fmt.Sprintf("eval error: %v", err)}
 		} else {
+			d.prettyPrint(s, cfg, v)
 			bpi.Variables[i] = *api.ConvertVar(v)
 		}
 	}
 	if bp.LoadArgs != nil {
-		if vars, err := s.FunctionArguments(*api.LoadConfigToProc(bp.LoadArgs)); err == nil {
+		cfg := *api.LoadConfigToProc(bp.LoadArgs)
+		if vars, err := s.FunctionArguments(cfg); err == nil {
+			d.prettyPrint(s, cfg, vars...)
 			bpi.Arguments = api.ConvertVars(vars)
 		}
 	}
 	if bp.LoadLocals != nil {
-		if locals, err := s.LocalVariables(*api.LoadConfigToProc(bp.LoadLocals)); err == nil {
+		cfg := *api.LoadConfigToProc(bp.LoadLocals)
+		if locals, err := s.LocalVariables(cfg); err == nil {
+			d.prettyPrint(s, cfg, locals...)
 			bpi.Locals = api.ConvertVars(locals)
 		}
 	}
@@ -1407,6 +1945,21 @@ func (d *Debugger) Sources(filter string) ([]string, error) {
 	return files, nil
 }
 
+// EmbeddedSource returns the contents of the source file path, if they are
+// embedded in the debug information of the target binary.
+func (d *Debugger) EmbeddedSource(path string) (string, bool) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	t := proc.ValidTargets{Group: d.target}
+	for t.Next() {
+		if src, ok := t.BinInfo().EmbeddedSource(path); ok {
+			return src, true
+		}
+	}
+	return "", false
+}
+
 func uniq(s []string) []string {
 	if len(s) <= 0 {
 		return s
@@ -1477,6 +2030,32 @@ func (d *Debugger) Types(filter string) ([]string, error) {
 	return r, nil
 }
 
+// PackageVariableNames returns the names of the package variables in the
+// target process, optionally regexp filtered using regexp described in
+// 'filter'. Unlike PackageVariables it does not read their values.
+func (d *Debugger) PackageVariableNames(filter string) ([]string, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	regex, err := regexp.Compile(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter argument: %s", err.Error())
+	}
+
+	r := []string{}
+	t := proc.ValidTargets{Group: d.target}
+	for t.Next() {
+		for _, name := range t.BinInfo().PackageVariableNames() {
+			if regex.MatchString(name) {
+				r = append(r, name)
+			}
+		}
+	}
+	sort.Strings(r)
+	r = uniq(r)
+	return r, nil
+}
+
 // PackageVariables returns a list of package variables for the thread,
 // optionally regexp filtered using regexp described in 'filter'.
 func (d *Debugger) PackageVariables(filter string, cfg proc.LoadConfig) ([]*proc.Variable, error) {
@@ -1503,6 +2082,7 @@ func (d *Debugger) PackageVariables(filter string, cfg proc.LoadConfig) ([]*proc
 			pvr = append(pvr, pv[i])
 		}
 	}
+	d.prettyPrint(scope, cfg, pvr...)
 	return pvr, nil
 }
 
@@ -1548,7 +2128,12 @@ func (d *Debugger) LocalVariables(goid int64, frame, deferredCall int, cfg proc.
 	if err != nil {
 		return nil, err
 	}
-	return s.LocalVariables(cfg)
+	vars, err := s.LocalVariables(cfg)
+	if err != nil {
+		return nil, err
+	}
+	d.prettyPrint(s, cfg, vars...)
+	return vars, nil
 }
 
 // FunctionArguments returns the arguments to the current function.
@@ -1560,7 +2145,12 @@ func (d *Debugger) FunctionArguments(goid int64, frame, deferredCall int, cfg pr
 	if err != nil {
 		return nil, err
 	}
-	return s.FunctionArguments(cfg)
+	vars, err := s.FunctionArguments(cfg)
+	if err != nil {
+		return nil, err
+	}
+	d.prettyPrint(s, cfg, vars...)
+	return vars, nil
 }
 
 // Function returns the current function.
@@ -1585,7 +2175,39 @@ func (d *Debugger) EvalVariableInScope(goid int64, frame, deferredCall int, expr
 	if err != nil {
 		return nil, err
 	}
-	return s.EvalExpression(expr, cfg)
+	v, err := s.EvalExpression(expr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	d.prettyPrint(s, cfg, v)
+	return v, nil
+}
+
+// HeapReferences returns the chains of references, starting at GC roots,
+// that keep alive the heap object referred to by expr, evaluated in the
+// scope specified by goid, frame and deferredCall. The scan of the heap
+// stops with proc.ErrCancelled when cancel is closed.
+func (d *Debugger) HeapReferences(goid int64, frame, deferredCall int, expr string, cancel <-chan struct{}) (*proc.HeapReferences, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target.Selected, goid, frame, deferredCall)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.EvalExpression(expr, proc.LoadConfig{Cancel: cancel})
+	if err != nil {
+		return nil, err
+	}
+	return d.target.Selected.HeapReferences(v, cancel)
+}
+
+// prettyPrint formats vars with the pretty printers of the debugger
+// configuration.
+func (d *Debugger) prettyPrint(s *proc.EvalScope, cfg proc.LoadConfig, vars ...*proc.Variable) {
+	for _, v := range vars {
+		s.PrettyPrint(v, d.config.PrettyPrinters, cfg)
+	}
 }
 
 // LoadResliced will attempt to 'reslice' a map, array or slice so that the values
@@ -1616,6 +2238,14 @@ func (d *Debugger) Goroutines(start, count int) ([]*proc.G, int, error) {
 	return proc.GoroutinesInfo(d.target.Selected, start, count)
 }
 
+// GoroutinesWithCancel is like Goroutines but stops listing goroutines and
+// returns proc.ErrCancelled when cancel is closed.
+func (d *Debugger) GoroutinesWithCancel(start, count int, cancel <-chan struct{}) ([]*proc.G, int, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return proc.GoroutinesInfoWithCancel(d.target.Selected, start, count, cancel)
+}
+
 // FilterGoroutines returns the goroutines in gs that satisfy the specified filters.
 func (d *Debugger) FilterGoroutines(gs []*proc.G, filters []api.ListGoroutinesFilter) []*proc.G {
 	if len(filters) == 0 {
@@ -1771,6 +2401,53 @@ func (d *Debugger) Stacktrace(goroutineID int64, depth int, opts api.StacktraceO
 	}
 }
 
+// StepIntoTargets returns the calls made by the current line of the
+// specified goroutine that can be stepped into with the StepIntoCall
+// command.
+func (d *Debugger) StepIntoTargets(goroutineID int64) ([]proc.StepIntoTarget, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	if _, err := d.target.Valid(); err != nil {
+		return nil, err
+	}
+
+	g, err := proc.FindGoroutine(d.target.Selected, goroutineID)
+	if err != nil {
+		return nil, err
+	}
+	return proc.StepIntoTargets(d.target.Selected, g)
+}
+
+// StatementLines returns the lines of file, between startLine and endLine
+// included, where breakpoints can be set: the lines with at least one
+// address marked as the start of a statement in the line table.
+func (d *Debugger) StatementLines(file string, startLine, endLine int) []int {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	linenos := make([]int, 0, endLine-startLine+1)
+	for line := startLine; line <= endLine; line++ {
+		linenos = append(linenos, line)
+	}
+	found := make(map[int]bool)
+	t := proc.ValidTargets{Group: d.target}
+	for t.Next() {
+		for line, pcs := range t.BinInfo().AllPCsForFileLines(file, linenos) {
+			if len(pcs) > 0 {
+				found[line] = true
+			}
+		}
+	}
+	r := []int{}
+	for _, line := range linenos {
+		if found[line] {
+			r = append(r, line)
+		}
+	}
+	return r
+}
+
 // Ancestors returns the stacktraces for the ancestors of a goroutine.
 func (d *Debugger) Ancestors(goroutineID int64, numAncestors, depth int) ([]api.Ancestor, error) {
 	d.targetMutex.Lock()
@@ -2059,6 +2736,151 @@ func (d *Debugger) ClearCheckpoint(id int) error {
 	return d.target.ClearCheckpoint(id)
 }
 
+// TimeLimits returns the portion of the recording that execution is limited to.
+func (d *Debugger) TimeLimits() (string, string, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.TimeLimits()
+}
+
+// SetTimeLimits limits execution to the portion of the recording between
+// start and end.
+func (d *Debugger) SetTimeLimits(start, end string) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.SetTimeLimits(start, end)
+}
+
+// SaveRecording saves the portion of the recording between from and to as
+// a new recording in path, returning the checkpoints carried into it.
+func (d *Debugger) SaveRecording(path, from, to string) ([]proc.Checkpoint, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.SaveRecording(path, from, to)
+}
+
+// GoroutineTimeline replays the portion of the recording between from and
+// to and returns the goroutine scheduling events that happened in it. The
+// current position in the recording is restored before returning.
+func (d *Debugger) GoroutineTimeline(from, to string, resumeNotify chan struct{}) ([]api.GoroutineEvent, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	if recorded, _ := d.target.Recorded(); !recorded {
+		return nil, proc.ErrNotRecorded
+	}
+
+	d.setRunning(true)
+	defer d.setRunning(false)
+
+	d.target.ResumeNotify(resumeNotify)
+	events, err := d.target.GoroutineTimeline(from, to)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]api.GoroutineEvent, len(events))
+	for i := range events {
+		r[i] = api.ConvertGoroutineEvent(d.target.Selected, events[i])
+	}
+	return r, nil
+}
+
+// LastWrite travels back in the execution history of a recording to the
+// most recent write to the memory of expr, evaluated in the scope specified
+// by goid, frame and deferredCall.
+// The write is found by running backwards with a temporary write
+// watchpoint on expr, which is cleared before returning. If the target
+// stops for a different reason, for example because it hits a breakpoint,
+// the returned LastWrite will have Found set to false.
+func (d *Debugger) LastWrite(goid int64, frame, deferredCall int, expr string, cfg proc.LoadConfig, depth int, resumeNotify chan struct{}) (*api.LastWrite, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	if recorded, _ := d.target.Recorded(); !recorded {
+		return nil, proc.ErrNotRecorded
+	}
+
+	d.setRunning(true)
+	defer d.setRunning(false)
+
+	p := d.target.Selected
+	s, err := proc.ConvertEvalScope(p, goid, frame, deferredCall)
+	if err != nil {
+		return nil, err
+	}
+	newv, err := s.EvalExpression(expr, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	wp, err := p.SetInternalWatchpoint(s, expr, proc.WatchWrite)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := p.ClearInternalBreakpoint(wp); err != nil {
+			d.log.Errorf("could not clear watchpoint on %s: %v", expr, err)
+		}
+	}()
+
+	dir := d.target.GetDirection()
+	if err := d.target.ChangeDirection(proc.Backward); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := d.target.ChangeDirection(dir); err != nil {
+			d.log.Errorf("could not restore direction: %v", err)
+		}
+	}()
+
+	d.target.ResumeNotify(resumeNotify)
+	if err := d.target.Continue(); err != nil {
+		return nil, err
+	}
+
+	state, err := d.state(nil, true)
+	if err != nil {
+		return nil, err
+	}
+	r := &api.LastWrite{State: *state}
+
+	tgt := d.target.Selected
+	th := tgt.CurrentThread()
+	bp := th.Breakpoint().Breakpoint
+	if bp != wp || tgt != p {
+		return r, nil
+	}
+	r.Found = true
+
+	r.NewValue = api.ConvertVar(newv)
+	r.OldValue = &api.Variable{Name: expr}
+	// The expression could refer to variables that are not in scope at the
+	// point of the write, read the old value directly from the watched
+	// address instead.
+	oldexpr := fmt.Sprintf("*(*%q)(%#x)", api.PrettyTypeName(newv.DwarfType), bp.Addr)
+	if s, err := proc.ConvertEvalScope(tgt, -1, 0, 0); err != nil {
+		r.OldValue.Unreadable = err.Error()
+	} else if oldv, err := s.EvalExpression(oldexpr, cfg); err != nil {
+		r.OldValue.Unreadable = err.Error()
+	} else {
+		r.OldValue = api.ConvertVar(oldv)
+		r.OldValue.Name = expr
+	}
+
+	if g, _ := proc.GetG(th); g != nil {
+		r.Goroutine = api.ConvertGoroutine(tgt, g)
+	}
+	rawlocs, err := proc.ThreadStacktrace(th, depth)
+	if err != nil {
+		return nil, err
+	}
+	r.Stacktrace, err = d.convertStacktrace(rawlocs, nil)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
 // ListDynamicLibraries returns a list of loaded dynamic libraries.
 func (d *Debugger) ListDynamicLibraries() []*proc.Image {
 	d.targetMutex.Lock()
@@ -2086,6 +2908,18 @@ func (d *Debugger) ExamineMemory(address uint64, length int) ([]byte, error) {
 	return data, nil
 }
 
+// WriteMemory writes data to the memory of the selected target starting at
+// address and returns the number of bytes written.
+func (d *Debugger) WriteMemory(address uint64, data []byte) (int, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	if _, err := d.target.Valid(); err != nil {
+		return 0, err
+	}
+	return d.target.Selected.Memory().WriteMemory(address, data)
+}
+
 func (d *Debugger) GetVersion(out *api.GetVersionOut) error {
 	if d.config.CoreFile != "" {
 		if d.config.Backend == "rr" || d.config.Backend == "undo" {
@@ -2242,6 +3076,14 @@ func (d *Debugger) AttachPid() int {
 	return d.config.AttachPid
 }
 
+// SetProcessArgs replaces the command line arguments of the target process,
+// they are used the next time it is restarted.
+func (d *Debugger) SetProcessArgs(args []string) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	d.processArgs = append([]string{d.processArgs[0]}, args...)
+}
+
 func (d *Debugger) GetBufferedTracepoints() []api.TracepointResult {
 	traces := d.target.Selected.GetBufferedTracepoints()
 	if traces == nil {
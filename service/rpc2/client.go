@@ -177,6 +177,18 @@ func (c *RPCClient) ReverseStepOut() (*api.DebuggerState, error) {
 	return &out.State, err
 }
 
+func (c *RPCClient) ReverseCallEntry() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ReverseCallEntry}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) ReverseCallSite() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ReverseCallSite}, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) Call(goroutineID int64, expr string, unsafe bool) (*api.DebuggerState, error) {
 	var out CommandOut
 	err := c.call("Command", api.DebuggerCommand{Name: api.Call, ReturnInfoLoadConfig: c.retValLoadCfg, Expr: expr, UnsafeCall: unsafe, GoroutineID: goroutineID}, &out)
@@ -305,6 +317,20 @@ func (c *RPCClient) AmendBreakpoint(bp *api.Breakpoint) error {
 	return err
 }
 
+// SaveBreakpoints writes the user breakpoints to the file path.
+func (c *RPCClient) SaveBreakpoints(path string) ([]api.SavedBreakpoint, error) {
+	var out SaveBreakpointsOut
+	err := c.call("SaveBreakpoints", SaveBreakpointsIn{path}, &out)
+	return out.Breakpoints, err
+}
+
+// LoadBreakpoints creates the breakpoints saved to the file path.
+func (c *RPCClient) LoadBreakpoints(path string) ([]*api.Breakpoint, []api.DiscardedBreakpoint, error) {
+	var out LoadBreakpointsOut
+	err := c.call("LoadBreakpoints", LoadBreakpointsIn{path}, &out)
+	return out.Breakpoints, out.Discarded, err
+}
+
 func (c *RPCClient) CancelNext() error {
 	var out CancelNextOut
 	return c.call("CancelNext", CancelNextIn{}, &out)
@@ -328,6 +354,13 @@ func (c *RPCClient) EvalVariable(scope api.EvalScope, expr string, cfg api.LoadC
 	return out.Variable, err
 }
 
+// HeapReferences returns the chains of references that keep alive the heap object referred to by expr.
+func (c *RPCClient) HeapReferences(scope api.EvalScope, expr string) (*api.HeapReferences, error) {
+	var out HeapReferencesOut
+	err := c.call("HeapReferences", HeapReferencesIn{scope, expr}, &out)
+	return out.References, err
+}
+
 func (c *RPCClient) SetVariable(scope api.EvalScope, symbol, value string) error {
 	out := new(SetOut)
 	return c.call("Set", SetIn{scope, symbol, value}, out)
@@ -469,6 +502,40 @@ func (c *RPCClient) ClearCheckpoint(id int) error {
 	return err
 }
 
+// TimeLimits returns the portion of the recording that execution is limited to.
+func (c *RPCClient) TimeLimits() (string, string, error) {
+	var out TimeLimitsOut
+	err := c.call("TimeLimits", TimeLimitsIn{}, &out)
+	return out.Start, out.End, err
+}
+
+// SetTimeLimits limits execution to the portion of the recording between start and end.
+func (c *RPCClient) SetTimeLimits(start, end string) error {
+	var out SetTimeLimitsOut
+	return c.call("SetTimeLimits", SetTimeLimitsIn{start, end}, &out)
+}
+
+// SaveRecording saves the portion of the recording between from and to as a new recording in path.
+func (c *RPCClient) SaveRecording(path, from, to string) ([]api.Checkpoint, error) {
+	var out SaveRecordingOut
+	err := c.call("SaveRecording", SaveRecordingIn{path, from, to}, &out)
+	return out.Checkpoints, err
+}
+
+// GoroutineTimeline returns the goroutine scheduling events that happened between from and to.
+func (c *RPCClient) GoroutineTimeline(from, to string) ([]api.GoroutineEvent, error) {
+	var out GoroutineTimelineOut
+	err := c.call("GoroutineTimeline", GoroutineTimelineIn{from, to}, &out)
+	return out.Events, err
+}
+
+// LastWrite travels back to the most recent write to the memory of expr.
+func (c *RPCClient) LastWrite(scope api.EvalScope, expr string, cfg api.LoadConfig, depth int) (*api.LastWrite, error) {
+	var out LastWriteOut
+	err := c.call("LastWrite", LastWriteIn{scope, expr, cfg, depth}, &out)
+	return &out.LastWrite, err
+}
+
 func (c *RPCClient) SetReturnValuesLoadConfig(cfg *api.LoadConfig) {
 	c.retValLoadCfg = cfg
 }
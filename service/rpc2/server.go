@@ -366,6 +366,54 @@ func (s *RPCServer) AmendBreakpoint(arg AmendBreakpointIn, out *AmendBreakpointO
 	return s.debugger.AmendBreakpoint(&arg.Breakpoint)
 }
 
+type SaveBreakpointsIn struct {
+	// Path of the file the breakpoints are written to.
+	Path string
+}
+
+type SaveBreakpointsOut struct {
+	Breakpoints []api.SavedBreakpoint
+}
+
+// SaveBreakpoints writes the user breakpoints, along with their conditions
+// and the information retrieved when they are hit, to the file Path, so
+// that LoadBreakpoints can recreate them in a later debugging session.
+// Breakpoints are anchored to the function containing them. Watchpoints
+// and breakpoints set on addresses are not saved.
+func (s *RPCServer) SaveBreakpoints(arg SaveBreakpointsIn, out *SaveBreakpointsOut) error {
+	sbps, err := s.debugger.SaveBreakpoints(arg.Path)
+	if err != nil {
+		return err
+	}
+	out.Breakpoints = sbps
+	return nil
+}
+
+type LoadBreakpointsIn struct {
+	// Path of a file written by SaveBreakpoints.
+	Path string
+}
+
+type LoadBreakpointsOut struct {
+	// Breakpoints are the breakpoints created.
+	Breakpoints []*api.Breakpoint
+	// Discarded are the saved breakpoints that could not be created.
+	Discarded []api.DiscardedBreakpoint
+}
+
+// LoadBreakpoints creates the breakpoints saved to the file Path by
+// SaveBreakpoints. Breakpoints anchored to a function are set at the same
+// line relative to the start of the function, even if it moved.
+func (s *RPCServer) LoadBreakpoints(arg LoadBreakpointsIn, out *LoadBreakpointsOut) error {
+	bps, discarded, err := s.debugger.LoadBreakpoints(arg.Path)
+	if err != nil {
+		return err
+	}
+	out.Breakpoints = bps
+	out.Discarded = discarded
+	return nil
+}
+
 type CancelNextIn struct {
 }
 
@@ -542,6 +590,31 @@ func (s *RPCServer) Eval(arg EvalIn, out *EvalOut) error {
 	return nil
 }
 
+type HeapReferencesIn struct {
+	Scope api.EvalScope
+	Expr  string
+}
+
+type HeapReferencesOut struct {
+	References *api.HeapReferences
+}
+
+// HeapReferences returns the chains of references that keep alive the
+// heap object referred to by Expr: the object pointed to, if Expr is a
+// pointer, map, channel or function, the backing array of slices and
+// strings, the object containing Expr otherwise.
+// GC roots are package variables and the local variables and arguments
+// of all goroutines. Only pointers are followed, using the types of the
+// values, objects whose type is not known are scanned conservatively.
+func (s *RPCServer) HeapReferences(arg HeapReferencesIn, out *HeapReferencesOut) error {
+	refs, err := s.debugger.HeapReferences(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Expr, nil)
+	if err != nil {
+		return err
+	}
+	out.References = api.ConvertHeapReferences(refs)
+	return nil
+}
+
 type SetIn struct {
 	Scope  api.EvalScope
 	Symbol string
@@ -815,6 +888,119 @@ func (s *RPCServer) ClearCheckpoint(arg ClearCheckpointIn, out *ClearCheckpointO
 	return s.debugger.ClearCheckpoint(arg.ID)
 }
 
+type TimeLimitsIn struct {
+}
+
+type TimeLimitsOut struct {
+	// Start and End of the portion of the recording that execution is
+	// limited to, empty if there is no limit.
+	Start, End string
+}
+
+// TimeLimits returns the portion of the recording that execution is limited to.
+func (s *RPCServer) TimeLimits(arg TimeLimitsIn, out *TimeLimitsOut) error {
+	var err error
+	out.Start, out.End, err = s.debugger.TimeLimits()
+	return err
+}
+
+type SetTimeLimitsIn struct {
+	// Start and End are specified like the Position argument of Restart,
+	// an empty string removes the limit.
+	Start, End string
+}
+
+type SetTimeLimitsOut struct {
+}
+
+// SetTimeLimits limits continue and rewind to the portion of the recording
+// between Start and End. Only supported by the undo backend.
+func (s *RPCServer) SetTimeLimits(arg SetTimeLimitsIn, out *SetTimeLimitsOut) error {
+	return s.debugger.SetTimeLimits(arg.Start, arg.End)
+}
+
+type SaveRecordingIn struct {
+	// Path of the new recording.
+	Path string
+	// From and To delimit the portion of the recording to save, they are
+	// specified like the Position argument of Restart. An empty string means
+	// the corresponding end of the recording.
+	From, To string
+}
+
+type SaveRecordingOut struct {
+	// Checkpoints carried into the new recording, with the IDs they have in it.
+	Checkpoints []api.Checkpoint
+}
+
+// SaveRecording saves the portion of the recording between From and To as
+// a new standalone recording in Path. Checkpoints within the saved portion
+// of the recording are carried into the new recording. Only supported by
+// the undo backend.
+func (s *RPCServer) SaveRecording(arg SaveRecordingIn, out *SaveRecordingOut) error {
+	cps, err := s.debugger.SaveRecording(arg.Path, arg.From, arg.To)
+	if err != nil {
+		return err
+	}
+	out.Checkpoints = make([]api.Checkpoint, len(cps))
+	for i := range cps {
+		out.Checkpoints[i] = api.Checkpoint(cps[i])
+	}
+	return nil
+}
+
+type GoroutineTimelineIn struct {
+	// From and To delimit the portion of the recording to replay, they are
+	// specified like the position argument of Restart. Empty strings mean
+	// the corresponding end of the recording.
+	From string
+	To   string
+}
+
+type GoroutineTimelineOut struct {
+	Events []api.GoroutineEvent
+}
+
+// GoroutineTimeline replays the portion of the recording between From and
+// To and returns the goroutine scheduling events that happened in it. The
+// current position in the recording is restored afterwards. Only
+// available for recorded targets.
+func (s *RPCServer) GoroutineTimeline(arg GoroutineTimelineIn, cb service.RPCCallback) {
+	events, err := s.debugger.GoroutineTimeline(arg.From, arg.To, cb.SetupDoneChan())
+	if err != nil {
+		cb.Return(nil, err)
+		return
+	}
+	var out GoroutineTimelineOut
+	out.Events = events
+	cb.Return(out, nil)
+}
+
+type LastWriteIn struct {
+	Scope api.EvalScope
+	Expr  string
+	Cfg   api.LoadConfig
+	// StacktraceDepth is the maximum depth of the stacktrace of the write.
+	StacktraceDepth int
+}
+
+type LastWriteOut struct {
+	LastWrite api.LastWrite
+}
+
+// LastWrite travels back to the most recent write to the memory of the
+// expression Expr. Only available for recorded targets.
+func (s *RPCServer) LastWrite(arg LastWriteIn, cb service.RPCCallback) {
+	lw, err := s.debugger.LastWrite(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Expr, *api.LoadConfigToProc(&arg.Cfg), arg.StacktraceDepth, cb.SetupDoneChan())
+	if err != nil {
+		cb.Return(nil, err)
+		return
+	}
+	var out LastWriteOut
+	out.LastWrite = *lw
+	cb.Return(out, nil)
+}
+
 type IsMulticlientIn struct {
 }
 
@@ -1,6 +1,7 @@
 package service_test
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -12,12 +13,14 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/undoio/delve/pkg/proc/gdbserial/undotest"
 	protest "github.com/undoio/delve/pkg/proc/test"
 	"github.com/undoio/delve/service/debugger"
 
@@ -41,6 +44,9 @@ var normalLoadConfig = api.LoadConfig{
 var testBackend, buildMode string
 
 func TestMain(m *testing.M) {
+	if os.Getenv(undotest.ServerEnvVar) != "" {
+		os.Exit(undotest.ServerMain(os.Args[1:]))
+	}
 	flag.StringVar(&testBackend, "backend", "", "selects backend")
 	flag.StringVar(&buildMode, "test-buildmode", "", "selects build mode")
 	var logOutput string
@@ -61,7 +67,9 @@ func withTestClient2(name string, t *testing.T, fn func(c service.Client)) {
 	})
 }
 
-func startServer(name string, buildFlags protest.BuildFlags, t *testing.T, redirects [3]string, args []string) (clientConn net.Conn, fixture protest.Fixture) {
+// startServer starts a server debugging the fixture name, cfg is the
+// configuration of the debugger without the fields set by the helper.
+func startServer(name string, buildFlags protest.BuildFlags, t *testing.T, redirects [3]string, args []string, cfg debugger.Config) (clientConn net.Conn, fixture protest.Fixture) {
 	if testBackend == "rr" || testBackend == "undo" {
 		protest.MustHaveRecordingAllowed(t)
 	}
@@ -76,17 +84,18 @@ func startServer(name string, buildFlags protest.BuildFlags, t *testing.T, redir
 			redirects[i] = filepath.Join(fixture.BuildDir, redirects[i])
 		}
 	}
+	cfg.Backend = testBackend
+	cfg.CheckGoVersion = true
+	cfg.Packages = []string{fixture.Source}
+	cfg.BuildFlags = "" // build flags can be an empty string here because the only test that uses it, does not set special flags.
+	cfg.ExecuteKind = debugger.ExecutingGeneratedFile
+	cfg.Stdin = redirects[0]
+	cfg.Stdout = proc.OutputRedirect{Path: redirects[1]}
+	cfg.Stderr = proc.OutputRedirect{Path: redirects[2]}
 	server := rpccommon.NewServer(&service.Config{
 		Listener:    listener,
 		ProcessArgs: append([]string{fixture.Path}, args...),
-		Debugger: debugger.Config{
-			Backend:        testBackend,
-			CheckGoVersion: true,
-			Packages:       []string{fixture.Source},
-			BuildFlags:     "", // build flags can be an empty string here because the only test that uses it, does not set special flags.
-			ExecuteKind:    debugger.ExecutingGeneratedFile,
-			Redirects:      redirects,
-		},
+		Debugger:    cfg,
 	})
 	if err := server.Run(); err != nil {
 		t.Fatal(err)
@@ -95,7 +104,11 @@ func startServer(name string, buildFlags protest.BuildFlags, t *testing.T, redir
 }
 
 func withTestClient2Extended(name string, t *testing.T, buildFlags protest.BuildFlags, redirects [3]string, args []string, fn func(c service.Client, fixture protest.Fixture)) {
-	clientConn, fixture := startServer(name, buildFlags, t, redirects, args)
+	withTestClient2Config(name, t, buildFlags, redirects, args, debugger.Config{}, fn)
+}
+
+func withTestClient2Config(name string, t *testing.T, buildFlags protest.BuildFlags, redirects [3]string, args []string, cfg debugger.Config, fn func(c service.Client, fixture protest.Fixture)) {
+	clientConn, fixture := startServer(name, buildFlags, t, redirects, args, cfg)
 	client := rpc2.NewClientFromConn(clientConn)
 	defer func() {
 		client.Detach(true)
@@ -2240,7 +2253,7 @@ func (c *brokenRPCClient) call(method string, args, reply interface{}) error {
 }
 
 func TestUnknownMethodCall(t *testing.T) {
-	clientConn, _ := startServer("continuetestprog", 0, t, [3]string{}, nil)
+	clientConn, _ := startServer("continuetestprog", 0, t, [3]string{}, nil, debugger.Config{})
 	client := &brokenRPCClient{jsonrpc.NewClient(clientConn)}
 	client.call("SetApiVersion", api.SetAPIVersionIn{APIVersion: 2}, &api.SetAPIVersionOut{})
 	defer client.Detach(true)
@@ -3036,3 +3049,362 @@ func TestClientServer_breakpointOnFuncWithABIWrapper(t *testing.T) {
 		}
 	})
 }
+
+// withFakeUndoClient2 starts a server replaying a recording of fixture
+// name, with the execution history returned by history, using the fake
+// udbserver. Both history and fn receive the addresses of the symbols of
+// the fixture.
+func withFakeUndoClient2(name string, t *testing.T, history func(syms map[string]uint64) []undotest.Event, fn func(c service.Client, syms map[string]uint64)) {
+	fixture, recording, syms := fakeUndoRecording2(name, t, history)
+	client := startFakeUndoClient2(t, fixture, recording)
+	defer client.Detach(true)
+
+	fn(client, syms)
+}
+
+// fakeUndoRecording2 writes a scripted recording of fixture name for the
+// fake udbserver of package undotest. The history function receives the
+// ELF symbols of the fixture and returns the execution history to replay.
+func fakeUndoRecording2(name string, t *testing.T, history func(syms map[string]uint64) []undotest.Event) (protest.Fixture, string, map[string]uint64) {
+	fixture := protest.BuildFixture(name, 0)
+	recording, syms := undotest.SetupExe(t, fixture.Path, history)
+	return fixture, recording, syms
+}
+
+// startFakeUndoClient2 starts a headless instance replaying recording with
+// the undo backend and returns a client connected to it.
+func startFakeUndoClient2(t *testing.T, fixture protest.Fixture, recording string) service.Client {
+	listener, clientConn := service.ListenerPipe()
+	t.Cleanup(func() { listener.Close() })
+	server := rpccommon.NewServer(&service.Config{
+		Listener:    listener,
+		ProcessArgs: []string{fixture.Path},
+		Debugger: debugger.Config{
+			Backend:     "undo",
+			CoreFile:    recording,
+			ExecuteKind: debugger.ExecutingGeneratedFile,
+		},
+	})
+	if err := server.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return rpc2.NewClientFromConn(clientConn)
+}
+
+const (
+	fakeUndoUUID  = undotest.ExeUUID
+	fakeUndoTid   = undotest.ExePid
+	fakeUndoStack = undotest.ExeStack
+)
+
+func TestUndoLastWrite(t *testing.T) {
+	history := func(syms map[string]uint64) []undotest.Event {
+		return []undotest.Event{
+			{Bbcount: 100, Thread: fakeUndoTid, PC: syms["runtime.main"], Regs: map[string]uint64{"rsp": fakeUndoStack + 0x800}},
+			{Bbcount: 1000, Thread: fakeUndoTid, PC: syms["main.main"]},
+			{Bbcount: 2000, Thread: fakeUndoTid, PC: syms["main.f"], Writes: []undotest.Memory{
+				{Addr: syms["main.globalvar1"], Data: []byte{1, 0, 0, 0, 0, 0, 0, 0}},
+			}},
+			{Bbcount: 3000, Thread: fakeUndoTid, PC: syms["main.waitfunc"], Writes: []undotest.Memory{
+				{Addr: syms["main.globalvar2"], Data: []byte{5, 0, 0, 0, 0, 0, 0, 0}},
+			}},
+			{Bbcount: 4000, Thread: fakeUndoTid, PC: syms["runtime.main"]},
+		}
+	}
+	withFakeUndoClient2("databpeasy", t, history, func(c service.Client, syms map[string]uint64) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{Addr: syms["main.waitfunc"]})
+		assertNoError(err, t, "CreateBreakpoint()")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		lw, err := c.LastWrite(api.EvalScope{GoroutineID: -1}, "main.globalvar1", normalLoadConfig, 10)
+		assertNoError(err, t, "LastWrite()")
+		if !lw.Found {
+			t.Fatalf("write not found, stopped at %#x", lw.State.CurrentThread.PC)
+		}
+		if lw.State.CurrentThread.PC != syms["main.main"] {
+			t.Errorf("wrong position after LastWrite %#x (expected %#x)", lw.State.CurrentThread.PC, syms["main.main"])
+		}
+		if lw.OldValue.Value != "0" || lw.NewValue.Value != "1" {
+			t.Errorf("wrong values old=%s new=%s", lw.OldValue.Value, lw.NewValue.Value)
+		}
+		if len(lw.Stacktrace) == 0 || lw.Stacktrace[0].Function == nil || lw.Stacktrace[0].Function.Name() != "main.main" {
+			t.Errorf("wrong stacktrace %v", lw.Stacktrace)
+		}
+
+		// The temporary watchpoint is cleared and the direction of execution
+		// is restored.
+		bps, err := c.ListBreakpoints(false)
+		assertNoError(err, t, "ListBreakpoints()")
+		for _, bp := range bps {
+			if bp.WatchExpr != "" {
+				t.Errorf("watchpoint not cleared: %#v", bp)
+			}
+		}
+		state = <-c.DirectionCongruentContinue()
+		assertNoError(state.Err, t, "DirectionCongruentContinue()")
+		if state.CurrentThread.PC != syms["main.waitfunc"] {
+			t.Errorf("wrong position after continue %#x (expected %#x)", state.CurrentThread.PC, syms["main.waitfunc"])
+		}
+
+		lw, err = c.LastWrite(api.EvalScope{GoroutineID: -1}, "main.globalvar2", normalLoadConfig, 10)
+		assertNoError(err, t, "LastWrite()")
+		if !lw.Found || lw.State.CurrentThread.PC != syms["main.f"] {
+			t.Fatalf("wrong position after LastWrite %#x (found %v)", lw.State.CurrentThread.PC, lw.Found)
+		}
+
+		// There are no earlier writes, the start of the recording is reached.
+		lw, err = c.LastWrite(api.EvalScope{GoroutineID: -1}, "main.globalvar2", normalLoadConfig, 10)
+		assertNoError(err, t, "LastWrite()")
+		if lw.Found || lw.State.CurrentThread.PC != syms["runtime.main"] {
+			t.Errorf("expected to stop at the start of the recording, stopped at %#x (found %v)", lw.State.CurrentThread.PC, lw.Found)
+		}
+
+		// The temporary watchpoints did not use up any breakpoint ID.
+		bp, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.f"})
+		assertNoError(err, t, "CreateBreakpoint()")
+		if bp.ID != 2 {
+			t.Errorf("wrong breakpoint ID %d (expected 2)", bp.ID)
+		}
+	})
+}
+
+func TestUndoSessionBreakpoints(t *testing.T) {
+	history := func(syms map[string]uint64) []undotest.Event {
+		return []undotest.Event{
+			{Bbcount: 100, Thread: fakeUndoTid, PC: syms["runtime.main"], Regs: map[string]uint64{"rsp": fakeUndoStack + 0x800}},
+			{Bbcount: 1000, Thread: fakeUndoTid, PC: syms["main.main"]},
+		}
+	}
+	fixture, recording, _ := fakeUndoRecording2("databpeasy", t, history)
+
+	// Session file written by UDB: the location of breakpoint 1 is a C
+	// function that does not exist, breakpoint 4 has a condition that Delve
+	// can not parse and breakpoint 5 uses a path relative to the source
+	// directory.
+	sessionPath := filepath.Join(os.Getenv("XDG_DATA_HOME"), "undo", "sessions", fakeUndoUUID+".json")
+	assertNoError(os.MkdirAll(filepath.Dir(sessionPath), 0755), t, "MkdirAll")
+	session := fmt.Sprintf(`{
+		"version": 1,
+		"bookmarks": {},
+		"breakpoints": [
+			{"number": 1, "location": "sqlite3_step", "enabled": true},
+			{"number": 2, "location": "%s:40", "enabled": true},
+			{"number": 3, "location": "main.waitfunc", "condition": "main.globalvar1 == 1", "enabled": false, "ignore_count": 2},
+			{"number": 4, "location": "frobnicate.c:12", "condition": "p->len > 3", "enabled": true},
+			{"number": 5, "location": "databpeasy.go:46", "enabled": true}
+		],
+		"breakpoints_max": 5,
+		"telemetry_id": "abcd"
+	}`, fixture.Source)
+	assertNoError(ioutil.WriteFile(sessionPath, []byte(session), 0644), t, "WriteFile")
+
+	c := startFakeUndoClient2(t, fixture, recording)
+	bps, err := c.ListBreakpoints(false)
+	assertNoError(err, t, "ListBreakpoints()")
+	found := map[int]*api.Breakpoint{}
+	for _, bp := range bps {
+		found[bp.ID] = bp
+	}
+	if bp := found[2]; bp == nil || bp.File != fixture.Source || bp.Line != 40 || bp.Disabled {
+		t.Errorf("wrong breakpoint 2 %#v", bp)
+	}
+	if bp := found[3]; bp == nil || bp.FunctionName != "main.waitfunc" || bp.Cond != "main.globalvar1 == 1" || !bp.Disabled {
+		t.Errorf("wrong breakpoint 3 %#v", bp)
+	}
+	if found[1] != nil {
+		t.Errorf("breakpoint on a missing function created %#v", found[1])
+	}
+	if found[4] != nil {
+		t.Errorf("breakpoint with unparsable condition created %#v", found[4])
+	}
+	if bp := found[5]; bp == nil || bp.File != fixture.Source || bp.Line != 46 || bp.FunctionName != "main.waitfunc" || bp.Disabled {
+		t.Errorf("wrong breakpoint 5 %#v", bp)
+	}
+
+	bp, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.f"})
+	assertNoError(err, t, "CreateBreakpoint()")
+	if bp.ID != 6 {
+		t.Errorf("wrong ID for new breakpoint %d (expected 6)", bp.ID)
+	}
+	_, err = c.ClearBreakpoint(2)
+	assertNoError(err, t, "ClearBreakpoint()")
+	assertNoError(c.Detach(true), t, "Detach()")
+
+	buf, err := ioutil.ReadFile(sessionPath)
+	assertNoError(err, t, "ReadFile")
+	var saved struct {
+		Version     int `json:"version"`
+		Breakpoints []struct {
+			Number      int    `json:"number"`
+			Location    string `json:"location"`
+			Condition   string `json:"condition"`
+			Enabled     bool   `json:"enabled"`
+			IgnoreCount int    `json:"ignore_count"`
+		} `json:"breakpoints"`
+		BreakpointsMax int    `json:"breakpoints_max"`
+		TelemetryId    string `json:"telemetry_id"`
+	}
+	assertNoError(json.Unmarshal(buf, &saved), t, "Unmarshal")
+	if saved.Version != 1 || saved.TelemetryId != "abcd" || saved.BreakpointsMax != 6 {
+		t.Errorf("wrong session file:\n%s", buf)
+	}
+	if len(saved.Breakpoints) != 5 {
+		t.Fatalf("wrong breakpoints in session file:\n%s", buf)
+	}
+	if sbp := saved.Breakpoints[0]; sbp.Number != 1 || sbp.Location != "sqlite3_step" || !sbp.Enabled {
+		t.Errorf("wrong breakpoint 1 in session file %#v", sbp)
+	}
+	saved.Breakpoints = saved.Breakpoints[1:]
+	if sbp := saved.Breakpoints[0]; sbp.Number != 3 || sbp.Location != "main.waitfunc" || sbp.Condition != "main.globalvar1 == 1" || sbp.Enabled || sbp.IgnoreCount != 2 {
+		t.Errorf("wrong breakpoint 3 in session file %#v", sbp)
+	}
+	if sbp := saved.Breakpoints[1]; sbp.Number != 4 || sbp.Location != "frobnicate.c:12" || sbp.Condition != "p->len > 3" || !sbp.Enabled {
+		t.Errorf("wrong breakpoint 4 in session file %#v", sbp)
+	}
+	if sbp := saved.Breakpoints[2]; sbp.Number != 5 || sbp.Location != "databpeasy.go:46" || !sbp.Enabled {
+		t.Errorf("wrong breakpoint 5 in session file %#v", sbp)
+	}
+	if sbp := saved.Breakpoints[3]; sbp.Number != 6 || sbp.Location != "main.f" || !sbp.Enabled {
+		t.Errorf("wrong breakpoint 6 in session file %#v", sbp)
+	}
+}
+
+func TestUndoAttach(t *testing.T) {
+	history := func(syms map[string]uint64) []undotest.Event {
+		return []undotest.Event{
+			{Bbcount: 100, Thread: fakeUndoTid, PC: syms["runtime.main"], Regs: map[string]uint64{"rsp": fakeUndoStack + 0x800}},
+			{Bbcount: 1000, Thread: fakeUndoTid, PC: syms["main.main"]},
+		}
+	}
+	fixture, _, _ := fakeUndoRecording2("databpeasy", t, history)
+
+	// Breakpoints saved in the session file are restored once the recording
+	// is replayed.
+	sessionPath := filepath.Join(os.Getenv("XDG_DATA_HOME"), "undo", "sessions", fakeUndoUUID+".json")
+	assertNoError(os.MkdirAll(filepath.Dir(sessionPath), 0755), t, "MkdirAll")
+	session := `{"version": 1, "bookmarks": {}, "breakpoints": [{"number": 1, "location": "main.f", "enabled": true}], "breakpoints_max": 1}`
+	assertNoError(ioutil.WriteFile(sessionPath, []byte(session), 0644), t, "WriteFile")
+
+	cmd := exec.Command("sleep", "60")
+	assertNoError(cmd.Start(), t, "starting sleep")
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	listener, clientConn := service.ListenerPipe()
+	defer listener.Close()
+	server := rpccommon.NewServer(&service.Config{
+		Listener:    listener,
+		ProcessArgs: []string{fixture.Path},
+		Debugger: debugger.Config{
+			Backend:   "undo",
+			AttachPid: cmd.Process.Pid,
+		},
+	})
+	if err := server.Run(); err != nil {
+		t.Fatal(err)
+	}
+	c := rpc2.NewClientFromConn(clientConn)
+
+	state, err := c.GetStateNonBlocking()
+	assertNoError(err, t, "GetStateNonBlocking()")
+	if !state.Recording {
+		t.Fatal("not recording after attach")
+	}
+	time.Sleep(time.Second) // hopefully live-record started...
+	assertNoError(c.StopRecording(), t, "StopRecording()")
+
+	state, err = c.GetState()
+	assertNoError(err, t, "GetState()")
+	if state.Recording {
+		t.Error("still recording after StopRecording")
+	}
+	if !c.Recorded() {
+		t.Error("not replaying a recording after StopRecording")
+	}
+	bps, err := c.ListBreakpoints(false)
+	assertNoError(err, t, "ListBreakpoints()")
+	found := false
+	for _, bp := range bps {
+		if bp.ID == 1 && bp.FunctionName == "main.f" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("breakpoint saved in the session file not restored")
+	}
+	recording, err := c.TraceDirectory()
+	assertNoError(err, t, "TraceDirectory()")
+	defer os.Remove(recording)
+	assertNoError(c.Detach(true), t, "Detach()")
+}
+
+func TestPrettyPrinters(t *testing.T) {
+	protest.AllowRecording(t)
+	cfg := debugger.Config{
+		PrettyPrinters: []proc.PrettyPrinter{
+			{Type: "main.ID", Expr: "v.prefix"},
+			{TypeRegexp: regexp.MustCompile(`^main\.Money$`), Expr: "v.cents"},
+		},
+	}
+	withTestClient2Config("prettyprinters", t, 0, [3]string{}, nil, cfg, func(c service.Client, fixture protest.Fixture) {
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		acct, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "acct", normalLoadConfig)
+		assertNoError(err, t, "EvalVariable(acct)")
+		if acct.Display != "" || acct.Children[0].Display != "acct" || acct.Children[1].Display != "5" {
+			t.Errorf("wrong display fields for acct: %q %q %q", acct.Display, acct.Children[0].Display, acct.Children[1].Display)
+		}
+		if acct.Children[0].Children[1].Value != "7" {
+			t.Errorf("value of acct.id changed: %#v", acct.Children[0])
+		}
+
+		locals, err := c.ListLocalVariables(api.EvalScope{GoroutineID: -1}, normalLoadConfig)
+		assertNoError(err, t, "ListLocalVariables()")
+		for _, v := range locals {
+			if v.Name == "id" && v.SinglelineString() != "main.ID(acct)" {
+				t.Errorf("wrong value for id: %s", v.SinglelineString())
+			}
+		}
+	})
+}
+
+func TestHeapReferences(t *testing.T) {
+	withTestClient2("heaprefs", t, func(c service.Client) {
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		headv, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "head", normalLoadConfig)
+		assertNoError(err, t, "EvalVariable(head)")
+		refs, err := c.HeapReferences(api.EvalScope{GoroutineID: -1}, "head.next")
+		assertNoError(err, t, "HeapReferences(head.next)")
+		found := false
+		for _, chain := range refs.Chains {
+			if chain.Root.Name != "main.head" {
+				continue
+			}
+			found = true
+			if chain.Root.Kind != "global" || len(chain.Links) != 1 || chain.Links[0].Object.Addr != headv.Children[0].Addr {
+				t.Errorf("wrong reference chain from main.head: %#v", chain)
+			}
+		}
+		if !found {
+			t.Errorf("no reference chain from main.head: %#v", refs.Chains)
+		}
+
+		refs, err = c.HeapReferences(api.EvalScope{GoroutineID: -1}, "leaf")
+		assertNoError(err, t, "HeapReferences(leaf)")
+		found = false
+		for _, chain := range refs.Chains {
+			if chain.Root.Name == "leaf" && chain.Root.Kind == "stack" && chain.Root.Function != nil && chain.Root.Function.Name() == "main.main" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("no reference chain from the local variable leaf: %#v", refs.Chains)
+		}
+	})
+}